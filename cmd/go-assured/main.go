@@ -2,23 +2,28 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/jesse0michael/go-rest-assured/v4/pkg/assured"
 )
 
-// Preload is the expected format for preloading assured endpoints through the go rest assured application
-type Preload struct {
-	Calls []assured.Call `json:"calls"`
-}
-
 func main() {
+	// Lint preload files without starting a server, e.g. go-assured lint ./stubs
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(lint(os.Args[2:]))
+	}
+
 	ctx, cancel := context.WithCancelCause(context.Background())
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
@@ -26,20 +31,79 @@ func main() {
 		cancel(fmt.Errorf("%s", <-sig))
 	}()
 
-	port := flag.Int("port", 0, "a port to listen on. default automatically assigns a port.")
-	preload := flag.String("preload", "", "a file to parse preloaded calls from.")
-	trackMade := flag.Bool("track", true, "a flag to enable the storing of calls made to the service.")
-	host := flag.String("host", "localhost", "a host to use in the client's url.")
-	tlsCert := flag.String("tlsCert", "", "location of tls cert for serving https traffic. tlsKey also required, if specified.")
-	tlsKey := flag.String("tlsKey", "", "location of tls key for serving https traffic. tlsCert also required, if specified")
+	// Log to stdout so container runtimes collect the logs
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	port := flag.Int("port", envInt("ASSURED_PORT", 0), "a port to listen on. default automatically assigns a port.")
+	portFile := flag.String("portFile", envString("ASSURED_PORT_FILE", ""), "a file to write the listening port to.")
+	preload := flag.String("preload", envString("ASSURED_PRELOAD", ""), "a file, or directory of files, to parse preloaded calls from.")
+	trackMade := flag.Bool("track", envBool("ASSURED_TRACK", true), "a flag to enable the storing of calls made to the service.")
+	journalTTL := flag.Duration("journalTTL", envDuration("ASSURED_JOURNAL_TTL", 0), "how long to keep calls made to the service before purging them. default keeps them forever.")
+	host := flag.String("host", envString("ASSURED_HOST", "localhost"), "a host to use in the client's url.")
+	basePath := flag.String("basePath", envString("ASSURED_BASE_PATH", ""), "a path prefix to serve the rest assured endpoints under.")
+	root := flag.Bool("root", envBool("ASSURED_ROOT", false), "a flag to serve stubbed endpoints at the root path, without the /when prefix.")
+	latency := flag.Duration("latency", envDuration("ASSURED_LATENCY", 0), "a network latency to simulate for every stubbed call, including unmatched calls.")
+	maxBodySize := flag.Int("maxBodySize", envInt("ASSURED_MAX_BODY_SIZE", 0), "the size in bytes of the largest request body accepted by stubbed calls, responding 413 to larger bodies. default disables the limit.")
+	maxHeaderSize := flag.Int("maxHeaderSize", envInt("ASSURED_MAX_HEADER_SIZE", 0), "the size in bytes of the largest request headers accepted by stubbed calls, responding 431 to larger headers. default disables the limit.")
+	maxURILength := flag.Int("maxURILength", envInt("ASSURED_MAX_URI_LENGTH", 0), "the length in bytes of the longest request uri accepted by stubbed calls, responding 414 to longer uris. default disables the limit.")
+	watch := flag.Duration("watch", envDuration("ASSURED_WATCH", 0), "an interval to poll the preload file for changes and reload the calls. default disables watching.")
+	pprof := flag.Bool("pprof", envBool("ASSURED_PPROF", false), "a flag to serve the pprof profiling endpoints under /debug/pprof.")
+	s3Buckets := flag.String("s3Buckets", envString("ASSURED_S3_BUCKETS", ""), "a comma separated list of buckets to mock with s3 object storage semantics.")
+	counterFile := flag.String("counterFile", envString("ASSURED_COUNTER_FILE", ""), "a file to persist the counters of responses' counter placeholders to, so they continue across restarts.")
+	tusEndpoints := flag.String("tusEndpoints", envString("ASSURED_TUS_ENDPOINTS", ""), "a comma separated list of upload endpoint paths to mock with tus resumable upload semantics.")
+	stubHistory := flag.Int("stubHistory", envInt("ASSURED_STUB_HISTORY", 10), "the number of stub set revisions to keep for rolling back with /stubs/rollback.")
+	rawURI := flag.Bool("rawURI", envBool("ASSURED_RAW_URI", false), "a flag to capture the raw request uri of the calls made to the service.")
+	inferContentType := flag.Bool("inferContentType", envBool("ASSURED_INFER_CONTENT_TYPE", true), "a flag to set the Content-Type of stubbed responses that look like json or xml, if they aren't stubbed with one.")
+	plain := flag.Bool("plain", envBool("ASSURED_PLAIN", false), "a flag to serve the rest assured endpoints with plain net/http handlers instead of go-kit.")
+	readTimeout := flag.Duration("readTimeout", envDuration("ASSURED_READ_TIMEOUT", 0), "a timeout for reading requests. default disables the timeout.")
+	writeTimeout := flag.Duration("writeTimeout", envDuration("ASSURED_WRITE_TIMEOUT", 0), "a timeout for writing responses, including stubbed delays. default disables the timeout.")
+	idleTimeout := flag.Duration("idleTimeout", envDuration("ASSURED_IDLE_TIMEOUT", 0), "how long to keep idle keep-alive connections open. default keeps them open until the read timeout.")
+	tlsCert := flag.String("tlsCert", envString("ASSURED_TLS_CERT", ""), "location of tls cert for serving https traffic. tlsKey also required, if specified.")
+	tlsKey := flag.String("tlsKey", envString("ASSURED_TLS_KEY", ""), "location of tls key for serving https traffic. tlsCert also required, if specified")
+	validateResponses := flag.String("validateResponses", envString("ASSURED_VALIDATE_RESPONSES", ""), "a mode to check stubbed json and xml responses parse as their content type as they're served: warn logs invalid responses, strict responds 500 in their place. default disables the check.")
+	errorFormat := flag.String("errorFormat", envString("ASSURED_ERROR_FORMAT", "text"), "the format of the error responses, to match the upstream being mocked: text, json, problem, or xml.")
+	tlsFault := flag.String("tlsFault", envString("ASSURED_TLS_FAULT", ""), "a fault to break the tls handshake with: wrong-host, expired, or version. serves https traffic, if specified.")
+	autoTLS := flag.Bool("autoTLS", envBool("ASSURED_AUTO_TLS", false), "a flag to serve https traffic with generated certificates, signed by a generated certificate authority served at /certificate.")
+	certLifetime := flag.Duration("certLifetime", envDuration("ASSURED_CERT_LIFETIME", 0), "how long each autoTLS certificate is valid for. default is 24 hours.")
+	certRotation := flag.Duration("certRotation", envDuration("ASSURED_CERT_ROTATION", 0), "an interval to rotate the autoTLS certificate at. default disables rotating.")
 
 	flag.Parse()
 
-	client := assured.NewClient(
+	opts := []assured.Option{
 		assured.WithPort(*port),
+		assured.WithPortFile(*portFile),
 		assured.WithCallTracking(*trackMade),
+		assured.WithJournalTTL(*journalTTL),
+		assured.WithLatency(*latency),
+		assured.WithMaxBodySize(int64(*maxBodySize)),
+		assured.WithMaxHeaderSize(*maxHeaderSize),
+		assured.WithMaxURILength(*maxURILength),
 		assured.WithHost(*host),
-		assured.WithTLS(*tlsCert, *tlsKey))
+		assured.WithBasePath(*basePath),
+		assured.WithRootServing(*root),
+		assured.WithPprof(*pprof),
+		assured.WithPlainHandlers(*plain),
+		assured.WithContentTypeInference(*inferContentType),
+		assured.WithRawURI(*rawURI),
+		assured.WithCounterFile(*counterFile),
+		assured.WithStubHistory(*stubHistory),
+		assured.WithS3Buckets(splitList(*s3Buckets)...),
+		assured.WithTusEndpoints(splitList(*tusEndpoints)...),
+		assured.WithServerTimeouts(*readTimeout, *writeTimeout, *idleTimeout),
+		assured.WithTLS(*tlsCert, *tlsKey),
+		assured.WithResponseValidation(assured.ResponseValidation(*validateResponses)),
+		assured.WithErrorFormat(assured.ErrorFormat(*errorFormat)),
+		assured.WithTLSFault(assured.TLSFault(*tlsFault)),
+	}
+	if *autoTLS {
+		opts = append(opts, assured.WithAutoTLS(*certLifetime, *certRotation))
+	}
+
+	client, err := assured.NewClientE(opts...)
+	if err != nil {
+		slog.With("error", err).Error("failed to create go rest assured client")
+		os.Exit(1)
+	}
 
 	go func() {
 		slog.With("port", client.Port).Info("starting go rest assured client")
@@ -50,24 +114,157 @@ func main() {
 
 	// If preload file specified, parse the file and load all calls into the assured client
 	if *preload != "" {
-		b, err := os.ReadFile(*preload)
+		// Handle SIGHUP before the initial load, so a reload signaled while loading doesn't terminate the process
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+
+		checksum := preloadChecksum(*preload)
+		calls, err := loadPreload(client, *preload, nil)
 		if err != nil {
-			slog.With("error", err).Info("failed to read preload file")
-			cancel(err)
-		}
-		var preload Preload
-		// TODO response won't unmarshal string to []byte
-		if err := json.Unmarshal(b, &preload); err != nil {
-			slog.With("error", err).Info("failed to unmarshal preload file")
-			cancel(err)
-		}
-		if err = client.Given(preload.Calls...); err != nil {
-			slog.With("error", err).Info("failed to set given preload file calls")
 			cancel(err)
 		}
+		go watchPreload(ctx, client, *preload, *watch, hup, checksum, calls)
 	}
 
 	<-ctx.Done()
 	client.Close()
 	slog.Info("exiting go rest assured")
 }
+
+// lint validates the preload files and reports every problem found
+func lint(paths []string) int {
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: go-assured lint <preload file or directory>...")
+		return 2
+	}
+
+	code := 0
+	for _, path := range paths {
+		if err := assured.ValidateStubs(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// loadPreload parses the preload file and loads all calls into the assured client
+// Reloading replaces all of the previous calls stubbed on the assured client, the server keeps serving traffic while reloading
+// The loaded calls are logged with a summary, and the differences from the previous calls when reloading
+func loadPreload(client *assured.Client, path string, previous []assured.Call) ([]assured.Call, error) {
+	reload := previous != nil
+	calls, err := assured.LoadPreload(path)
+	if err != nil {
+		slog.With("error", err).Info("failed to read preload file")
+		return previous, err
+	}
+	if reload {
+		// Replace the calls as one stub set revision, so a broken preload file can be rolled back
+		if err = client.Replace(calls...); err != nil {
+			slog.With("error", err).Info("failed to replace calls for preload reload")
+			return previous, err
+		}
+	} else if err = client.Given(calls...); err != nil {
+		slog.With("error", err).Info("failed to set given preload file calls")
+		return previous, err
+	}
+
+	logger := slog.With("path", path, "calls", len(calls), "paths", assured.CountPaths(calls), "reload", reload)
+	if reload {
+		diff := assured.DiffCalls(previous, calls)
+		logger = logger.With("added", diff.Added, "removed", diff.Removed, "changed", diff.Changed)
+	}
+	logger.Info(fmt.Sprintf("loaded %d calls across %d paths from preload file", len(calls), assured.CountPaths(calls)))
+	return calls, nil
+}
+
+// watchPreload reloads the preload file on SIGHUP, or when the preload file changes if a watch interval is set
+// Mounted Kubernetes ConfigMaps are updated by swapping a symlink, so the contents are polled rather than the file events
+// The checksum only advances once the preload file loads, so a file that fails to load is retried until it is fixed
+func watchPreload(ctx context.Context, client *assured.Client, path string, interval time.Duration, hup <-chan os.Signal, checksum string, calls []assured.Call) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-tick:
+			if preloadChecksum(path) == checksum {
+				continue
+			}
+		}
+		// Hash the preload file before loading it, so a change made while loading is reloaded on the next tick
+		current := preloadChecksum(path)
+		var err error
+		if calls, err = loadPreload(client, path, calls); err == nil {
+			checksum = current
+		}
+	}
+}
+
+// preloadChecksum hashes the contents of the preload file, or directory of files
+func preloadChecksum(path string) string {
+	files := []string{path}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		files, _ = filepath.Glob(filepath.Join(path, "*.json"))
+	}
+
+	hash := sha256.New()
+	for _, file := range files {
+		if b, err := os.ReadFile(file); err == nil {
+			_, _ = hash.Write([]byte(file))
+			_, _ = hash.Write(b)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// splitList splits a comma separated list, without its empty values
+func splitList(list string) []string {
+	values := []string{}
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// envString returns the environment variable value for the key, or the fallback if it is not set
+func envString(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// envInt returns the environment variable value for the key as an int, or the fallback if it is not set or invalid
+func envInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// envDuration returns the environment variable value for the key as a duration, or the fallback if it is not set or invalid
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// envBool returns the environment variable value for the key as a bool, or the fallback if it is not set or invalid
+func envBool(key string, fallback bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
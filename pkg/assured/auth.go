@@ -0,0 +1,148 @@
+package assured
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// The authentication schemes of an Authentication
+const (
+	AuthBasic  = "Basic"
+	AuthBearer = "Bearer"
+	AuthDigest = "Digest"
+)
+
+// Authentication is a structure containing the credentials a request must authenticate with, in the Authorization header, by the scheme
+// Basic requires the username and password, Bearer requires the token, and Digest requires the username and password,
+// hashed with the algorithm, MD5 or SHA-256, the realm, and the nonce of the challenge
+type Authentication struct {
+	Scheme    string `json:"scheme"`
+	Realm     string `json:"realm,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+	Token     string `json:"token,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Nonce     string `json:"nonce,omitempty"`
+}
+
+// BasicAuthCall returns the stubbed call challenging requests without the username and password with 401 Unauthorized
+func BasicAuthCall(call Call, realm, username, password string) Call {
+	return AuthChallengeCall(call, Authentication{Scheme: AuthBasic, Realm: realm, Username: username, Password: password})
+}
+
+// BearerAuthCall returns the stubbed call challenging requests without the bearer token with 401 Unauthorized
+func BearerAuthCall(call Call, realm, token string) Call {
+	return AuthChallengeCall(call, Authentication{Scheme: AuthBearer, Realm: realm, Token: token})
+}
+
+// DigestAuthCall returns the stubbed call challenging requests without an MD5 digest of the username and password with 401 Unauthorized
+func DigestAuthCall(call Call, realm, username, password string) Call {
+	return AuthChallengeCall(call, Authentication{Scheme: AuthDigest, Realm: realm, Username: username, Password: password})
+}
+
+// AuthChallengeCall returns the stubbed call responding 401 Unauthorized with the WWW-Authenticate challenge of the authentication
+// to requests without its credentials, so a client retrying with the credentials is responded with the call
+// A Digest authentication without a nonce is challenged with a random nonce
+func AuthChallengeCall(call Call, auth Authentication) Call {
+	if strings.EqualFold(auth.Scheme, AuthDigest) && auth.Nonce == "" {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		auth.Nonce = hex.EncodeToString(b)
+	}
+	call.Branches = append([]Branch{{
+		When:       Condition{Unauthenticated: &auth},
+		StatusCode: http.StatusUnauthorized,
+		Headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8", "WWW-Authenticate": auth.Challenge()},
+		Response:   []byte(http.StatusText(http.StatusUnauthorized)),
+	}}, call.Branches...)
+	return call
+}
+
+// Challenge returns the WWW-Authenticate header challenging a request for the authentication's credentials
+func (a Authentication) Challenge() string {
+	challenge := fmt.Sprintf("%s realm=%q", a.Scheme, a.Realm)
+	if strings.EqualFold(a.Scheme, AuthDigest) {
+		challenge += fmt.Sprintf(", qop=\"auth\", algorithm=%s, nonce=%q", a.algorithm(), a.Nonce)
+	}
+	return challenge
+}
+
+// Authenticates checks if the call made has the authentication's credentials in its Authorization header
+func (a Authentication) Authenticates(call *Call) bool {
+	scheme, credentials, _ := strings.Cut(call.Headers["Authorization"], " ")
+	if !strings.EqualFold(scheme, a.Scheme) {
+		return false
+	}
+	switch {
+	case strings.EqualFold(a.Scheme, AuthBasic):
+		return credentials == base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+	case strings.EqualFold(a.Scheme, AuthBearer):
+		return credentials == a.Token
+	case strings.EqualFold(a.Scheme, AuthDigest):
+		return a.digested(call.Method, authParams(credentials))
+	}
+	return false
+}
+
+// digested checks if the digest authorization's response is the digest of the authentication's credentials, RFC 7616
+func (a Authentication) digested(method string, params map[string]string) bool {
+	if params["username"] != a.Username || params["realm"] != a.Realm || params["nonce"] != a.Nonce {
+		return false
+	}
+	if algorithm := params["algorithm"]; algorithm != "" && !strings.EqualFold(algorithm, a.algorithm()) {
+		return false
+	}
+	digest := func(values ...string) string {
+		var h hash.Hash = md5.New()
+		if strings.EqualFold(a.algorithm(), "SHA-256") {
+			h = sha256.New()
+		}
+		h.Write([]byte(strings.Join(values, ":")))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+	ha1 := digest(a.Username, a.Realm, a.Password)
+	ha2 := digest(method, params["uri"])
+	if qop := params["qop"]; qop != "" {
+		return params["response"] == digest(ha1, params["nonce"], params["nc"], params["cnonce"], qop, ha2)
+	}
+	return params["response"] == digest(ha1, params["nonce"], ha2)
+}
+
+// algorithm returns the digest algorithm of the authentication, MD5 by default
+func (a Authentication) algorithm() string {
+	if a.Algorithm == "" {
+		return "MD5"
+	}
+	return a.Algorithm
+}
+
+// authParams parses the comma separated, optionally quoted, key=value parameters of an authorization header's credentials
+func authParams(credentials string) map[string]string {
+	params := map[string]string{}
+	for credentials != "" {
+		key, rest, ok := strings.Cut(strings.TrimLeft(credentials, " ,"), "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		credentials = rest
+	}
+	return params
+}
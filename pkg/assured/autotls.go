@@ -0,0 +1,172 @@
+package assured
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultCertLifetime is how long the auto TLS certificates are valid for, if no lifetime is set
+const defaultCertLifetime = 24 * time.Hour
+
+// autoCertificates are the certificates served by the auto TLS mode, signed by their own certificate authority
+// so clients that trust the authority keep trusting the certificate as it is rotated
+// A certificate is generated for each SNI server name, so each impersonated host is served its own certificate
+type autoCertificates struct {
+	authority *tls.Certificate
+	lifetime  time.Duration
+	sync.RWMutex
+	current *tls.Certificate
+	hosts   map[string]*tls.Certificate
+}
+
+// newAutoCertificates creates a certificate authority and the first certificate it signs, valid for the lifetime
+func newAutoCertificates(lifetime time.Duration) (*autoCertificates, error) {
+	if lifetime <= 0 {
+		lifetime = defaultCertLifetime
+	}
+	authority, err := signCertificate(&x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{Organization: []string{"go-rest-assured"}, CommonName: "go-rest-assured auto tls"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	a := &autoCertificates{authority: authority, lifetime: lifetime}
+	return a, a.rotate()
+}
+
+// rotate replaces the served certificates with a new certificate, valid for the lifetime from now
+// The certificates of the SNI server names are generated again on their next handshake
+func (a *autoCertificates) rotate() error {
+	cert, err := signCertificate(serverCertificateTemplate(time.Now().Add(a.lifetime)), a.authority)
+	if err != nil {
+		return err
+	}
+	a.Lock()
+	defer a.Unlock()
+	a.current = cert
+	a.hosts = map[string]*tls.Certificate{}
+	return nil
+}
+
+// rotateEvery rotates the served certificate every interval, until the context is done
+func (a *autoCertificates) rotateEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.rotate(); err != nil {
+				slog.With("error", err).Error("failed to rotate auto tls certificate")
+			}
+		}
+	}
+}
+
+// getCertificate returns the currently served certificate for each TLS handshake
+// If the client sent an SNI server name, the certificate for that host is returned, generating it if needed, which expires with the current certificate
+func (a *autoCertificates) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	a.RLock()
+	current := a.current
+	if hello == nil || hello.ServerName == "" {
+		a.RUnlock()
+		return current, nil
+	}
+	host, ok := a.hosts[hello.ServerName]
+	a.RUnlock()
+	if ok {
+		return host, nil
+	}
+
+	template := serverCertificateTemplate(current.Leaf.NotAfter)
+	template.DNSNames = []string{hello.ServerName}
+	template.IPAddresses = nil
+	host, err := signCertificate(template, a.authority)
+	if err != nil {
+		return nil, err
+	}
+	a.Lock()
+	defer a.Unlock()
+	// Keep the certificate generated by a concurrent handshake, and don't cache a certificate that was rotated while generating
+	if existing, ok := a.hosts[hello.ServerName]; ok {
+		return existing, nil
+	}
+	if a.current == current {
+		a.hosts[hello.ServerName] = host
+	}
+	return host, nil
+}
+
+// rootCAs returns a cert pool of the certificate authority
+func (a *autoCertificates) rootCAs() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(a.authority.Leaf)
+	return pool
+}
+
+// authorityPEM returns the PEM encoded certificate authority
+func (a *autoCertificates) authorityPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: a.authority.Certificate[0]})
+}
+
+// certificateHandler serves the PEM encoded certificate authority of the auto TLS certificates, so clients of a standalone server can trust it
+func certificateHandler(a *autoCertificates) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if a == nil {
+			http.Error(w, "auto tls is not enabled", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		_, _ = w.Write(a.authorityPEM())
+	}
+}
+
+// RotateCertificate replaces the certificate served by the auto TLS mode with a new certificate, valid for the certificate lifetime from now
+func (c *Client) RotateCertificate() error {
+	if c.autoCerts == nil {
+		return errors.New("auto tls is not enabled")
+	}
+	return c.autoCerts.rotate()
+}
+
+// RootCAs returns a cert pool of the certificate authority that signs the certificates served by the auto TLS mode,
+// or nil if auto TLS is not enabled
+func (c *Client) RootCAs() *x509.CertPool {
+	if c.autoCerts == nil {
+		return nil
+	}
+	return c.autoCerts.rootCAs()
+}
+
+// CACertPEM returns the PEM encoded certificate authority that signs the certificates served by the auto TLS mode,
+// such as for writing a CA bundle file, or nil if auto TLS is not enabled
+func (c *Client) CACertPEM() []byte {
+	if c.autoCerts == nil {
+		return nil
+	}
+	return c.autoCerts.authorityPEM()
+}
+
+// TLSTransport returns a new http transport that trusts the certificates served by the auto TLS mode,
+// so HTTPS tests don't need to skip verifying them. The transport trusts the system roots, if auto TLS is not enabled
+func (c *Client) TLSTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: c.RootCAs()}
+	return transport
+}
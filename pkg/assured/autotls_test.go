@@ -0,0 +1,164 @@
+package assured
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientAutoTLS(t *testing.T) {
+	_, client := NewTestTLSServer(t, WithAutoTLS(time.Hour, 0))
+	require.NoError(t, client.Given(Call{Path: "secure/assured"}))
+
+	trusted := autoTLSClient(client.RootCAs())
+	resp, err := trusted.Get(client.URL() + "/secure/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	first := resp.TLS.PeerCertificates[0]
+	require.WithinDuration(t, time.Now().Add(time.Hour), first.NotAfter, time.Minute)
+
+	require.NoError(t, client.RotateCertificate())
+	trusted.CloseIdleConnections()
+	resp, err = trusted.Get(client.URL() + "/secure/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotEqual(t, first.SerialNumber, resp.TLS.PeerCertificates[0].SerialNumber)
+	require.Equal(t, first.Issuer, resp.TLS.PeerCertificates[0].Issuer)
+}
+
+func TestClientAutoTLSServerNames(t *testing.T) {
+	_, client := NewTestTLSServer(t, WithAutoTLS(time.Hour, 0))
+	require.NoError(t, client.Given(Call{Path: "secure/assured"}))
+
+	served := map[string]*x509.Certificate{}
+	for _, host := range []string{"api.example.com", "billing.example.com", "api.example.com"} {
+		trusted := autoTLSClient(client.RootCAs())
+		trusted.Transport.(*http.Transport).TLSClientConfig.ServerName = host
+		resp, err := trusted.Get(client.URL() + "/secure/assured")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		cert := resp.TLS.PeerCertificates[0]
+		require.Equal(t, []string{host}, cert.DNSNames)
+		if previous, ok := served[host]; ok {
+			require.Equal(t, previous.SerialNumber, cert.SerialNumber)
+		}
+		served[host] = cert
+	}
+	require.Error(t, served["api.example.com"].VerifyHostname("billing.example.com"))
+	current, err := client.autoCerts.getCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, current.Leaf.NotAfter, served["api.example.com"].NotAfter)
+
+	calls, err := client.Verify(http.MethodGet, "secure/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 3)
+	require.Equal(t, "billing.example.com", calls[1].TLS.ServerName)
+
+	require.NoError(t, client.RotateCertificate())
+	trusted := autoTLSClient(client.RootCAs())
+	trusted.Transport.(*http.Transport).TLSClientConfig.ServerName = "api.example.com"
+	resp, err := trusted.Get(client.URL() + "/secure/assured")
+	require.NoError(t, err)
+	require.NotEqual(t, served["api.example.com"].SerialNumber, resp.TLS.PeerCertificates[0].SerialNumber)
+}
+
+func TestClientAutoTLSExpiring(t *testing.T) {
+	_, client := NewTestTLSServer(t, WithAutoTLS(2*time.Second, 0))
+	require.NoError(t, client.Given(Call{Path: "secure/assured"}))
+
+	trusted := autoTLSClient(client.RootCAs())
+	_, err := trusted.Get(client.URL() + "/secure/assured")
+	require.NoError(t, err)
+
+	time.Sleep(2100 * time.Millisecond)
+	trusted.CloseIdleConnections()
+	_, err = trusted.Get(client.URL() + "/secure/assured")
+	var invalidErr x509.CertificateInvalidError
+	require.ErrorAs(t, err, &invalidErr)
+	require.Equal(t, x509.Expired, invalidErr.Reason)
+
+	require.NoError(t, client.RotateCertificate())
+	_, err = trusted.Get(client.URL() + "/secure/assured")
+	require.NoError(t, err)
+}
+
+func TestClientAutoTLSRotation(t *testing.T) {
+	_, client := NewTestTLSServer(t, WithAutoTLS(time.Hour, 100*time.Millisecond))
+
+	first, err := client.autoCerts.getCertificate(nil)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		current, _ := client.autoCerts.getCertificate(nil)
+		return current != first
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestClientAutoTLSStandalone(t *testing.T) {
+	client := NewClientServe(WithAutoTLS(time.Hour, 0))
+	defer client.Close()
+	time.Sleep(time.Second)
+
+	require.Equal(t, fmt.Sprintf("https://localhost:%d/when", client.Port), client.URL())
+	require.NoError(t, client.Given(Call{Path: "secure/assured"}))
+
+	_, err := http.Get(client.URL() + "/secure/assured")
+	require.Error(t, err)
+
+	resp, err := client.httpClient.Get(fmt.Sprintf("https://localhost:%d/certificate", client.Port))
+	require.NoError(t, err)
+	require.Equal(t, "application/x-pem-file", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	block, _ := pem.Decode(body)
+	require.NotNil(t, block)
+	authority, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(authority)
+
+	resp, err = autoTLSClient(pool).Get(client.URL() + "/secure/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestClientCACertPEM(t *testing.T) {
+	_, client := NewTestTLSServer(t, WithAutoTLS(time.Hour, 0))
+	require.NoError(t, client.Given(Call{Path: "secure/assured"}))
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(client.CACertPEM()))
+	resp, err := autoTLSClient(pool).Get(client.URL() + "/secure/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = (&http.Client{Transport: client.TLSTransport()}).Get(client.URL() + "/secure/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestClientAutoTLSDisabled(t *testing.T) {
+	server, client := NewTestTLSServer(t)
+	require.Nil(t, client.RootCAs())
+	require.Nil(t, client.CACertPEM())
+	require.Nil(t, client.TLSTransport().TLSClientConfig.RootCAs)
+	require.EqualError(t, client.RotateCertificate(), "auto tls is not enabled")
+
+	resp, err := server.Client().Get(server.URL + "/certificate")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	_, err = NewClientE(WithAutoTLS(time.Hour, 0), WithTLSFault(TLSFaultExpired))
+	require.EqualError(t, err, "tls fault and auto tls cannot be used together")
+}
+
+// autoTLSClient returns an http client that trusts the root CAs
+func autoTLSClient(rootCAs *x509.CertPool) *http.Client {
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs}}}
+}
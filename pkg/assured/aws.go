@@ -0,0 +1,136 @@
+package assured
+
+import (
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+)
+
+// The request ID headers of AWS responses. Most services respond with x-amzn-RequestId, and S3 with x-amz-request-id
+const (
+	AWSRequestIDHeader   = "X-Amzn-Requestid"
+	AWSS3RequestIDHeader = "X-Amz-Request-Id"
+)
+
+// sigV4Algorithm is the algorithm of an AWS Signature Version 4 signature
+const sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+// AWSErrorResponse is the XML error envelope of AWS query protocol services, such as SQS, SNS, STS, and IAM
+type AWSErrorResponse struct {
+	XMLName   xml.Name `xml:"ErrorResponse"`
+	Error     AWSError `xml:"Error"`
+	RequestID string   `xml:"RequestId"`
+}
+
+// AWSError is the error of an AWS XML error envelope. The type is Sender for client errors and Receiver for server errors
+type AWSError struct {
+	Type    string `xml:"Type,omitempty"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// S3ErrorResponse is the XML error envelope of S3
+type S3ErrorResponse struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource,omitempty"`
+	RequestID string   `xml:"RequestId"`
+}
+
+// AWSRequestID returns a random request ID, formatted like the request IDs of AWS responses
+func AWSRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// AWSCall returns the stubbed call with the request ID headers of an AWS response,
+// which responds 403 Forbidden with a MissingAuthenticationToken error to requests without an AWS SigV4 signature
+// The signature is only required to be present, it is not verified
+func AWSCall(call Call) Call {
+	requestID := AWSRequestID()
+	call.Headers = awsHeaders(call.Headers, requestID)
+	unsigned := awsErrorCall(call, http.StatusForbidden, requestID, awsErrorResponse(http.StatusForbidden, "MissingAuthenticationToken", "Request is missing Authentication Token", requestID))
+	call.Branches = append([]Branch{{
+		When:       Condition{Unsigned: true},
+		StatusCode: unsigned.StatusCode,
+		Headers:    unsigned.Headers,
+		Response:   unsigned.Response,
+	}}, call.Branches...)
+	return call
+}
+
+// AWSErrorCall returns the stubbed call responding with the status code and the XML error envelope of AWS query protocol services,
+// with the error code and message, and request ID headers
+func AWSErrorCall(call Call, statusCode int, code, message string) Call {
+	requestID := AWSRequestID()
+	return awsErrorCall(call, statusCode, requestID, awsErrorResponse(statusCode, code, message, requestID))
+}
+
+// awsErrorResponse returns the XML error envelope of AWS query protocol services
+func awsErrorResponse(statusCode int, code, message, requestID string) []byte {
+	errorType := "Sender"
+	if statusCode >= http.StatusInternalServerError {
+		errorType = "Receiver"
+	}
+	response, _ := xml.Marshal(AWSErrorResponse{
+		Error:     AWSError{Type: errorType, Code: code, Message: message},
+		RequestID: requestID,
+	})
+	return response
+}
+
+// S3ErrorCall returns the stubbed call responding with the status code and the XML error envelope of S3,
+// with the error code and message, the call's path as the resource, and request ID headers
+func S3ErrorCall(call Call, statusCode int, code, message string) Call {
+	requestID := AWSRequestID()
+	response, _ := xml.Marshal(S3ErrorResponse{
+		Code:      code,
+		Message:   message,
+		Resource:  "/" + strings.Trim(call.Path, "/"),
+		RequestID: requestID,
+	})
+	return awsErrorCall(call, statusCode, requestID, response)
+}
+
+// awsErrorCall returns the stubbed call responding with the status code and XML error envelope
+func awsErrorCall(call Call, statusCode int, requestID string, response []byte) Call {
+	call.StatusCode = statusCode
+	call.Headers = awsHeaders(call.Headers, requestID)
+	call.Headers["Content-Type"] = "text/xml"
+	delete(call.Headers, "Content-Length")
+	call.Response = append([]byte(xml.Header), response...)
+	return call
+}
+
+// awsHeaders returns a copy of the headers with the request ID headers of an AWS response
+func awsHeaders(headers map[string]string, requestID string) map[string]string {
+	headers = maps.Clone(headers)
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers[AWSRequestIDHeader] = requestID
+	headers[AWSS3RequestIDHeader] = requestID
+	return headers
+}
+
+// sigV4Signed reports whether the call made has an AWS SigV4 signature, in its Authorization header or presigned in its query
+func sigV4Signed(call *Call) bool {
+	if call.Query["X-Amz-Algorithm"] == sigV4Algorithm && call.Query["X-Amz-Credential"] != "" && call.Query["X-Amz-Signature"] != "" {
+		return true
+	}
+	authorization := call.Headers["Authorization"]
+	if !strings.HasPrefix(authorization, sigV4Algorithm+" ") {
+		return false
+	}
+	for _, component := range []string{"Credential=", "SignedHeaders=", "Signature="} {
+		if !strings.Contains(authorization, component) {
+			return false
+		}
+	}
+	return call.Headers["X-Amz-Date"] != "" || call.Headers["Date"] != ""
+}
@@ -0,0 +1,113 @@
+package assured
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigV4Signed(t *testing.T) {
+	authorization := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	tests := []struct {
+		name   string
+		call   Call
+		signed bool
+	}{
+		{
+			name:   "signed",
+			call:   Call{Headers: map[string]string{"Authorization": authorization, "X-Amz-Date": "20150830T123600Z"}},
+			signed: true,
+		},
+		{
+			name:   "presigned",
+			call:   Call{Query: map[string]string{"X-Amz-Algorithm": "AWS4-HMAC-SHA256", "X-Amz-Credential": "AKIDEXAMPLE/20150830/us-east-1/s3/aws4_request", "X-Amz-Signature": "5d672d79"}},
+			signed: true,
+		},
+		{
+			name: "unsigned",
+			call: Call{Headers: map[string]string{"X-Amz-Date": "20150830T123600Z"}},
+		},
+		{
+			name: "missing date",
+			call: Call{Headers: map[string]string{"Authorization": authorization}},
+		},
+		{
+			name: "incomplete signature",
+			call: Call{Headers: map[string]string{"Authorization": "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/s3/aws4_request", "X-Amz-Date": "20150830T123600Z"}},
+		},
+		{
+			name: "other scheme",
+			call: Call{Headers: map[string]string{"Authorization": "Bearer token", "X-Amz-Date": "20150830T123600Z"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.signed, sigV4Signed(&tc.call))
+			require.Equal(t, !tc.signed, Condition{Unsigned: true}.Matches(&tc.call))
+		})
+	}
+}
+
+func TestAWSRequestID(t *testing.T) {
+	require.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`, AWSRequestID())
+	require.NotEqual(t, AWSRequestID(), AWSRequestID())
+}
+
+func TestAWSCall(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(AWSCall(Call{Path: "bucket/report.csv", Response: []byte("id,total")})))
+
+	resp, err := http.Get(client.URL() + "/bucket/report.csv")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Equal(t, "text/xml", resp.Header.Get("Content-Type"))
+	requestID := resp.Header.Get("x-amzn-RequestId")
+	require.NotEmpty(t, requestID)
+	require.Equal(t, requestID, resp.Header.Get("x-amz-request-id"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var envelope AWSErrorResponse
+	require.NoError(t, xml.Unmarshal(body, &envelope))
+	require.Equal(t, AWSError{Type: "Sender", Code: "MissingAuthenticationToken", Message: "Request is missing Authentication Token"}, envelope.Error)
+
+	req, err := http.NewRequest(http.MethodGet, client.URL()+"/bucket/report.csv", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=5d672d79")
+	req.Header.Set("X-Amz-Date", "20150830T123600Z")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, requestID, resp.Header.Get("x-amzn-RequestId"))
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "id,total", string(body))
+}
+
+func TestAWSErrorCalls(t *testing.T) {
+	call := AWSErrorCall(Call{Path: "queue", Method: http.MethodPost, Headers: map[string]string{"Content-Length": "3"}}, http.StatusServiceUnavailable, "ServiceUnavailable", "Service is unable to handle request.")
+	require.Equal(t, http.StatusServiceUnavailable, call.StatusCode)
+	require.Equal(t, "text/xml", call.Headers["Content-Type"])
+	require.NotContains(t, call.Headers, "Content-Length")
+	var envelope AWSErrorResponse
+	require.NoError(t, xml.Unmarshal(call.Response, &envelope))
+	require.Equal(t, AWSErrorResponse{
+		XMLName:   xml.Name{Local: "ErrorResponse"},
+		Error:     AWSError{Type: "Receiver", Code: "ServiceUnavailable", Message: "Service is unable to handle request."},
+		RequestID: call.Headers[AWSRequestIDHeader],
+	}, envelope)
+
+	call = S3ErrorCall(Call{Path: "bucket/missing.txt"}, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+	require.Equal(t, http.StatusNotFound, call.StatusCode)
+	var s3 S3ErrorResponse
+	require.NoError(t, xml.Unmarshal(call.Response, &s3))
+	require.Equal(t, S3ErrorResponse{
+		XMLName:   xml.Name{Local: "Error"},
+		Code:      "NoSuchKey",
+		Message:   "The specified key does not exist.",
+		Resource:  "/bucket/missing.txt",
+		RequestID: call.Headers[AWSS3RequestIDHeader],
+	}, s3)
+}
@@ -0,0 +1,159 @@
+package assured
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BatchOperation is an operation of a batch request made to a batch stubbed call
+// The path is relative to the stubbed calls, e.g. orders/1?expand=items, or the URL for batch APIs that name it so, e.g. /orders/1
+// A body that is a JSON string is the string, for bodies that are not JSON
+type BatchOperation struct {
+	ID      string            `json:"id,omitempty"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResult is the result of an operation of a batch request, responded by a batch stubbed call in the order of the operations
+// A body that is not JSON is a JSON string
+type BatchResult struct {
+	ID      string            `json:"id,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// batchRequests is a batch request that wraps its operations in requests, such as the Microsoft Graph JSON batch format
+type batchRequests struct {
+	Requests []BatchOperation `json:"requests"`
+}
+
+// batchResponses is the batch response to a batch request that wraps its operations in requests
+type batchResponses struct {
+	Responses []BatchResult `json:"responses"`
+}
+
+// BatchCall returns the stubbed call of a batch endpoint, responding 207 Multi-Status with the results of the batch request's operations
+func BatchCall(call Call) Call {
+	call.Batch = true
+	call.StatusCode = http.StatusMultiStatus
+	return call
+}
+
+// batched returns a copy of the stubbed batch call responding with the results of the operations of the call made
+// Each operation is resolved by the stubbed calls as if it was made, so it is tracked, branched, and sequenced like any call made,
+// and an operation without a stubbed call results in 404 Not Found. The operations are a JSON array,
+// or the requests of a JSON object, responded with the results in the same shape
+func (a *AssuredEndpoints) batched(ctx context.Context, assured *Call, made *Call) (*Call, error) {
+	batched := *assured
+	batched.Headers = maps.Clone(assured.Headers)
+	if batched.Headers == nil {
+		batched.Headers = map[string]string{}
+	}
+	delete(batched.Headers, "Content-Length")
+	batched.Headers["Content-Type"] = "application/json"
+
+	var operations []BatchOperation
+	body := bytes.TrimSpace(made.Response)
+	wrapped := bytes.HasPrefix(body, []byte("{"))
+	err := json.Unmarshal(body, &operations)
+	if wrapped {
+		var requests batchRequests
+		err = json.Unmarshal(body, &requests)
+		operations = requests.Requests
+	}
+	if err != nil {
+		batched.StatusCode = http.StatusBadRequest
+		batched.Response, _ = json.Marshal(map[string]string{"error": fmt.Sprintf("invalid batch request: %s", err)})
+		return &batched, nil
+	}
+
+	results := make([]BatchResult, len(operations))
+	for i, operation := range operations {
+		result, err := a.batchOperation(ctx, operation)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	if wrapped {
+		batched.Response, _ = json.Marshal(batchResponses{Responses: results})
+	} else {
+		batched.Response, _ = json.Marshal(results)
+	}
+	return &batched, nil
+}
+
+// batchOperation returns the result of resolving the operation by the stubbed calls
+func (a *AssuredEndpoints) batchOperation(ctx context.Context, operation BatchOperation) (BatchResult, error) {
+	target := operation.Path
+	if target == "" {
+		target = operation.URL
+	}
+	path, rawQuery, _ := strings.Cut(strings.TrimLeft(target, "/"), "?")
+	values, _ := url.ParseQuery(rawQuery)
+	call := &Call{
+		Path:        path,
+		Method:      strings.ToUpper(operation.Method),
+		Headers:     map[string]string{},
+		Query:       map[string]string{},
+		QueryValues: map[string][]string{},
+	}
+	if call.Method == "" {
+		call.Method = http.MethodGet
+	}
+	for key, value := range operation.Headers {
+		call.Headers[http.CanonicalHeaderKey(key)] = value
+	}
+	for key, value := range values {
+		call.Query[key] = value[0]
+		call.QueryValues[key] = value
+	}
+	_, call.Matrix = parseMatrix(call.Path)
+	call.Response = []byte(operation.Body)
+	var s string
+	if json.Unmarshal(operation.Body, &s) == nil {
+		call.Response = []byte(s)
+	}
+
+	result := BatchResult{ID: operation.ID, Status: http.StatusNotFound}
+	// The operation is matched on its own, not as the batch request matched by the when endpoint's middleware
+	resolved, err := a.WhenEndpoint(context.WithValue(ctx, matchKey{}, (*match)(nil)), call)
+	if err != nil && ctx.Err() != nil {
+		return result, err
+	}
+	response, ok := resolved.(*Call)
+	if err != nil || !ok {
+		return result, nil
+	}
+
+	result.Status = response.StatusCode
+	headers := map[string]string{}
+	for key, value := range response.Headers {
+		if !strings.HasPrefix(key, "Assured-") && key != "Content-Length" {
+			headers[key] = value
+		}
+	}
+	for _, header := range response.ResponseHeaders {
+		headers[header.Name] = header.Value
+	}
+	if len(headers) > 0 {
+		result.Headers = headers
+	}
+	if len(response.Response) > 0 {
+		result.Body = json.RawMessage(response.Response)
+		if !json.Valid(response.Response) {
+			result.Body, _ = json.Marshal(string(response.Response))
+		}
+	}
+	return result, nil
+}
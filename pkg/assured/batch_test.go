@@ -0,0 +1,94 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchCall(t *testing.T) {
+	call := BatchCall(Call{Path: "batch", Method: http.MethodPost})
+
+	require.Equal(t, Call{Path: "batch", Method: http.MethodPost, StatusCode: http.StatusMultiStatus, Batch: true}, call)
+}
+
+func TestClientBatchCall(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(
+		BatchCall(Call{Path: "batch", Method: http.MethodPost}),
+		Call{Path: "users/1", Method: http.MethodGet, StatusCode: http.StatusOK, Headers: map[string]string{"Content-Type": "application/json"}, Response: []byte(`{"id":1}`)},
+		Call{
+			Path:       "users",
+			Method:     http.MethodPost,
+			StatusCode: http.StatusCreated,
+			Response:   []byte("created"),
+			Branches:   []Branch{{When: Condition{BodyContains: "taken", Headers: map[string]string{"X-Tenant": "acme"}}, StatusCode: http.StatusConflict, Response: []byte(`{"error":"taken"}`)}},
+		},
+		Call{Path: "users/2", Method: http.MethodDelete, StatusCode: http.StatusNoContent},
+	))
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "array",
+			body: `[
+				{"method": "GET", "path": "users/1"},
+				{"method": "post", "path": "/users?notify=true", "headers": {"x-tenant": "acme"}, "body": {"name": "taken"}},
+				{"method": "POST", "path": "users", "body": "plain"},
+				{"method": "DELETE", "path": "users/2"},
+				{"method": "GET", "path": "users/3"}
+			]`,
+			want: `[
+				{"status": 200, "headers": {"Content-Type": "application/json"}, "body": {"id": 1}},
+				{"status": 409, "body": {"error": "taken"}},
+				{"status": 201, "body": "created"},
+				{"status": 204},
+				{"status": 404}
+			]`,
+		},
+		{
+			name: "requests",
+			body: `{"requests": [{"id": "1", "method": "GET", "url": "/users/1"}, {"id": "2", "method": "GET", "url": "/users/3"}]}`,
+			want: `{"responses": [{"id": "1", "status": 200, "headers": {"Content-Type": "application/json"}, "body": {"id": 1}}, {"id": "2", "status": 404}]}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Post(client.URL()+"/batch", "application/json", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+			require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			require.JSONEq(t, tc.want, string(body))
+		})
+	}
+
+	// The operations are tracked like calls made
+	calls, err := client.Verify(http.MethodPost, "users")
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	require.Equal(t, "true", calls[0].Query["notify"])
+	require.Equal(t, `{"name": "taken"}`, string(calls[0].Response))
+	require.Equal(t, "plain", string(calls[1].Response))
+}
+
+func TestClientBatchCallInvalid(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(BatchCall(Call{Path: "batch", Method: http.MethodPost})))
+
+	resp, err := http.Post(client.URL()+"/batch", "application/json", strings.NewReader(`[{"method":`))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.JSONEq(t, `{"error": "invalid batch request: unexpected end of JSON input"}`, string(body))
+}
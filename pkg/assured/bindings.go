@@ -1,30 +1,79 @@
 package assured
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/http/pprof"
+	"net/textproto"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 )
 
 const (
-	AssuredStatus         = "Assured-Status"
-	AssuredMethod         = "Assured-Method"
-	AssuredDelay          = "Assured-Delay"
-	AssuredCallbackKey    = "Assured-Callback-Key"
-	AssuredCallbackTarget = "Assured-Callback-Target"
-	AssuredCallbackDelay  = "Assured-Callback-Delay"
+	AssuredStatus          = "Assured-Status"
+	AssuredStatusSequence  = "Assured-Status-Sequence"
+	AssuredMethod          = "Assured-Method"
+	AssuredPathRegex       = "Assured-Path-Regex"
+	AssuredDelay           = "Assured-Delay"
+	AssuredConcurrency     = "Assured-Concurrency"
+	AssuredMaxBodySize     = "Assured-Max-Body-Size"
+	AssuredMaxHeaderSize   = "Assured-Max-Header-Size"
+	AssuredMaxURILength    = "Assured-Max-URI-Length"
+	AssuredCallbackKey     = "Assured-Callback-Key"
+	AssuredCallbackTarget  = "Assured-Callback-Target"
+	AssuredCallbackDelay   = "Assured-Callback-Delay"
+	AssuredBranches        = "Assured-Branches"
+	AssuredBreaker         = "Assured-Breaker"
+	AssuredHandshake       = "Assured-Handshake"
+	AssuredSession         = "Assured-Session"
+	AssuredCSRF            = "Assured-CSRF"
+	AssuredGraphQL         = "Assured-GraphQL"
+	AssuredLocale          = "Assured-Locale"
+	AssuredExtract         = "Assured-Extract"
+	AssuredRequiredHeaders = "Assured-Required-Headers"
+	AssuredBatch           = "Assured-Batch"
+	AssuredJob             = "Assured-Job"
+	AssuredSignedURL       = "Assured-Signed-URL"
+	AssuredCache           = "Assured-Cache"
+	AssuredPadding         = "Assured-Padding"
+	AssuredFraming         = "Assured-Framing"
+	AssuredInformational   = "Assured-Informational"
+	AssuredResponseHeaders = "Assured-Response-Headers"
+	AssuredRawHeaders      = "Assured-Raw-Headers"
+	AssuredTrace           = "Assured-Trace"
+	AssuredTraceMatch      = "Assured-Trace-Match"
+	AssuredTraceCandidates = "Assured-Trace-Candidates"
+	AssuredAPIVersion      = "Assured-Api-Version"
 )
 
+// APIVersion is the version of the rest assured endpoints' wire format, negotiated with the Assured-Api-Version header
+// Version 1 is the legacy header protocol, version 2 adds the JSON given endpoint
+const APIVersion = "2"
+
+// legacyAPIVersion is the version of the header protocol, used by clients that predate the Assured-Api-Version header
+const legacyAPIVersion = "1"
+
+// supportedAPIVersions are the wire format versions the rest assured endpoints can be called with
+var supportedAPIVersions = []string{legacyAPIVersion, APIVersion}
+
 // createApplicationRouter sets up the router that will handle all of the application routes
 func (c *Client) createApplicationRouter() *mux.Router {
-	router := mux.NewRouter()
+	root := mux.NewRouter()
+	router := root
+	if c.basePath != "" {
+		router = root.PathPrefix(c.basePath).Subrouter()
+	}
 	e := NewAssuredEndpoints(c.Options)
 	assuredMethods := []string{
 		http.MethodGet,
@@ -37,61 +86,461 @@ func (c *Client) createApplicationRouter() *mux.Router {
 		http.MethodOptions,
 	}
 
-	router.Handle(
-		"/given/{path:.*}",
-		kithttp.NewServer(
-			e.WrappedEndpoint(e.GivenEndpoint),
-			decodeAssuredCall,
-			encodeAssuredCall,
-			kithttp.ServerAfter(kithttp.SetResponseHeader("Access-Control-Allow-Origin", "*"))),
-	).Methods(assuredMethods...)
-
-	router.Handle(
-		"/callback",
-		kithttp.NewServer(
-			e.WrappedEndpoint(e.GivenCallbackEndpoint),
-			decodeAssuredCallback,
-			encodeAssuredCall,
-			kithttp.ServerAfter(kithttp.SetResponseHeader("Access-Control-Allow-Origin", "*"))),
-	).Methods(assuredMethods...)
-
-	router.Handle(
-		"/when/{path:.*}",
-		kithttp.NewServer(
-			e.WrappedEndpoint(e.WhenEndpoint),
-			decodeAssuredCall,
-			encodeAssuredCall,
-			kithttp.ServerAfter(kithttp.SetResponseHeader("Access-Control-Allow-Origin", "*"))),
-	).Methods(assuredMethods...)
-
-	router.Handle(
-		"/verify/{path:.*}",
-		kithttp.NewServer(
-			e.WrappedEndpoint(e.VerifyEndpoint),
-			decodeAssuredCall,
-			encodeAssuredCall,
-			kithttp.ServerAfter(kithttp.SetResponseHeader("Access-Control-Allow-Origin", "*"))),
-	).Methods(assuredMethods...)
-
-	router.Handle(
-		"/clear/{path:.*}",
-		kithttp.NewServer(
-			e.WrappedEndpoint(e.ClearEndpoint),
-			decodeAssuredCall,
-			encodeAssuredCall,
-			kithttp.ServerAfter(kithttp.SetResponseHeader("Access-Control-Allow-Origin", "*"))),
-	).Methods(assuredMethods...)
-
-	router.Handle(
-		"/clear",
-		kithttp.NewServer(
-			e.ClearAllEndpoint,
-			decodeAssuredCall,
-			encodeAssuredCall,
-			kithttp.ServerAfter(kithttp.SetResponseHeader("Access-Control-Allow-Origin", "*"))),
-	).Methods(http.MethodDelete)
-
-	return router
+	router.Handle("/given", versioned(revisioned(e, e.handler(e.GivenJSONEndpoint, decodeGivenCall, encodeJSON)), APIVersion)).Methods(http.MethodPost)
+
+	router.Handle("/given/{path:.*}", versioned(revisioned(e, e.handler(e.GivenEndpoint, decodeAssuredCall, encodeAssuredCall)), supportedAPIVersions...)).Methods(assuredMethods...)
+
+	router.Handle("/callback", versioned(revisioned(e, e.handler(e.GivenCallbackEndpoint, decodeAssuredCallback, encodeAssuredCall)), supportedAPIVersions...)).Methods(assuredMethods...)
+
+	when := e.matchHandler(e.recoveryHandler(e.latencyHandler(connectionHandler(e.uriLimitHandler(e.headerLimitHandler(e.bodyLimitHandler(e.handshakeHandler(e.sessionHandler(e.csrfHandler(e.staticWhenHandler(e.handler(e.WhenEndpoint, e.decodeWhenCall, e.encodeWhenCall))))))))))))
+
+	router.Handle("/when/{path:.*}", when).Methods(assuredMethods...)
+
+	router.Handle("/verify/{path:.*}", versioned(e.handler(e.VerifyEndpoint, decodeAssuredCall, encodeAssuredCall), supportedAPIVersions...)).Methods(assuredMethods...)
+	router.Handle("/duplicates/{path:.*}", versioned(e.handler(e.DuplicatesEndpoint, decodeAssuredCall, encodeJSON), supportedAPIVersions...)).Methods(assuredMethods...)
+
+	router.Handle("/clear/{path:.*}", versioned(revisioned(e, e.handler(e.ClearEndpoint, decodeAssuredCall, encodeAssuredCall)), supportedAPIVersions...)).Methods(assuredMethods...)
+
+	router.Handle("/clear-matching", versioned(revisioned(e, clearMatchingHandler(e)), supportedAPIVersions...)).Methods(http.MethodDelete)
+
+	router.Handle("/clear", versioned(revisioned(e, e.handler(func(ctx context.Context, call *Call) (interface{}, error) {
+		return e.ClearAllEndpoint(ctx, call)
+	}, decodeAssuredCall, encodeAssuredCall)), supportedAPIVersions...)).Methods(http.MethodDelete)
+
+	router.Handle("/stubs", versioned(revisioned(e, replaceHandler(e)), APIVersion)).Methods(http.MethodPut)
+
+	router.Handle("/stubs/rollback", versioned(rollbackHandler(e), supportedAPIVersions...)).Methods(http.MethodPost)
+
+	router.Handle("/freeze", versioned(freezeHandler(e), supportedAPIVersions...)).Methods(http.MethodPost, http.MethodDelete)
+
+	router.Handle("/journal", versioned(clearJournalHandler(e), supportedAPIVersions...)).Methods(http.MethodDelete)
+
+	router.Handle("/sessions", versioned(expireSessionsHandler(e), supportedAPIVersions...)).Methods(http.MethodDelete)
+	router.Handle("/counters", versioned(countersHandler(e), supportedAPIVersions...)).Methods(http.MethodGet)
+	router.Handle("/counters", versioned(resetCountersHandler(e), supportedAPIVersions...)).Methods(http.MethodDelete)
+	router.Handle("/state", versioned(stateHandler(e), supportedAPIVersions...)).Methods(http.MethodGet)
+	router.Handle("/state", versioned(clearStateHandler(e), supportedAPIVersions...)).Methods(http.MethodDelete)
+
+	router.Handle("/csrf/mismatches", versioned(csrfMismatchesHandler(e), supportedAPIVersions...)).Methods(http.MethodGet)
+
+	router.Handle("/diagnostics/panics", versioned(panicsHandler(e), supportedAPIVersions...)).Methods(http.MethodGet)
+	router.Handle("/diagnostics/panics", versioned(clearPanicsHandler(e), supportedAPIVersions...)).Methods(http.MethodDelete)
+
+	router.Handle("/compact", versioned(compactHandler(e), supportedAPIVersions...)).Methods(http.MethodPost)
+
+	router.Handle("/stats", versioned(statsHandler(e), supportedAPIVersions...)).Methods(http.MethodGet)
+
+	router.Handle("/certificate", versioned(certificateHandler(c.autoCerts), supportedAPIVersions...)).Methods(http.MethodGet)
+
+	router.Handle("/health", versioned(http.HandlerFunc(healthHandler), supportedAPIVersions...)).Methods(http.MethodGet, http.MethodHead)
+
+	// Echo any request's metadata without the overhead of the stubbed calls, for load generation benchmarks
+	router.HandleFunc("/__echo__", echoHandler)
+	router.HandleFunc("/__echo__/{path:.*}", echoHandler)
+
+	// Serve the profiling endpoints for investigating the rest assured server under load
+	if c.pprof {
+		router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+	}
+
+	// Rotate the auto tls certificate in the background, until the client is closed
+	if c.autoCerts != nil && c.certRotation > 0 {
+		go c.autoCerts.rotateEvery(c.ctx, c.certRotation)
+	}
+
+	// Record the calls made into the journal in the background, until the client is closed
+	if c.trackMadeCalls {
+		go e.recorder.run(c.ctx, e.madeCalls)
+	}
+
+	// Purge the made calls older than the journal ttl in the background, until the client is closed
+	if c.journalTTL > 0 {
+		go e.retainJournal(c.ctx)
+	}
+
+	// Respond to the routes that aren't served in the error format, unless it is the default text
+	if !c.errorFormat.plain() {
+		root.NotFoundHandler = e.notFoundHandler()
+	}
+
+	// Serve the stubbed endpoints at the root, when no other routes have been matched
+	if c.rootServing {
+		root.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			when.ServeHTTP(w, mux.SetURLVars(req, map[string]string{"path": strings.TrimPrefix(req.URL.Path, "/")}))
+		})
+	}
+
+	return root
+}
+
+// versioned serves the rest assured endpoint for the api version requested with the Assured-Api-Version header,
+// and responds with the api version served. Requests without the header predate it, and are served the legacy api version
+func versioned(handler http.Handler, versions ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		version := req.Header.Get(AssuredAPIVersion)
+		if version == "" {
+			version = legacyAPIVersion
+		}
+		if !slices.Contains(versions, version) {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set(AssuredAPIVersion, APIVersion)
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "unsupported '%s' header: %s, supported versions: %s", AssuredAPIVersion, version, strings.Join(versions, ","))
+			return
+		}
+		// The version is not part of the stubbed call
+		req.Header.Del(AssuredAPIVersion)
+		w.Header().Set(AssuredAPIVersion, version)
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// handler serves the assured endpoint, decoding the request into a call and encoding the endpoint's response
+// The endpoint is served by a go-kit server, unless plain handlers are enabled
+func (a *AssuredEndpoints) handler(handler func(context.Context, *Call) (interface{}, error), decode kithttp.DecodeRequestFunc, encode kithttp.EncodeResponseFunc) http.Handler {
+	if a.plainHandlers {
+		return a.plainHandler(handler, decode, encode)
+	}
+	return kithttp.NewServer(
+		a.WrappedEndpoint(handler),
+		decode,
+		encode,
+		kithttp.ServerErrorEncoder(func(_ context.Context, err error, w http.ResponseWriter) { a.encodeError(w, err) }),
+		kithttp.ServerAfter(kithttp.SetResponseHeader("Access-Control-Allow-Origin", "*")))
+}
+
+// plainHandler serves the assured endpoint with net/http alone, responding the same as the go-kit server
+func (a *AssuredEndpoints) plainHandler(handler func(context.Context, *Call) (interface{}, error), decode kithttp.DecodeRequestFunc, encode kithttp.EncodeResponseFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		request, err := decode(ctx, req)
+		if err != nil {
+			a.encodeError(w, err)
+			return
+		}
+		response, err := handler(ctx, request.(*Call))
+		if err != nil {
+			a.encodeError(w, err)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if err := encode(ctx, w, response); err != nil {
+			a.encodeError(w, err)
+		}
+	}
+}
+
+// staticWhenHandler serves the static stubbed calls matched for their literal path directly, without decoding the request into a call unless it is tracked
+// Calls that are not static, not stubbed, or stubbed with query parameters or required headers, are served by the when endpoint,
+// as are all calls while the responses are validated, and tracked calls that can't be decoded
+func (a *AssuredEndpoints) staticWhenHandler(when http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		m := matched(req.Context())
+		assured := m.stub()
+		if assured == nil || assured.pathRegex != nil || m.path != mux.Vars(req)["path"] || !assured.static() || hasConditions(m.candidates) ||
+			a.validation != "" || req.Header.Get(AssuredTrace) == "true" {
+			when.ServeHTTP(w, req)
+			return
+		}
+		id := assured.ID()
+
+		// Serve calls that can't be decoded with the when endpoint, which responds with the decoding error
+		var call interface{}
+		if a.trackMadeCalls {
+			var err error
+			if call, err = a.decodeWhenCall(req.Context(), req); err != nil {
+				when.ServeHTTP(w, req)
+				return
+			}
+		}
+
+		// Simulate network latency
+		time.Sleep(a.latency)
+
+		if call != nil {
+			a.trackCall(call.(*Call))
+		}
+		a.assuredCalls.RotateAt(id, assured)
+
+		w.Header()["Access-Control-Allow-Origin"] = allowAllOrigins
+		a.inferHeader(w.Header(), assured)
+		writeCall(w, assured)
+
+		slog.LogAttrs(req.Context(), slog.LevelInfo, "assured call responded", slog.String("path", id))
+	})
+}
+
+// bodyLimitHandler responds 413 Request Entity Too Large to calls with a body larger than the stubbed call's max body size, else the server's
+// A body declaring a larger Content-Length is rejected without reading it, and the call is tracked without its body
+func (a *AssuredEndpoints) bodyLimitHandler(when http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		limit := a.maxBodySize
+		if stub := matched(req.Context()).stub(); stub != nil && stub.MaxBodySize > 0 {
+			limit = stub.MaxBodySize
+		}
+		if limit <= 0 || req.Body == nil {
+			when.ServeHTTP(w, req)
+			return
+		}
+
+		tooLarge := req.ContentLength > limit
+		if !tooLarge {
+			body, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+			if err != nil {
+				a.encodeError(w, err)
+				return
+			}
+			tooLarge = int64(len(body)) > limit
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if !tooLarge {
+			when.ServeHTTP(w, req)
+			return
+		}
+
+		a.reject(w, req, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body too large, the limit is %d bytes", limit))
+	})
+}
+
+// headerLimitHandler responds 431 Request Header Fields Too Large to calls with headers larger than the stubbed call's max header size, else the server's
+// The size of the headers is the size of their lines, "Name: value\r\n", including the Host header and excluding the Assured headers
+func (a *AssuredEndpoints) headerLimitHandler(when http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		limit := a.maxHeaderSize
+		if stub := matched(req.Context()).stub(); stub != nil && stub.MaxHeaderSize > 0 {
+			limit = stub.MaxHeaderSize
+		}
+		if limit <= 0 || headerSize(req) <= limit {
+			when.ServeHTTP(w, req)
+			return
+		}
+		a.reject(w, req, http.StatusRequestHeaderFieldsTooLarge, fmt.Sprintf("request headers too large, the limit is %d bytes", limit))
+	})
+}
+
+// uriLimitHandler responds 414 URI Too Long to calls with a request URI longer than the stubbed call's max URI length, else the server's
+// The length of the request URI is the length of its path and query as sent, like a gateway's limit
+func (a *AssuredEndpoints) uriLimitHandler(when http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		limit := a.maxURILength
+		if stub := matched(req.Context()).stub(); stub != nil && stub.MaxURILength > 0 {
+			limit = stub.MaxURILength
+		}
+		if limit <= 0 || len(req.RequestURI) <= limit {
+			when.ServeHTTP(w, req)
+			return
+		}
+		a.reject(w, req, http.StatusRequestURITooLong, fmt.Sprintf("request uri too long, the limit is %d bytes", limit))
+	})
+}
+
+// headerSize returns the size of the request's header lines, including the Host header and excluding the Assured headers
+func headerSize(req *http.Request) int {
+	size := len("Host: \r\n") + len(req.Host)
+	for name, values := range req.Header {
+		if strings.HasPrefix(name, "Assured-") {
+			continue
+		}
+		for _, value := range values {
+			size += len(name) + len(": \r\n") + len(value)
+		}
+	}
+	return size
+}
+
+// reject responds to the call with the status code and message and closes the connection, as servers do when a request exceeds their limits
+// The call is tracked without its body, which is not read
+func (a *AssuredEndpoints) reject(w http.ResponseWriter, req *http.Request, statusCode int, message string) {
+	if a.trackMadeCalls {
+		req.Body = http.NoBody
+		if call, err := a.decodeWhenCall(req.Context(), req); err == nil {
+			a.trackCall(call.(*Call))
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Connection", "close")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write([]byte(message))
+	slog.With("path", req.Method+":"+mux.Vars(req)["path"], "status_code", statusCode).Info("assured call rejected")
+}
+
+// allowAllOrigins is the Access-Control-Allow-Origin header value set on every stubbed call's response
+var allowAllOrigins = []string{"*"}
+
+// healthHandler reports that the rest assured server is up and serving traffic
+func healthHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"status":"ok"}`))
+}
+
+// compactHandler purges the made calls older than the journal ttl and reports the number of calls purged
+func compactHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]int{"purged": e.Compact()})
+	}
+}
+
+// clearMatchingHandler clears the assured calls matching the method and prefix query parameters and reports the number of Method/Paths cleared
+// One of the parameters is required, so a malformed request can't clear every call
+func clearMatchingHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		method, prefix := req.URL.Query().Get("method"), req.URL.Query().Get("prefix")
+		if method == "" && prefix == "" {
+			http.Error(w, "method or prefix is required", http.StatusBadRequest)
+			return
+		}
+		cleared, err := e.ClearMatching(strings.ToUpper(method), strings.TrimLeft(prefix, "/"))
+		if err != nil {
+			e.encodeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]int{"cleared": cleared})
+	}
+}
+
+// freezeHandler freezes the stubbed calls on POST, and unfreezes them on DELETE, reporting whether they are frozen
+func freezeHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		frozen := req.Method == http.MethodPost
+		e.Freeze(frozen)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]bool{"frozen": frozen})
+	}
+}
+
+// replaceHandler replaces every stubbed call with the JSON array of calls given and reports the number of calls stubbed
+func replaceHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var calls []*Call
+		if err := json.NewDecoder(req.Body).Decode(&calls); err != nil {
+			e.encodeError(w, fmt.Errorf("invalid given calls: %w", err))
+			return
+		}
+		replaced, err := e.ReplaceEndpoint(req.Context(), calls)
+		if err != nil {
+			e.encodeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]int{"replaced": replaced})
+	}
+}
+
+// rollbackHandler restores the stub set to the revision before the latest change and reports the number of revisions left
+func rollbackHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		remaining, err := e.Rollback()
+		if err != nil {
+			e.encodeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]int{"remaining": remaining})
+	}
+}
+
+// clearJournalHandler clears the made calls journal and reports the number of made calls cleared
+func clearJournalHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]int{"cleared": e.ClearJournal()})
+	}
+}
+
+// expireSessionsHandler expires the sessions with the id query parameters, or every session without them,
+// and reports the number of sessions expired
+func expireSessionsHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]int{"expired": e.ExpireSessions(req.URL.Query()["id"]...)})
+	}
+}
+
+// countersHandler reports the current value of each counter of the counter placeholders in responses
+func countersHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(e.Counters())
+	}
+}
+
+// resetCountersHandler resets the counters with the name query parameters, or every counter without them,
+// and reports the number of counters reset
+func resetCountersHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]int{"reset": e.ResetCounters(req.URL.Query()["name"]...)})
+	}
+}
+
+// stateHandler reports the shared state of the values extracted by stubbed calls
+func stateHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(e.State())
+	}
+}
+
+// clearStateHandler clears the state's values with the name query parameters, or every value without them,
+// and reports the number of values cleared
+func clearStateHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]int{"cleared": e.ClearState(req.URL.Query()["name"]...)})
+	}
+}
+
+// csrfMismatchesHandler reports the CSRF mismatches recorded by the stubbed calls validating CSRF tokens
+func csrfMismatchesHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(e.CSRFMismatches())
+	}
+}
+
+// panicsHandler reports the panics recovered while serving the calls made
+func panicsHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(e.Panics())
+	}
+}
+
+// clearPanicsHandler clears the panics recovered, responding with the number of panics cleared
+func clearPanicsHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]int{"cleared": e.ClearPanics()})
+	}
+}
+
+// statsHandler reports the memory usage of the rest assured server
+func statsHandler(e *AssuredEndpoints) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(e.Stats())
+	}
+}
+
+// decodeWhenCall converts an http request made to a stubbed call into an assured Call object
+// The details of the connection and the TLS connection are captured when serving HTTPS, and the raw request URI as it was sent on the request line, if enabled
+func (a *AssuredEndpoints) decodeWhenCall(ctx context.Context, req *http.Request) (interface{}, error) {
+	call, err := decodeAssuredCall(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	call.(*Call).Connection = connectionDetails(req)
+	call.(*Call).TLS = tlsDetails(req.TLS)
+	if a.rawURI {
+		call.(*Call).RawURI = req.RequestURI
+	}
+	return call, nil
 }
 
 // decodeAssuredCall converts an http request into an assured Call object
@@ -108,11 +557,213 @@ func decodeAssuredCall(ctx context.Context, req *http.Request) (interface{}, err
 		StatusCode: http.StatusOK,
 	}
 
+	// Set path regex
+	if pathRegex := req.Header.Get(AssuredPathRegex); pathRegex != "" {
+		if _, err := regexp.Compile(pathRegex); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredPathRegex, err)
+		}
+		ac.PathRegex = pathRegex
+	}
+
 	// Set status code override
 	if statusCode, err := strconv.ParseInt(req.Header.Get(AssuredStatus), 10, 64); err == nil {
 		ac.StatusCode = int(statusCode)
 	}
 
+	// Set status code sequence
+	if sequence := req.Header.Get(AssuredStatusSequence); sequence != "" {
+		for _, code := range strings.Split(sequence, ",") {
+			statusCode, err := strconv.Atoi(strings.TrimSpace(code))
+			if err != nil {
+				return nil, fmt.Errorf("invalid '%s' header: %w", AssuredStatusSequence, err)
+			}
+			ac.StatusCodes = append(ac.StatusCodes, statusCode)
+		}
+	}
+
+	// Set concurrency limit
+	if concurrency := req.Header.Get(AssuredConcurrency); concurrency != "" {
+		limit, err := strconv.Atoi(concurrency)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid '%s' header: %s", AssuredConcurrency, concurrency)
+		}
+		ac.Concurrency = limit
+	}
+
+	// Set max body size
+	if maxBodySize := req.Header.Get(AssuredMaxBodySize); maxBodySize != "" {
+		limit, err := strconv.ParseInt(maxBodySize, 10, 64)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid '%s' header: %s", AssuredMaxBodySize, maxBodySize)
+		}
+		ac.MaxBodySize = limit
+	}
+
+	// Set max header size
+	if maxHeaderSize := req.Header.Get(AssuredMaxHeaderSize); maxHeaderSize != "" {
+		limit, err := strconv.Atoi(maxHeaderSize)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid '%s' header: %s", AssuredMaxHeaderSize, maxHeaderSize)
+		}
+		ac.MaxHeaderSize = limit
+	}
+
+	// Set max uri length
+	if maxURILength := req.Header.Get(AssuredMaxURILength); maxURILength != "" {
+		limit, err := strconv.Atoi(maxURILength)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid '%s' header: %s", AssuredMaxURILength, maxURILength)
+		}
+		ac.MaxURILength = limit
+	}
+
+	// Set response framing
+	if framing := req.Header.Get(AssuredFraming); framing != "" {
+		if !validFraming(framing) {
+			return nil, fmt.Errorf("invalid '%s' header: %s", AssuredFraming, framing)
+		}
+		ac.Framing = framing
+	}
+
+	// Set informational responses
+	if informational := req.Header.Get(AssuredInformational); informational != "" {
+		if err := json.Unmarshal([]byte(informational), &ac.Informational); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredInformational, err)
+		}
+		for _, info := range ac.Informational {
+			if !validInformationalStatus(info.StatusCode) {
+				return nil, fmt.Errorf("invalid '%s' header: invalid informational status code %d", AssuredInformational, info.StatusCode)
+			}
+		}
+	}
+
+	// Set repeatable response headers
+	if headers := req.Header.Get(AssuredResponseHeaders); headers != "" {
+		if err := json.Unmarshal([]byte(headers), &ac.ResponseHeaders); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredResponseHeaders, err)
+		}
+	}
+
+	// Set raw header casing
+	if raw := req.Header.Get(AssuredRawHeaders); raw != "" {
+		rawHeaders, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %s", AssuredRawHeaders, raw)
+		}
+		ac.RawHeaders = rawHeaders
+	}
+
+	// Set batch resolution
+	if batch := req.Header.Get(AssuredBatch); batch != "" {
+		isBatch, err := strconv.ParseBool(batch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %s", AssuredBatch, batch)
+		}
+		ac.Batch = isBatch
+	}
+
+	// Set conditional branches
+	if branches := req.Header.Get(AssuredBranches); branches != "" {
+		if err := json.Unmarshal([]byte(branches), &ac.Branches); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredBranches, err)
+		}
+	}
+
+	// Set circuit breaker
+	if breaker := req.Header.Get(AssuredBreaker); breaker != "" {
+		if err := json.Unmarshal([]byte(breaker), &ac.Breaker); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredBreaker, err)
+		}
+	}
+
+	// Set authentication handshake
+	if handshake := req.Header.Get(AssuredHandshake); handshake != "" {
+		if err := json.Unmarshal([]byte(handshake), &ac.Handshake); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredHandshake, err)
+		}
+	}
+
+	// Set session
+	if session := req.Header.Get(AssuredSession); session != "" {
+		if err := json.Unmarshal([]byte(session), &ac.Session); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredSession, err)
+		}
+	}
+
+	// Set csrf protection
+	if csrf := req.Header.Get(AssuredCSRF); csrf != "" {
+		if err := json.Unmarshal([]byte(csrf), &ac.CSRF); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredCSRF, err)
+		}
+	}
+
+	// Set asynchronous job
+	if job := req.Header.Get(AssuredJob); job != "" {
+		if err := json.Unmarshal([]byte(job), &ac.Job); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredJob, err)
+		}
+	}
+
+	// Set signed urls
+	if signedURL := req.Header.Get(AssuredSignedURL); signedURL != "" {
+		if err := json.Unmarshal([]byte(signedURL), &ac.SignedURL); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredSignedURL, err)
+		}
+	}
+
+	// Set localized responses
+	if locale := req.Header.Get(AssuredLocale); locale != "" {
+		if err := json.Unmarshal([]byte(locale), &ac.Locale); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredLocale, err)
+		}
+	}
+
+	// Set state extractions
+	if extract := req.Header.Get(AssuredExtract); extract != "" {
+		if err := json.Unmarshal([]byte(extract), &ac.Extract); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredExtract, err)
+		}
+		for name, extraction := range ac.Extract {
+			if field, err := extraction.validate(); err != nil {
+				return nil, fmt.Errorf("invalid '%s' header: %s.%s: %w", AssuredExtract, name, field, err)
+			}
+		}
+	}
+
+	// Set required headers
+	if required := req.Header.Get(AssuredRequiredHeaders); required != "" {
+		if err := json.Unmarshal([]byte(required), &ac.RequiredHeaders); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredRequiredHeaders, err)
+		}
+		for name, matcher := range ac.RequiredHeaders {
+			if _, err := matcher.compile(); err != nil {
+				return nil, fmt.Errorf("invalid '%s' header: %s.regex: %w", AssuredRequiredHeaders, name, err)
+			}
+		}
+	}
+
+	// Set GraphQL schema
+	if sdl := req.Header.Get(AssuredGraphQL); sdl != "" {
+		if _, err := ParseGraphQLSchema(strings.NewReader(sdl)); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredGraphQL, err)
+		}
+		ac.GraphQL = sdl
+	}
+
+	// Set caching headers
+	if cache := req.Header.Get(AssuredCache); cache != "" {
+		if err := json.Unmarshal([]byte(cache), &ac.Cache); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredCache, err)
+		}
+	}
+
+	// Set response padding
+	if padding := req.Header.Get(AssuredPadding); padding != "" {
+		if err := json.Unmarshal([]byte(padding), &ac.Padding); err != nil {
+			return nil, fmt.Errorf("invalid '%s' header: %w", AssuredPadding, err)
+		}
+	}
+
 	// Set headers
 	headers := map[string]string{}
 	for key, value := range req.Header {
@@ -120,12 +771,18 @@ func decodeAssuredCall(ctx context.Context, req *http.Request) (interface{}, err
 	}
 	ac.Headers = headers
 
-	// Set query
+	// Set query, with every value of the repeated query parameters
 	query := map[string]string{}
+	queryValues := map[string][]string{}
 	for key, value := range req.URL.Query() {
 		query[key] = value[0]
+		queryValues[key] = value
 	}
 	ac.Query = query
+	ac.QueryValues = queryValues
+
+	// Set matrix parameters
+	_, ac.Matrix = parseMatrix(ac.Path)
 
 	// Set response body
 	if req.Body != nil {
@@ -138,6 +795,15 @@ func decodeAssuredCall(ctx context.Context, req *http.Request) (interface{}, err
 	return &ac, nil
 }
 
+// decodeGivenCall converts a JSON given request into an assured Call object
+func decodeGivenCall(ctx context.Context, req *http.Request) (interface{}, error) {
+	var call Call
+	if err := json.NewDecoder(req.Body).Decode(&call); err != nil {
+		return nil, fmt.Errorf("invalid given call: %w", err)
+	}
+	return &call, nil
+}
+
 // decodeAssuredCallback converts an http request into an assured Callback object
 func decodeAssuredCallback(ctx context.Context, req *http.Request) (interface{}, error) {
 	ac := Call{
@@ -171,20 +837,90 @@ func decodeAssuredCallback(ctx context.Context, req *http.Request) (interface{},
 	return &ac, nil
 }
 
+// encodeJSON writes the response to the http response as JSON
+func encodeJSON(ctx context.Context, w http.ResponseWriter, i interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(i)
+}
+
 // encodeAssuredCall writes the assured Call to the http response as it is intended to be stubbed
 func encodeAssuredCall(ctx context.Context, w http.ResponseWriter, i interface{}) error {
 	switch resp := i.(type) {
 	case *Call:
-		for key, value := range resp.Headers {
-			if !strings.HasPrefix(key, "Assured-") {
-				w.Header().Set(key, value)
-			}
-		}
-		w.WriteHeader(resp.StatusCode)
-		_, _ = w.Write([]byte(resp.String()))
+		writeCall(w, resp)
 	case []*Call:
 		w.Header().Set("Content-Type", "application/json")
 		return json.NewEncoder(w).Encode(resp)
 	}
 	return nil
 }
+
+// encodeWhenCall writes the stubbed call served to the http response as it is intended to be stubbed, with its inferred Content-Type, if applicable
+func (a *AssuredEndpoints) encodeWhenCall(ctx context.Context, w http.ResponseWriter, i interface{}) error {
+	if call, ok := i.(*Call); ok {
+		a.inferHeader(w.Header(), call)
+	}
+	return encodeAssuredCall(ctx, w, i)
+}
+
+// setHeader sets the response header, keeping the casing of the header name if the headers are raw
+func setHeader(header http.Header, key, value string, raw bool) {
+	delete(header, key)
+	header.Del(key)
+	addHeader(header, key, value, raw)
+}
+
+// addHeader adds the response header value, keeping the casing of the header name if the headers are raw
+// The header with the canonical name is set to nil, which suppresses the automatic headers net/http would write, like Content-Type
+func addHeader(header http.Header, key, value string, raw bool) {
+	canonical := textproto.CanonicalMIMEHeaderKey(key)
+	if !raw || canonical == key || canonicalOnly(canonical) {
+		header[canonical] = append(header[canonical], value)
+		return
+	}
+	header[canonical] = nil
+	header[key] = append(header[key], value)
+}
+
+// canonicalOnly reports whether the header must keep its canonical name, because it controls the rest assured server
+// or net/http relies on it to frame the response
+func canonicalOnly(canonical string) bool {
+	return strings.HasPrefix(canonical, "Assured-") || canonical == "Content-Length" || canonical == "Transfer-Encoding"
+}
+
+// addHeaders adds the repeatable response headers in order, replacing the values of any headers with the same names
+// net/http writes the header names in sorted order, so only the order of a repeated header's values is preserved
+func addHeaders(header http.Header, headers []Header, raw bool) {
+	for _, h := range headers {
+		delete(header, h.Name)
+		header.Del(h.Name)
+	}
+	for _, h := range headers {
+		addHeader(header, h.Name, h.Value, raw)
+	}
+}
+
+// frame sets the response headers that delimit the response body with the framing
+// An identity transfer encoding has net/http delimit the response by closing the connection
+func frame(header http.Header, framing string, body []byte) {
+	switch framing {
+	case FramingContentLength:
+		header.Del("Transfer-Encoding")
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+	case FramingChunked:
+		header.Del("Content-Length")
+		header.Set("Transfer-Encoding", "chunked")
+	case FramingClose:
+		header.Del("Content-Length")
+		header.Set("Transfer-Encoding", "identity")
+	}
+}
+
+// validFraming reports whether the framing is a supported response framing
+func validFraming(framing string) bool {
+	switch framing {
+	case FramingContentLength, FramingChunked, FramingClose:
+		return true
+	}
+	return false
+}
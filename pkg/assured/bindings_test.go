@@ -3,10 +3,13 @@ package assured
 import (
 	"bytes"
 	"context"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
@@ -95,6 +98,171 @@ func TestApplicationRouterClearAllBinding(t *testing.T) {
 	require.Equal(t, "*", resp.Header().Get("Access-Control-Allow-Origin"))
 }
 
+func TestApplicationRouterBasePathBinding(t *testing.T) {
+	router := NewClient(WithBasePath("/mock/")).createApplicationRouter()
+
+	req, err := http.NewRequest(http.MethodGet, "/mock/given/rest/assured", bytes.NewBuffer([]byte(`{"assured": true}`)))
+	require.NoError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	req, err = http.NewRequest(http.MethodGet, "/mock/when/rest/assured", nil)
+	require.NoError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, `{"assured": true}`, resp.Body.String())
+
+	req, err = http.NewRequest(http.MethodGet, "/when/rest/assured", nil)
+	require.NoError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestApplicationRouterRootServingBinding(t *testing.T) {
+	router := NewClient(WithBasePath("mock"), WithRootServing(true)).createApplicationRouter()
+
+	for _, verb := range verbs {
+		req, err := http.NewRequest(verb, "/mock/given/rest/assured", bytes.NewBuffer([]byte(`{"assured": true}`)))
+		require.NoError(t, err)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		req, err = http.NewRequest(verb, "/rest/assured", nil)
+		require.NoError(t, err)
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "*", resp.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestApplicationRouterHealthBinding(t *testing.T) {
+	router := NewClient().createApplicationRouter()
+
+	req, err := http.NewRequest(http.MethodGet, "/health", nil)
+	require.NoError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"status":"ok"}`, resp.Body.String())
+}
+
+func TestApplicationRouterPprofBinding(t *testing.T) {
+	router := NewClient(WithPprof(true)).createApplicationRouter()
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine", "/debug/pprof/cmdline"} {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code, path)
+	}
+}
+
+func TestApplicationRouterPprofDisabled(t *testing.T) {
+	router := NewClient().createApplicationRouter()
+
+	req, err := http.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+	require.NoError(t, err)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestApplicationRouterPlainHandlersDecodeFailure(t *testing.T) {
+	router := NewClient(WithPlainHandlers(true)).createApplicationRouter()
+
+	req, err := http.NewRequest(http.MethodGet, "/given/rest/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredStatusSequence, "500,teapot")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusInternalServerError, resp.Code)
+	require.Equal(t, "text/plain; charset=utf-8", resp.Header().Get("Content-Type"))
+	require.Contains(t, resp.Body.String(), "invalid 'Assured-Status-Sequence' header")
+}
+
+func TestApplicationRouterAPIVersion(t *testing.T) {
+	router := NewClient().createApplicationRouter()
+
+	req, err := http.NewRequest(http.MethodGet, "/given/rest/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredAPIVersion, APIVersion)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, APIVersion, resp.Header().Get(AssuredAPIVersion))
+
+	req, err = http.NewRequest(http.MethodGet, "/verify/rest/assured", nil)
+	require.NoError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, legacyAPIVersion, resp.Header().Get(AssuredAPIVersion))
+
+	req, err = http.NewRequest(http.MethodGet, "/when/rest/assured", nil)
+	require.NoError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Empty(t, resp.Header().Get(AssuredAPIVersion))
+}
+
+func TestApplicationRouterAPIVersionUnsupported(t *testing.T) {
+	router := NewClient().createApplicationRouter()
+
+	req, err := http.NewRequest(http.MethodGet, "/given/rest/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredAPIVersion, "0")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Equal(t, APIVersion, resp.Header().Get(AssuredAPIVersion))
+	require.Equal(t, "unsupported 'Assured-Api-Version' header: 0, supported versions: 1,2", resp.Body.String())
+
+	req, err = http.NewRequest(http.MethodPost, "/given", bytes.NewBufferString(`{"path":"rest/assured"}`))
+	require.NoError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Equal(t, "unsupported 'Assured-Api-Version' header: 1, supported versions: 2", resp.Body.String())
+}
+
+func TestApplicationRouterGivenJSONBinding(t *testing.T) {
+	router := NewClient().createApplicationRouter()
+
+	req, err := http.NewRequest(http.MethodPost, "/given", bytes.NewBufferString(`{"path":"/rest/assured","status_code":201,"headers":{"x-assured":"json"},"response":"{\"assured\": true}"}`))
+	require.NoError(t, err)
+	req.Header.Set(AssuredAPIVersion, APIVersion)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+	req, err = http.NewRequest(http.MethodGet, "/when/rest/assured", nil)
+	require.NoError(t, err)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusCreated, resp.Code)
+	require.Equal(t, "json", resp.Header().Get("X-Assured"))
+	require.Equal(t, `{"assured": true}`, resp.Body.String())
+}
+
+func TestApplicationRouterGivenJSONBindingFailure(t *testing.T) {
+	router := NewClient().createApplicationRouter()
+
+	req, err := http.NewRequest(http.MethodPost, "/given", bytes.NewBufferString(`{"path":`))
+	require.NoError(t, err)
+	req.Header.Set(AssuredAPIVersion, APIVersion)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusInternalServerError, resp.Code)
+	require.Equal(t, "invalid given call: unexpected EOF", resp.Body.String())
+}
+
 func TestApplicationRouterFailure(t *testing.T) {
 	router := NewClient().createApplicationRouter()
 
@@ -108,12 +276,13 @@ func TestApplicationRouterFailure(t *testing.T) {
 func TestDecodeAssuredCall(t *testing.T) {
 	decoded := false
 	expected := &Call{
-		Path:       "test/assured",
-		StatusCode: http.StatusOK,
-		Method:     http.MethodPost,
-		Response:   []byte(`{"assured": true}`),
-		Headers:    map[string]string{},
-		Query:      map[string]string{"assured": "max"},
+		Path:        "test/assured",
+		StatusCode:  http.StatusOK,
+		Method:      http.MethodPost,
+		Response:    []byte(`{"assured": true}`),
+		Headers:     map[string]string{},
+		Query:       map[string]string{"assured": "max"},
+		QueryValues: map[string][]string{"assured": {"max"}},
 	}
 	testDecode := func(resp http.ResponseWriter, req *http.Request) {
 		c, err := decodeAssuredCall(context.TODO(), req)
@@ -137,11 +306,12 @@ func TestDecodeAssuredCall(t *testing.T) {
 func TestDecodeAssuredCallNilBody(t *testing.T) {
 	decoded := false
 	expected := &Call{
-		Path:       "test/assured",
-		StatusCode: http.StatusOK,
-		Method:     http.MethodDelete,
-		Headers:    map[string]string{},
-		Query:      map[string]string{},
+		Path:        "test/assured",
+		StatusCode:  http.StatusOK,
+		Method:      http.MethodDelete,
+		Headers:     map[string]string{},
+		Query:       map[string]string{},
+		QueryValues: map[string][]string{},
 	}
 	testDecode := func(resp http.ResponseWriter, req *http.Request) {
 		c, err := decodeAssuredCall(context.TODO(), req)
@@ -165,11 +335,12 @@ func TestDecodeAssuredCallNilBody(t *testing.T) {
 func TestDecodeAssuredCallStatus(t *testing.T) {
 	decoded := false
 	expected := &Call{
-		Path:       "test/assured",
-		StatusCode: http.StatusForbidden,
-		Method:     http.MethodGet,
-		Headers:    map[string]string{"Assured-Status": "403"},
-		Query:      map[string]string{},
+		Path:        "test/assured",
+		StatusCode:  http.StatusForbidden,
+		Method:      http.MethodGet,
+		Headers:     map[string]string{"Assured-Status": "403"},
+		Query:       map[string]string{},
+		QueryValues: map[string][]string{},
 	}
 	testDecode := func(resp http.ResponseWriter, req *http.Request) {
 		c, err := decodeAssuredCall(context.TODO(), req)
@@ -191,14 +362,567 @@ func TestDecodeAssuredCallStatus(t *testing.T) {
 	require.True(t, decoded, "decode method was not hit")
 }
 
+func TestDecodeAssuredCallStatusSequence(t *testing.T) {
+	decoded := false
+	expected := &Call{
+		Path:        "test/assured",
+		StatusCode:  http.StatusOK,
+		StatusCodes: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK},
+		Method:      http.MethodGet,
+		Headers:     map[string]string{"Assured-Status-Sequence": "500, 500, 200"},
+		Query:       map[string]string{},
+		QueryValues: map[string][]string{},
+	}
+	testDecode := func(resp http.ResponseWriter, req *http.Request) {
+		c, err := decodeAssuredCall(context.TODO(), req)
+
+		require.NoError(t, err)
+		require.Equal(t, expected, c)
+		decoded = true
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set("Assured-Status-Sequence", "500, 500, 200")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/given/{path:.*}", testDecode).Methods(http.MethodGet)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.True(t, decoded, "decode method was not hit")
+}
+
+func TestDecodeAssuredCallStatusSequenceFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set("Assured-Status-Sequence", "500,teapot")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid 'Assured-Status-Sequence' header")
+}
+
+func TestDecodeAssuredCallConcurrency(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredConcurrency, "2")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, 2, c.(*Call).Concurrency)
+}
+
+func TestDecodeAssuredCallMaxBodySize(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredMaxBodySize, "1024")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(1024), c.(*Call).MaxBodySize)
+}
+
+func TestDecodeAssuredCallMaxBodySizeFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredMaxBodySize, "big")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.EqualError(t, err, "invalid 'Assured-Max-Body-Size' header: big")
+}
+
+func TestDecodeAssuredCallMaxHeaderSize(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredMaxHeaderSize, "8192")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, 8192, c.(*Call).MaxHeaderSize)
+}
+
+func TestDecodeAssuredCallMaxHeaderSizeFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredMaxHeaderSize, "-1")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.EqualError(t, err, "invalid 'Assured-Max-Header-Size' header: -1")
+}
+
+func TestDecodeAssuredCallMaxURILength(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredMaxURILength, "2048")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, 2048, c.(*Call).MaxURILength)
+}
+
+func TestDecodeAssuredCallMaxURILengthFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredMaxURILength, "long")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.EqualError(t, err, "invalid 'Assured-Max-URI-Length' header: long")
+}
+
+func TestDecodeAssuredCallConcurrencyFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredConcurrency, "-1")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.EqualError(t, err, "invalid 'Assured-Concurrency' header: -1")
+}
+
+func TestDecodeAssuredCallBreaker(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredBreaker, `{"failures":3,"cooldown":10}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, &Breaker{Failures: 3, Cooldown: 10}, c.(*Call).Breaker)
+}
+
+func TestDecodeAssuredCallBreakerFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredBreaker, `{"failures":`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.ErrorContains(t, err, "invalid 'Assured-Breaker' header")
+}
+
+func TestDecodeAssuredCallHandshake(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredHandshake, `{"scheme":"Negotiate","challenges":["oRQwEqADCgEBoQsGCSqGSIb3EgECAg=="]}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, &Handshake{Scheme: HandshakeNegotiate, Challenges: []string{"oRQwEqADCgEBoQsGCSqGSIb3EgECAg=="}}, c.(*Call).Handshake)
+}
+
+func TestDecodeAssuredCallHandshakeFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredHandshake, `{"scheme":`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.ErrorContains(t, err, "invalid 'Assured-Handshake' header")
+}
+
+func TestDecodeAssuredCallSession(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredSession, `{"cookie":"sid","start":true,"ttl":60}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, &Session{Cookie: "sid", Start: true, TTL: 60}, c.(*Call).Session)
+}
+
+func TestDecodeAssuredCallSessionFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredSession, `{"start":`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.ErrorContains(t, err, "invalid 'Assured-Session' header")
+}
+
+func TestDecodeAssuredCallCSRF(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredCSRF, `{"validate":true,"header":"X-XSRF-Token"}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, &CSRF{Validate: true, Header: "X-XSRF-Token"}, c.(*Call).CSRF)
+}
+
+func TestDecodeAssuredCallCSRFFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredCSRF, `{"validate":`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.ErrorContains(t, err, "invalid 'Assured-CSRF' header")
+}
+
+func TestDecodeAssuredCallJob(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredJob, `{"name":"export","states":[{"status":"running"},{"status":"done","after":5}]}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, &Job{Name: "export", States: []JobState{{Status: "running"}, {Status: "done", After: 5}}}, c.(*Call).Job)
+}
+
+func TestDecodeAssuredCallJobFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredJob, `{"name":`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.ErrorContains(t, err, "invalid 'Assured-Job' header")
+}
+
+func TestDecodeAssuredCallSignedURL(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredSignedURL, `{"secret":"s3cr3t","issue":"downloads/report.pdf","ttl":60}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, &SignedURL{Secret: "s3cr3t", Issue: "downloads/report.pdf", TTL: 60}, c.(*Call).SignedURL)
+}
+
+func TestDecodeAssuredCallSignedURLFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredSignedURL, `{"secret":`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.ErrorContains(t, err, "invalid 'Assured-Signed-URL' header")
+}
+
+func TestDecodeAssuredCallLocale(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredLocale, `{"default":"en","messages":{"en":{"greeting":"Hello"}}}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, &Locale{Default: "en", Messages: map[string]map[string]string{"en": {"greeting": "Hello"}}}, c.(*Call).Locale)
+}
+
+func TestDecodeAssuredCallLocaleFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredLocale, `{"default":`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.ErrorContains(t, err, "invalid 'Assured-Locale' header")
+}
+
+func TestDecodeAssuredCallExtract(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredExtract, `{"orderID":{"from":"response","json_path":"$.id"}}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]Extraction{"orderID": {From: ExtractResponse, JSONPath: "$.id"}}, c.(*Call).Extract)
+}
+
+func TestDecodeAssuredCallExtractFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		extract string
+		want    string
+	}{
+		{name: "invalid json", extract: `{"orderID":`, want: "invalid 'Assured-Extract' header"},
+		{name: "invalid from", extract: `{"orderID":{"from":"cookie"}}`, want: `invalid 'Assured-Extract' header: orderID.from: invalid from "cookie"`},
+		{name: "invalid regex", extract: `{"orderID":{"regex":"("}}`, want: "invalid 'Assured-Extract' header: orderID.regex: invalid regex"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+			require.NoError(t, err)
+			req.Header.Set(AssuredExtract, tc.extract)
+
+			c, err := decodeAssuredCall(context.TODO(), req)
+
+			require.Nil(t, c)
+			require.ErrorContains(t, err, tc.want)
+		})
+	}
+}
+
+func TestDecodeAssuredCallRequiredHeaders(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredRequiredHeaders, `{"X-Tenant":{"equals":"acme"},"Authorization":{"regex":"^Bearer "}}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]HeaderMatcher{"X-Tenant": {Equals: "acme"}, "Authorization": {Regex: "^Bearer "}}, c.(*Call).RequiredHeaders)
+}
+
+func TestDecodeAssuredCallRequiredHeadersFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		required string
+		want     string
+	}{
+		{name: "invalid json", required: `{"X-Tenant":`, want: "invalid 'Assured-Required-Headers' header"},
+		{name: "invalid regex", required: `{"X-Tenant":{"regex":"("}}`, want: "invalid 'Assured-Required-Headers' header: X-Tenant.regex: invalid regex"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+			require.NoError(t, err)
+			req.Header.Set(AssuredRequiredHeaders, tc.required)
+
+			c, err := decodeAssuredCall(context.TODO(), req)
+
+			require.Nil(t, c)
+			require.ErrorContains(t, err, tc.want)
+		})
+	}
+}
+
+func TestDecodeAssuredCallGraphQL(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredGraphQL, `type Query { hello: String }`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, `type Query { hello: String }`, c.(*Call).GraphQL)
+}
+
+func TestDecodeAssuredCallGraphQLFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredGraphQL, `type Query { hello: }`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.ErrorContains(t, err, "invalid 'Assured-GraphQL' header")
+}
+
+func TestDecodeAssuredCallCache(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredCache, `{"max_age":60,"revalidate":true,"vary":["Accept"]}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, &Cache{MaxAge: 60, Revalidate: true, Vary: []string{"Accept"}}, c.(*Call).Cache)
+}
+
+func TestDecodeAssuredCallCacheFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredCache, `{"max_age":`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.ErrorContains(t, err, "invalid 'Assured-Cache' header")
+}
+
+func TestDecodeAssuredCallPadding(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredPadding, `{"size":1024,"incompressible":true}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, &Padding{Size: 1024, Incompressible: true}, c.(*Call).Padding)
+}
+
+func TestDecodeAssuredCallPaddingFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredPadding, `{"size":`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.ErrorContains(t, err, "invalid 'Assured-Padding' header")
+}
+
+func TestDecodeAssuredCallPathRegex(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredPathRegex, "users/[0-9]+/orders")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, "users/[0-9]+/orders", c.(*Call).PathRegex)
+}
+
+func TestDecodeAssuredCallPathRegexFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredPathRegex, "users/(")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.EqualError(t, err, "invalid 'Assured-Path-Regex' header: error parsing regexp: missing closing ): `users/(`")
+}
+
+func TestDecodeAssuredCallFraming(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredFraming, FramingChunked)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, FramingChunked, c.(*Call).Framing)
+}
+
+func TestDecodeAssuredCallFramingFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredFraming, "gzip")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.EqualError(t, err, "invalid 'Assured-Framing' header: gzip")
+}
+
+func TestDecodeAssuredCallInformational(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredInformational, `[{"status_code":103,"headers":{"Link":"</style.css>; rel=preload"}}]`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, []Informational{{StatusCode: http.StatusEarlyHints, Headers: map[string]string{"Link": "</style.css>; rel=preload"}}}, c.(*Call).Informational)
+}
+
+func TestDecodeAssuredCallInformationalFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredInformational, `[{"status_code":101}]`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.EqualError(t, err, "invalid 'Assured-Informational' header: invalid informational status code 101")
+}
+
+func TestDecodeAssuredCallResponseHeaders(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredResponseHeaders, `[{"name":"Set-Cookie","value":"a=1"},{"name":"Set-Cookie","value":"b=2"}]`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, []Header{{Name: "Set-Cookie", Value: "a=1"}, {Name: "Set-Cookie", Value: "b=2"}}, c.(*Call).ResponseHeaders)
+}
+
+func TestDecodeAssuredCallResponseHeadersFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredResponseHeaders, `{"name":"Set-Cookie"}`)
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.ErrorContains(t, err, "invalid 'Assured-Response-Headers' header")
+}
+
+func TestDecodeAssuredCallRawHeaders(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredRawHeaders, "true")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.True(t, c.(*Call).RawHeaders)
+}
+
+func TestDecodeAssuredCallRawHeadersFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredRawHeaders, "sometimes")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.EqualError(t, err, "invalid 'Assured-Raw-Headers' header: sometimes")
+}
+
+func TestDecodeAssuredCallBatch(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredBatch, "true")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.NoError(t, err)
+	require.True(t, c.(*Call).Batch)
+}
+
+func TestDecodeAssuredCallBatchFailure(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/given/test/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredBatch, "bulk")
+
+	c, err := decodeAssuredCall(context.TODO(), req)
+
+	require.Nil(t, c)
+	require.EqualError(t, err, "invalid 'Assured-Batch' header: bulk")
+}
+
 func TestDecodeAssuredCallMethod(t *testing.T) {
 	decoded := false
 	expected := &Call{
-		Path:       "test/assured",
-		StatusCode: http.StatusOK,
-		Method:     http.MethodDelete,
-		Headers:    map[string]string{"Assured-Method": "DELETE"},
-		Query:      map[string]string{},
+		Path:        "test/assured",
+		StatusCode:  http.StatusOK,
+		Method:      http.MethodDelete,
+		Headers:     map[string]string{"Assured-Method": "DELETE"},
+		Query:       map[string]string{},
+		QueryValues: map[string][]string{},
 	}
 	testDecode := func(resp http.ResponseWriter, req *http.Request) {
 		c, err := decodeAssuredCall(context.TODO(), req)
@@ -223,11 +947,12 @@ func TestDecodeAssuredCallMethod(t *testing.T) {
 func TestDecodeAssuredCallStatusFailure(t *testing.T) {
 	decoded := false
 	expected := &Call{
-		Path:       "test/assured",
-		StatusCode: http.StatusOK,
-		Method:     http.MethodGet,
-		Headers:    map[string]string{"Assured-Status": "four oh three"},
-		Query:      map[string]string{},
+		Path:        "test/assured",
+		StatusCode:  http.StatusOK,
+		Method:      http.MethodGet,
+		Headers:     map[string]string{"Assured-Status": "four oh three"},
+		Query:       map[string]string{},
+		QueryValues: map[string][]string{},
 	}
 	testDecode := func(resp http.ResponseWriter, req *http.Request) {
 		c, err := decodeAssuredCall(context.TODO(), req)
@@ -345,6 +1070,98 @@ func TestEncodeAssuredCall(t *testing.T) {
 	require.Empty(t, resp.Header().Get("Assured-Status"))
 }
 
+func TestEncodeAssuredCallFraming(t *testing.T) {
+	tests := []struct {
+		framing string
+		want    http.Header
+	}{
+		{framing: FramingContentLength, want: http.Header{"Content-Length": {"17"}}},
+		{framing: FramingChunked, want: http.Header{"Transfer-Encoding": {"chunked"}}},
+		{framing: FramingClose, want: http.Header{"Transfer-Encoding": {"identity"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.framing, func(t *testing.T) {
+			call := &Call{
+				StatusCode: http.StatusOK,
+				Response:   []byte(`{"assured": true}`),
+				Headers:    map[string]string{"Content-Length": "19"},
+				Framing:    tt.framing,
+			}
+			resp := httptest.NewRecorder()
+
+			err := encodeAssuredCall(context.TODO(), resp, call)
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, resp.Header())
+		})
+	}
+}
+
+func TestEncodeAssuredCallResponseHeaders(t *testing.T) {
+	call := &Call{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Set-Cookie": "stale=0", "Content-Type": "application/json"},
+		ResponseHeaders: []Header{
+			{Name: "Set-Cookie", Value: "session=abc; HttpOnly"},
+			{Name: "Link", Value: "</page/2>; rel=next"},
+			{Name: "set-cookie", Value: "theme=dark"},
+		},
+	}
+	resp := httptest.NewRecorder()
+
+	err := encodeAssuredCall(context.TODO(), resp, call)
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"session=abc; HttpOnly", "theme=dark"}, resp.Header().Values("Set-Cookie"))
+	require.Equal(t, []string{"</page/2>; rel=next"}, resp.Header().Values("Link"))
+	require.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+}
+
+func TestEncodeAssuredCallRawHeaders(t *testing.T) {
+	call := &Call{
+		StatusCode:      http.StatusOK,
+		Headers:         map[string]string{"x-request-ID": "abc", "content-type": "application/json", "Content-Length": "2", "Assured-Status": "403"},
+		ResponseHeaders: []Header{{Name: "set-cookie", Value: "a=1"}, {Name: "set-cookie", Value: "b=2"}},
+		Response:        []byte("{}"),
+		RawHeaders:      true,
+	}
+	resp := httptest.NewRecorder()
+
+	err := encodeAssuredCall(context.TODO(), resp, call)
+
+	require.NoError(t, err)
+	require.Equal(t, http.Header{
+		"x-request-ID":   {"abc"},
+		"X-Request-Id":   nil,
+		"content-type":   {"application/json"},
+		"Content-Type":   nil,
+		"Content-Length": {"2"},
+		"set-cookie":     {"a=1", "b=2"},
+		"Set-Cookie":     nil,
+	}, resp.Header())
+}
+
+func TestEncodeAssuredCallTrace(t *testing.T) {
+	call := &Call{
+		Path:       "/test/assured",
+		StatusCode: http.StatusOK,
+		Method:     http.MethodGet,
+		Headers: map[string]string{
+			AssuredTrace:           "true",
+			AssuredTraceMatch:      "GET:test/assured[0]",
+			AssuredTraceCandidates: "GET:test/assured[0]=matched",
+		},
+	}
+	resp := httptest.NewRecorder()
+
+	err := encodeAssuredCall(context.TODO(), resp, call)
+
+	require.NoError(t, err)
+	require.Equal(t, "GET:test/assured[0]", resp.Header().Get(AssuredTraceMatch))
+	require.Equal(t, "GET:test/assured[0]=matched", resp.Header().Get(AssuredTraceCandidates))
+	require.Empty(t, resp.Header().Get(AssuredTrace))
+}
+
 func TestEncodeAssuredCalls(t *testing.T) {
 	resp := httptest.NewRecorder()
 	expected, err := os.ReadFile("testdata/calls.json")
@@ -376,6 +1193,84 @@ var (
 	}
 )
 
+func TestApplicationRouterWhenStaticDecodeFailure(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{Path: "static/assured", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("static")}))
+
+	req, err := http.NewRequest(http.MethodGet, client.URL()+"/static/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredStatusSequence, "abc")
+	resp, err := http.DefaultClient.Do(req)
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "invalid 'Assured-Status-Sequence' header: strconv.Atoi: parsing \"abc\": invalid syntax", string(body))
+	panics, err := client.Panics()
+	require.NoError(t, err)
+	require.Empty(t, panics)
+	calls, err := client.Verify(http.MethodGet, "static/assured")
+	require.NoError(t, err)
+	require.Empty(t, calls)
+}
+
+func BenchmarkApplicationRouterWhenStatic(b *testing.B) {
+	benchmarkApplicationRouterWhen(b, Call{Path: "bench/assured", Response: []byte(`{"assured": true}`), Headers: map[string]string{"Content-Type": "application/json"}}, WithCallTracking(false))
+}
+
+func BenchmarkApplicationRouterWhenStaticTracking(b *testing.B) {
+	benchmarkApplicationRouterWhen(b, Call{Path: "bench/assured", Response: []byte(`{"assured": true}`), Headers: map[string]string{"Content-Type": "application/json"}}, WithJournalTTL(time.Millisecond))
+}
+
+func BenchmarkApplicationRouterWhenBranches(b *testing.B) {
+	benchmarkApplicationRouterWhen(b, Call{
+		Path:     "bench/assured",
+		Response: []byte(`{"assured": true}`),
+		Branches: []Branch{{When: Condition{Headers: map[string]string{"X-Tenant": "assured"}}, Response: []byte("tenant")}},
+	}, WithCallTracking(false))
+}
+
+func BenchmarkApplicationRouterWhenBranchesPlain(b *testing.B) {
+	benchmarkApplicationRouterWhen(b, Call{
+		Path:     "bench/assured",
+		Response: []byte(`{"assured": true}`),
+		Branches: []Branch{{When: Condition{Headers: map[string]string{"X-Tenant": "assured"}}, Response: []byte("tenant")}},
+	}, WithCallTracking(false), WithPlainHandlers(true))
+}
+
+// benchmarkApplicationRouterWhen measures serving the stubbed call through the application router, without the network
+func benchmarkApplicationRouterWhen(b *testing.B, call Call, opts ...Option) {
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	_, client := NewTestServer(b, opts...)
+	require.NoError(b, client.Given(call))
+	router := client.Router()
+	req := httptest.NewRequest(http.MethodGet, "/when/bench/assured", nil)
+	w := &discardResponseWriter{header: http.Header{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(w, req)
+	}
+	b.StopTimer()
+	require.Equal(b, http.StatusOK, w.status)
+}
+
+// discardResponseWriter is a reusable http.ResponseWriter that discards the response body
+type discardResponseWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardResponseWriter) Header() http.Header { return w.header }
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+
+func (w *discardResponseWriter) WriteHeader(status int) { w.status = status }
+
 func testCall1() *Call {
 	return &Call{
 		Path:       "test/assured",
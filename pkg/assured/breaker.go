@@ -0,0 +1,105 @@
+package assured
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Breaker models an upstream with a circuit breaker, which opens after a number of consecutive failures are served
+// While open, the upstream responds with fast 503 Service Unavailable responses for the cooldown, in seconds
+// Once the cooldown passes the breaker is half-open, and the next response decides whether it closes or opens again
+type Breaker struct {
+	Failures int `json:"failures"`
+	Cooldown int `json:"cooldown"`
+}
+
+// breakerState is the state of a stubbed call's circuit breaker
+type breakerState struct {
+	failures  int
+	openUntil time.Time
+	halfOpen  bool
+}
+
+// breakers are the circuit breaker states of the stubbed calls, by stubbed call ID, so every path a stubbed call matches shares its breaker
+type breakers struct {
+	states map[string]*breakerState
+	sync.Mutex
+}
+
+// open reports whether the call's breaker is open, how long until it is half-open, and whether the request let through is the half-open trial
+// Once the cooldown passes, the next request is let through as the half-open trial, and the rest are rejected until it responds
+func (b *breakers) open(id string) (bool, time.Duration, bool) {
+	b.Lock()
+	defer b.Unlock()
+	state, ok := b.states[id]
+	if !ok || state.openUntil.IsZero() {
+		return false, 0, false
+	}
+	if remaining := time.Until(state.openUntil); remaining > 0 || state.halfOpen {
+		return true, max(remaining, 0), false
+	}
+	state.halfOpen = true
+	return false, 0, true
+}
+
+// abandon lets the next request through as the half-open trial, if the trial let through never responded,
+// such as when its client disconnected, so the breaker doesn't reject every request waiting on it
+func (b *breakers) abandon(id string) {
+	b.Lock()
+	defer b.Unlock()
+	if state, ok := b.states[id]; ok {
+		state.halfOpen = false
+	}
+}
+
+// record counts the status code served for the call, opening the breaker after the consecutive failures
+// The half-open trial closes the breaker if it succeeds, or opens it again for the cooldown if it fails
+func (b *breakers) record(id string, breaker *Breaker, statusCode int) {
+	b.Lock()
+	defer b.Unlock()
+	if b.states == nil {
+		b.states = map[string]*breakerState{}
+	}
+	state, ok := b.states[id]
+	if !ok {
+		state = &breakerState{}
+		b.states[id] = state
+	}
+
+	if statusCode < http.StatusInternalServerError {
+		*state = breakerState{}
+		return
+	}
+	state.failures++
+	if state.halfOpen || state.failures >= breaker.Failures {
+		state.openUntil = time.Now().Add(time.Duration(breaker.Cooldown) * time.Second)
+		state.halfOpen = false
+	}
+}
+
+// reset closes the call's breaker
+func (b *breakers) reset(id string) {
+	b.Lock()
+	delete(b.states, id)
+	b.Unlock()
+}
+
+// resetAll closes every breaker
+func (b *breakers) resetAll() {
+	b.Lock()
+	b.states = nil
+	b.Unlock()
+}
+
+// openBreakerCall returns the fast 503 Service Unavailable response of the stubbed call's open breaker
+func openBreakerCall(stub *Call, remaining time.Duration) *Call {
+	return &Call{
+		Path:       stub.Path,
+		Method:     stub.Method,
+		StatusCode: http.StatusServiceUnavailable,
+		Headers:    map[string]string{"Retry-After": strconv.Itoa(int(math.Ceil(remaining.Seconds())))},
+	}
+}
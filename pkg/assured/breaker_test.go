@@ -0,0 +1,179 @@
+package assured
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakers(t *testing.T) {
+	b := &breakers{}
+	breaker := &Breaker{Failures: 2, Cooldown: 60}
+
+	b.record("GET:test", breaker, http.StatusInternalServerError)
+	open, _, _ := b.open("GET:test")
+	require.False(t, open)
+
+	b.record("GET:test", breaker, http.StatusBadGateway)
+	open, remaining, _ := b.open("GET:test")
+	require.True(t, open)
+	require.InDelta(t, time.Minute, remaining, float64(time.Second))
+
+	// The cooldown passes, so the next request is the half-open trial and the rest are rejected until it responds
+	b.states["GET:test"].openUntil = time.Now().Add(-time.Second)
+	open, _, _ = b.open("GET:test")
+	require.False(t, open)
+	open, remaining, _ = b.open("GET:test")
+	require.True(t, open)
+	require.Zero(t, remaining)
+
+	// The failed trial opens the breaker again
+	b.record("GET:test", breaker, http.StatusInternalServerError)
+	open, _, _ = b.open("GET:test")
+	require.True(t, open)
+
+	// The successful trial closes the breaker
+	b.states["GET:test"].openUntil = time.Now().Add(-time.Second)
+	open, _, _ = b.open("GET:test")
+	require.False(t, open)
+	b.record("GET:test", breaker, http.StatusOK)
+	open, _, _ = b.open("GET:test")
+	require.False(t, open)
+	require.Equal(t, &breakerState{}, b.states["GET:test"])
+}
+
+func TestBreakersReset(t *testing.T) {
+	b := &breakers{}
+	breaker := &Breaker{Failures: 1, Cooldown: 60}
+	b.record("GET:test", breaker, http.StatusInternalServerError)
+	b.record("GET:other", breaker, http.StatusInternalServerError)
+
+	b.reset("GET:test")
+	open, _, _ := b.open("GET:test")
+	require.False(t, open)
+	open, _, _ = b.open("GET:other")
+	require.True(t, open)
+
+	b.resetAll()
+	open, _, _ = b.open("GET:other")
+	require.False(t, open)
+}
+
+func TestWhenEndpointBreaker(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	stub := &Call{
+		Path:        "test/assured",
+		Method:      http.MethodGet,
+		StatusCodes: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK},
+		Breaker:     &Breaker{Failures: 2, Cooldown: 60},
+	}
+	endpoints.assuredCalls.Add(stub)
+	call := &Call{Path: "test/assured", Method: http.MethodGet}
+
+	for _, expected := range []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusServiceUnavailable} {
+		c, err := endpoints.WhenEndpoint(context.TODO(), call)
+		require.NoError(t, err)
+		require.Equal(t, expected, c.(*Call).StatusCode)
+	}
+
+	c, err := endpoints.WhenEndpoint(context.TODO(), call)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"Retry-After": "60"}, c.(*Call).Headers)
+
+	// The half-open trial reaches the upstream, which responds with the next status code in the sequence
+	endpoints.breakers.states[call.ID()].openUntil = time.Now().Add(-time.Second)
+	c, err = endpoints.WhenEndpoint(context.TODO(), call)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, c.(*Call).StatusCode)
+}
+
+func TestWhenEndpointBreakerPathTemplate(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	stub := &Call{Path: "users/{id}", Method: http.MethodGet, StatusCode: http.StatusInternalServerError, Breaker: &Breaker{Failures: 2, Cooldown: 30}}
+	_, err := endpoints.GivenEndpoint(context.TODO(), stub)
+	require.NoError(t, err)
+
+	// Failures served for any path the stubbed call matches open its breaker
+	for _, path := range []string{"users/1", "users/2"} {
+		c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: path, Method: http.MethodGet})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, c.(*Call).StatusCode)
+	}
+	c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "users/3", Method: http.MethodGet})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, c.(*Call).StatusCode)
+
+	// Clearing the stubbed call closes its breaker
+	_, err = endpoints.ClearEndpoint(context.TODO(), stub)
+	require.NoError(t, err)
+	open, _, _ := endpoints.breakers.open(stub.ID())
+	require.False(t, open)
+}
+
+func TestBreakersAbandon(t *testing.T) {
+	b := &breakers{}
+	b.record("GET:test", &Breaker{Failures: 1, Cooldown: 60}, http.StatusInternalServerError)
+	b.states["GET:test"].openUntil = time.Now().Add(-time.Second)
+
+	open, _, trial := b.open("GET:test")
+	require.False(t, open)
+	require.True(t, trial)
+	open, _, trial = b.open("GET:test")
+	require.True(t, open)
+	require.False(t, trial)
+
+	// The abandoned trial lets the next request through as the trial
+	b.abandon("GET:test")
+	open, _, trial = b.open("GET:test")
+	require.False(t, open)
+	require.True(t, trial)
+}
+
+func TestWhenEndpointBreakerTrialCanceled(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	stub := &Call{Path: "test/assured", Method: http.MethodGet, StatusCode: http.StatusInternalServerError, Concurrency: 1, Breaker: &Breaker{Failures: 1, Cooldown: 60}}
+	_, err := endpoints.GivenEndpoint(context.TODO(), stub)
+	require.NoError(t, err)
+	call := &Call{Path: "test/assured", Method: http.MethodGet}
+	_, err = endpoints.WhenEndpoint(context.TODO(), call)
+	require.NoError(t, err)
+	endpoints.breakers.states[stub.ID()].openUntil = time.Now().Add(-time.Second)
+
+	// The trial is canceled while queued for the only slot
+	limiter := endpoints.limiter(stub.ID(), 1)
+	limiter <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = endpoints.WhenEndpoint(ctx, call)
+	require.ErrorIs(t, err, context.Canceled)
+	<-limiter
+
+	c, err := endpoints.WhenEndpoint(context.TODO(), call)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, c.(*Call).StatusCode)
+	require.Empty(t, c.(*Call).Headers["Retry-After"])
+}
+
+func TestClientBreaker(t *testing.T) {
+	_, client := NewTestServer(t)
+
+	require.NoError(t, client.Given(Call{Path: "breaker/assured", StatusCode: http.StatusInternalServerError, Breaker: &Breaker{Failures: 1, Cooldown: 60}}))
+
+	resp, err := http.Get(client.URL() + "/breaker/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	resp, err = http.Get(client.URL() + "/breaker/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.Equal(t, "60", resp.Header.Get("Retry-After"))
+
+	require.NoError(t, client.Clear(http.MethodGet, "breaker/assured"))
+	require.NoError(t, client.Given(Call{Path: "breaker/assured", Breaker: &Breaker{Failures: 1, Cooldown: 60}}))
+	resp, err = http.Get(client.URL() + "/breaker/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
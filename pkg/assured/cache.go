@@ -0,0 +1,102 @@
+package assured
+
+import (
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cache models an upstream's HTTP caching headers, so HTTP cache middleware in front of a client can be exercised
+// The Cache-Control, Expires, and Vary headers are set from the max age, in seconds, and the cache's directives
+// A revalidating cache also sets an ETag for the response, and responds 304 Not Modified to requests with a matching If-None-Match
+type Cache struct {
+	MaxAge     int      `json:"max_age,omitempty"`
+	Private    bool     `json:"private,omitempty"`
+	NoStore    bool     `json:"no_store,omitempty"`
+	Revalidate bool     `json:"revalidate,omitempty"`
+	Vary       []string `json:"vary,omitempty"`
+}
+
+// PublicCache returns a cache that shared caches can store for the max age, varying by the request headers
+func PublicCache(maxAge time.Duration, vary ...string) *Cache {
+	return &Cache{MaxAge: int(maxAge.Seconds()), Vary: vary}
+}
+
+// PrivateCache returns a cache that only the client's own cache can store for the max age, varying by the request headers
+func PrivateCache(maxAge time.Duration, vary ...string) *Cache {
+	return &Cache{MaxAge: int(maxAge.Seconds()), Private: true, Vary: vary}
+}
+
+// RevalidateCache returns a cache that must revalidate the response with its ETag once the max age passes
+// A zero max age revalidates on every request
+func RevalidateCache(maxAge time.Duration, vary ...string) *Cache {
+	return &Cache{MaxAge: int(maxAge.Seconds()), Revalidate: true, Vary: vary}
+}
+
+// NoStoreCache returns a cache that must not store the response at all
+func NoStoreCache() *Cache {
+	return &Cache{NoStore: true}
+}
+
+// cacheControl returns the Cache-Control header value of the cache's directives
+func (c *Cache) cacheControl() string {
+	if c.NoStore {
+		return "no-store"
+	}
+	directives := []string{"public"}
+	if c.Private {
+		directives[0] = "private"
+	}
+	switch {
+	case c.Revalidate && c.MaxAge == 0:
+		directives = append(directives, "no-cache")
+	case c.Revalidate:
+		directives = append(directives, fmt.Sprintf("max-age=%d", c.MaxAge), "must-revalidate")
+	default:
+		directives = append(directives, fmt.Sprintf("max-age=%d", c.MaxAge))
+	}
+	return strings.Join(directives, ", ")
+}
+
+// cached returns the stubbed call's response with its caching headers
+// If the request revalidates a matching ETag, the response is 304 Not Modified without a body
+func (c *Call) cached(made *Call) *Call {
+	if c.Cache == nil {
+		return c
+	}
+	cached := *c
+	cached.Headers = maps.Clone(c.Headers)
+	if cached.Headers == nil {
+		cached.Headers = map[string]string{}
+	}
+
+	cached.Headers["Cache-Control"] = c.Cache.cacheControl()
+	if len(c.Cache.Vary) > 0 {
+		cached.Headers["Vary"] = strings.Join(c.Cache.Vary, ", ")
+	}
+	if c.Cache.NoStore {
+		return &cached
+	}
+	cached.Headers["Expires"] = time.Now().Add(time.Duration(c.Cache.MaxAge) * time.Second).UTC().Format(http.TimeFormat)
+	if !c.Cache.Revalidate {
+		return &cached
+	}
+
+	etag := c.etag()
+	cached.Headers["Etag"] = etag
+	if match := made.Headers["If-None-Match"]; match != "" && (match == "*" || strings.Contains(match, etag)) {
+		cached.StatusCode = http.StatusNotModified
+		cached.Response = nil
+	}
+	return &cached
+}
+
+// etag returns a strong entity tag of the stubbed call's response
+func (c *Call) etag() string {
+	hash := fnv.New64a()
+	_, _ = hash.Write(c.Response)
+	return fmt.Sprintf(`"%x"`, hash.Sum64())
+}
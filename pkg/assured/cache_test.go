@@ -0,0 +1,119 @@
+package assured
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheControl(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache *Cache
+		want  string
+	}{
+		{name: "public", cache: PublicCache(time.Minute), want: "public, max-age=60"},
+		{name: "private", cache: PrivateCache(time.Hour), want: "private, max-age=3600"},
+		{name: "revalidate", cache: RevalidateCache(time.Minute), want: "public, max-age=60, must-revalidate"},
+		{name: "revalidate every request", cache: RevalidateCache(0), want: "public, no-cache"},
+		{name: "no store", cache: NoStoreCache(), want: "no-store"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.cache.cacheControl())
+		})
+	}
+}
+
+func TestCallCached(t *testing.T) {
+	stub := &Call{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Response:   []byte(`{"assured": true}`),
+		Cache:      PublicCache(time.Minute, "Accept", "Accept-Encoding"),
+	}
+
+	cached := stub.cached(&Call{})
+
+	require.Equal(t, "public, max-age=60", cached.Headers["Cache-Control"])
+	require.Equal(t, "Accept, Accept-Encoding", cached.Headers["Vary"])
+	expires, err := http.ParseTime(cached.Headers["Expires"])
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(time.Minute), expires, 2*time.Second)
+	require.Empty(t, cached.Headers["Etag"])
+	require.Equal(t, map[string]string{"Content-Type": "application/json"}, stub.Headers)
+
+	stub.Cache = NoStoreCache()
+	cached = stub.cached(&Call{})
+
+	require.Equal(t, map[string]string{"Content-Type": "application/json", "Cache-Control": "no-store"}, cached.Headers)
+
+	stub.Cache = nil
+	require.Same(t, stub, stub.cached(&Call{}))
+}
+
+func TestCallCachedRevalidate(t *testing.T) {
+	stub := &Call{
+		StatusCode: http.StatusOK,
+		Response:   []byte(`{"assured": true}`),
+		Cache:      RevalidateCache(0),
+	}
+
+	cached := stub.cached(&Call{})
+
+	require.Equal(t, http.StatusOK, cached.StatusCode)
+	require.Equal(t, stub.etag(), cached.Headers["Etag"])
+	require.Equal(t, CallResponse(`{"assured": true}`), cached.Response)
+
+	for _, match := range []string{stub.etag(), `W/"stale", ` + stub.etag(), "*"} {
+		cached = stub.cached(&Call{Headers: map[string]string{"If-None-Match": match}})
+
+		require.Equal(t, http.StatusNotModified, cached.StatusCode)
+		require.Equal(t, stub.etag(), cached.Headers["Etag"])
+		require.Nil(t, cached.Response)
+	}
+
+	cached = stub.cached(&Call{Headers: map[string]string{"If-None-Match": `"stale"`}})
+	require.Equal(t, http.StatusOK, cached.StatusCode)
+}
+
+func TestWhenEndpointCache(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	endpoints.assuredCalls.Add(&Call{Path: "test/assured", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("assured"), Cache: RevalidateCache(time.Minute)})
+	etag := (&Call{Response: []byte("assured")}).etag()
+
+	c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "test/assured", Method: http.MethodGet})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, c.(*Call).StatusCode)
+	require.Equal(t, etag, c.(*Call).Headers["Etag"])
+
+	c, err = endpoints.WhenEndpoint(context.TODO(), &Call{Path: "test/assured", Method: http.MethodGet, Headers: map[string]string{"If-None-Match": etag}})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotModified, c.(*Call).StatusCode)
+}
+
+func TestClientCache(t *testing.T) {
+	_, client := NewTestServer(t)
+
+	require.NoError(t, client.Given(Call{Path: "cache/assured", Response: []byte("assured"), Cache: RevalidateCache(time.Minute, "Accept")}))
+
+	resp, err := http.Get(client.URL() + "/cache/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "public, max-age=60, must-revalidate", resp.Header.Get("Cache-Control"))
+	require.Equal(t, "Accept", resp.Header.Get("Vary"))
+	require.NotEmpty(t, resp.Header.Get("Expires"))
+	etag := resp.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req, err := http.NewRequest(http.MethodGet, client.URL()+"/cache/assured", nil)
+	require.NoError(t, err)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotModified, resp.StatusCode)
+	require.Equal(t, etag, resp.Header.Get("ETag"))
+}
@@ -1,28 +1,84 @@
 package assured
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 // Call is a structure containing a request that is stubbed or made
 type Call struct {
-	Path       string            `json:"path"`
-	Method     string            `json:"method"`
-	StatusCode int               `json:"status_code"`
-	Delay      int               `json:"delay"`
-	Headers    map[string]string `json:"headers"`
-	Query      map[string]string `json:"query,omitempty"`
-	Response   CallResponse      `json:"response,omitempty"`
-	Callbacks  []Callback        `json:"callbacks,omitempty"`
+	Path            string                   `json:"path"`
+	PathRegex       string                   `json:"path_regex,omitempty"`
+	Method          string                   `json:"method"`
+	StatusCode      int                      `json:"status_code"`
+	StatusCodes     []int                    `json:"status_codes,omitempty"`
+	Delay           int                      `json:"delay"`
+	Concurrency     int                      `json:"concurrency,omitempty"`
+	MaxBodySize     int64                    `json:"max_body_size,omitempty"`
+	MaxHeaderSize   int                      `json:"max_header_size,omitempty"`
+	MaxURILength    int                      `json:"max_uri_length,omitempty"`
+	Headers         map[string]string        `json:"headers"`
+	ResponseHeaders []Header                 `json:"response_headers,omitempty"`
+	RawHeaders      bool                     `json:"raw_headers,omitempty"`
+	Query           map[string]string        `json:"query,omitempty"`
+	QueryValues     map[string][]string      `json:"query_values,omitempty"`
+	Matrix          map[string]string        `json:"matrix,omitempty"`
+	RequiredHeaders map[string]HeaderMatcher `json:"required_headers,omitempty"`
+	PathParams      map[string]string        `json:"path_params,omitempty"`
+	RawURI          string                   `json:"raw_uri,omitempty"`
+	Connection      *ConnectionDetails       `json:"connection,omitempty"`
+	TLS             *TLSDetails              `json:"tls,omitempty"`
+	Response        CallResponse             `json:"response,omitempty"`
+	Callbacks       []Callback               `json:"callbacks,omitempty"`
+	Branches        []Branch                 `json:"branches,omitempty"`
+	Breaker         *Breaker                 `json:"breaker,omitempty"`
+	Handshake       *Handshake               `json:"handshake,omitempty"`
+	Session         *Session                 `json:"session,omitempty"`
+	CSRF            *CSRF                    `json:"csrf,omitempty"`
+	GraphQL         string                   `json:"graphql,omitempty"`
+	Locale          *Locale                  `json:"locale,omitempty"`
+	Extract         map[string]Extraction    `json:"extract,omitempty"`
+	Batch           bool                     `json:"batch,omitempty"`
+	Job             *Job                     `json:"job,omitempty"`
+	SignedURL       *SignedURL               `json:"signed_url,omitempty"`
+	Cache           *Cache                   `json:"cache,omitempty"`
+	Padding         *Padding                 `json:"padding,omitempty"`
+	Framing         string                   `json:"framing,omitempty"`
+	Informational   []Informational          `json:"informational,omitempty"`
+
+	// pathRegex is the compiled path regex, once the call is stubbed
+	pathRegex *regexp.Regexp
+	// pathRank is how specific the call's path template is, once the call is stubbed, or 0 for a path regex
+	pathRank int
+}
+
+// Header is a response header of a stubbed call. Unlike the call's Headers, a header name can be repeated, such as Set-Cookie
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
+// The framings of a stubbed call's response body, to test clients sensitive to how the response is delimited
+// Without a framing, the response uses a Content-Length if the body is written at once, else chunked encoding
+const (
+	// FramingContentLength delimits the response body with a Content-Length header
+	FramingContentLength = "content-length"
+	// FramingChunked delimits the response body with chunked transfer encoding
+	FramingChunked = "chunked"
+	// FramingClose delimits the response body by closing the connection, without a Content-Length or chunked encoding
+	FramingClose = "close"
+)
+
 // ID is used as a key when managing stubbed and made calls
 func (c Call) ID() string {
-	return fmt.Sprintf("%s:%s", c.Method, c.Path)
+	return c.Method + ":" + c.Path
 }
 
 // String converts a Call's Response into a string
@@ -73,3 +129,174 @@ type Callback struct {
 	Headers  map[string]string `json:"headers"`
 	Response CallResponse      `json:"response,omitempty"`
 }
+
+// Branch is a conditional response of a stubbed call, used when its condition matches the request
+type Branch struct {
+	When       Condition         `json:"when"`
+	StatusCode int               `json:"status_code,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Response   CallResponse      `json:"response,omitempty"`
+}
+
+// Condition is a structure containing the request values a Branch requires to be used
+// An empty Condition matches every request. Unsigned requires the request to be missing an AWS SigV4 signature,
+// Unauthenticated requires the request to be missing the authentication's credentials,
+// and ConnectionRequest requires the request to be the nth request made on its connection, e.g. 1 for the first request on each connection
+type Condition struct {
+	Headers           map[string]string         `json:"headers,omitempty"`
+	Query             map[string]string         `json:"query,omitempty"`
+	QueryValues       map[string]QueryCondition `json:"query_values,omitempty"`
+	Matrix            map[string]string         `json:"matrix,omitempty"`
+	BodyContains      string                    `json:"body_contains,omitempty"`
+	Unsigned          bool                      `json:"unsigned,omitempty"`
+	Unauthenticated   *Authentication           `json:"unauthenticated,omitempty"`
+	ConnectionRequest int                       `json:"connection_request,omitempty"`
+}
+
+// QueryCondition is a structure containing the values a repeated query parameter requires, e.g. ?id=1&id=2
+// Equals requires exactly the values in order, Contains requires every value in any order, and Count requires the number of values
+type QueryCondition struct {
+	Equals   []string `json:"equals,omitempty"`
+	Contains []string `json:"contains,omitempty"`
+	Count    *int     `json:"count,omitempty"`
+}
+
+// Matches checks if the query parameter's values satisfy the condition
+func (q QueryCondition) Matches(values []string) bool {
+	if q.Equals != nil && !slices.Equal(q.Equals, values) {
+		return false
+	}
+	for _, value := range q.Contains {
+		if !slices.Contains(values, value) {
+			return false
+		}
+	}
+	return q.Count == nil || *q.Count == len(values)
+}
+
+// Matches checks if the call made satisfies the condition
+func (c Condition) Matches(call *Call) bool {
+	for key, value := range c.Headers {
+		if call.Headers[http.CanonicalHeaderKey(key)] != value {
+			return false
+		}
+	}
+	for key, value := range c.Query {
+		if call.Query[key] != value {
+			return false
+		}
+	}
+	for key, condition := range c.QueryValues {
+		if !condition.Matches(call.QueryValues[key]) {
+			return false
+		}
+	}
+	for key, value := range c.Matrix {
+		if call.Matrix[key] != value {
+			return false
+		}
+	}
+	if c.Unsigned && sigV4Signed(call) {
+		return false
+	}
+	if c.Unauthenticated != nil && c.Unauthenticated.Authenticates(call) {
+		return false
+	}
+	if c.ConnectionRequest > 0 && (call.Connection == nil || call.Connection.Request != c.ConnectionRequest) {
+		return false
+	}
+	return bytes.Contains(call.Response, []byte(c.BodyContains))
+}
+
+// matchesQuery reports whether the call made has each of the stubbed call's query parameters
+func (c *Call) matchesQuery(made *Call) bool {
+	for key, value := range c.Query {
+		if made.Query[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// static reports whether the stubbed call always responds the same way, without a status sequence, branches, callbacks, delay, concurrency limit, breaker, cache, GraphQL schema, locale, counters, state, batch, job, or signed URL
+func (c *Call) static() bool {
+	return len(c.StatusCodes) == 0 && len(c.Branches) == 0 && c.Headers[AssuredCallbackKey] == "" && c.Headers[AssuredDelay] == "" && c.Concurrency == 0 && c.Breaker == nil && c.Cache == nil && c.GraphQL == "" && c.Locale == nil && !hasCounters(c.Response) && !c.hasState() && !c.hasPathParams() && !c.Batch && c.Job == nil && c.SignedURL == nil
+}
+
+// branch returns a copy of the stubbed call with the response of the first branch that matches the call made
+// If no branch matches, the stubbed call is returned
+func (c *Call) branch(made *Call) *Call {
+	for _, branch := range c.Branches {
+		if !branch.When.Matches(made) {
+			continue
+		}
+
+		branched := *c
+		if branch.StatusCode != 0 {
+			branched.StatusCode = branch.StatusCode
+		}
+		if branch.Response != nil {
+			branched.Response = branch.Response
+		}
+		branched.Headers = map[string]string{}
+		for key, value := range c.Headers {
+			branched.Headers[key] = value
+		}
+		// The stubbed content length no longer applies to the branch's response
+		if branch.Response != nil {
+			delete(branched.Headers, "Content-Length")
+		}
+		for key, value := range branch.Headers {
+			branched.Headers[key] = value
+		}
+		return &branched
+	}
+	return c
+}
+
+// size approximates the number of bytes the call holds in memory, by its path, method, headers, query, and response
+func (c *Call) size() int {
+	size := len(c.Path) + len(c.Method) + len(c.Response) + len(c.RawURI)
+	for key, value := range c.Headers {
+		size += len(key) + len(value)
+	}
+	for key, value := range c.Query {
+		size += len(key) + len(value)
+	}
+	for key, values := range c.QueryValues {
+		for _, value := range values {
+			size += len(key) + len(value)
+		}
+	}
+	if c.TLS != nil {
+		size += len(c.TLS.Version) + len(c.TLS.CipherSuite) + len(c.TLS.ServerName) + len(c.TLS.Protocol) + len(c.TLS.ClientSubject)
+	}
+	for key, value := range c.Matrix {
+		size += len(key) + len(value)
+	}
+	for _, header := range c.ResponseHeaders {
+		size += len(header.Name) + len(header.Value)
+	}
+	return size
+}
+
+// parseMatrix splits the semicolon delimited matrix parameters from the path's segments, e.g. users;id=1;role=admin/orders
+// A parameter repeated across segments keeps its first value, like the query, and a parameter without a value is empty
+func parseMatrix(path string) (string, map[string]string) {
+	if !strings.Contains(path, ";") {
+		return path, nil
+	}
+	matrix := map[string]string{}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		params := strings.Split(segment, ";")
+		segments[i] = params[0]
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(param, "=")
+			if _, ok := matrix[key]; !ok && key != "" {
+				matrix[key] = value
+			}
+		}
+	}
+	return strings.Join(segments, "/"), matrix
+}
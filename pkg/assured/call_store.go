@@ -1,11 +1,21 @@
 package assured
 
 import (
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type CallStore struct {
-	data map[string][]*Call
+	data  map[string][]*Call
+	times map[*Call]time.Time
+	// view is the immutable snapshot of the data that calls are read from without locking, if the store publishes one
+	view atomic.Pointer[map[string][]*Call]
+	// served are the ticks the calls were last served at, by call, kept out of the snapshot so serving a call never publishes one
+	served sync.Map
+	ticks  atomic.Uint64
 	sync.Mutex
 }
 
@@ -13,39 +23,266 @@ func NewCallStore() *CallStore {
 	return &CallStore{data: map[string][]*Call{}}
 }
 
+// NewStubStore creates a call store that publishes an immutable snapshot of its calls, swapped atomically on each change,
+// so matching calls at a high rate never contends on the lock with stubbing and clearing calls
+func NewStubStore() *CallStore {
+	c := NewCallStore()
+	c.view.Store(&map[string][]*Call{})
+	return c
+}
+
+// publish swaps the store's snapshot for a copy of its data, if it publishes one. The lock must be held
+// The snapshot's slices are clipped, so appending to the data's slices never writes to the calls they hold
+func (c *CallStore) publish() {
+	if c.view.Load() == nil {
+		return
+	}
+	view := make(map[string][]*Call, len(c.data))
+	for key, calls := range c.data {
+		view[key] = slices.Clip(calls)
+	}
+	c.view.Store(&view)
+}
+
 func (c *CallStore) Add(call *Call) {
 	c.Lock()
 	c.data[call.ID()] = append(c.data[call.ID()], call)
+	c.publish()
+	c.Unlock()
+}
+
+func (c *CallStore) Record(call *Call, at time.Time) {
+	c.Lock()
+	if c.times == nil {
+		c.times = map[*Call]time.Time{}
+	}
+	c.data[call.ID()] = append(c.data[call.ID()], call)
+	c.times[call] = at
+	c.publish()
+	c.Unlock()
+}
+
+func (c *CallStore) RecordBatch(calls []*Call, times []time.Time) {
+	c.Lock()
+	if c.times == nil {
+		c.times = map[*Call]time.Time{}
+	}
+	for i, call := range calls {
+		c.data[call.ID()] = append(c.data[call.ID()], call)
+		c.times[call] = times[i]
+	}
+	c.publish()
 	c.Unlock()
 }
 
 func (c *CallStore) AddAt(key string, call *Call) {
 	c.Lock()
 	c.data[key] = append(c.data[key], call)
+	c.publish()
 	c.Unlock()
 }
 
+// Rotate marks the call as served, so the calls stored with it are served before it again
 func (c *CallStore) Rotate(call *Call) {
+	c.RotateAt(call.ID(), call)
+}
+
+func (c *CallStore) Set(key string, calls ...*Call) {
 	c.Lock()
-	c.data[call.ID()] = append(c.data[call.ID()][1:], call)
+	c.forget(c.data[key])
+	c.data[key] = calls
+	c.publish()
 	c.Unlock()
 }
 
+func (c *CallStore) Keys(prefix string) []string {
+	c.Lock()
+	defer c.Unlock()
+	keys := []string{}
+	for key := range c.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Best returns the key with the prefix, and its calls, whose calls rank highest, or the first in order of those that rank the same
+// Calls that rank negative don't match
+func (c *CallStore) Best(prefix string, rank func(calls []*Call) int) (string, []*Call) {
+	var data map[string][]*Call
+	if view := c.view.Load(); view != nil {
+		data = *view
+	} else {
+		c.Lock()
+		defer c.Unlock()
+		data = c.data
+	}
+	found, best := "", -1
+	for key, calls := range data {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if r := rank(calls); r > best || (r == best && r >= 0 && key < found) {
+			found, best = key, r
+		}
+	}
+	return found, data[found]
+}
+
+// RotateAt marks the call stored at the key as served, so the calls stored with it are served before it again
+// The only call stored at a key is always served next, so it isn't marked
+func (c *CallStore) RotateAt(key string, call *Call) {
+	if calls := c.Get(key); len(calls) == 1 && calls[0] == call {
+		return
+	}
+	served, ok := c.served.Load(call)
+	if !ok {
+		served, _ = c.served.LoadOrStore(call, new(atomic.Uint64))
+	}
+	served.(*atomic.Uint64).Store(c.ticks.Add(1))
+}
+
+// Next returns the call to serve of the calls, the first of those served longest ago, so the calls are served in turn in the order they were stored
+func (c *CallStore) Next(calls []*Call) *Call {
+	if len(calls) == 0 {
+		return nil
+	}
+	next, oldest := calls[0], c.lastServed(calls[0])
+	for _, call := range calls[1:] {
+		if served := c.lastServed(call); served < oldest {
+			next, oldest = call, served
+		}
+	}
+	return next
+}
+
+// lastServed returns the tick the call was last served at, or 0 if it hasn't been served
+func (c *CallStore) lastServed(call *Call) uint64 {
+	if served, ok := c.served.Load(call); ok {
+		return served.(*atomic.Uint64).Load()
+	}
+	return 0
+}
+
+// forget drops the ticks the calls were served at. The lock must be held
+func (c *CallStore) forget(calls []*Call) {
+	for _, call := range calls {
+		c.served.Delete(call)
+	}
+}
+
+// forgetAll drops the ticks every call was served at. The lock must be held
+func (c *CallStore) forgetAll() {
+	c.served.Range(func(call, _ any) bool {
+		c.served.Delete(call)
+		return true
+	})
+}
+
 func (c *CallStore) Get(key string) []*Call {
+	if view := c.view.Load(); view != nil {
+		return (*view)[key]
+	}
 	c.Lock()
 	calls := c.data[key]
 	c.Unlock()
 	return calls
 }
 
+func (c *CallStore) Recorded(key string) ([]*Call, []time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	calls := append([]*Call{}, c.data[key]...)
+	times := make([]time.Time, len(calls))
+	for i, call := range calls {
+		times[i] = c.times[call]
+	}
+	return calls, times
+}
+
+func (c *CallStore) Purge(before time.Time) int {
+	c.Lock()
+	defer c.Unlock()
+	defer c.publish()
+	purged := 0
+	for key, calls := range c.data {
+		kept := []*Call{}
+		for _, call := range calls {
+			if at, ok := c.times[call]; ok && at.Before(before) {
+				delete(c.times, call)
+				purged++
+				continue
+			}
+			kept = append(kept, call)
+		}
+		if len(kept) == 0 {
+			delete(c.data, key)
+		} else {
+			c.data[key] = kept
+		}
+	}
+	return purged
+}
+
+func (c *CallStore) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	count := 0
+	for _, calls := range c.data {
+		count += len(calls)
+	}
+	return count
+}
+
+func (c *CallStore) Size() int {
+	c.Lock()
+	defer c.Unlock()
+	size := 0
+	for _, calls := range c.data {
+		for _, call := range calls {
+			size += call.size()
+		}
+	}
+	return size
+}
+
 func (c *CallStore) Clear(key string) {
 	c.Lock()
+	for _, call := range c.data[key] {
+		delete(c.times, call)
+	}
+	c.forget(c.data[key])
 	delete(c.data, key)
+	c.publish()
+	c.Unlock()
+}
+
+func (c *CallStore) Snapshot() map[string][]*Call {
+	c.Lock()
+	defer c.Unlock()
+	data := make(map[string][]*Call, len(c.data))
+	for key, calls := range c.data {
+		data[key] = slices.Clone(calls)
+	}
+	return data
+}
+
+func (c *CallStore) Restore(data map[string][]*Call) {
+	c.Lock()
+	c.data = data
+	c.times = nil
+	c.forgetAll()
+	c.publish()
 	c.Unlock()
 }
 
 func (c *CallStore) ClearAll() {
 	c.Lock()
 	c.data = map[string][]*Call{}
+	c.times = nil
+	c.forgetAll()
+	c.publish()
 	c.Unlock()
 }
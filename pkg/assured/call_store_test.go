@@ -0,0 +1,152 @@
+package assured
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStubStoreSnapshot(t *testing.T) {
+	store := NewStubStore()
+	call1, call2 := testCall1(), testCall2()
+	store.Add(call1)
+	snapshot := store.Get(call1.ID())
+
+	store.Add(call2)
+	view := store.view.Load()
+	store.Rotate(call1)
+
+	require.Equal(t, []*Call{call1}, snapshot)
+	require.Same(t, view, store.view.Load())
+	require.Equal(t, []*Call{call1, call2}, store.Get(call1.ID()))
+	require.Same(t, call2, store.Next(store.Get(call1.ID())))
+
+	store.Clear(call1.ID())
+
+	require.Equal(t, []*Call{call1}, snapshot)
+	require.Empty(t, store.Get(call1.ID()))
+
+	store.Restore(map[string][]*Call{call1.ID(): {call1, call2}})
+
+	require.Equal(t, []*Call{call1, call2}, store.Get(call1.ID()))
+	require.Same(t, call1, store.Next(store.Get(call1.ID())))
+
+	store.ClearAll()
+
+	require.Empty(t, store.Get(call1.ID()))
+}
+
+func TestStubStoreRotateSingleCall(t *testing.T) {
+	store := NewStubStore()
+	call := testCall1()
+	store.Add(call)
+	view := store.view.Load()
+
+	store.Rotate(call)
+	store.RotateAt(call.ID(), call)
+
+	require.Same(t, view, store.view.Load())
+	require.Equal(t, []*Call{call}, store.Get(call.ID()))
+}
+
+func TestStubStoreRotateMatched(t *testing.T) {
+	store := NewStubStore()
+	call1, call2, call3 := testCall1(), testCall2(), testCall1()
+	store.Add(call1)
+	store.Add(call2)
+	store.Add(call3)
+	snapshot := store.Get(call1.ID())
+
+	store.Rotate(call2)
+
+	require.Equal(t, []*Call{call1, call2, call3}, snapshot)
+	require.Equal(t, []*Call{call1, call2, call3}, store.Get(call1.ID()))
+	require.Same(t, call1, store.Next(snapshot))
+	require.Same(t, call3, store.Next([]*Call{call2, call3}))
+
+	store.Rotate(call1)
+	store.Rotate(call3)
+
+	require.Same(t, call2, store.Next(snapshot))
+	require.Nil(t, store.Next(nil))
+
+	store.Clear(call1.ID())
+	store.Add(call2)
+	store.Add(call1)
+
+	require.Same(t, call2, store.Next(store.Get(call1.ID())))
+}
+
+func BenchmarkStubStoreRotateManyKeys(b *testing.B) {
+	store := NewStubStore()
+	for i := 0; i < 10000; i++ {
+		store.Add(&Call{Method: http.MethodGet, Path: fmt.Sprintf("bench/%d", i)})
+		store.Add(&Call{Method: http.MethodGet, Path: fmt.Sprintf("bench/%d", i)})
+	}
+	id := "GET:bench/5000"
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store.Rotate(store.Next(store.Get(id)))
+	}
+}
+
+func TestStubStoreConcurrentMatching(t *testing.T) {
+	store := NewStubStore()
+	call1, call2 := testCall1(), testCall2()
+	store.Add(call1)
+	store.Add(call2)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				calls := store.Get(call1.ID())
+				if len(calls) > 0 {
+					store.Rotate(store.Next(calls))
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		store.Set(call1.ID(), call1, call2)
+		store.Clear(call1.ID())
+		store.Add(call1)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	require.NotEmpty(t, store.Get(call1.ID()))
+}
+
+func TestCallStoreBest(t *testing.T) {
+	for _, store := range []*CallStore{NewCallStore(), NewStubStore()} {
+		a, b, c := &Call{Method: "GET", Path: "a", Delay: 1}, &Call{Method: "GET", Path: "b", Delay: 2}, &Call{Method: "GET", Path: "c", Delay: 2}
+		store.Add(a)
+		store.Add(b)
+		store.Add(c)
+		store.Add(&Call{Method: "POST", Path: "d", Delay: 3})
+		delay := func(calls []*Call) int { return calls[0].Delay }
+
+		key, calls := store.Best("GET:", delay)
+		require.Equal(t, "GET:b", key)
+		require.Equal(t, []*Call{b}, calls)
+
+		key, calls = store.Best("GET:", func(calls []*Call) int { return -calls[0].Delay })
+		require.Empty(t, key)
+		require.Nil(t, calls)
+	}
+}
@@ -2,6 +2,7 @@ package assured
 
 import (
 	"encoding/json"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -144,3 +145,153 @@ func TestCallUnmarshalCallbacks(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, expected, call)
 }
+
+func TestConditionMatches(t *testing.T) {
+	made := &Call{
+		Headers:  map[string]string{"X-Tenant": "assured"},
+		Query:    map[string]string{"page": "2"},
+		Response: []byte(`{"type": "premium"}`),
+	}
+
+	require.True(t, Condition{}.Matches(made))
+	require.True(t, Condition{Headers: map[string]string{"x-tenant": "assured"}}.Matches(made))
+	require.True(t, Condition{Query: map[string]string{"page": "2"}, BodyContains: "premium"}.Matches(made))
+	require.False(t, Condition{Headers: map[string]string{"X-Tenant": "other"}}.Matches(made))
+	require.False(t, Condition{Query: map[string]string{"page": "3"}}.Matches(made))
+	require.False(t, Condition{BodyContains: "basic"}.Matches(made))
+}
+
+func TestQueryConditionMatches(t *testing.T) {
+	two, zero := 2, 0
+	tests := []struct {
+		name      string
+		condition QueryCondition
+		values    []string
+		want      bool
+	}{
+		{name: "empty", condition: QueryCondition{}, values: []string{"1"}, want: true},
+		{name: "equals", condition: QueryCondition{Equals: []string{"1", "2"}}, values: []string{"1", "2"}, want: true},
+		{name: "equals out of order", condition: QueryCondition{Equals: []string{"2", "1"}}, values: []string{"1", "2"}, want: false},
+		{name: "equals subset", condition: QueryCondition{Equals: []string{"1"}}, values: []string{"1", "2"}, want: false},
+		{name: "contains all", condition: QueryCondition{Contains: []string{"3", "1"}}, values: []string{"1", "2", "3"}, want: true},
+		{name: "contains missing", condition: QueryCondition{Contains: []string{"1", "4"}}, values: []string{"1", "2", "3"}, want: false},
+		{name: "count", condition: QueryCondition{Count: &two}, values: []string{"1", "1"}, want: true},
+		{name: "count mismatch", condition: QueryCondition{Count: &two}, values: []string{"1"}, want: false},
+		{name: "count absent", condition: QueryCondition{Count: &zero}, values: nil, want: true},
+		{name: "contains and count", condition: QueryCondition{Contains: []string{"1"}, Count: &two}, values: []string{"1", "2"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.condition.Matches(tt.values))
+		})
+	}
+}
+
+func TestConditionMatchesQueryValues(t *testing.T) {
+	made := &Call{
+		Query:       map[string]string{"id": "1"},
+		QueryValues: map[string][]string{"id": {"1", "2"}},
+	}
+
+	require.True(t, Condition{QueryValues: map[string]QueryCondition{"id": {Contains: []string{"2"}}}}.Matches(made))
+	require.False(t, Condition{QueryValues: map[string]QueryCondition{"id": {Equals: []string{"1"}}}}.Matches(made))
+	require.False(t, Condition{QueryValues: map[string]QueryCondition{"tag": {Contains: []string{"1"}}}}.Matches(made))
+}
+
+func TestParseMatrix(t *testing.T) {
+	tests := []struct {
+		path       string
+		wantPath   string
+		wantMatrix map[string]string
+	}{
+		{path: "users/orders", wantPath: "users/orders"},
+		{path: "users;id=1;role=admin/orders", wantPath: "users/orders", wantMatrix: map[string]string{"id": "1", "role": "admin"}},
+		{path: "users;id=1/orders;id=2;jsessionid", wantPath: "users/orders", wantMatrix: map[string]string{"id": "1", "jsessionid": ""}},
+		{path: "cars;color=red;;", wantPath: "cars", wantMatrix: map[string]string{"color": "red"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, matrix := parseMatrix(tt.path)
+
+			require.Equal(t, tt.wantPath, path)
+			require.Equal(t, tt.wantMatrix, matrix)
+		})
+	}
+}
+
+func TestConditionMatchesMatrix(t *testing.T) {
+	made := &Call{Matrix: map[string]string{"id": "1", "role": "admin"}}
+
+	require.True(t, Condition{Matrix: map[string]string{"role": "admin"}}.Matches(made))
+	require.False(t, Condition{Matrix: map[string]string{"role": "user"}}.Matches(made))
+	require.False(t, Condition{Matrix: map[string]string{"tenant": "acme"}}.Matches(made))
+}
+
+func TestConditionMatchesConnectionRequest(t *testing.T) {
+	made := &Call{Connection: &ConnectionDetails{ID: 7, Request: 1}}
+
+	require.True(t, Condition{ConnectionRequest: 1}.Matches(made))
+	require.False(t, Condition{ConnectionRequest: 2}.Matches(made))
+	require.False(t, Condition{ConnectionRequest: 1}.Matches(&Call{}))
+}
+
+func TestCallBranch(t *testing.T) {
+	stub := testCall1()
+	stub.Branches = []Branch{
+		{
+			When:       Condition{Headers: map[string]string{"X-Tenant": "assured"}},
+			StatusCode: http.StatusAccepted,
+			Headers:    map[string]string{"X-Branch": "tenant"},
+			Response:   []byte("tenant"),
+		},
+		{
+			When:       Condition{BodyContains: "premium"},
+			StatusCode: http.StatusCreated,
+		},
+	}
+
+	branched := stub.branch(&Call{Headers: map[string]string{"X-Tenant": "assured"}})
+	require.Equal(t, http.StatusAccepted, branched.StatusCode)
+	require.Equal(t, []byte("tenant"), []byte(branched.Response))
+	require.Equal(t, "tenant", branched.Headers["X-Branch"])
+	require.Equal(t, "gzip", branched.Headers["Accept-Encoding"])
+	require.Empty(t, stub.Headers["X-Branch"])
+
+	branched = stub.branch(&Call{Response: []byte(`{"type": "premium"}`)})
+	require.Equal(t, http.StatusCreated, branched.StatusCode)
+	require.Equal(t, stub.Response, branched.Response)
+
+	require.Equal(t, stub, stub.branch(&Call{}))
+}
+
+func TestCallUnmarshalBranches(t *testing.T) {
+	raw := `{
+		"path": "test/assured",
+		"method": "GET",
+		"status_code": 200,
+		"branches": [
+			{
+				"when": {"headers": {"X-Tenant": "assured"}, "body_contains": "premium"},
+				"status_code": 202,
+				"response": "{\"premium\": true}"
+			}
+		]
+	}`
+	expected := Call{
+		Path:       "test/assured",
+		Method:     "GET",
+		StatusCode: http.StatusOK,
+		Branches: []Branch{
+			{
+				When:       Condition{Headers: map[string]string{"X-Tenant": "assured"}, BodyContains: "premium"},
+				StatusCode: http.StatusAccepted,
+				Response:   []byte(`{"premium": true}`),
+			},
+		},
+	}
+
+	call := Call{}
+	err := json.Unmarshal([]byte(raw), &call)
+	require.NoError(t, err)
+	require.Equal(t, expected, call)
+}
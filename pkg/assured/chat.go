@@ -0,0 +1,176 @@
+package assured
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// ChatMessage is a message posted to a mock chat webhook API
+type ChatMessage struct {
+	Text            string          `json:"text,omitempty"`
+	Title           string          `json:"title,omitempty"`
+	Channel         string          `json:"channel,omitempty"`
+	Username        string          `json:"username,omitempty"`
+	ResponseType    string          `json:"response_type,omitempty"`
+	ReplaceOriginal bool            `json:"replace_original,omitempty"`
+	DeleteOriginal  bool            `json:"delete_original,omitempty"`
+	Payload         json.RawMessage `json:"payload"`
+}
+
+// chatPayload is the JSON payload of Slack's and Teams' webhook APIs
+type chatPayload struct {
+	Text            string `json:"text"`
+	Title           string `json:"title"`
+	Summary         string `json:"summary"`
+	Channel         string `json:"channel"`
+	Username        string `json:"username"`
+	ResponseType    string `json:"response_type"`
+	ReplaceOriginal bool   `json:"replace_original"`
+	DeleteOriginal  bool   `json:"delete_original"`
+	Attachments     []struct {
+		ContentType string `json:"contentType"`
+		Content     struct {
+			Body []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"body"`
+		} `json:"content"`
+	} `json:"attachments"`
+}
+
+// SlackWebhookCall returns the call to stub for a mock of a Slack incoming webhook, e.g. services/T000/B000/XXXX,
+// which accepts every message with 200 OK and the plain text body ok
+func SlackWebhookCall(path string) Call {
+	return Call{
+		Path:       path,
+		Method:     http.MethodPost,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		Response:   []byte("ok"),
+	}
+}
+
+// SlackResponseURLCall returns the call to stub for a mock of the response_url of a Slack interaction or slash command,
+// which accepts every message with 200 OK and the JSON body {"ok":true}
+func SlackResponseURLCall(path string) Call {
+	return Call{
+		Path:       path,
+		Method:     http.MethodPost,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Response:   []byte(`{"ok":true}`),
+	}
+}
+
+// SlackWebhookErrorCall returns the call to stub for a Slack webhook rejecting every message with the status code and error,
+// e.g. http.StatusNotFound and no_service, or http.StatusBadRequest and invalid_payload
+func SlackWebhookErrorCall(path string, statusCode int, slackError string) Call {
+	call := SlackWebhookCall(path)
+	call.StatusCode = statusCode
+	call.Response = []byte(slackError)
+	return call
+}
+
+// TeamsWebhookCall returns the call to stub for a mock of a Microsoft Teams incoming webhook,
+// which accepts every message card or adaptive card with 200 OK and the plain text body 1
+func TeamsWebhookCall(path string) Call {
+	return Call{
+		Path:       path,
+		Method:     http.MethodPost,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		Response:   []byte("1"),
+	}
+}
+
+// ChatMessages returns the messages posted to the stubbed chat webhook API calls, in the order they were posted for each call
+func (c *Client) ChatMessages(calls ...Call) ([]ChatMessage, error) {
+	messages := []ChatMessage{}
+	for _, call := range calls {
+		made, err := c.Verify(call.Method, strings.Trim(call.Path, "/"))
+		if err != nil {
+			return nil, err
+		}
+		for _, posted := range made {
+			message, err := ParseChatMessage(posted)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// AssertChatMessages reports a test error unless the stubbed chat webhook API call was posted a message with each expected text, in order
+func (c *Client) AssertChatMessages(t testing.TB, call Call, texts ...string) bool {
+	t.Helper()
+	messages, err := c.ChatMessages(call)
+	if err != nil {
+		t.Errorf("failed to verify chat messages %s: %s", call.Path, err)
+		return false
+	}
+	if len(messages) != len(texts) {
+		t.Errorf("expected %s to be posted %d chat messages, posted %d", call.Path, len(texts), len(messages))
+		return false
+	}
+	ok := true
+	for i, message := range messages {
+		if message.Text != texts[i] {
+			t.Errorf("expected chat message %d of %s to have text %q, found %q", i, call.Path, texts[i], message.Text)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// ParseChatMessage parses the message of a call made to a mock chat webhook API, a JSON payload,
+// or a Slack form encoded payload parameter
+// The text of a Teams adaptive card is its text blocks joined by new lines, and the title of a Teams message card falls back to its summary
+func ParseChatMessage(call Call) (ChatMessage, error) {
+	body := []byte(call.Response)
+	if strings.HasPrefix(call.Headers["Content-Type"], "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return ChatMessage{}, fmt.Errorf("invalid chat message: %w", err)
+		}
+		body = []byte(form.Get("payload"))
+	}
+	var payload chatPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ChatMessage{}, fmt.Errorf("invalid chat message: %w", err)
+	}
+
+	message := ChatMessage{
+		Text:            payload.Text,
+		Title:           payload.Title,
+		Channel:         payload.Channel,
+		Username:        payload.Username,
+		ResponseType:    payload.ResponseType,
+		ReplaceOriginal: payload.ReplaceOriginal,
+		DeleteOriginal:  payload.DeleteOriginal,
+		Payload:         body,
+	}
+	if message.Title == "" {
+		message.Title = payload.Summary
+	}
+	if message.Text == "" {
+		var lines []string
+		for _, attachment := range payload.Attachments {
+			if attachment.ContentType != "application/vnd.microsoft.card.adaptive" {
+				continue
+			}
+			for _, element := range attachment.Content.Body {
+				if element.Type == "TextBlock" {
+					lines = append(lines, element.Text)
+				}
+			}
+		}
+		message.Text = strings.Join(lines, "\n")
+	}
+	return message, nil
+}
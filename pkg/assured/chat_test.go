@@ -0,0 +1,77 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientChatMessages(t *testing.T) {
+	_, client := NewTestServer(t)
+	slack := SlackWebhookCall("services/T000/B000/XXXX")
+	responseURL := SlackResponseURLCall("commands/1234/5678")
+	teams := TeamsWebhookCall("webhookb2/alerts")
+	require.NoError(t, client.Given(slack, responseURL, teams))
+
+	resp, err := http.Post(client.URL()+"/services/T000/B000/XXXX", "application/json", strings.NewReader(`{"text": "Deploy failed", "channel": "#alerts", "username": "ci"}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(body))
+
+	resp, err = http.PostForm(client.URL()+"/services/T000/B000/XXXX", url.Values{"payload": {`{"text": "Deploy recovered"}`}})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Post(client.URL()+"/commands/1234/5678", "application/json", strings.NewReader(`{"text": "Approved", "response_type": "in_channel", "replace_original": true}`))
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"ok":true}`, string(body))
+
+	_, err = http.Post(client.URL()+"/webhookb2/alerts", "application/json", strings.NewReader(`{"@type": "MessageCard", "summary": "Disk full", "text": "/var is at 98%"}`))
+	require.NoError(t, err)
+	_, err = http.Post(client.URL()+"/webhookb2/alerts", "application/json", strings.NewReader(`{"type": "message", "attachments": [{"contentType": "application/vnd.microsoft.card.adaptive", "content": {"type": "AdaptiveCard", "body": [{"type": "TextBlock", "text": "CPU high"}, {"type": "Image", "url": "https://example.com/cpu.png"}, {"type": "TextBlock", "text": "host-1"}]}}]}`))
+	require.NoError(t, err)
+
+	messages, err := client.ChatMessages(slack, responseURL, teams)
+	require.NoError(t, err)
+	require.Len(t, messages, 5)
+	require.Equal(t, ChatMessage{Text: "Deploy failed", Channel: "#alerts", Username: "ci", Payload: messages[0].Payload}, messages[0])
+	require.Equal(t, "Deploy recovered", messages[1].Text)
+	require.Equal(t, ChatMessage{Text: "Approved", ResponseType: "in_channel", ReplaceOriginal: true, Payload: messages[2].Payload}, messages[2])
+	require.Equal(t, "Disk full", messages[3].Title)
+	require.Equal(t, "/var is at 98%", messages[3].Text)
+	require.Equal(t, "CPU high\nhost-1", messages[4].Text)
+
+	require.True(t, client.AssertChatMessages(t, slack, "Deploy failed", "Deploy recovered"))
+	mock := &testing.T{}
+	require.False(t, client.AssertChatMessages(mock, slack, "Deploy failed"))
+	require.False(t, client.AssertChatMessages(mock, slack, "Deploy failed", "Deploy failed"))
+	require.True(t, mock.Failed())
+}
+
+func TestClientSlackWebhookError(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(SlackWebhookErrorCall("services/T000/B000/GONE", http.StatusNotFound, "no_service")))
+
+	resp, err := http.Post(client.URL()+"/services/T000/B000/GONE", "application/json", strings.NewReader(`{"text": "hello"}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "no_service", string(body))
+}
+
+func TestParseChatMessageInvalid(t *testing.T) {
+	_, err := ParseChatMessage(Call{Response: []byte("hello")})
+	require.EqualError(t, err, "invalid chat message: invalid character 'h' looking for beginning of value")
+
+	_, err = ParseChatMessage(Call{Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, Response: []byte("payload=%zz")})
+	require.EqualError(t, err, `invalid chat message: invalid URL escape "%zz"`)
+}
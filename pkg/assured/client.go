@@ -2,13 +2,23 @@ package assured
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/handlers"
@@ -20,27 +30,87 @@ type Client struct {
 	Options
 	listener net.Listener
 	router   *mux.Router
+	server   *http.Server
+	err      error
+	remote   *url.URL
+	ctx      context.Context
+	cancel   context.CancelFunc
+	legacy   atomic.Bool
 }
 
 // NewClient creates a new go-rest-assured client
+// If the client is unable to listen on the configured port, the error is logged and returned by the client's methods
 func NewClient(opts ...Option) *Client {
-	c := Client{
-		Options: DefaultOptions,
-	}
-	c.Options.applyOptions(opts...)
-
-	var err error
-	c.listener, err = net.Listen("tcp", fmt.Sprintf(":%d", c.Options.Port))
+	c, err := NewClientE(opts...)
 	if err != nil {
 		slog.With("error", err, "port", c.Options.Port).Error("unable to create http listener")
-	} else {
-		c.Options.Port = c.listener.Addr().(*net.TCPAddr).Port
 	}
+	return c
+}
 
+// NewClientE creates a new go-rest-assured client and returns an error if the client is unable to listen on the configured port
+func NewClientE(opts ...Option) (*Client, error) {
+	c := newClient(opts...)
+	if c.err == nil {
+		c.err = c.listen()
+	}
 	c.router = c.createApplicationRouter()
+	c.server = &http.Server{
+		Handler:      handlers.RecoveryHandler()(c.router),
+		ConnContext:  withConnection,
+		ReadTimeout:  c.serverReadTimeout,
+		WriteTimeout: c.serverWriteTimeout,
+		IdleTimeout:  c.serverIdleTimeout,
+		TLSConfig:    c.serverTLSConfig(),
+	}
+	return c, c.err
+}
+
+// NewRemoteClient creates a new go-rest-assured client for a rest assured server that is already serving traffic,
+// such as the standalone application. The path of the url is used as the base path of the rest assured endpoints
+func NewRemoteClient(rawURL string, opts ...Option) (*Client, error) {
+	remote, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if remote.Scheme == "" || remote.Host == "" {
+		return nil, fmt.Errorf("invalid remote url %q", rawURL)
+	}
+
+	c := newClient(append([]Option{WithBasePath(remote.Path)}, opts...)...)
+	c.setRemote(remote)
+	return c, nil
+}
+
+// newClient creates a go-rest-assured client with the options applied
+func newClient(opts ...Option) *Client {
+	c := Client{
+		Options: DefaultOptions,
+	}
+	c.Options.applyOptions(opts...)
+	c.err = c.Options.generateCertificates()
+	if c.err == nil && !c.Options.errorFormat.valid() {
+		c.err = fmt.Errorf("invalid error format %q", c.Options.errorFormat)
+	}
+	if c.err == nil && !c.Options.responseValidation.valid() {
+		c.err = fmt.Errorf("invalid response validation %q", c.Options.responseValidation)
+	}
+	c.Options.httpClient = c.Options.tunedHTTPClient()
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	// Reserve a prefix for the rest assured endpoints so they don't collide with stubbed endpoints served at the root
+	if c.Options.rootServing && c.Options.basePath == "" {
+		c.Options.basePath = RootServingBasePath
+	}
 	return &c
 }
 
+// setRemote points the client at a rest assured server that it is not serving itself
+func (c *Client) setRemote(remote *url.URL) {
+	c.remote = remote
+	c.Options.host = remote.Hostname()
+	c.Options.Port, _ = strconv.Atoi(remote.Port())
+}
+
 // NewClient creates a new go-rest-assured client and starts serving traffic
 func NewClientServe(opts ...Option) *Client {
 	client := NewClient(opts...)
@@ -51,40 +121,215 @@ func NewClientServe(opts ...Option) *Client {
 	return client
 }
 
+// listen creates the http listener for the client, retrying on the subsequent ports or port range if configured
+func (c *Client) listen() error {
+	err := errors.New("no ports to listen on")
+	for _, port := range c.Options.ports() {
+		c.listener, err = net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err == nil {
+			c.Options.Port = c.listener.Addr().(*net.TCPAddr).Port
+			// Release the port if it can't be published, so a failed client doesn't hold it open
+			if err := c.writePortFile(); err != nil {
+				_ = c.listener.Close()
+				c.listener = nil
+				return err
+			}
+			return nil
+		}
+	}
+	if c.Options.portMin > 0 {
+		return fmt.Errorf("unable to listen on port range %d-%d: %w", c.Options.portMin, c.Options.portMax, err)
+	}
+	return fmt.Errorf("unable to listen on port %d: %w", c.Options.Port, err)
+}
+
+// writePortFile writes the port the client is listening on to the port file, if configured
+// The port is written to a temporary file that is renamed into place, so a process polling the port file never reads it partially written
+func (c *Client) writePortFile() error {
+	if c.Options.portFile == "" {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.Options.portFile), filepath.Base(c.Options.portFile)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to write port file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(strconv.Itoa(c.Options.Port))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.Options.portFile)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write port file: %w", err)
+	}
+	return nil
+}
+
 // Serve starts the Rest Assured client to begin listening on the application endpoints
 func (c *Client) Serve() error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.remote != nil {
+		return fmt.Errorf("cannot serve a remote client")
+	}
 	if c.listener == nil {
 		return fmt.Errorf("invalid client")
 	}
 
-	if c.tlsCertFile != "" && c.tlsKeyFile != "" {
-		return http.ServeTLS(c.listener, handlers.RecoveryHandler()(c.router), c.tlsCertFile, c.tlsKeyFile)
+	if c.tlsFaultCert != nil || c.autoCerts != nil {
+		return c.server.ServeTLS(c.listener, "", "")
+	} else if c.tlsCertFile != "" && c.tlsKeyFile != "" {
+		return c.server.ServeTLS(c.listener, c.tlsCertFile, c.tlsKeyFile)
 	} else {
-		return http.Serve(c.listener, handlers.RecoveryHandler()(c.router))
+		return c.server.Serve(c.listener)
 	}
 }
 
-// url returns the url to used by the client internally
-func (c *Client) url() string {
+// hostURL returns the url of the rest assured server without any path
+func (c *Client) hostURL() string {
+	if c.remote != nil {
+		return fmt.Sprintf("%s://%s", c.remote.Scheme, c.remote.Host)
+	}
 	schema := "http"
-	if c.tlsCertFile != "" && c.tlsKeyFile != "" {
+	if c.tlsFaultCert != nil || c.autoCerts != nil || (c.tlsCertFile != "" && c.tlsKeyFile != "") {
 		schema = "https"
 	}
 	return fmt.Sprintf("%s://%s:%d", schema, c.host, c.Port)
 }
 
+// url returns the url to used by the client internally
+func (c *Client) url() string {
+	return fmt.Sprintf("%s%s", c.hostURL(), c.basePath)
+}
+
 // URL returns the url to use to test you stubbed endpoints
 func (c *Client) URL() string {
+	if c.rootServing {
+		return c.hostURL()
+	}
 	return fmt.Sprintf("%s/when", c.url())
 }
 
+// EchoURL returns the url of the echo endpoint, which responds to any request with its metadata with near-zero overhead,
+// to measure the ceiling of a client stack against the same server used for mocks
+func (c *Client) EchoURL() string {
+	return fmt.Sprintf("%s/__echo__", c.url())
+}
+
+// Router returns the underlying router so custom handlers can be registered alongside the rest assured endpoints
+func (c *Client) Router() *mux.Router {
+	return c.router
+}
+
 // Close is used to close the running service
 func (c *Client) Close() error {
-	return c.listener.Close()
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.listener == nil {
+		return c.err
+	}
+	err := c.listener.Close()
+	// Close the open connections as well, so clients can't keep stubbing calls over kept-alive connections
+	_ = c.server.Close()
+	return err
 }
 
 // Given stubs assured Call(s)
+// The calls are one stub set revision, so they are rolled back at once
 func (c *Client) Given(calls ...Call) error {
+	return c.given(uuid.NewString(), calls...)
+}
+
+// Replace stubs the Call(s) in place of all assured calls at once, as one stub set revision, keeping the made calls
+// Rollback restores the calls replaced, such as when a broken fixture set is loaded
+// A server that predates replacing calls at once has its calls cleared, along with the made calls, and then stubbed
+func (c *Client) Replace(calls ...Call) error {
+	if c.err != nil {
+		return c.err
+	}
+	revision := uuid.NewString()
+	if !c.legacy.Load() {
+		replaced, err := c.replace(revision, calls)
+		if err != nil || replaced {
+			return err
+		}
+	}
+	if err := c.clearAll(revision); err != nil {
+		return err
+	}
+	return c.given(revision, calls...)
+}
+
+// replace replaces all assured calls with the calls at once, and reports whether the server replaced them
+func (c *Client) replace(revision string, calls []Call) (bool, error) {
+	stubs := make([]Call, len(calls))
+	for i, call := range calls {
+		if call.Method == "" {
+			call.Method = http.MethodGet
+		}
+		call.Path = strings.Trim(call.Path, "/")
+		stubs[i] = call
+	}
+	body, err := json.Marshal(stubs)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/stubs", c.url()), bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(AssuredRevision, revision)
+	resp, err := c.do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if version := resp.Header.Get(AssuredAPIVersion); version == "" || version == legacyAPIVersion {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("failure to replace calls: %s", message)
+	}
+	return true, nil
+}
+
+// Rollback restores the assured calls to the stub set revision before the latest change
+func (c *Client) Rollback() error {
+	if c.err != nil {
+		return c.err
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/stubs/rollback", c.url()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failure to roll back calls: %s", message)
+	}
+	return nil
+}
+
+// given stubs the assured Call(s) as the stub set revision
+func (c *Client) given(revision string, calls ...Call) error {
+	if c.err != nil {
+		return c.err
+	}
 	for _, call := range calls {
 		// Default method to GET
 		if call.Method == "" {
@@ -94,52 +339,255 @@ func (c *Client) Given(calls ...Call) error {
 		// Sanitize Path
 		call.Path = strings.Trim(call.Path, "/")
 
-		req, err := http.NewRequest(call.Method, fmt.Sprintf("%s/given/%s", c.url(), call.Path), bytes.NewReader(call.Response))
+		if !c.legacy.Load() {
+			stubbed, err := c.givenJSON(call, revision)
+			if err != nil {
+				return err
+			}
+			if stubbed {
+				continue
+			}
+			// The server predates the JSON given endpoint, so stub calls with the legacy header protocol from now on
+			c.legacy.Store(true)
+		}
+		if err := c.givenHeaders(call, revision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// givenJSON stubs the call with the JSON given endpoint, and reports false if the server predates the endpoint
+func (c *Client) givenJSON(call Call, revision string) (bool, error) {
+	// Validate the methods the same as the header protocol, which sends them as the request methods
+	if _, err := http.NewRequest(call.Method, c.url(), nil); err != nil {
+		return false, err
+	}
+	for _, callback := range call.Callbacks {
+		if callback.Target == "" {
+			return false, fmt.Errorf("cannot stub callback without target")
+		}
+		if _, err := http.NewRequest(callback.Method, callback.Target, nil); err != nil {
+			return false, err
+		}
+	}
+	body, err := json.Marshal(call)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/given", c.url()), bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(AssuredRevision, revision)
+	resp, err := c.do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if version := resp.Header.Get(AssuredAPIVersion); version == "" || version == legacyAPIVersion {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("failure to stub call: %s", message)
+	}
+	return true, nil
+}
+
+// givenHeaders stubs the call with the legacy header protocol
+func (c *Client) givenHeaders(call Call, revision string) error {
+	req, err := http.NewRequest(call.Method, fmt.Sprintf("%s/given/%s", c.url(), call.Path), bytes.NewReader(call.Response))
+	if err != nil {
+		return err
+	}
+	if len(call.Query) > 0 {
+		query := url.Values{}
+		for key, value := range call.Query {
+			query.Set(key, value)
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+	if call.PathRegex != "" {
+		req.Header.Set(AssuredPathRegex, call.PathRegex)
+	}
+	if call.StatusCode != 0 {
+		req.Header.Set(AssuredStatus, strconv.Itoa(call.StatusCode))
+	}
+	if call.Delay > 0 {
+		req.Header.Set(AssuredDelay, strconv.Itoa(call.Delay))
+	}
+	if call.Concurrency > 0 {
+		req.Header.Set(AssuredConcurrency, strconv.Itoa(call.Concurrency))
+	}
+	if call.MaxBodySize > 0 {
+		req.Header.Set(AssuredMaxBodySize, strconv.FormatInt(call.MaxBodySize, 10))
+	}
+	if call.MaxHeaderSize > 0 {
+		req.Header.Set(AssuredMaxHeaderSize, strconv.Itoa(call.MaxHeaderSize))
+	}
+	if call.MaxURILength > 0 {
+		req.Header.Set(AssuredMaxURILength, strconv.Itoa(call.MaxURILength))
+	}
+	if len(call.StatusCodes) > 0 {
+		codes := make([]string, len(call.StatusCodes))
+		for i, code := range call.StatusCodes {
+			codes[i] = strconv.Itoa(code)
+		}
+		req.Header.Set(AssuredStatusSequence, strings.Join(codes, ","))
+	}
+	if len(call.Branches) > 0 {
+		branches, err := json.Marshal(call.Branches)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(AssuredBranches, string(branches))
+	}
+	if call.Breaker != nil {
+		breaker, err := json.Marshal(call.Breaker)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(AssuredBreaker, string(breaker))
+	}
+	if call.CSRF != nil {
+		csrf, err := json.Marshal(call.CSRF)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(AssuredCSRF, string(csrf))
+	}
+	if call.Job != nil {
+		job, err := json.Marshal(call.Job)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(AssuredJob, string(job))
+	}
+	if call.SignedURL != nil {
+		signedURL, err := json.Marshal(call.SignedURL)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(AssuredSignedURL, string(signedURL))
+	}
+	if call.Locale != nil {
+		locale, err := json.Marshal(call.Locale)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(AssuredLocale, string(locale))
+	}
+	if len(call.Extract) > 0 {
+		extract, err := json.Marshal(call.Extract)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(AssuredExtract, string(extract))
+	}
+	if len(call.RequiredHeaders) > 0 {
+		required, err := json.Marshal(call.RequiredHeaders)
 		if err != nil {
 			return err
 		}
-		if call.StatusCode != 0 {
-			req.Header.Set(AssuredStatus, strconv.Itoa(call.StatusCode))
+		req.Header.Set(AssuredRequiredHeaders, string(required))
+	}
+	if call.GraphQL != "" {
+		// Header values cannot span lines, and whitespace between GraphQL tokens is insignificant
+		req.Header.Set(AssuredGraphQL, strings.Join(strings.Fields(call.GraphQL), " "))
+	}
+	if call.Session != nil {
+		session, err := json.Marshal(call.Session)
+		if err != nil {
+			return err
 		}
-		if call.Delay > 0 {
-			req.Header.Set(AssuredDelay, strconv.Itoa(call.Delay))
+		req.Header.Set(AssuredSession, string(session))
+	}
+	if call.Handshake != nil {
+		handshake, err := json.Marshal(call.Handshake)
+		if err != nil {
+			return err
 		}
-		for key, value := range call.Headers {
-			req.Header.Set(key, value)
+		req.Header.Set(AssuredHandshake, string(handshake))
+	}
+	if call.RawHeaders {
+		req.Header.Set(AssuredRawHeaders, "true")
+	}
+	if call.Batch {
+		req.Header.Set(AssuredBatch, "true")
+	}
+	if len(call.ResponseHeaders) > 0 {
+		headers, err := json.Marshal(call.ResponseHeaders)
+		if err != nil {
+			return err
 		}
+		req.Header.Set(AssuredResponseHeaders, string(headers))
+	}
+	if len(call.Informational) > 0 {
+		informational, err := json.Marshal(call.Informational)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(AssuredInformational, string(informational))
+	}
+	if call.Framing != "" {
+		req.Header.Set(AssuredFraming, call.Framing)
+	}
+	if call.Cache != nil {
+		cache, err := json.Marshal(call.Cache)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(AssuredCache, string(cache))
+	}
+	if call.Padding != nil {
+		padding, err := json.Marshal(call.Padding)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(AssuredPadding, string(padding))
+	}
+	for key, value := range call.Headers {
+		req.Header.Set(key, value)
+	}
 
-		// Create callbacks
-		callbacks := make([]*http.Request, len(call.Callbacks))
-		callbackKey := uuid.NewString()
-		for i, callback := range call.Callbacks {
-			if callback.Target == "" {
-				return fmt.Errorf("cannot stub callback without target")
-			}
-			callbackReq, err := http.NewRequest(callback.Method, fmt.Sprintf("%s/callback", c.url()), bytes.NewReader(callback.Response))
-			if err != nil {
-				return err
-			}
-			callbackReq.Header.Set(AssuredCallbackTarget, callback.Target)
-			callbackReq.Header.Set(AssuredCallbackKey, callbackKey)
-			if callback.Delay > 0 {
-				callbackReq.Header.Set(AssuredCallbackDelay, strconv.Itoa(callback.Delay))
-			}
-			for key, value := range callback.Headers {
-				callbackReq.Header.Set(key, value)
-			}
-			callbacks[i] = callbackReq
+	// Create callbacks
+	callbacks := make([]*http.Request, len(call.Callbacks))
+	callbackKey := uuid.NewString()
+	for i, callback := range call.Callbacks {
+		if callback.Target == "" {
+			return fmt.Errorf("cannot stub callback without target")
+		}
+		callbackReq, err := http.NewRequest(callback.Method, fmt.Sprintf("%s/callback", c.url()), bytes.NewReader(callback.Response))
+		if err != nil {
+			return err
 		}
-		if len(callbacks) > 0 {
-			req.Header.Set(AssuredCallbackKey, callbackKey)
+		callbackReq.Header.Set(AssuredCallbackTarget, callback.Target)
+		callbackReq.Header.Set(AssuredCallbackKey, callbackKey)
+		if callback.Delay > 0 {
+			callbackReq.Header.Set(AssuredCallbackDelay, strconv.Itoa(callback.Delay))
 		}
+		for key, value := range callback.Headers {
+			callbackReq.Header.Set(key, value)
+		}
+		callbacks[i] = callbackReq
+	}
+	if len(callbacks) > 0 {
+		req.Header.Set(AssuredCallbackKey, callbackKey)
+	}
 
-		if _, err = c.httpClient.Do(req); err != nil {
+	for _, req := range append([]*http.Request{req}, callbacks...) {
+		req.Header.Set(AssuredRevision, revision)
+		resp, err := c.do(req)
+		if err != nil {
 			return err
 		}
-		for _, cReq := range callbacks {
-			if _, err = c.httpClient.Do(cReq); err != nil {
-				return err
-			}
+		message, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failure to stub call: %s", message)
 		}
 	}
 	return nil
@@ -147,11 +595,14 @@ func (c *Client) Given(calls ...Call) error {
 
 // Verify returns all of the calls made against a stubbed method and path
 func (c *Client) Verify(method, path string) ([]Call, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
 	req, err := http.NewRequest(method, fmt.Sprintf("%s/verify/%s", c.url(), path), nil)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -168,22 +619,437 @@ func (c *Client) Verify(method, path string) ([]Call, error) {
 	return calls, nil
 }
 
+// Duplicates returns the groups of duplicate calls made to the rest assured server, calls with the same method, path, and body
+// made within the window of each other. A zero window finds every call with the same body regardless of when it was made
+func (c *Client) Duplicates(method, path string, window time.Duration) ([][]Call, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/duplicates/%s", c.url(), path), nil)
+	if err != nil {
+		return nil, err
+	}
+	if window > 0 {
+		req.URL.RawQuery = url.Values{"window": []string{window.String()}}.Encode()
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failure to find duplicate calls")
+	}
+	defer resp.Body.Close()
+
+	var duplicates [][]Call
+	if err = json.NewDecoder(resp.Body).Decode(&duplicates); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
+
+// AssertNoDuplicates reports a test error if duplicate calls were made to the rest assured server within the window,
+// such as a client double submitting a request on retries
+func (c *Client) AssertNoDuplicates(t testing.TB, method, path string, window time.Duration) bool {
+	t.Helper()
+	duplicates, err := c.Duplicates(method, path, window)
+	if err != nil {
+		t.Errorf("failed to find duplicate calls for %s:%s: %s", method, path, err)
+		return false
+	}
+	for _, group := range duplicates {
+		t.Errorf("found %d duplicate calls for %s:%s within %s: %s", len(group), method, path, window, group[0].String())
+	}
+	return len(duplicates) == 0
+}
+
+// Compact purges the made calls older than the journal ttl and returns the number of calls purged
+func (c *Client) Compact() (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/compact", c.url()), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failure to compact calls")
+	}
+
+	var compacted struct {
+		Purged int `json:"purged"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&compacted); err != nil {
+		return 0, err
+	}
+	return compacted.Purged, nil
+}
+
+// Stats returns the memory usage of the rest assured server
+func (c *Client) Stats() (Stats, error) {
+	if c.err != nil {
+		return Stats{}, c.err
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/stats", c.url()), nil)
+	if err != nil {
+		return Stats{}, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Stats{}, fmt.Errorf("failure to get stats")
+	}
+
+	var stats Stats
+	if err = json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return Stats{}, err
+	}
+	return stats, nil
+}
+
 // Clear assured calls for a Method and Path
 func (c *Client) Clear(method, path string) error {
+	if c.err != nil {
+		return c.err
+	}
 	req, err := http.NewRequest(method, fmt.Sprintf("%s/clear/%s", c.url(), path), nil)
 	if err != nil {
 		return err
 	}
-	_, err = c.httpClient.Do(req)
-	return err
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failure to clear calls: %s", message)
+	}
+	return nil
+}
+
+// ClearMethod clears the assured calls for every Path with a Method
+func (c *Client) ClearMethod(method string) error {
+	return c.clearMatching(url.Values{"method": {method}})
+}
+
+// ClearPrefix clears the assured calls for every Method with a Path starting with the prefix, e.g. v1/payments/
+func (c *Client) ClearPrefix(prefix string) error {
+	return c.clearMatching(url.Values{"prefix": {prefix}})
+}
+
+// clearMatching clears the assured calls matching the method and prefix query
+func (c *Client) clearMatching(query url.Values) error {
+	if c.err != nil {
+		return c.err
+	}
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/clear-matching?%s", c.url(), query.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failure to clear calls matching %s", query.Encode())
+	}
+	return nil
 }
 
 // ClearAll clears all assured calls
 func (c *Client) ClearAll() error {
+	return c.clearAll("")
+}
+
+// clearAll clears all assured calls as the stub set revision
+func (c *Client) clearAll(revision string) error {
+	if c.err != nil {
+		return c.err
+	}
 	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/clear", c.url()), nil)
 	if err != nil {
 		return err
 	}
-	_, err = c.httpClient.Do(req)
-	return err
+	if revision != "" {
+		req.Header.Set(AssuredRevision, revision)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failure to clear calls: %s", message)
+	}
+	return nil
+}
+
+// Freeze rejects stubbing and clearing calls until unfrozen, so a misbehaving parallel test can't change the stubbed calls mid-run
+func (c *Client) Freeze() error {
+	return c.freeze(http.MethodPost)
+}
+
+// Unfreeze allows stubbing and clearing calls again
+func (c *Client) Unfreeze() error {
+	return c.freeze(http.MethodDelete)
+}
+
+// freeze freezes or unfreezes the stubbed calls with the method
+func (c *Client) freeze(method string) error {
+	if c.err != nil {
+		return c.err
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/freeze", c.url()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failure to freeze calls")
+	}
+	return nil
+}
+
+// ClearJournal clears all made calls, keeping the stubbed calls, to reset what is verified between test cases
+func (c *Client) ClearJournal() error {
+	if c.err != nil {
+		return c.err
+	}
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/journal", c.url()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failure to clear journal")
+	}
+	return nil
+}
+
+// ExpireSessions expires the sessions with the IDs, the values of their session cookies, or every session without IDs,
+// so calls requiring a session are challenged until a new session is started
+func (c *Client) ExpireSessions(ids ...string) error {
+	if c.err != nil {
+		return c.err
+	}
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/sessions?%s", c.url(), url.Values{"id": ids}.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failure to expire sessions")
+	}
+	return nil
+}
+
+// CSRFMismatches returns the calls made to stubbed calls validating CSRF tokens that were rejected, and the reasons they were rejected
+// The mismatches are cleared with the made calls journal
+func (c *Client) CSRFMismatches() ([]CSRFMismatch, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/csrf/mismatches", c.url()), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failure to get csrf mismatches")
+	}
+	var mismatches []CSRFMismatch
+	if err := json.NewDecoder(resp.Body).Decode(&mismatches); err != nil {
+		return nil, err
+	}
+	return mismatches, nil
+}
+
+// Counters returns the current value of each counter of the counter placeholders rendered in responses
+func (c *Client) Counters() (map[string]int64, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/counters", c.url()), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failure to get counters")
+	}
+	var counters map[string]int64
+	if err := json.NewDecoder(resp.Body).Decode(&counters); err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
+
+// ResetCounters resets the counters with the names, or every counter without names, so they start over at 1
+func (c *Client) ResetCounters(names ...string) error {
+	if c.err != nil {
+		return c.err
+	}
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/counters?%s", c.url(), url.Values{"name": names}.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failure to reset counters")
+	}
+	return nil
+}
+
+// State returns the shared state of the values extracted by stubbed calls
+func (c *Client) State() (map[string]string, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/state", c.url()), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failure to get state")
+	}
+	var state map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// ClearState clears the state's values with the names, or every value without names
+func (c *Client) ClearState(names ...string) error {
+	if c.err != nil {
+		return c.err
+	}
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/state?%s", c.url(), url.Values{"name": names}.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failure to clear state")
+	}
+	return nil
+}
+
+// Panics returns the panics recovered while serving the calls made, with their stacks and the stubbed calls they were matched to
+func (c *Client) Panics() ([]Panic, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/diagnostics/panics", c.url()), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failure to get panics")
+	}
+	var panics []Panic
+	if err := json.NewDecoder(resp.Body).Decode(&panics); err != nil {
+		return nil, err
+	}
+	return panics, nil
+}
+
+// ClearPanics clears the panics recovered
+func (c *Client) ClearPanics() error {
+	if c.err != nil {
+		return c.err
+	}
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/diagnostics/panics", c.url()), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failure to clear panics")
+	}
+	return nil
+}
+
+// do sends the request to the rest assured endpoints, negotiating the api version with the Assured-Api-Version header
+// Servers that predate the header don't respond with it, and are assumed to serve the legacy api version
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set(AssuredAPIVersion, APIVersion)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if version := resp.Header.Get(AssuredAPIVersion); version != "" && !slices.Contains(supportedAPIVersions, version) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("rest assured server api version %s is not supported by the client api version %s", version, APIVersion)
+	}
+	return resp, nil
 }
@@ -4,14 +4,20 @@ import (
 	"bytes"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,7 +33,7 @@ func TestClient(t *testing.T) {
 	require.NoError(t, client.Given(*testCall2()))
 	require.NoError(t, client.Given(*testCall3()))
 
-	req, err := http.NewRequest(http.MethodGet, url+"/test/assured", bytes.NewReader([]byte(`{"calling":"you"}`)))
+	req, err := http.NewRequest(http.MethodGet, url+"/test/assured?assured=max", bytes.NewReader([]byte(`{"calling":"you"}`)))
 	require.NoError(t, err)
 
 	resp, err := http.DefaultClient.Do(req)
@@ -59,19 +65,27 @@ func TestClient(t *testing.T) {
 
 	calls, err := client.Verify("GET", "test/assured")
 	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	// The calls are made on one keep-alive connection, with an ID assigned by the server
+	require.NotNil(t, calls[0].Connection)
+	conn := calls[0].Connection.ID
 	require.Equal(t, []Call{
 		{
-			Method:     "GET",
-			Path:       "test/assured",
-			StatusCode: 200,
-			Response:   []byte(`{"calling":"you"}`),
-			Headers:    map[string]string{"Content-Length": "17", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"}},
+			Method:      "GET",
+			Path:        "test/assured",
+			StatusCode:  200,
+			Response:    []byte(`{"calling":"you"}`),
+			Headers:     map[string]string{"Content-Length": "17", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"},
+			Query:       map[string]string{"assured": "max"},
+			QueryValues: map[string][]string{"assured": {"max"}},
+			Connection:  &ConnectionDetails{ID: conn, Request: 1}},
 		{
 			Method:     "GET",
 			Path:       "test/assured",
 			StatusCode: 200,
 			Response:   []byte(`{"calling":"again"}`),
-			Headers:    map[string]string{"Content-Length": "19", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"}}}, calls)
+			Headers:    map[string]string{"Content-Length": "19", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"},
+			Connection: &ConnectionDetails{ID: conn, Request: 2}}}, calls)
 
 	calls, err = client.Verify("POST", "teapot/assured")
 	require.NoError(t, err)
@@ -81,7 +95,8 @@ func TestClient(t *testing.T) {
 			Path:       "teapot/assured",
 			StatusCode: 200,
 			Response:   []byte(`{"calling":"here"}`),
-			Headers:    map[string]string{"Content-Length": "18", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"}}}, calls)
+			Headers:    map[string]string{"Content-Length": "18", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"},
+			Connection: &ConnectionDetails{ID: conn, Request: 3}}}, calls)
 
 	err = client.Clear("GET", "test/assured")
 	require.NoError(t, err)
@@ -98,7 +113,8 @@ func TestClient(t *testing.T) {
 			Path:       "teapot/assured",
 			StatusCode: 200,
 			Response:   []byte(`{"calling":"here"}`),
-			Headers:    map[string]string{"Content-Length": "18", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"}}}, calls)
+			Headers:    map[string]string{"Content-Length": "18", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"},
+			Connection: &ConnectionDetails{ID: conn, Request: 3}}}, calls)
 
 	err = client.ClearAll()
 	require.NoError(t, err)
@@ -112,6 +128,202 @@ func TestClient(t *testing.T) {
 	require.Nil(t, calls)
 }
 
+func TestClientClearMatching(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(
+		Call{Path: "v1/payments/1", Method: http.MethodGet},
+		Call{Path: "v1/payments/1", Method: http.MethodPost},
+		Call{Path: "v1/payments", Method: http.MethodPost},
+		Call{Path: "v1/refunds", Method: http.MethodPost},
+		Call{Path: "v1/refunds", Method: http.MethodGet},
+	))
+	for _, call := range []Call{{Path: "v1/payments/1", Method: http.MethodGet}, {Path: "v1/refunds", Method: http.MethodGet}} {
+		req, err := http.NewRequest(call.Method, client.URL()+"/"+call.Path, nil)
+		require.NoError(t, err)
+		_, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+	}
+	stubbed := func(method, path string) int {
+		req, err := http.NewRequest(method, client.URL()+"/"+path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	require.NoError(t, client.ClearPrefix("v1/payments/"))
+	calls, err := client.Verify(http.MethodGet, "v1/payments/1")
+	require.NoError(t, err)
+	require.Nil(t, calls)
+	require.Equal(t, http.StatusInternalServerError, stubbed(http.MethodGet, "v1/payments/1"))
+	require.Equal(t, http.StatusInternalServerError, stubbed(http.MethodPost, "v1/payments/1"))
+	require.Equal(t, http.StatusOK, stubbed(http.MethodPost, "v1/payments"))
+
+	require.NoError(t, client.ClearMethod(http.MethodPost))
+	require.Equal(t, http.StatusInternalServerError, stubbed(http.MethodPost, "v1/payments"))
+	require.Equal(t, http.StatusInternalServerError, stubbed(http.MethodPost, "v1/refunds"))
+	require.Equal(t, http.StatusOK, stubbed(http.MethodGet, "v1/refunds"))
+	calls, err = client.Verify(http.MethodGet, "v1/refunds")
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+
+	req, err := http.NewRequest(http.MethodDelete, strings.TrimSuffix(client.URL(), "/when")+"/clear-matching", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Equal(t, http.StatusOK, stubbed(http.MethodGet, "v1/refunds"))
+}
+
+func TestClientClearJournal(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{Path: "test/assured", Method: http.MethodGet, Response: []byte("stubbed")}))
+	_, err := http.Get(client.URL() + "/test/assured")
+	require.NoError(t, err)
+
+	require.NoError(t, client.ClearJournal())
+	calls, err := client.Verify(http.MethodGet, "test/assured")
+	require.NoError(t, err)
+	require.Nil(t, calls)
+
+	resp, err := http.Get(client.URL() + "/test/assured")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "stubbed", string(body))
+	calls, err = client.Verify(http.MethodGet, "test/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+}
+
+func TestClientFreeze(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{Path: "test/assured", Method: http.MethodGet, Response: []byte("stubbed")}))
+	require.NoError(t, client.Freeze())
+
+	require.EqualError(t, client.Given(Call{Path: "test/assured", Method: http.MethodGet, Response: []byte("changed")}), "failure to stub call: stubbed calls are frozen")
+	require.EqualError(t, client.Given(Call{Path: "test/assured", Callbacks: []Callback{{Target: "http://localhost/callback"}}}), "failure to stub call: stubbed calls are frozen")
+	require.EqualError(t, client.Clear(http.MethodGet, "test/assured"), "failure to clear calls: stubbed calls are frozen")
+	require.EqualError(t, client.ClearAll(), "failure to clear calls: stubbed calls are frozen")
+	require.Error(t, client.ClearPrefix("test/"))
+
+	resp, err := http.Get(client.URL() + "/test/assured")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "stubbed", string(body))
+	calls, err := client.Verify(http.MethodGet, "test/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	require.NoError(t, client.ClearJournal())
+
+	require.NoError(t, client.Unfreeze())
+	require.NoError(t, client.ClearAll())
+	require.NoError(t, client.Given(Call{Path: "test/assured", Method: http.MethodGet, Response: []byte("changed")}))
+}
+
+func TestClientFreezeLegacy(t *testing.T) {
+	_, client := NewTestServer(t)
+	client.legacy.Store(true)
+	require.NoError(t, client.Freeze())
+	require.EqualError(t, client.Given(Call{Path: "test/assured", Method: http.MethodGet}), "failure to stub call: stubbed calls are frozen")
+}
+
+func TestClientMaxBodySize(t *testing.T) {
+	_, client := NewTestServer(t, WithMaxBodySize(8))
+	require.NoError(t, client.Given(
+		Call{Path: "upload/small", Method: http.MethodPost},
+		Call{Path: "upload/large", Method: http.MethodPost, MaxBodySize: 16},
+	))
+
+	resp, err := http.Post(client.URL()+"/upload/small", "text/plain", strings.NewReader("12345678"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Post(client.URL()+"/upload/small", "text/plain", strings.NewReader("123456789"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "request body too large, the limit is 8 bytes", string(body))
+
+	// Chunked bodies without a Content-Length are limited as they are read
+	resp, err = http.Post(client.URL()+"/upload/small", "text/plain", io.MultiReader(strings.NewReader("12345"), strings.NewReader("67890")))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp, err = http.Post(client.URL()+"/upload/large", "text/plain", strings.NewReader("0123456789abcdef"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = http.Post(client.URL()+"/upload/large", "text/plain", strings.NewReader("0123456789abcdefg"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	calls, err := client.Verify(http.MethodPost, "upload/small")
+	require.NoError(t, err)
+	require.Len(t, calls, 3)
+	require.Equal(t, "12345678", string(calls[0].Response))
+	require.Empty(t, calls[1].Response)
+}
+
+func TestClientMaxHeaderSize(t *testing.T) {
+	_, client := NewTestServer(t, WithMaxHeaderSize(256))
+	require.NoError(t, client.Given(
+		Call{Path: "auth/default", Method: http.MethodGet},
+		Call{Path: "auth/large", Method: http.MethodGet, MaxHeaderSize: 1024},
+	))
+	get := func(path, token string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, client.URL()+"/"+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	require.Equal(t, http.StatusOK, get("auth/default", strings.Repeat("a", 64)).StatusCode)
+	resp := get("auth/default", strings.Repeat("a", 256))
+	require.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "request headers too large, the limit is 256 bytes", string(body))
+
+	require.Equal(t, http.StatusOK, get("auth/large", strings.Repeat("a", 512)).StatusCode)
+	require.Equal(t, http.StatusRequestHeaderFieldsTooLarge, get("auth/large", strings.Repeat("a", 1024)).StatusCode)
+
+	calls, err := client.Verify(http.MethodGet, "auth/default")
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+}
+
+func TestClientMaxURILength(t *testing.T) {
+	_, client := NewTestServer(t, WithMaxURILength(64))
+	require.NoError(t, client.Given(
+		Call{Path: "search/default", Method: http.MethodGet},
+		Call{Path: "search/long", Method: http.MethodGet, MaxURILength: 256},
+	))
+	get := func(path string, query int) *http.Response {
+		resp, err := http.Get(client.URL() + "/" + path + "?q=" + strings.Repeat("a", query))
+		require.NoError(t, err)
+		return resp
+	}
+
+	require.Equal(t, http.StatusOK, get("search/default", 8).StatusCode)
+	resp := get("search/default", 64)
+	require.Equal(t, http.StatusRequestURITooLong, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "request uri too long, the limit is 64 bytes", string(body))
+
+	require.Equal(t, http.StatusOK, get("search/long", 128).StatusCode)
+	require.Equal(t, http.StatusRequestURITooLong, get("search/long", 256).StatusCode)
+
+	calls, err := client.Verify(http.MethodGet, "search/default")
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	require.Equal(t, strings.Repeat("a", 64), calls[1].Query["q"])
+}
+
 func TestClientTLS(t *testing.T) {
 	insecureClient := http.Client{Transport: &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
@@ -126,7 +338,7 @@ func TestClientTLS(t *testing.T) {
 	require.Equal(t, "https://localhost:9092/when", url)
 	require.NoError(t, client.Given(*testCall1()))
 
-	req, err := http.NewRequest(http.MethodGet, url+"/test/assured", bytes.NewReader([]byte(`{"calling":"you"}`)))
+	req, err := http.NewRequest(http.MethodGet, url+"/test/assured?assured=max", bytes.NewReader([]byte(`{"calling":"you"}`)))
 	require.NoError(t, err)
 
 	resp, err := insecureClient.Do(req)
@@ -138,13 +350,21 @@ func TestClientTLS(t *testing.T) {
 
 	calls, err := client.Verify("GET", "test/assured")
 	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	// The cipher suite negotiated for TLS 1.3 depends on the hardware support for AES
+	require.NotNil(t, calls[0].TLS)
+	require.NotNil(t, calls[0].Connection)
 	require.Equal(t, []Call{
 		{
-			Method:     "GET",
-			Path:       "test/assured",
-			StatusCode: 200,
-			Response:   []byte(`{"calling":"you"}`),
-			Headers:    map[string]string{"Content-Length": "17", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"},
+			Method:      "GET",
+			Path:        "test/assured",
+			StatusCode:  200,
+			Response:    []byte(`{"calling":"you"}`),
+			Headers:     map[string]string{"Content-Length": "17", "User-Agent": "Go-http-client/1.1", "Accept-Encoding": "gzip"},
+			Query:       map[string]string{"assured": "max"},
+			QueryValues: map[string][]string{"assured": {"max"}},
+			Connection:  &ConnectionDetails{ID: calls[0].Connection.ID, Request: 1},
+			TLS:         &TLSDetails{Version: "TLS 1.3", CipherSuite: calls[0].TLS.CipherSuite, ServerName: "localhost"},
 		},
 	}, calls)
 }
@@ -155,6 +375,551 @@ func TestClientInvalidPort(t *testing.T) {
 	require.Error(t, client.Serve())
 }
 
+func TestNewClientEPortUnavailable(t *testing.T) {
+	client := NewClientServe()
+	defer client.Close()
+
+	unavailable, err := NewClientE(WithPort(client.Port))
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("unable to listen on port %d", client.Port))
+	require.Equal(t, err, unavailable.Serve())
+	require.Equal(t, err, unavailable.Given(*testCall1()))
+	require.Equal(t, err, unavailable.Clear("GET", "test/assured"))
+	require.Equal(t, err, unavailable.ClearAll())
+	require.Equal(t, err, unavailable.ClearMethod("GET"))
+	require.Equal(t, err, unavailable.ClearPrefix("test/"))
+	require.Equal(t, err, unavailable.ClearJournal())
+	require.Equal(t, err, unavailable.Freeze())
+	require.Equal(t, err, unavailable.Unfreeze())
+	require.Equal(t, err, unavailable.ExpireSessions())
+	mismatches, mismatchesErr := unavailable.CSRFMismatches()
+	require.Equal(t, err, mismatchesErr)
+	require.Nil(t, mismatches)
+	counters, countersErr := unavailable.Counters()
+	require.Equal(t, err, countersErr)
+	require.Nil(t, counters)
+	require.Equal(t, err, unavailable.ResetCounters())
+	state, stateErr := unavailable.State()
+	require.Equal(t, err, stateErr)
+	require.Nil(t, state)
+	require.Equal(t, err, unavailable.ClearState())
+	calls, verifyErr := unavailable.Verify("GET", "test/assured")
+	require.Equal(t, err, verifyErr)
+	require.Nil(t, calls)
+	require.Equal(t, err, unavailable.Close())
+}
+
+func TestNewClientEPortRetries(t *testing.T) {
+	client := NewClientServe()
+	defer client.Close()
+
+	retried, err := NewClientE(WithPort(client.Port), WithPortRetries(5))
+	require.NoError(t, err)
+	defer retried.Close()
+
+	require.Greater(t, retried.Port, client.Port)
+	require.LessOrEqual(t, retried.Port, client.Port+5)
+}
+
+func TestNewClientEPortRange(t *testing.T) {
+	client := NewClientServe()
+	defer client.Close()
+
+	ranged, err := NewClientE(WithPortRange(client.Port, client.Port+5))
+	require.NoError(t, err)
+	defer ranged.Close()
+
+	require.Greater(t, ranged.Port, client.Port)
+	require.LessOrEqual(t, ranged.Port, client.Port+5)
+
+	_, err = NewClientE(WithPortRange(client.Port, client.Port))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("unable to listen on port range %d-%d", client.Port, client.Port))
+}
+
+func TestNewClientEPortFile(t *testing.T) {
+	portFile := filepath.Join(t.TempDir(), "port")
+	client, err := NewClientE(WithPortFile(portFile))
+	require.NoError(t, err)
+	defer client.Close()
+
+	port, err := os.ReadFile(portFile)
+	require.NoError(t, err)
+	require.Equal(t, strconv.Itoa(client.Port), string(port))
+	entries, err := os.ReadDir(filepath.Dir(portFile))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	_, err = NewClientE(WithPortFile(filepath.Join(t.TempDir(), "missing", "port")))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to write port file")
+}
+
+func TestNewClientEPortFileFailureReleasesPort(t *testing.T) {
+	reserved, err := NewClientE()
+	require.NoError(t, err)
+	port := reserved.Port
+	require.NoError(t, reserved.Close())
+
+	failed, err := NewClientE(WithPort(port), WithPortFile(filepath.Join(t.TempDir(), "missing", "port")))
+	require.Error(t, err)
+	require.Nil(t, failed.listener)
+
+	client, err := NewClientE(WithPort(port))
+	require.NoError(t, err)
+	defer client.Close()
+}
+
+func TestNewClientETuning(t *testing.T) {
+	client, err := NewClientE(WithServerTimeouts(time.Second, 2*time.Second, time.Minute), WithConnectionPool(100, time.Minute))
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.Equal(t, time.Second, client.server.ReadTimeout)
+	require.Equal(t, 2*time.Second, client.server.WriteTimeout)
+	require.Equal(t, time.Minute, client.server.IdleTimeout)
+	require.Equal(t, 100, client.httpClient.Transport.(*http.Transport).MaxIdleConnsPerHost)
+}
+
+func TestClientBasePath(t *testing.T) {
+	client := NewClientServe(WithBasePath("mock"))
+	defer client.Close()
+	time.Sleep(time.Second)
+
+	require.Equal(t, fmt.Sprintf("http://localhost:%d/mock/when", client.Port), client.URL())
+	require.NoError(t, client.Given(*testCall1()))
+
+	resp, err := http.Get(client.URL() + "/test/assured?assured=max")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	calls, err := client.Verify("GET", "test/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+}
+
+func TestClientRootServing(t *testing.T) {
+	client := NewClientServe(WithBasePath("mock"), WithRootServing(true))
+	defer client.Close()
+	time.Sleep(time.Second)
+
+	require.Equal(t, fmt.Sprintf("http://localhost:%d", client.Port), client.URL())
+	require.NoError(t, client.Given(*testCall1()))
+
+	resp, err := http.Get(client.URL() + "/test/assured?assured=max")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"assured": true}`), body)
+}
+
+func TestClientRootServingReservedPrefix(t *testing.T) {
+	client := NewClientServe(WithRootServing(true))
+	defer client.Close()
+	time.Sleep(time.Second)
+
+	require.Equal(t, RootServingBasePath, client.basePath)
+	require.NoError(t, client.Given(Call{Path: "given/assured", StatusCode: http.StatusAccepted}))
+
+	resp, err := http.Get(client.URL() + "/given/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp, err = http.Get(client.URL() + RootServingBasePath + "/when/given/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	calls, err := client.Verify("GET", "given/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+}
+
+func TestNewRemoteClient(t *testing.T) {
+	server := NewClientServe(WithBasePath("mock"))
+	defer server.Close()
+	time.Sleep(time.Second)
+
+	client, err := NewRemoteClient(fmt.Sprintf("http://localhost:%d/mock", server.Port))
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.Equal(t, server.URL(), client.URL())
+	require.Error(t, client.Serve())
+	require.NoError(t, client.Given(*testCall1()))
+
+	resp, err := http.Get(client.URL() + "/test/assured?assured=max")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	calls, err := client.Verify("GET", "test/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+}
+
+func TestNewRemoteClientInvalidURL(t *testing.T) {
+	client, err := NewRemoteClient("localhost")
+
+	require.Error(t, err)
+	require.Equal(t, `invalid remote url "localhost"`, err.Error())
+	require.Nil(t, client)
+
+	client, err = NewRemoteClient(":/bad")
+
+	require.Error(t, err)
+	require.Nil(t, client)
+}
+
+func TestClientRouter(t *testing.T) {
+	client := NewClient(WithRootServing(true))
+	client.Router().HandleFunc("/custom/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(mux.Vars(r)["id"]))
+	})
+	go func() { _ = client.Serve() }()
+	defer client.Close()
+	time.Sleep(time.Second)
+
+	require.NoError(t, client.Given(*testCall1()))
+
+	resp, err := http.Get(client.URL() + "/custom/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, []byte("assured"), body)
+
+	resp, err = http.Get(client.URL() + "/test/assured?assured=max")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestClientStatusSequence(t *testing.T) {
+	_, client := NewTestServer(t)
+
+	require.NoError(t, client.Given(Call{Path: "retry/assured", StatusCodes: []int{http.StatusInternalServerError, http.StatusOK}}))
+
+	for _, expected := range []int{http.StatusInternalServerError, http.StatusOK, http.StatusInternalServerError} {
+		resp, err := http.Get(client.URL() + "/retry/assured")
+		require.NoError(t, err)
+		require.Equal(t, expected, resp.StatusCode)
+	}
+}
+
+func TestClientBranches(t *testing.T) {
+	_, client := NewTestServer(t)
+
+	require.NoError(t, client.Given(Call{
+		Path:     "branch/assured",
+		Method:   http.MethodPost,
+		Response: []byte("default"),
+		Branches: []Branch{
+			{When: Condition{Headers: map[string]string{"X-Tenant": "assured"}}, Response: []byte("tenant")},
+			{When: Condition{BodyContains: "premium"}, StatusCode: http.StatusCreated, Response: []byte("premium")},
+		},
+	}))
+
+	tests := []struct {
+		header string
+		body   string
+		status int
+		want   string
+	}{
+		{header: "assured", body: "premium", status: http.StatusOK, want: "tenant"},
+		{body: "premium", status: http.StatusCreated, want: "premium"},
+		{body: "basic", status: http.StatusOK, want: "default"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodPost, client.URL()+"/branch/assured", strings.NewReader(tt.body))
+		require.NoError(t, err)
+		if tt.header != "" {
+			req.Header.Set("X-Tenant", tt.header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, tt.status, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, string(body))
+	}
+}
+
+func TestClientQueryValues(t *testing.T) {
+	_, client := NewTestServer(t)
+	one := 1
+	require.NoError(t, client.Given(Call{
+		Path:     "query/assured",
+		Response: []byte("default"),
+		Branches: []Branch{
+			{When: Condition{QueryValues: map[string]QueryCondition{"id": {Equals: []string{"1", "2"}}}}, Response: []byte("exact")},
+			{When: Condition{QueryValues: map[string]QueryCondition{"id": {Contains: []string{"3", "1"}}}}, Response: []byte("contains")},
+			{When: Condition{QueryValues: map[string]QueryCondition{"id": {Count: &one}}}, Response: []byte("single")},
+		},
+	}))
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "id=1&id=2", want: "exact"},
+		{query: "id=1&id=2&id=3", want: "contains"},
+		{query: "id=2", want: "single"},
+		{query: "id=2&id=1", want: "default"},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(client.URL() + "/query/assured?" + tt.query)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, string(body), tt.query)
+	}
+
+	calls, err := client.Verify(http.MethodGet, "query/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, len(tests))
+	require.Equal(t, map[string]string{"id": "1"}, calls[0].Query)
+	require.Equal(t, map[string][]string{"id": {"1", "2"}}, calls[0].QueryValues)
+}
+
+func TestClientMatrix(t *testing.T) {
+	_, client := NewTestServer(t, WithRawURI(true))
+	require.NoError(t, client.Given(
+		Call{Path: "users/orders", Response: []byte("orders"), Branches: []Branch{{When: Condition{Matrix: map[string]string{"role": "admin"}}, Response: []byte("admin")}}},
+		Call{Path: "users;id=0/orders", Response: []byte("exact")},
+	))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "users;id=1;role=admin/orders", want: "admin"},
+		{path: "users;id=2/orders", want: "orders"},
+		{path: "users;id=0/orders", want: "exact"},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(client.URL() + "/" + tt.path + "?page=2")
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, string(body), tt.path)
+	}
+
+	calls, err := client.Verify(http.MethodGet, "users/orders")
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	require.Equal(t, map[string]string{"id": "1", "role": "admin"}, calls[0].Matrix)
+	require.Equal(t, "/when/users;id=1;role=admin/orders?page=2", calls[0].RawURI)
+	require.Equal(t, map[string]string{"id": "2"}, calls[1].Matrix)
+}
+
+func TestClientRawURIFragment(t *testing.T) {
+	_, client := NewTestServer(t, WithRawURI(true))
+	require.NoError(t, client.Given(Call{Path: "legacy"}))
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", client.Port))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /when/legacy?page=2#top HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+	_, err = io.ReadAll(conn)
+	require.NoError(t, err)
+
+	calls, err := client.Verify(http.MethodGet, "legacy")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	require.Equal(t, "/when/legacy?page=2#top", calls[0].RawURI)
+}
+
+func TestClientJournalTTL(t *testing.T) {
+	_, client := NewTestServer(t, WithJournalTTL(20*time.Millisecond))
+
+	require.NoError(t, client.Given(Call{Path: "journal/assured"}))
+	_, err := http.Get(client.URL() + "/journal/assured")
+	require.NoError(t, err)
+
+	calls, err := client.Verify(http.MethodGet, "journal/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+
+	require.Eventually(t, func() bool {
+		calls, err := client.Verify(http.MethodGet, "journal/assured")
+		return err == nil && len(calls) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestClientCompact(t *testing.T) {
+	_, client := NewTestServer(t, WithJournalTTL(time.Hour))
+
+	require.NoError(t, client.Given(Call{Path: "journal/assured"}))
+	_, err := http.Get(client.URL() + "/journal/assured")
+	require.NoError(t, err)
+
+	purged, err := client.Compact()
+	require.NoError(t, err)
+	require.Equal(t, 0, purged)
+
+	calls, err := client.Verify(http.MethodGet, "journal/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+}
+
+func TestClientFraming(t *testing.T) {
+	tests := []struct {
+		framing          string
+		contentLength    int64
+		transferEncoding []string
+		close            bool
+	}{
+		{framing: FramingContentLength, contentLength: 7},
+		{framing: FramingChunked, contentLength: -1, transferEncoding: []string{"chunked"}},
+		{framing: FramingClose, contentLength: -1, close: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.framing, func(t *testing.T) {
+			_, client := NewTestServer(t)
+			require.NoError(t, client.Given(Call{Path: "framing/assured", Response: []byte("assured"), Framing: tt.framing}))
+
+			resp, err := http.Get(client.URL() + "/framing/assured")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, "assured", string(body))
+			require.Equal(t, tt.contentLength, resp.ContentLength)
+			require.Equal(t, tt.transferEncoding, resp.TransferEncoding)
+			require.Equal(t, tt.close, resp.Close)
+		})
+	}
+}
+
+func TestClientResponseHeaders(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{
+		Path:            "cookies/assured",
+		ResponseHeaders: []Header{{Name: "Set-Cookie", Value: "session=abc"}, {Name: "Set-Cookie", Value: "theme=dark"}},
+	}))
+
+	resp, err := http.Get(client.URL() + "/cookies/assured")
+	require.NoError(t, err)
+	require.Equal(t, []string{"session=abc", "theme=dark"}, resp.Header.Values("Set-Cookie"))
+	require.Len(t, resp.Cookies(), 2)
+}
+
+func TestClientRawHeaders(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{
+		Path:       "raw/assured",
+		Headers:    map[string]string{"x-legacy-ID": "abc", "content-type": "application/json"},
+		Response:   []byte("{}"),
+		RawHeaders: true,
+	}))
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", client.Port))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /when/raw/assured HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err := io.ReadAll(conn)
+	require.NoError(t, err)
+
+	require.Contains(t, string(resp), "\r\nx-legacy-ID: abc\r\n")
+	require.Contains(t, string(resp), "\r\ncontent-type: application/json\r\n")
+	require.NotContains(t, string(resp), "Content-Type")
+	require.NotContains(t, string(resp), "X-Legacy-Id")
+}
+
+func TestClientDuplicates(t *testing.T) {
+	_, client := NewTestServer(t)
+
+	require.NoError(t, client.Given(Call{Path: "orders/assured", Method: http.MethodPost}))
+	for _, body := range []string{`{"id":1}`, `{"id":2}`, `{"id":1}`} {
+		_, err := http.Post(client.URL()+"/orders/assured", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+	}
+
+	duplicates, err := client.Duplicates(http.MethodPost, "orders/assured", time.Minute)
+	require.NoError(t, err)
+	require.Len(t, duplicates, 1)
+	require.Len(t, duplicates[0], 2)
+	require.Equal(t, `{"id":1}`, duplicates[0][0].String())
+
+	mock := &testing.T{}
+	require.False(t, client.AssertNoDuplicates(mock, http.MethodPost, "orders/assured", time.Minute))
+	require.True(t, mock.Failed())
+	require.True(t, client.AssertNoDuplicates(t, http.MethodGet, "orders/assured", time.Minute))
+}
+
+func TestClientDuplicatesTrackingDisabled(t *testing.T) {
+	_, client := NewTestServer(t, WithCallTracking(false))
+
+	duplicates, err := client.Duplicates(http.MethodPost, "orders/assured", time.Minute)
+	require.Nil(t, duplicates)
+	require.Error(t, err)
+	require.Equal(t, "failure to find duplicate calls", err.Error())
+}
+
+func TestClientStats(t *testing.T) {
+	_, client := NewTestServer(t)
+
+	require.NoError(t, client.Given(Call{Path: "stats/assured"}, Call{Path: "stats/assured", StatusCode: http.StatusTeapot}))
+	_, err := http.Get(client.URL() + "/stats/assured")
+	require.NoError(t, err)
+
+	stats, err := client.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.Stubs)
+	require.Equal(t, 1, stats.JournalEntries)
+	require.Positive(t, stats.JournalBytes)
+	require.Equal(t, 0, stats.PendingCallbacks)
+}
+
+func TestClientPlainHandlers(t *testing.T) {
+	_, client := NewTestServer(t, WithPlainHandlers(true))
+
+	require.NoError(t, client.Given(Call{Path: "plain/assured", StatusCode: http.StatusTeapot, Response: []byte("plain")}))
+
+	resp, err := http.Get(client.URL() + "/plain/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTeapot, resp.StatusCode)
+	require.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "plain", string(body))
+
+	calls, err := client.Verify(http.MethodGet, "plain/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+
+	require.NoError(t, client.ClearAll())
+	resp, err = http.Get(client.URL() + "/plain/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "No assured calls", string(body))
+}
+
+func TestClientConcurrency(t *testing.T) {
+	_, client := NewTestServer(t)
+
+	require.NoError(t, client.Given(Call{Path: "single/assured", Concurrency: 1, Delay: 1}))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(client.URL() + "/single/assured")
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+	require.GreaterOrEqual(t, time.Since(start), 2*time.Second)
+}
+
 func TestClientCallbacks(t *testing.T) {
 	httpClient := http.Client{}
 	called := false
@@ -376,6 +1141,59 @@ func TestClientVerifyBodyFailure(t *testing.T) {
 	require.Nil(t, calls)
 }
 
+func TestClientAPIVersionMismatch(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, APIVersion, r.Header.Get(AssuredAPIVersion))
+		w.Header().Set(AssuredAPIVersion, "3")
+	}))
+	defer testServer.Close()
+	client, err := NewRemoteClient(testServer.URL)
+	require.NoError(t, err)
+
+	err = client.Given(Call{Path: "test/assured"})
+
+	require.Error(t, err)
+	require.Equal(t, "rest assured server api version 3 is not supported by the client api version 2", err.Error())
+}
+
+func TestClientAPIVersionLegacyServer(t *testing.T) {
+	paths := []string{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer testServer.Close()
+	client, err := NewRemoteClient(testServer.URL)
+	require.NoError(t, err)
+
+	require.NoError(t, client.Given(Call{Path: "test/assured"}))
+	require.NoError(t, client.Given(Call{Path: "test/assured"}))
+	require.Equal(t, []string{"/given", "/given/test/assured", "/given/test/assured"}, paths)
+}
+
+func TestClientGivenJSONCallbacks(t *testing.T) {
+	called := make(chan string, 1)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		called <- r.Header.Get("X-Callback") + ":" + string(body)
+	}))
+	defer target.Close()
+	_, client := NewTestServer(t)
+
+	require.NoError(t, client.Given(Call{
+		Path:      "json/assured",
+		Delay:     1,
+		Callbacks: []Callback{{Method: http.MethodPost, Target: target.URL, Headers: map[string]string{"X-Callback": "json"}, Response: []byte("called")}},
+	}))
+
+	start := time.Now()
+	resp, err := http.Get(client.URL() + "/json/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.GreaterOrEqual(t, time.Since(start), time.Second)
+	require.Equal(t, "json:called", <-called)
+	require.False(t, client.legacy.Load())
+}
+
 func TestClientPathSanitization(t *testing.T) {
 	client := NewClient()
 	go func() { _ = client.Serve() }()
@@ -391,3 +1209,22 @@ func TestClientPathSanitization(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusAccepted, resp.StatusCode)
 }
+
+func TestClientQuery(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		_, client := NewTestServer(t)
+		client.legacy.Store(legacy)
+		require.NoError(t, client.Given(
+			Call{Path: "search", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("foo"), Query: map[string]string{"term": "foo"}},
+			Call{Path: "search", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("bar"), Query: map[string]string{"term": "bar"}},
+		))
+
+		for _, term := range []string{"bar", "foo"} {
+			resp, err := http.Get(client.URL() + "/search?term=" + term)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, term, string(body))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package assured
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// ConnectionDetails are the details of the connection a call was made on, the connection's ID and the number of the call on the connection
+// The first call made on a connection is request 1
+type ConnectionDetails struct {
+	ID      uint64 `json:"id"`
+	Request int    `json:"request"`
+}
+
+// connection is the state of a connection made to the rest assured server,
+// the number of calls made on it and the handshakes of the stubbed calls by call ID
+type connection struct {
+	id         uint64
+	requests   int
+	handshakes map[string]*handshakeState
+	sync.Mutex
+}
+
+// connectionKey is the context key of the connection a request was made on
+type connectionKey struct{}
+
+// connectionDetailsKey is the context key of the details of the connection a request was made on
+type connectionDetailsKey struct{}
+
+// connections counts the connections made to the rest assured servers, for their IDs
+var connections atomic.Uint64
+
+// withConnection returns the context of a new connection, for the http server's ConnContext
+func withConnection(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, connectionKey{}, &connection{id: connections.Add(1), handshakes: map[string]*handshakeState{}})
+}
+
+// connectionHandler counts the calls made on each connection, recording the connection details of the calls
+// Calls made without a connection, outside of the rest assured server, have no connection details
+func connectionHandler(when http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if conn, ok := req.Context().Value(connectionKey{}).(*connection); ok {
+			conn.Lock()
+			conn.requests++
+			details := &ConnectionDetails{ID: conn.id, Request: conn.requests}
+			conn.Unlock()
+			req = req.WithContext(context.WithValue(req.Context(), connectionDetailsKey{}, details))
+		}
+		when.ServeHTTP(w, req)
+	})
+}
+
+// connectionDetails returns the details of the connection the request was made on, if it was made on a connection
+func connectionDetails(req *http.Request) *ConnectionDetails {
+	details, _ := req.Context().Value(connectionDetailsKey{}).(*ConnectionDetails)
+	return details
+}
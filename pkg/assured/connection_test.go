@@ -0,0 +1,58 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientConnectionRequest(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{
+		Path:     "session/assured",
+		Response: []byte("warm"),
+		Branches: []Branch{{
+			When:       Condition{ConnectionRequest: 1},
+			StatusCode: http.StatusCreated,
+			Headers:    map[string]string{"Set-Cookie": "session=sticky"},
+			Response:   []byte("cold"),
+		}},
+	}))
+	get := func(httpClient *http.Client) (int, string) {
+		resp, err := httpClient.Get(client.URL() + "/session/assured")
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp.StatusCode, string(body)
+	}
+	first := &http.Client{Transport: &http.Transport{}}
+	defer first.CloseIdleConnections()
+	second := &http.Client{Transport: &http.Transport{}}
+	defer second.CloseIdleConnections()
+
+	for _, httpClient := range []*http.Client{first, first, second, first, second} {
+		get(httpClient)
+	}
+
+	calls, err := client.Verify(http.MethodGet, "session/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 5)
+	for i, request := range []int{1, 2, 1, 3, 2} {
+		require.NotNil(t, calls[i].Connection)
+		require.Equal(t, request, calls[i].Connection.Request)
+	}
+	require.Equal(t, calls[0].Connection.ID, calls[3].Connection.ID)
+	require.NotEqual(t, calls[0].Connection.ID, calls[2].Connection.ID)
+
+	third := &http.Client{Transport: &http.Transport{}}
+	defer third.CloseIdleConnections()
+	statusCode, body := get(third)
+	require.Equal(t, http.StatusCreated, statusCode)
+	require.Equal(t, "cold", body)
+	statusCode, body = get(third)
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Equal(t, "warm", body)
+}
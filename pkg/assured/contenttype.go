@@ -0,0 +1,76 @@
+package assured
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// inferContentType returns the content type of a response that looks like JSON or XML, or empty if it looks like neither
+// A response looks like JSON if it is wrapped in braces or brackets, even with placeholders, e.g. {"id":{{counter "id"}}},
+// but not a placeholder alone, and like XML if it is wrapped in angle brackets and isn't HTML
+func inferContentType(response []byte) string {
+	trimmed := bytes.TrimSpace(response)
+	if len(trimmed) < 2 {
+		return ""
+	}
+	first, last := trimmed[0], trimmed[len(trimmed)-1]
+	switch {
+	case first == '{' && last == '}' && !bytes.HasPrefix(trimmed, []byte("{{")), first == '[' && last == ']':
+		return "application/json"
+	case first == '<' && last == '>' && !strings.HasPrefix(http.DetectContentType(trimmed), "text/html"):
+		return "application/xml"
+	}
+	return ""
+}
+
+// hasContentType reports whether the headers, or response headers, set the Content-Type
+func hasContentType(headers map[string]string, responseHeaders []Header) bool {
+	for name := range headers {
+		if strings.EqualFold(name, "Content-Type") {
+			return true
+		}
+	}
+	for _, header := range responseHeaders {
+		if strings.EqualFold(header.Name, "Content-Type") {
+			return true
+		}
+	}
+	return false
+}
+
+// contentType returns the Content-Type the stubbed call declares, in its headers or response headers
+func (c *Call) contentType() string {
+	for name, value := range c.Headers {
+		if strings.EqualFold(name, "Content-Type") {
+			return value
+		}
+	}
+	for _, header := range c.ResponseHeaders {
+		if strings.EqualFold(header.Name, "Content-Type") {
+			return header.Value
+		}
+	}
+	return ""
+}
+
+// inferHeader sets the Content-Type of the stubbed call's response that looks like JSON or XML, unless it is stubbed with a Content-Type,
+// since forgetting it is the most common mistake in a stubbed call. It is inferred on the response as the call is served,
+// after its branch is chosen, so the stubbed call is left as it was given
+func (a *AssuredEndpoints) inferHeader(header http.Header, call *Call) {
+	if !a.inferType || hasContentType(call.Headers, call.ResponseHeaders) {
+		return
+	}
+	if contentType := inferContentType(call.Response); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+}
+
+// servedContentType returns the Content-Type the stubbed call is served with, declared in its headers or response headers,
+// or else inferred from its response, if applicable
+func (a *AssuredEndpoints) servedContentType(call *Call) string {
+	if contentType := call.contentType(); contentType != "" || !a.inferType {
+		return contentType
+	}
+	return inferContentType(call.Response)
+}
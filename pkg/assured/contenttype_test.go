@@ -0,0 +1,117 @@
+package assured
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferContentType(t *testing.T) {
+	for response, want := range map[string]string{
+		``:                                      "",
+		`created`:                               "",
+		`{"id":1}`:                              "application/json",
+		" [1, 2]\n":                             "application/json",
+		`{"id":{{counter "id"}}}`:               "application/json",
+		`{{state "id"}}`:                        "",
+		`<?xml version="1.0"?><user/>`:          "application/xml",
+		`<user><id>1</id></user>`:               "application/xml",
+		`<!DOCTYPE html><html></html>`:          "",
+		`<html><body>hello</body></html>`:       "",
+		`{"truncated":`:                         "",
+		`<user>`:                                "application/xml",
+		`["a", "b"] trailing`:                   "",
+		"\x00\x01binary":                        "",
+		`{}`:                                    "application/json",
+		`[{"id":1},{"id":2}] `:                  "application/json",
+		`{"html":"<html><body></body></html>"}`: "application/json",
+	} {
+		require.Equal(t, want, inferContentType([]byte(response)), response)
+	}
+}
+
+func TestInferHeader(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	for _, test := range []struct {
+		call *Call
+		want string
+	}{
+		{call: &Call{Response: []byte(`{"id":1}`)}, want: "application/json"},
+		{call: &Call{Response: []byte(`<id>1</id>`)}, want: "application/xml"},
+		{call: &Call{Response: []byte("created")}},
+		{call: &Call{Headers: map[string]string{"content-type": "text/plain"}, Response: []byte(`{"id":1}`)}},
+		{call: &Call{ResponseHeaders: []Header{{Name: "Content-Type", Value: "application/vnd.api+json"}}, Response: []byte(`{"id":2}`)}},
+	} {
+		header := http.Header{}
+		endpoints.inferHeader(header, test.call)
+		require.Equal(t, test.want, header.Get("Content-Type"), string(test.call.Response))
+	}
+}
+
+func TestGivenEndpointInferContentTypeUnchanged(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	call := &Call{
+		Path:     "users",
+		Method:   http.MethodGet,
+		Response: []byte("created"),
+		Branches: []Branch{{When: Condition{Query: map[string]string{"format": "json"}}, Response: []byte(`{"id":1}`)}},
+	}
+
+	_, err := endpoints.GivenEndpoint(context.TODO(), call)
+	require.NoError(t, err)
+	c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "users", Method: http.MethodGet, Query: map[string]string{"format": "json"}})
+
+	require.NoError(t, err)
+	require.Nil(t, call.Headers)
+	require.Nil(t, call.Branches[0].Headers)
+	require.Empty(t, c.(*Call).Headers["Content-Type"])
+}
+
+func TestClientInferContentTypeDisabled(t *testing.T) {
+	_, client := NewTestServer(t, WithContentTypeInference(false))
+	require.NoError(t, client.Given(Call{Path: "users/1", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte(`{"id":1}`)}))
+
+	resp, err := http.Get(client.URL() + "/users/1")
+
+	require.NoError(t, err)
+	require.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+	_ = resp.Body.Close()
+}
+
+func TestClientInferContentType(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		_, client := NewTestServer(t)
+		client.legacy.Store(legacy)
+		require.NoError(t, client.Given(Call{Path: "users/1", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte(`<user><id>1</id></user>`)}))
+
+		resp, err := http.Get(client.URL() + "/users/1")
+
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/xml", resp.Header.Get("Content-Type"))
+		_ = resp.Body.Close()
+	}
+}
+
+func TestClientInferContentTypeBranch(t *testing.T) {
+	_, client := NewTestServer(t)
+	stub := Call{
+		Path:       "users",
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Response:   []byte("created"),
+		Branches:   []Branch{{When: Condition{Query: map[string]string{"format": "json"}}, Response: []byte(`{"id":1}`)}},
+	}
+	require.NoError(t, client.Given(stub))
+
+	for query, want := range map[string]string{"": "text/plain; charset=utf-8", "?format=json": "application/json"} {
+		resp, err := http.Get(client.URL() + "/users" + query)
+
+		require.NoError(t, err)
+		require.Equal(t, want, resp.Header.Get("Content-Type"), query)
+		_ = resp.Body.Close()
+	}
+	require.Nil(t, stub.Headers)
+}
@@ -0,0 +1,142 @@
+package assured
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// counterPattern matches the counter placeholders of a response, e.g. {{counter "orderID"}}
+var counterPattern = regexp.MustCompile(`\{\{\s*counter\s+"([^"]+)"\s*\}\}`)
+
+// counters are the named, auto-incrementing counters of the counter placeholders in responses
+// The counters are persisted to the counter file, if set, so they continue across restarts
+type counters struct {
+	values map[string]int64
+	file   string
+	sync.Mutex
+}
+
+// newCounters returns the counters, loading the counters persisted to the file, if it exists
+func newCounters(file string) *counters {
+	c := &counters{values: map[string]int64{}, file: file}
+	if file == "" {
+		return c
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return c
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &c.values)
+	}
+	if err != nil {
+		slog.With("error", err, "file", file).Error("unable to load counters")
+	}
+	return c
+}
+
+// hasCounters reports whether the response has counter placeholders
+func hasCounters(response []byte) bool {
+	return bytes.Contains(response, []byte("{{")) && counterPattern.Match(response)
+}
+
+// render returns the response with each counter placeholder replaced by the next value of its counter
+// Each counter is incremented once per response, so the placeholders of the same counter have the same value, e.g. in an id and a link
+func (c *counters) render(response []byte) []byte {
+	c.Lock()
+	defer c.Unlock()
+	next := map[string][]byte{}
+	rendered := counterPattern.ReplaceAllFunc(response, func(placeholder []byte) []byte {
+		name := string(counterPattern.FindSubmatch(placeholder)[1])
+		value, ok := next[name]
+		if !ok {
+			c.values[name]++
+			value = []byte(strconv.FormatInt(c.values[name], 10))
+			next[name] = value
+		}
+		return value
+	})
+	c.persist()
+	return rendered
+}
+
+// reset resets the counters with the names, or every counter without names, and returns the number of counters reset
+func (c *counters) reset(names ...string) int {
+	c.Lock()
+	defer c.Unlock()
+	reset := 0
+	if len(names) == 0 {
+		reset = len(c.values)
+		c.values = map[string]int64{}
+	}
+	for _, name := range names {
+		if _, ok := c.values[name]; ok {
+			delete(c.values, name)
+			reset++
+		}
+	}
+	c.persist()
+	return reset
+}
+
+// snapshot returns the current value of each counter
+func (c *counters) snapshot() map[string]int64 {
+	c.Lock()
+	defer c.Unlock()
+	return maps.Clone(c.values)
+}
+
+// persist writes the counters to the counter file, if set, replacing it atomically
+func (c *counters) persist() {
+	if c.file == "" {
+		return
+	}
+	data, _ := json.Marshal(c.values)
+	tmp, err := os.CreateTemp(filepath.Dir(c.file), filepath.Base(c.file)+".*")
+	if err == nil {
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), c.file)
+		}
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		slog.With("error", err, "file", c.file).Error("unable to persist counters")
+	}
+}
+
+// countered returns a copy of the stubbed call with the next values of its response's counters
+func (a *AssuredEndpoints) countered(assured *Call) *Call {
+	countered := *assured
+	countered.Headers = maps.Clone(assured.Headers)
+	// The stubbed content length no longer applies to the rendered response
+	delete(countered.Headers, "Content-Length")
+	countered.Response = a.counters.render(assured.Response)
+	return &countered
+}
+
+// Counters returns the current value of each counter of the counter placeholders rendered in responses
+func (a *AssuredEndpoints) Counters() map[string]int64 {
+	return a.counters.snapshot()
+}
+
+// ResetCounters resets the counters with the names, or every counter without names, and returns the number of counters reset
+// A reset counter starts over at 1
+func (a *AssuredEndpoints) ResetCounters(names ...string) int {
+	reset := a.counters.reset(names...)
+	slog.With("reset", reset).Info("reset counters")
+	return reset
+}
@@ -0,0 +1,81 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountersRender(t *testing.T) {
+	c := newCounters("")
+
+	require.Equal(t, `{"id":1,"href":"/orders/1","line":1}`, string(c.render([]byte(`{"id":{{counter "order"}},"href":"/orders/{{ counter "order" }}","line":{{counter "line"}}}`))))
+	require.Equal(t, `{"id":2,"line":2}`, string(c.render([]byte(`{"id":{{counter "order"}},"line":{{counter "line"}}}`))))
+	require.Equal(t, map[string]int64{"order": 2, "line": 2}, c.snapshot())
+
+	require.Equal(t, 1, c.reset("line", "missing"))
+	require.Equal(t, "3 1", string(c.render([]byte(`{{counter "order"}} {{counter "line"}}`))))
+	require.Equal(t, 2, c.reset())
+	require.Empty(t, c.snapshot())
+}
+
+func TestHasCounters(t *testing.T) {
+	require.True(t, hasCounters([]byte(`{"id":{{counter "order"}}}`)))
+	require.False(t, hasCounters([]byte(`{"id":"{{csrf_token}}"}`)))
+	require.False(t, hasCounters([]byte(`{"counter":"order"}`)))
+}
+
+func TestCountersPersisted(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "counters.json")
+	c := newCounters(file)
+	c.render([]byte(`{{counter "order"}} {{counter "invoice"}}`))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"order":1,"invoice":1}`, string(data))
+
+	restarted := newCounters(file)
+	require.Equal(t, "2", string(restarted.render([]byte(`{{counter "order"}}`))))
+}
+
+func TestCountersLoadFailure(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "counters.json")
+	require.NoError(t, os.WriteFile(file, []byte("not json"), 0o600))
+
+	c := newCounters(file)
+
+	require.Equal(t, "1", string(c.render([]byte(`{{counter "order"}}`))))
+}
+
+func TestClientCounters(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{
+		Path:       "orders",
+		Method:     http.MethodPost,
+		StatusCode: http.StatusCreated,
+		Headers:    map[string]string{"Location": "/orders/1"},
+		Response:   []byte(`{"id":"ord_{{counter "orderID"}}"}`),
+	}))
+
+	post := func() string {
+		resp, err := http.Post(client.URL()+"/orders", "application/json", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+	require.Equal(t, `{"id":"ord_1"}`, post())
+	require.Equal(t, `{"id":"ord_2"}`, post())
+
+	counters, err := client.Counters()
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"orderID": 2}, counters)
+
+	require.NoError(t, client.ResetCounters("orderID"))
+	require.Equal(t, `{"id":"ord_1"}`, post())
+}
@@ -0,0 +1,241 @@
+package assured
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The defaults of a CSRF without a cookie, header, or form field name
+const (
+	DefaultCSRFCookie = "csrf_token"
+	DefaultCSRFHeader = "X-CSRF-Token"
+	DefaultCSRFField  = "csrf_token"
+)
+
+// CSRFTokenPlaceholder is replaced with the issued token in the response of a call issuing a CSRF token, e.g. in a form's hidden input
+const CSRFTokenPlaceholder = "{{csrf_token}}"
+
+// CSRF simulates the double submit CSRF protection of an upstream. A call that issues a token, such as a GET of a form,
+// sets a new token in the cookie and the header of its response, and in place of the {{csrf_token}} placeholder in its response body
+// A call that validates the token, such as a POST of the form, responds 403 Forbidden and records a mismatch
+// unless the request has an issued token in the cookie, and the same token in the header or the url encoded form field
+type CSRF struct {
+	Issue    bool   `json:"issue,omitempty"`
+	Validate bool   `json:"validate,omitempty"`
+	Cookie   string `json:"cookie,omitempty"`
+	Header   string `json:"header,omitempty"`
+	Field    string `json:"field,omitempty"`
+}
+
+// CSRFMismatch is a call made to a stubbed call validating its CSRF token that was rejected, and the reason it was rejected
+type CSRFMismatch struct {
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// csrfTokens are the CSRF tokens issued by the stubbed calls, and the mismatches recorded by the stubbed calls validating them
+type csrfTokens struct {
+	issued     map[string]bool
+	mismatches []CSRFMismatch
+	sync.Mutex
+}
+
+// CSRFTokenCall returns the stubbed GET call issuing a CSRF token, responding with the token in the JSON body {"csrf_token":"..."}
+func CSRFTokenCall(path string) Call {
+	return Call{
+		Path:       path,
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Response:   []byte(`{"csrf_token":"` + CSRFTokenPlaceholder + `"}`),
+		CSRF:       &CSRF{Issue: true},
+	}
+}
+
+// CSRFProtectedCall returns the stubbed call validating the CSRF token issued by a CSRFTokenCall
+func CSRFProtectedCall(call Call) Call {
+	call.CSRF = &CSRF{Validate: true}
+	return call
+}
+
+// cookie returns the name of the CSRF cookie
+func (c CSRF) cookie() string {
+	if c.Cookie == "" {
+		return DefaultCSRFCookie
+	}
+	return c.Cookie
+}
+
+// header returns the name of the CSRF header
+func (c CSRF) header() string {
+	if c.Header == "" {
+		return DefaultCSRFHeader
+	}
+	return c.Header
+}
+
+// field returns the name of the CSRF form field
+func (c CSRF) field() string {
+	if c.Field == "" {
+		return DefaultCSRFField
+	}
+	return c.Field
+}
+
+// issue issues a new CSRF token
+func (t *csrfTokens) issue() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	token := hex.EncodeToString(b)
+	t.Lock()
+	defer t.Unlock()
+	if t.issued == nil {
+		t.issued = map[string]bool{}
+	}
+	t.issued[token] = true
+	return token
+}
+
+// mismatch checks the request's CSRF token, returning the reason it is rejected, or an empty reason if it is valid
+func (t *csrfTokens) mismatch(csrf CSRF, req *http.Request) string {
+	cookie, err := req.Cookie(csrf.cookie())
+	if err != nil || cookie.Value == "" {
+		return "missing csrf cookie " + csrf.cookie()
+	}
+	submitted := req.Header.Get(csrf.header())
+	if submitted == "" {
+		submitted = csrfFormField(req, csrf.field())
+	}
+	switch {
+	case submitted == "":
+		return "missing csrf token in header " + csrf.header() + " or form field " + csrf.field()
+	case submitted != cookie.Value:
+		return "csrf token does not match cookie " + csrf.cookie()
+	}
+	t.Lock()
+	defer t.Unlock()
+	if !t.issued[submitted] {
+		return "csrf token was not issued"
+	}
+	return ""
+}
+
+// csrfFormField returns the form field of a url encoded request body, restoring the body for the stubbed endpoint
+func csrfFormField(req *http.Request, field string) string {
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType != "application/x-www-form-urlencoded" || req.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	form, _ := url.ParseQuery(string(body))
+	return form.Get(field)
+}
+
+// CSRFMismatches returns the CSRF mismatches recorded, in the order they were recorded
+func (a *AssuredEndpoints) CSRFMismatches() []CSRFMismatch {
+	a.csrf.Lock()
+	defer a.csrf.Unlock()
+	return append([]CSRFMismatch{}, a.csrf.mismatches...)
+}
+
+// csrfHandler simulates the CSRF protection of stubbed calls with a CSRF, rejecting calls without a valid token with 403 Forbidden
+// when the stubbed call validates it, and issuing a new token with the responses of stubbed calls that issue one
+func (a *AssuredEndpoints) csrfHandler(when http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		stub := matched(req.Context()).stub()
+		if stub == nil || stub.CSRF == nil {
+			when.ServeHTTP(w, req)
+			return
+		}
+		csrf := *stub.CSRF
+
+		if csrf.Validate {
+			if reason := a.csrf.mismatch(csrf, req); reason != "" {
+				a.csrf.Lock()
+				a.csrf.mismatches = append(a.csrf.mismatches, CSRFMismatch{Method: stub.Method, Path: stub.Path, Reason: reason, Time: time.Now()})
+				a.csrf.Unlock()
+				if a.trackMadeCalls {
+					if call, err := a.decodeWhenCall(req.Context(), req); err == nil {
+						a.trackCall(call.(*Call))
+					}
+				}
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(reason))
+				return
+			}
+		}
+		if csrf.Issue {
+			token := a.csrf.issue()
+			http.SetCookie(w, &http.Cookie{Name: csrf.cookie(), Value: token, Path: "/", SameSite: http.SameSiteStrictMode})
+			w.Header().Set(csrf.header(), token)
+			writer := &csrfWriter{ResponseWriter: w, token: []byte(token)}
+			defer writer.flush()
+			w = writer
+		}
+		when.ServeHTTP(w, req)
+	})
+}
+
+// csrfWriter is an http.ResponseWriter that replaces the CSRF token placeholder in the response body with the issued token
+// Writing the status code is deferred until the body is written, so the Content-Length of the response can be corrected
+type csrfWriter struct {
+	http.ResponseWriter
+	token      []byte
+	statusCode int
+	written    bool
+}
+
+// WriteHeader defers writing a final status code until the body is written, writing informational status codes immediately
+func (w *csrfWriter) WriteHeader(statusCode int) {
+	if statusCode < http.StatusOK {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
+
+// Write writes the response body with the token in place of the placeholder
+func (w *csrfWriter) Write(b []byte) (int, error) {
+	body := bytes.ReplaceAll(b, []byte(CSRFTokenPlaceholder), w.token)
+	if !w.written && w.Header().Get("Content-Length") != "" {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	w.flush()
+	if _, err := w.ResponseWriter.Write(body); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// flush writes the deferred status code, if it has not been written
+func (w *csrfWriter) flush() {
+	if w.written {
+		return
+	}
+	w.written = true
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.statusCode)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController
+func (w *csrfWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
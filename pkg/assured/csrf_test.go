@@ -0,0 +1,116 @@
+package assured
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCSRF(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(
+		CSRFTokenCall("form"),
+		CSRFProtectedCall(Call{Path: "form", Method: http.MethodPost, StatusCode: http.StatusCreated}),
+	))
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	httpClient := &http.Client{Jar: jar}
+
+	resp, err := httpClient.Get(client.URL() + "/form")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var body struct {
+		Token string `json:"csrf_token"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Len(t, body.Token, 32)
+	require.Equal(t, body.Token, resp.Header.Get(DefaultCSRFHeader))
+	require.Len(t, resp.Cookies(), 1)
+	require.Equal(t, DefaultCSRFCookie, resp.Cookies()[0].Name)
+	require.Equal(t, body.Token, resp.Cookies()[0].Value)
+
+	post := func(header, field string) int {
+		form := url.Values{}
+		if field != "" {
+			form.Set(DefaultCSRFField, field)
+		}
+		req, err := http.NewRequest(http.MethodPost, client.URL()+"/form", strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			req.Header.Set(DefaultCSRFHeader, header)
+		}
+		resp, err := httpClient.Do(req)
+		require.NoError(t, err)
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	require.Equal(t, http.StatusCreated, post(body.Token, ""))
+	require.Equal(t, http.StatusCreated, post("", body.Token))
+	require.Equal(t, http.StatusForbidden, post("", ""))
+	require.Equal(t, http.StatusForbidden, post("forged", ""))
+
+	resp, err = http.Post(client.URL()+"/form", "text/plain", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	mismatches, err := client.CSRFMismatches()
+	require.NoError(t, err)
+	require.Len(t, mismatches, 3)
+	var reasons []string
+	for _, mismatch := range mismatches {
+		require.Equal(t, http.MethodPost, mismatch.Method)
+		require.Equal(t, "form", mismatch.Path)
+		reasons = append(reasons, mismatch.Reason)
+	}
+	require.Equal(t, []string{
+		"missing csrf token in header X-CSRF-Token or form field csrf_token",
+		"csrf token does not match cookie csrf_token",
+		"missing csrf cookie csrf_token",
+	}, reasons)
+
+	calls, err := client.Verify(http.MethodPost, "form")
+	require.NoError(t, err)
+	require.Len(t, calls, 5)
+	require.Equal(t, "csrf_token="+body.Token, string(calls[1].Response))
+
+	require.NoError(t, client.ClearJournal())
+	mismatches, err = client.CSRFMismatches()
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+}
+
+func TestClientCSRFNotIssued(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(
+		Call{Path: "form", Method: http.MethodGet, Headers: map[string]string{"Content-Length": "42"}, Response: []byte(`<input name="csrf" value="` + CSRFTokenPlaceholder + `">`), CSRF: &CSRF{Issue: true, Cookie: "XSRF-TOKEN"}},
+		Call{Path: "form", Method: http.MethodPut, CSRF: &CSRF{Validate: true, Cookie: "XSRF-TOKEN", Header: "X-XSRF-Token"}},
+	))
+
+	resp, err := http.Get(client.URL() + "/form")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, `<input name="csrf" value="`+resp.Header.Get(DefaultCSRFHeader)+`">`, string(body))
+	require.Equal(t, int64(len(body)), resp.ContentLength)
+
+	req, err := http.NewRequest(http.MethodPut, client.URL()+"/form", nil)
+	require.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "XSRF-TOKEN", Value: "forged"})
+	req.Header.Set("X-XSRF-Token", "forged")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	mismatches, err := client.CSRFMismatches()
+	require.NoError(t, err)
+	require.Len(t, mismatches, 1)
+	require.Equal(t, "csrf token was not issued", mismatches[0].Reason)
+}
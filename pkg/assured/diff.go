@@ -0,0 +1,63 @@
+package assured
+
+import (
+	"reflect"
+	"sort"
+)
+
+// CallsDiff is the difference between two sets of calls, by the IDs of the calls
+type CallsDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty checks if there are no differences
+func (d CallsDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffCalls compares the previous and current sets of calls
+// Calls with the same ID are compared together, in order, so any change to the calls rotated for an ID is a change
+func DiffCalls(previous, current []Call) CallsDiff {
+	before := groupCalls(previous)
+	after := groupCalls(current)
+
+	diff := CallsDiff{}
+	for id, calls := range after {
+		previousCalls, ok := before[id]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, id)
+		case !reflect.DeepEqual(previousCalls, calls):
+			diff.Changed = append(diff.Changed, id)
+		}
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// CountPaths returns the number of distinct paths the calls are stubbed for
+func CountPaths(calls []Call) int {
+	paths := map[string]bool{}
+	for _, call := range calls {
+		paths[call.Path] = true
+	}
+	return len(paths)
+}
+
+// groupCalls groups the calls by their ID
+func groupCalls(calls []Call) map[string][]Call {
+	grouped := map[string][]Call{}
+	for _, call := range calls {
+		grouped[call.ID()] = append(grouped[call.ID()], call)
+	}
+	return grouped
+}
@@ -0,0 +1,29 @@
+package assured
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffCalls(t *testing.T) {
+	previous := []Call{*testCall1(), *testCall2(), *testCall3(), {Method: "DELETE", Path: "removed"}}
+	changed := *testCall2()
+	changed.StatusCode = 500
+	current := []Call{*testCall1(), changed, *testCall3(), {Method: "PUT", Path: "added"}}
+
+	diff := DiffCalls(previous, current)
+
+	require.Equal(t, CallsDiff{
+		Added:   []string{"PUT:added"},
+		Removed: []string{"DELETE:removed"},
+		Changed: []string{"GET:test/assured"},
+	}, diff)
+	require.False(t, diff.Empty())
+	require.True(t, DiffCalls(current, current).Empty())
+}
+
+func TestCountPaths(t *testing.T) {
+	require.Equal(t, 2, CountPaths([]Call{*testCall1(), *testCall2(), *testCall3()}))
+	require.Equal(t, 0, CountPaths(nil))
+}
@@ -0,0 +1,109 @@
+package assured
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DownloadCall returns the stubbed call responding with the content as an attachment with the filename, for download handling tests
+// The content type is by the filename's extension, or sniffed from the content if the extension is unknown
+func DownloadCall(call Call, filename string, content []byte) Call {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	call = mediaTypeCall(call, contentType, content)
+	call.Headers["Content-Disposition"] = mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	return call
+}
+
+// FileCall returns the stubbed call responding with the file as an attachment with the file's name, see DownloadCall
+func FileCall(call Call, path string) (Call, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return call, err
+	}
+	return DownloadCall(call, filepath.Base(path), content), nil
+}
+
+// RandomFixture returns n random bytes, for download size and checksum tests
+func RandomFixture(n int) []byte {
+	b := make([]byte, max(n, 0))
+	_, _ = rand.Read(b)
+	return b
+}
+
+// ZipFixture returns a zip archive of the files, by name, in the order of their names
+func ZipFixture(files map[string][]byte) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)})
+		if err != nil {
+			return nil
+		}
+		_, _ = f.Write(files[name])
+	}
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+// PNGFixture returns a PNG image of the width and height, a gradient so that each image size has different content
+func PNGFixture(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, max(width, 1), max(height, 1)))
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 255 / bounds.Dx()), G: uint8(y * 255 / bounds.Dy()), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// PDFFixture returns a single page PDF document with the line of text
+func PDFFixture(text string) []byte {
+	text = strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\n", " ", "\r", " ").Replace(text)
+	content := fmt.Sprintf("BT /F1 24 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
@@ -0,0 +1,115 @@
+package assured
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadCall(t *testing.T) {
+	tests := []struct {
+		name        string
+		filename    string
+		content     []byte
+		contentType string
+		disposition string
+	}{
+		{name: "png", filename: "logo.png", content: PNGFixture(2, 2), contentType: "image/png", disposition: `attachment; filename=logo.png`},
+		{name: "pdf", filename: "invoice 42.pdf", content: PDFFixture("Invoice"), contentType: "application/pdf", disposition: `attachment; filename="invoice 42.pdf"`},
+		{name: "sniffed", filename: "archive.unknownext", content: ZipFixture(map[string][]byte{"a.txt": []byte("a")}), contentType: "application/zip", disposition: `attachment; filename=archive.unknownext`},
+		{name: "unicode", filename: "résumé.pdf", content: PDFFixture("CV"), contentType: "application/pdf", disposition: `attachment; filename*=utf-8''r%C3%A9sum%C3%A9.pdf`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			call := DownloadCall(Call{Path: "download", Headers: map[string]string{"Content-Length": "1"}}, tc.filename, tc.content)
+
+			require.Equal(t, map[string]string{"Content-Type": tc.contentType, "Content-Disposition": tc.disposition}, call.Headers)
+			require.Equal(t, tc.content, []byte(call.Response))
+		})
+	}
+}
+
+func TestFileCall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.pdf")
+	require.NoError(t, os.WriteFile(path, PDFFixture("Report"), 0o600))
+
+	call, err := FileCall(Call{Path: "report"}, path)
+
+	require.NoError(t, err)
+	require.Equal(t, "application/pdf", call.Headers["Content-Type"])
+	require.Equal(t, "attachment; filename=report.pdf", call.Headers["Content-Disposition"])
+
+	_, err = FileCall(Call{Path: "report"}, filepath.Join(t.TempDir(), "missing.pdf"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRandomFixture(t *testing.T) {
+	require.Len(t, RandomFixture(1024), 1024)
+	require.NotEqual(t, RandomFixture(16), RandomFixture(16))
+	require.Empty(t, RandomFixture(-1))
+}
+
+func TestZipFixture(t *testing.T) {
+	archive := ZipFixture(map[string][]byte{"b/report.csv": []byte("id\n1\n"), "a.txt": []byte("hello")})
+
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	require.Len(t, r.File, 2)
+	require.Equal(t, "a.txt", r.File[0].Name)
+	require.Equal(t, "b/report.csv", r.File[1].Name)
+	f, err := r.File[0].Open()
+	require.NoError(t, err)
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(content))
+	require.Equal(t, archive, ZipFixture(map[string][]byte{"a.txt": []byte("hello"), "b/report.csv": []byte("id\n1\n")}))
+}
+
+func TestPNGFixture(t *testing.T) {
+	img, err := png.Decode(bytes.NewReader(PNGFixture(64, 32)))
+
+	require.NoError(t, err)
+	require.Equal(t, 64, img.Bounds().Dx())
+	require.Equal(t, 32, img.Bounds().Dy())
+}
+
+func TestPDFFixture(t *testing.T) {
+	pdf := string(PDFFixture(`Total (USD) \ 42`))
+
+	require.True(t, strings.HasPrefix(pdf, "%PDF-1.4\n"))
+	require.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+	require.Contains(t, pdf, `(Total \(USD\) \\ 42) Tj`)
+	// Each cross-reference entry is the byte offset of its object
+	xref := pdf[strings.Index(pdf, "xref\n"):]
+	for i, entry := range strings.Split(xref, "\n")[3:8] {
+		var offset int
+		_, err := fmt.Sscanf(entry, "%d", &offset)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(pdf[offset:], strconv.Itoa(i+1)+" 0 obj"), entry)
+	}
+}
+
+func TestClientDownloadCall(t *testing.T) {
+	_, client := NewTestServer(t)
+	content := RandomFixture(4096)
+	require.NoError(t, client.Given(DownloadCall(Call{Path: "files/blob", Method: http.MethodGet, StatusCode: http.StatusOK}, "blob.bin", content)))
+
+	resp, err := http.Get(client.URL() + "/files/blob")
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "attachment; filename=blob.bin", resp.Header.Get("Content-Disposition"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, content, body)
+}
@@ -0,0 +1,46 @@
+package assured
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Echo is the request metadata the echo endpoint responds with
+type Echo struct {
+	Method        string            `json:"method"`
+	Path          string            `json:"path"`
+	Query         string            `json:"query,omitempty"`
+	Proto         string            `json:"proto"`
+	Host          string            `json:"host"`
+	RemoteAddr    string            `json:"remote_addr"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	ContentLength int64             `json:"content_length"`
+}
+
+// echoHandler responds to any request with its metadata, for measuring the ceiling of a client stack against the rest assured server
+// The request is not matched, tracked, or delayed, and its body is discarded, only counted, so the handler adds near-zero overhead
+func echoHandler(w http.ResponseWriter, req *http.Request) {
+	n, _ := io.Copy(io.Discard, req.Body)
+	echo := Echo{
+		Method:        req.Method,
+		Path:          req.URL.Path,
+		Query:         req.URL.RawQuery,
+		Proto:         req.Proto,
+		Host:          req.Host,
+		RemoteAddr:    req.RemoteAddr,
+		ContentLength: n,
+	}
+	if len(req.Header) > 0 {
+		echo.Headers = make(map[string]string, len(req.Header))
+		for key, values := range req.Header {
+			echo.Headers[key] = strings.Join(values, ", ")
+		}
+	}
+	response, _ := json.Marshal(echo)
+	w.Header().Set("Content-Type", "application/json")
+	if req.Method != http.MethodHead {
+		_, _ = w.Write(response)
+	}
+}
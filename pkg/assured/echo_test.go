@@ -0,0 +1,52 @@
+package assured
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplicationRouterEchoBinding(t *testing.T) {
+	router := NewClient().createApplicationRouter()
+
+	req, err := http.NewRequest(http.MethodPut, "/__echo__/orders/1?expand=items", strings.NewReader(`{"id":1}`))
+	require.NoError(t, err)
+	req.Header.Set("X-Request-Id", "abc")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	var echo Echo
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &echo))
+	require.Equal(t, Echo{
+		Method:        http.MethodPut,
+		Path:          "/__echo__/orders/1",
+		Query:         "expand=items",
+		Proto:         "HTTP/1.1",
+		Headers:       map[string]string{"X-Request-Id": "abc"},
+		ContentLength: 8,
+	}, echo)
+}
+
+func TestClientEcho(t *testing.T) {
+	_, client := NewTestServer(t, WithCallTracking(true))
+
+	resp, err := http.Post(client.EchoURL(), "text/plain", strings.NewReader("ping"))
+	require.NoError(t, err)
+	var echo Echo
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&echo))
+	require.Equal(t, http.MethodPost, echo.Method)
+	require.Equal(t, "/__echo__", echo.Path)
+	require.Equal(t, "text/plain", echo.Headers["Content-Type"])
+	require.Equal(t, int64(4), echo.ContentLength)
+	require.NotEmpty(t, echo.RemoteAddr)
+
+	stats, err := client.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 0, stats.JournalEntries)
+}
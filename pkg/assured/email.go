@@ -0,0 +1,191 @@
+package assured
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sendGridPath is the path of SendGrid's mail send API
+const sendGridPath = "v3/mail/send"
+
+// Email is a message sent through a mock email sending API
+type Email struct {
+	From    string   `json:"from"`
+	To      []string `json:"to"`
+	Cc      []string `json:"cc,omitempty"`
+	Bcc     []string `json:"bcc,omitempty"`
+	Subject string   `json:"subject"`
+	Text    string   `json:"text,omitempty"`
+	HTML    string   `json:"html,omitempty"`
+}
+
+// sendGridMail is the JSON request body of SendGrid's mail send API
+type sendGridMail struct {
+	Personalizations []struct {
+		To      []sendGridAddress `json:"to"`
+		Cc      []sendGridAddress `json:"cc"`
+		Bcc     []sendGridAddress `json:"bcc"`
+		Subject string            `json:"subject"`
+	} `json:"personalizations"`
+	From    sendGridAddress `json:"from"`
+	Subject string          `json:"subject"`
+	Content []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"content"`
+}
+
+// sendGridAddress is an email address of SendGrid's mail send API
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// SendGridCall returns the call to stub for a mock of SendGrid's mail send API, POST /v3/mail/send, which accepts every message with 202 Accepted
+func SendGridCall() Call {
+	return Call{
+		Path:       sendGridPath,
+		Method:     http.MethodPost,
+		StatusCode: http.StatusAccepted,
+		Headers:    map[string]string{"X-Message-Id": AWSRequestID()},
+	}
+}
+
+// MailgunCall returns the call to stub for a mock of Mailgun's messages API for the domain, POST /v3/{domain}/messages,
+// which queues every message with 200 OK
+func MailgunCall(domain string) Call {
+	response, _ := json.Marshal(map[string]string{
+		"id":      fmt.Sprintf("<%s@%s>", AWSRequestID(), domain),
+		"message": "Queued. Thank you.",
+	})
+	return Call{
+		Path:       fmt.Sprintf("v3/%s/messages", domain),
+		Method:     http.MethodPost,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Response:   response,
+	}
+}
+
+// SentEmails returns the messages sent to the stubbed email sending API calls, in the order they were sent for each call
+func (c *Client) SentEmails(calls ...Call) ([]Email, error) {
+	emails := []Email{}
+	for _, call := range calls {
+		made, err := c.Verify(call.Method, strings.Trim(call.Path, "/"))
+		if err != nil {
+			return nil, err
+		}
+		for _, sent := range made {
+			email, err := ParseEmail(sent)
+			if err != nil {
+				return nil, err
+			}
+			emails = append(emails, email)
+		}
+	}
+	return emails, nil
+}
+
+// ParseEmail parses the message of a call made to a mock email sending API, SendGrid's JSON mail send API,
+// or Mailgun's form encoded messages API
+func ParseEmail(call Call) (Email, error) {
+	if strings.Trim(call.Path, "/") == sendGridPath {
+		return parseSendGridEmail(call.Response)
+	}
+	if strings.HasSuffix(call.Path, "/messages") {
+		return parseMailgunEmail(call.Headers["Content-Type"], call.Response)
+	}
+	return Email{}, fmt.Errorf("unknown email api %s", call.Path)
+}
+
+// parseSendGridEmail parses the message of SendGrid's JSON mail send API request body
+// The recipients of every personalization are included, and the first personalization's subject overrides the message's
+func parseSendGridEmail(body []byte) (Email, error) {
+	var mail sendGridMail
+	if err := json.Unmarshal(body, &mail); err != nil {
+		return Email{}, fmt.Errorf("invalid sendgrid email: %w", err)
+	}
+	email := Email{From: mail.From.Email, Subject: mail.Subject}
+	for i, personalization := range mail.Personalizations {
+		for _, address := range personalization.To {
+			email.To = append(email.To, address.Email)
+		}
+		for _, address := range personalization.Cc {
+			email.Cc = append(email.Cc, address.Email)
+		}
+		for _, address := range personalization.Bcc {
+			email.Bcc = append(email.Bcc, address.Email)
+		}
+		if i == 0 && personalization.Subject != "" {
+			email.Subject = personalization.Subject
+		}
+	}
+	for _, content := range mail.Content {
+		switch content.Type {
+		case "text/plain":
+			email.Text = content.Value
+		case "text/html":
+			email.HTML = content.Value
+		}
+	}
+	return email, nil
+}
+
+// parseMailgunEmail parses the message of Mailgun's messages API request body, url or multipart form encoded
+// Recipients can be repeated, or comma separated
+func parseMailgunEmail(contentType string, body []byte) (Email, error) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	form := url.Values{}
+	switch mediaType {
+	case "multipart/form-data":
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return Email{}, fmt.Errorf("invalid mailgun email: %w", err)
+			}
+			value, err := io.ReadAll(part)
+			if err != nil {
+				return Email{}, fmt.Errorf("invalid mailgun email: %w", err)
+			}
+			form.Add(part.FormName(), string(value))
+		}
+	default:
+		var err error
+		if form, err = url.ParseQuery(string(body)); err != nil {
+			return Email{}, fmt.Errorf("invalid mailgun email: %w", err)
+		}
+	}
+
+	recipients := func(key string) []string {
+		var addresses []string
+		for _, value := range form[key] {
+			for _, address := range strings.Split(value, ",") {
+				if address = strings.TrimSpace(address); address != "" {
+					addresses = append(addresses, address)
+				}
+			}
+		}
+		return addresses
+	}
+	return Email{
+		From:    form.Get("from"),
+		To:      recipients("to"),
+		Cc:      recipients("cc"),
+		Bcc:     recipients("bcc"),
+		Subject: form.Get("subject"),
+		Text:    form.Get("text"),
+		HTML:    form.Get("html"),
+	}, nil
+}
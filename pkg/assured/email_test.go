@@ -0,0 +1,68 @@
+package assured
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSentEmails(t *testing.T) {
+	_, client := NewTestServer(t)
+	sendGrid := SendGridCall()
+	mailgun := MailgunCall("mg.example.com")
+	require.NoError(t, client.Given(sendGrid, mailgun))
+
+	resp, err := http.Post(client.URL()+"/v3/mail/send", "application/json", strings.NewReader(`{
+		"personalizations": [{"to": [{"email": "ada@example.com", "name": "Ada"}], "cc": [{"email": "ops@example.com"}], "subject": "Welcome, Ada"}],
+		"from": {"email": "noreply@example.com"},
+		"subject": "Welcome",
+		"content": [{"type": "text/plain", "value": "Hi Ada"}, {"type": "text/html", "value": "<p>Hi Ada</p>"}]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.NotEmpty(t, resp.Header.Get("X-Message-Id"))
+
+	resp, err = http.PostForm(client.URL()+"/v3/mg.example.com/messages", url.Values{
+		"from":    {"noreply@example.com"},
+		"to":      {"grace@example.com, alan@example.com", "edsger@example.com"},
+		"subject": {"Your receipt"},
+		"text":    {"Thanks"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	require.NoError(t, form.WriteField("from", "billing@example.com"))
+	require.NoError(t, form.WriteField("to", "barbara@example.com"))
+	require.NoError(t, form.WriteField("subject", "Invoice"))
+	require.NoError(t, form.WriteField("html", "<b>Due</b>"))
+	require.NoError(t, form.Close())
+	resp, err = http.Post(client.URL()+"/v3/mg.example.com/messages", form.FormDataContentType(), &body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	emails, err := client.SentEmails(sendGrid, mailgun)
+	require.NoError(t, err)
+	require.Equal(t, []Email{
+		{From: "noreply@example.com", To: []string{"ada@example.com"}, Cc: []string{"ops@example.com"}, Subject: "Welcome, Ada", Text: "Hi Ada", HTML: "<p>Hi Ada</p>"},
+		{From: "noreply@example.com", To: []string{"grace@example.com", "alan@example.com", "edsger@example.com"}, Subject: "Your receipt", Text: "Thanks"},
+		{From: "billing@example.com", To: []string{"barbara@example.com"}, Subject: "Invoice", HTML: "<b>Due</b>"},
+	}, emails)
+}
+
+func TestParseEmailInvalid(t *testing.T) {
+	_, err := ParseEmail(Call{Path: "v3/mail/send", Response: []byte("not json")})
+	require.EqualError(t, err, "invalid sendgrid email: invalid character 'o' in literal null (expecting 'u')")
+
+	_, err = ParseEmail(Call{Path: "v3/mg.example.com/messages", Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, Response: []byte("to=%zz")})
+	require.EqualError(t, err, `invalid mailgun email: invalid URL escape "%zz"`)
+
+	_, err = ParseEmail(Call{Path: "v1/email"})
+	require.EqualError(t, err, "unknown email api v1/email")
+}
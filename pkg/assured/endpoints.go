@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/google/uuid"
 )
 
 // AssuredEndpoints
@@ -17,18 +22,84 @@ type AssuredEndpoints struct {
 	httpClient     *http.Client
 	assuredCalls   *CallStore
 	madeCalls      *CallStore
+	recorder       *recorder
 	callbackCalls  *CallStore
 	trackMadeCalls bool
+	latency        time.Duration
+	maxBodySize    int64
+	maxHeaderSize  int
+	maxURILength   int
+	journalTTL     time.Duration
+	plainHandlers  bool
+	errorFormat    ErrorFormat
+	validation     ResponseValidation
+	rawURI         bool
+	inferType      bool
+	s3Buckets      []string
+	s3Objects      s3Objects
+	tusEndpoints   []string
+	tusUploads     tusUploads
+	started        time.Time
+	callbacks      atomic.Int64
+	frozen         atomic.Bool
+	history        stubHistory
+	limiters       map[string]chan struct{}
+	limitersMu     sync.Mutex
+	breakers       breakers
+	sequences      sequences
+	sessions       sessions
+	jobs           jobs
+	csrf           csrfTokens
+	signatures     signatures
+	latencies      latencies
+	panics         panics
+	pathRegexes    atomic.Int64
+	pathRegexesMu  sync.Mutex
+	graphQLSchemas sync.Map
+	counters       *counters
+	state          *state
+}
+
+// errFrozen is the error of stubbing or clearing calls while the stubbed calls are frozen
+var errFrozen = errors.New("stubbed calls are frozen")
+
+// Stats reports the memory usage of the rest assured server, and the latencies the stubbed calls were served with
+// and the number of panics serving them, by Method/Path
+type Stats struct {
+	Stubs            int                         `json:"stubs"`
+	JournalEntries   int                         `json:"journal_entries"`
+	JournalBytes     int                         `json:"journal_bytes"`
+	PendingCallbacks int                         `json:"pending_callbacks"`
+	Uptime           float64                     `json:"uptime_seconds"`
+	Latencies        map[string]LatencyHistogram `json:"latencies,omitempty"`
+	Panics           map[string]int              `json:"panics,omitempty"`
 }
 
 // NewAssuredEndpoints creates a new instance of assured endpoints
 func NewAssuredEndpoints(options Options) *AssuredEndpoints {
 	return &AssuredEndpoints{
-		assuredCalls:   NewCallStore(),
+		assuredCalls:   NewStubStore(),
 		madeCalls:      NewCallStore(),
+		recorder:       newRecorder(),
 		callbackCalls:  NewCallStore(),
 		httpClient:     options.httpClient,
 		trackMadeCalls: options.trackMadeCalls,
+		latency:        options.latency,
+		maxBodySize:    options.maxBodySize,
+		maxHeaderSize:  options.maxHeaderSize,
+		maxURILength:   options.maxURILength,
+		journalTTL:     options.journalTTL,
+		plainHandlers:  options.plainHandlers,
+		errorFormat:    options.errorFormat,
+		validation:     options.responseValidation,
+		rawURI:         options.rawURI,
+		inferType:      options.inferContentType,
+		s3Buckets:      options.s3Buckets,
+		tusEndpoints:   options.tusEndpoints,
+		history:        stubHistory{limit: options.stubHistory},
+		counters:       newCounters(options.counterFile),
+		state:          &state{values: map[string]string{}},
+		started:        time.Now(),
 	}
 }
 
@@ -46,14 +117,96 @@ func (a *AssuredEndpoints) WrappedEndpoint(handler func(context.Context, *Call)
 
 // GivenEndpoint is used to stub out a call for a given path
 func (a *AssuredEndpoints) GivenEndpoint(ctx context.Context, call *Call) (interface{}, error) {
+	if a.frozen.Load() {
+		return nil, errFrozen
+	}
+	// Compile the call's matchers once, so a malformed expression fails the stub instead of each call made
+	if err := call.compileExtractions(); err != nil {
+		return nil, err
+	}
+	if err := call.compileRequiredHeaders(); err != nil {
+		return nil, err
+	}
+	if err := call.compilePathRegex(); err != nil {
+		return nil, err
+	}
+	// Pad the response once, so the padded call responds as fast as any call
+	if call.Padding != nil {
+		call.Response = call.Padding.pad(call.Response)
+		delete(call.Headers, "Content-Length")
+	}
+	a.pathRegexesMu.Lock()
 	a.assuredCalls.Add(call)
+	if call.pathRegex != nil {
+		a.pathRegexes.Add(1)
+	}
+	a.pathRegexesMu.Unlock()
 	slog.With("path", call.ID()).Info("assured call set")
 
 	return call, nil
 }
 
+// GivenJSONEndpoint is used to stub out a call, and its callbacks, from the JSON given request
+// The call is translated into the same assured call and callbacks that the header protocol stubs
+func (a *AssuredEndpoints) GivenJSONEndpoint(ctx context.Context, call *Call) (interface{}, error) {
+	if a.frozen.Load() {
+		return nil, errFrozen
+	}
+	stub := *call
+	if stub.Method == "" {
+		stub.Method = http.MethodGet
+	}
+	if stub.StatusCode == 0 {
+		stub.StatusCode = http.StatusOK
+	}
+	stub.Path = strings.Trim(stub.Path, "/")
+	stub.Headers = map[string]string{}
+	for key, value := range call.Headers {
+		// Raw headers keep their casing, unless they control the rest assured server or the response framing
+		if canonical := http.CanonicalHeaderKey(key); !stub.RawHeaders || canonicalOnly(canonical) {
+			key = canonical
+		}
+		stub.Headers[key] = value
+	}
+	if stub.Delay > 0 {
+		stub.Headers[AssuredDelay] = strconv.Itoa(stub.Delay)
+	}
+
+	callbacks := make([]*Call, len(call.Callbacks))
+	callbackKey := uuid.NewString()
+	for i, callback := range call.Callbacks {
+		if callback.Target == "" {
+			return nil, errors.New("cannot stub callback without target")
+		}
+		headers := map[string]string{}
+		for key, value := range callback.Headers {
+			headers[http.CanonicalHeaderKey(key)] = value
+		}
+		headers[AssuredCallbackTarget] = callback.Target
+		headers[AssuredCallbackKey] = callbackKey
+		if callback.Delay > 0 {
+			headers[AssuredCallbackDelay] = strconv.Itoa(callback.Delay)
+		}
+		callbacks[i] = &Call{Method: callback.Method, StatusCode: http.StatusCreated, Headers: headers, Response: callback.Response}
+	}
+	if len(callbacks) > 0 {
+		stub.Headers[AssuredCallbackKey] = callbackKey
+	}
+	stub.Callbacks = nil
+
+	for _, callback := range callbacks {
+		if _, err := a.GivenCallbackEndpoint(ctx, callback); err != nil {
+			return nil, err
+		}
+	}
+	return a.GivenEndpoint(ctx, &stub)
+}
+
 // GivenCallbackEndpoint is used to stub out callbacks for a callback key
 func (a *AssuredEndpoints) GivenCallbackEndpoint(ctx context.Context, call *Call) (interface{}, error) {
+	if a.frozen.Load() {
+		return nil, errFrozen
+	}
 	a.callbackCalls.AddAt(call.Headers[AssuredCallbackKey], call)
 	slog.With("key", call.Headers[AssuredCallbackKey], "target", call.Headers[AssuredCallbackTarget]).Info("assured callback set")
 
@@ -62,44 +215,263 @@ func (a *AssuredEndpoints) GivenCallbackEndpoint(ctx context.Context, call *Call
 
 // WhenEndpoint is used to test the assured calls
 func (a *AssuredEndpoints) WhenEndpoint(ctx context.Context, call *Call) (interface{}, error) {
-	calls := a.assuredCalls.Get(call.ID())
+	// Simulate network latency, before matching so unmatched calls are delayed as well
+	time.Sleep(a.latency)
+
+	// Match the call made to the stubbed calls, unless the when endpoint's middleware already has
+	m := matched(ctx)
+	if m == nil {
+		m = a.match(call)
+	}
+	call.Path = m.path
+	calls := m.calls
 	if len(calls) == 0 {
+		// Mock the S3 semantics of calls made to the S3 buckets, if no call is stubbed for them
+		if s3, ok := a.s3Call(call); ok {
+			if a.trackMadeCalls {
+				a.trackCall(call)
+			}
+			slog.With("path", call.ID()).Info("assured s3 call responded")
+			return s3, nil
+		}
+		// Mock the tus resumable uploads of the calls made to the upload endpoints, if no call is stubbed for them
+		if tus, ok := a.tusCall(call); ok {
+			if a.trackMadeCalls {
+				a.trackCall(call)
+			}
+			slog.With("path", call.ID()).Info("assured tus call responded")
+			return tus, nil
+		}
 		slog.With("path", call.ID()).Info("assured call not found")
-		return nil, errors.New("No assured calls")
+		unmatched := &unmatchedError{}
+		// Include the match trace of the candidates, none of which matched, if requested
+		if call.Headers[AssuredTrace] == "true" {
+			_, unmatched.trace = traceCandidates(nil, m.candidates, call)
+		}
+		return nil, unmatched
 	}
 
+	// The stubbed call matched, kept as the key of its state, since the call served is copied from it as it is rendered
+	stub := m.stub()
+	assured := stub
+	// Capture the path parameters of the call made, if stubbed with a path template or regex
+	if assured.pathRegex != nil {
+		call.PathParams = assured.pathParams(call.Path)
+	}
 	if a.trackMadeCalls {
-		a.madeCalls.Add(call)
+		a.trackCall(call)
+	}
+
+	// Reject calls made without a valid signed URL, if applicable
+	if assured.SignedURL != nil && assured.SignedURL.Validate {
+		if reason := a.signedURLRejection(assured.SignedURL, call); reason != "" {
+			slog.With("path", call.ID(), "reason", reason).Info("assured call signed url rejected")
+			return assured.rejected(reason), nil
+		}
+	}
+
+	// Respond fast without reaching the upstream while its circuit breaker is open
+	trial := false
+	if assured.Breaker != nil {
+		var open bool
+		var remaining time.Duration
+		if open, remaining, trial = a.breakers.open(stub.ID()); open {
+			slog.With("path", call.ID()).Info("assured call breaker open")
+			return openBreakerCall(assured, remaining), nil
+		}
+	}
+	// Abandon the half-open trial if it doesn't respond, such as when it's canceled, fails, or panics, so the next request is the trial
+	if trial {
+		defer func() {
+			if trial {
+				a.breakers.abandon(stub.ID())
+			}
+		}()
 	}
-	assured := calls[0]
 	a.assuredCalls.Rotate(assured)
 
 	// Trigger callbacks, if applicable
 	for _, callback := range a.callbackCalls.Get(assured.Headers[AssuredCallbackKey]) {
-		go a.sendCallback(callback.Headers[AssuredCallbackTarget], callback)
+		a.callbacks.Add(1)
+		go func(callback *Call) {
+			defer a.callbacks.Add(-1)
+			a.sendCallback(callback.Headers[AssuredCallbackTarget], callback)
+		}(callback)
+	}
+
+	// Respond with the next status code in the call's sequence, if applicable
+	if len(assured.StatusCodes) > 0 {
+		sequenced := *assured
+		sequenced.StatusCode = a.sequences.advance(assured)
+		assured = &sequenced
 	}
 
-	// Delay response
+	// Respond in the language negotiated by the request's Accept-Language, if applicable
+	assured = assured.localized(call)
+
+	// Respond with the first matching conditional branch, if applicable
+	assured = assured.branch(call)
+
+	// Start the call's job, or respond with the state of its job, if applicable
+	if assured.Job != nil {
+		assured = a.job(assured)
+	}
+
+	// Respond with mock data resolved from the GraphQL schema, unless a branch responds, if applicable
+	if assured.GraphQL != "" && len(assured.Response) == 0 {
+		assured = a.mockGraphQL(assured, call)
+	}
+
+	// Respond with the results of the batch request's operations, unless a branch responds, if applicable
+	if assured.Batch && len(assured.Response) == 0 {
+		batched, err := a.batched(ctx, assured, call)
+		if err != nil {
+			return nil, err
+		}
+		assured = batched
+	}
+
+	// Render the path parameters captured from the call made, if applicable
+	if assured.hasPathParams() {
+		assured = assured.renderPathParams(call)
+	}
+
+	// Render the next values of the response's counters, if applicable
+	if hasCounters(assured.Response) {
+		assured = a.countered(assured)
+	}
+
+	// Extract the call made's values into the shared state, and render the state's values, if applicable
+	if assured.hasState() {
+		assured = a.stateful(assured, call)
+	}
+
+	// Issue a new signed URL in place of the response's signed URL placeholders, if applicable
+	if assured.SignedURL != nil && assured.SignedURL.Issue != "" {
+		assured = assured.signed()
+	}
+
+	// Include the match trace, if requested
+	if call.Headers[AssuredTrace] == "true" {
+		assured = traceCall(assured, m.candidates, call)
+	}
+
+	// Limit the concurrent requests being processed for the stubbed call, queueing the rest
+	if assured.Concurrency > 0 {
+		limiter := a.limiter(stub.ID(), assured.Concurrency)
+		select {
+		case limiter <- struct{}{}:
+			defer func() { <-limiter }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// Delay response to simulate upstream processing time
 	if delay, err := strconv.ParseInt(assured.Headers[AssuredDelay], 10, 64); err == nil {
 		time.Sleep(time.Duration(delay) * time.Second)
 	}
 
+	// Set the caching headers, responding 304 Not Modified to revalidations, if applicable
+	assured = assured.cached(call)
+
+	if assured.Breaker != nil {
+		a.breakers.record(stub.ID(), assured.Breaker, assured.StatusCode)
+		trial = false
+	}
+
+	// Check the response parses as its declared Content-Type, if applicable
+	if a.validation != "" {
+		if err := a.validateResponse(assured); err != nil {
+			return nil, err
+		}
+	}
+
 	slog.With("path", call.ID()).Info("assured call responded")
 	return assured, nil
 }
 
+// trackCall stores the call made, with the time it was made, through the recorder so the hot path doesn't wait on the journal
+func (a *AssuredEndpoints) trackCall(call *Call) {
+	a.recorder.record(a.madeCalls, call, time.Now())
+}
+
+// limiter returns the semaphore limiting the concurrent requests for the stubbed call ID to the concurrency limit
+// A new semaphore replaces the previous one if the concurrency limit changes
+func (a *AssuredEndpoints) limiter(id string, concurrency int) chan struct{} {
+	a.limitersMu.Lock()
+	defer a.limitersMu.Unlock()
+	if a.limiters == nil {
+		a.limiters = map[string]chan struct{}{}
+	}
+	limiter, ok := a.limiters[id]
+	if !ok || cap(limiter) != concurrency {
+		limiter = make(chan struct{}, concurrency)
+		a.limiters[id] = limiter
+	}
+	return limiter
+}
+
 // VerifyEndpoint is used to verify a particular call
 func (a *AssuredEndpoints) VerifyEndpoint(ctx context.Context, call *Call) (interface{}, error) {
 	if a.trackMadeCalls {
+		a.recorder.flush()
 		return a.madeCalls.Get(call.ID()), nil
 	}
 	return nil, errors.New("Tracking made calls is disabled")
 }
 
+// DuplicatesEndpoint is used to find the duplicate calls made against a particular call, with the same body
+// made within the window query parameter of each other. Without a window, every call with the same body is a duplicate
+func (a *AssuredEndpoints) DuplicatesEndpoint(ctx context.Context, call *Call) (interface{}, error) {
+	if !a.trackMadeCalls {
+		return nil, errors.New("Tracking made calls is disabled")
+	}
+	var window time.Duration
+	if w := call.Query["window"]; w != "" {
+		var err error
+		if window, err = time.ParseDuration(w); err != nil {
+			return nil, fmt.Errorf("invalid window: %w", err)
+		}
+	}
+
+	a.recorder.flush()
+	calls, times := a.madeCalls.Recorded(call.ID())
+	duplicates := [][]*Call{}
+	groups := map[string]int{}
+	last := []time.Time{}
+	for i, made := range calls {
+		body := string(made.Response)
+		if g, ok := groups[body]; ok && (window == 0 || times[i].Sub(last[g]) <= window) {
+			duplicates[g] = append(duplicates[g], made)
+			last[g] = times[i]
+			continue
+		}
+		groups[body] = len(duplicates)
+		duplicates = append(duplicates, []*Call{made})
+		last = append(last, times[i])
+	}
+
+	found := [][]*Call{}
+	for _, group := range duplicates {
+		if len(group) > 1 {
+			found = append(found, group)
+		}
+	}
+	return found, nil
+}
+
 // ClearEndpoint is used to clear a specific assured call
 func (a *AssuredEndpoints) ClearEndpoint(ctx context.Context, call *Call) (interface{}, error) {
+	if a.frozen.Load() {
+		return nil, errFrozen
+	}
+	a.recorder.flush()
 	a.assuredCalls.Clear(call.ID())
+	a.countPathRegexes()
 	a.madeCalls.Clear(call.ID())
+	a.breakers.reset(call.ID())
+	a.sequences.reset(call.ID())
 	slog.With("path", call.ID()).Info("cleared calls for path")
 	if call.Headers[AssuredCallbackKey] != "" {
 		a.callbackCalls.Clear(call.Headers[AssuredCallbackKey])
@@ -109,16 +481,192 @@ func (a *AssuredEndpoints) ClearEndpoint(ctx context.Context, call *Call) (inter
 	return nil, nil
 }
 
+// ClearMatching clears the assured calls of every path with the prefix, made with the method, if not empty
+// Returns the number of Method/Paths cleared
+func (a *AssuredEndpoints) ClearMatching(method, prefix string) (int, error) {
+	if a.frozen.Load() {
+		return 0, errFrozen
+	}
+	a.recorder.flush()
+	ids := map[string]bool{}
+	for _, store := range []*CallStore{a.assuredCalls, a.madeCalls} {
+		for _, id := range store.Keys("") {
+			callMethod, path, _ := strings.Cut(id, ":")
+			if (method == "" || callMethod == method) && strings.HasPrefix(path, prefix) {
+				ids[id] = true
+			}
+		}
+	}
+	for id := range ids {
+		for _, call := range a.assuredCalls.Get(id) {
+			if key := call.Headers[AssuredCallbackKey]; key != "" {
+				a.callbackCalls.Clear(key)
+			}
+		}
+		a.assuredCalls.Clear(id)
+		a.madeCalls.Clear(id)
+		a.breakers.reset(id)
+		a.sequences.reset(id)
+	}
+	a.countPathRegexes()
+	slog.With("method", method, "prefix", prefix, "cleared", len(ids)).Info("cleared calls matching")
+	return len(ids), nil
+}
+
 // ClearAllEndpoint is used to clear all assured calls
 func (a *AssuredEndpoints) ClearAllEndpoint(ctx context.Context, i interface{}) (interface{}, error) {
+	if a.frozen.Load() {
+		return nil, errFrozen
+	}
+	a.recorder.flush()
 	a.assuredCalls.ClearAll()
+	a.countPathRegexes()
 	a.madeCalls.ClearAll()
 	a.callbackCalls.ClearAll()
+	a.s3Objects.clear()
+	a.breakers.resetAll()
+	a.sequences.resetAll()
+	a.latencies.reset()
 	slog.Info("cleared all calls")
 
 	return nil, nil
 }
 
+// ReplaceEndpoint replaces every stubbed call and callback with the calls given, in the JSON given format, at once
+// The calls are stubbed aside before they are swapped in, so calls made meanwhile are served the calls replaced, and a call that
+// fails to stub leaves them in place. The made calls journal is kept
+func (a *AssuredEndpoints) ReplaceEndpoint(ctx context.Context, calls []*Call) (int, error) {
+	if a.frozen.Load() {
+		return 0, errFrozen
+	}
+	staged := &AssuredEndpoints{assuredCalls: NewCallStore(), callbackCalls: NewCallStore()}
+	for _, call := range calls {
+		if _, err := staged.GivenJSONEndpoint(ctx, call); err != nil {
+			return 0, err
+		}
+	}
+	a.assuredCalls.Restore(staged.assuredCalls.Snapshot())
+	a.callbackCalls.Restore(staged.callbackCalls.Snapshot())
+	a.countPathRegexes()
+	a.breakers.resetAll()
+	a.sequences.resetAll()
+	slog.With("calls", len(calls)).Info("replaced all calls")
+
+	return len(calls), nil
+}
+
+// Freeze rejects stubbing and clearing calls while frozen, so the stubbed calls can't be changed mid-run
+// Made calls are still tracked, and the made calls journal can still be cleared
+func (a *AssuredEndpoints) Freeze(frozen bool) {
+	a.frozen.Store(frozen)
+	slog.With("frozen", frozen).Info("assured calls freeze set")
+}
+
+// ClearJournal clears the made calls journal, keeping the stubbed calls, and returns the number of made calls cleared
+func (a *AssuredEndpoints) ClearJournal() int {
+	a.recorder.flush()
+	cleared := a.madeCalls.Len()
+	a.madeCalls.ClearAll()
+	a.csrf.Lock()
+	a.csrf.mismatches = nil
+	a.csrf.Unlock()
+	a.latencies.reset()
+	slog.With("cleared", cleared).Info("cleared made calls journal")
+	return cleared
+}
+
+// Stats reports the number of stubbed calls, the size of the made calls journal,
+// the number of callbacks waiting to be sent, how long the server has been up, and the latency histograms and panics of the stubbed calls
+func (a *AssuredEndpoints) Stats() Stats {
+	a.recorder.flush()
+	return Stats{
+		Stubs:            a.assuredCalls.Len(),
+		JournalEntries:   a.madeCalls.Len(),
+		JournalBytes:     a.madeCalls.Size(),
+		PendingCallbacks: int(a.callbacks.Load()),
+		Uptime:           time.Since(a.started).Seconds(),
+		Latencies:        a.latencies.report(),
+		Panics:           a.panics.counts(),
+	}
+}
+
+// Compact purges the made calls older than the journal ttl and returns the number of calls purged
+func (a *AssuredEndpoints) Compact() int {
+	if a.journalTTL <= 0 {
+		return 0
+	}
+	a.recorder.flush()
+	purged := a.madeCalls.Purge(time.Now().Add(-a.journalTTL))
+	if purged > 0 {
+		slog.With("purged", purged).Info("compacted made calls journal")
+	}
+	return purged
+}
+
+// retainJournal compacts the made calls journal in the background until the context is done
+// The journal is compacted at a fraction of the ttl so calls are not kept much longer than the window
+func (a *AssuredEndpoints) retainJournal(ctx context.Context) {
+	ticker := time.NewTicker(max(a.journalTTL/4, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.Compact()
+		}
+	}
+}
+
+// traceCall returns a copy of the matched call with headers describing how it was selected from the candidates for the call made
+func traceCall(matched *Call, candidates []*Call, made *Call) *Call {
+	traced := *matched
+	traced.Headers = map[string]string{}
+	for key, value := range matched.Headers {
+		traced.Headers[key] = value
+	}
+	match, trace := traceCandidates(matched, candidates, made)
+	if match != "" {
+		traced.Headers[AssuredTraceMatch] = match
+	}
+	traced.Headers[AssuredTraceCandidates] = trace
+
+	return &traced
+}
+
+// traceCandidates returns the matched call, of the candidates, and the evaluation of every candidate for the call made,
+// or "none" if no call is stubbed for the call made's path
+func traceCandidates(matched *Call, candidates []*Call, made *Call) (string, string) {
+	if len(candidates) == 0 {
+		return "", "none"
+	}
+	match := ""
+	trace := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		result := "queued"
+		if !candidate.matchesQuery(made) {
+			result = "query mismatch"
+		} else if !candidate.matchesHeaders(made) {
+			result = "header mismatch"
+		}
+		if candidate == matched {
+			result = "matched"
+			match = fmt.Sprintf("%s[%d]", candidate.ID(), i)
+		}
+		trace[i] = fmt.Sprintf("%s[%d]=%s", candidate.ID(), i, result)
+	}
+	return match, strings.Join(trace, ", ")
+}
+
+// unmatchedError is the error of a call made that no stubbed call matched, with the evaluation of its candidates, if traced
+type unmatchedError struct {
+	trace string
+}
+
+func (e *unmatchedError) Error() string {
+	return "No assured calls"
+}
+
 // sendCallback sends a given callback to its target
 func (a *AssuredEndpoints) sendCallback(target string, call *Call) {
 	var delay int64
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,13 +14,13 @@ import (
 func TestNewAssuredEndpoints(t *testing.T) {
 	expected := &AssuredEndpoints{
 		httpClient:     http.DefaultClient,
-		assuredCalls:   NewCallStore(),
+		assuredCalls:   NewStubStore(),
 		madeCalls:      NewCallStore(),
 		trackMadeCalls: true,
 	}
 	actual := NewAssuredEndpoints(DefaultOptions)
 
-	require.Equal(t, expected.assuredCalls, actual.assuredCalls)
+	require.Equal(t, expected.assuredCalls.data, actual.assuredCalls.data)
 	require.Equal(t, expected.madeCalls, actual.madeCalls)
 }
 
@@ -68,7 +69,7 @@ func TestGivenEndpointSuccess(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, testCall3(), c)
 
-	require.Equal(t, fullAssuredCalls, endpoints.assuredCalls)
+	require.Equal(t, fullAssuredCalls.data, endpoints.assuredCalls.data)
 }
 
 func TestGivenCallbackEndpointSuccess(t *testing.T) {
@@ -110,11 +111,52 @@ func TestGivenCallbackEndpointSuccess(t *testing.T) {
 	}
 	require.NoError(t, err)
 	require.Equal(t, testCallback(), c)
-	require.Equal(t, expectedAssured, endpoints.assuredCalls)
+	require.Equal(t, expectedAssured.data, endpoints.assuredCalls.data)
 	require.Equal(t, expectedCallback, endpoints.callbackCalls)
 
 }
 
+func TestGivenJSONEndpoint(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	call := &Call{
+		Path:      "/json/assured/",
+		Delay:     2,
+		Headers:   map[string]string{"x-assured": "json"},
+		Callbacks: []Callback{{Method: http.MethodPost, Target: "http://faketarget.com/", Delay: 1, Response: []byte("called")}},
+	}
+
+	c, err := endpoints.GivenJSONEndpoint(context.TODO(), call)
+
+	require.NoError(t, err)
+	stub := c.(*Call)
+	key := stub.Headers[AssuredCallbackKey]
+	require.NotEmpty(t, key)
+	require.Equal(t, &Call{
+		Path:       "json/assured",
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Delay:      2,
+		Headers:    map[string]string{"X-Assured": "json", AssuredDelay: "2", AssuredCallbackKey: key},
+	}, stub)
+	require.Equal(t, []*Call{stub}, endpoints.assuredCalls.Get("GET:json/assured"))
+	require.Equal(t, []*Call{{
+		Method:     http.MethodPost,
+		StatusCode: http.StatusCreated,
+		Response:   []byte("called"),
+		Headers:    map[string]string{AssuredCallbackTarget: "http://faketarget.com/", AssuredCallbackKey: key, AssuredCallbackDelay: "1"},
+	}}, endpoints.callbackCalls.Get(key))
+}
+
+func TestGivenJSONEndpointCallbackMissingTarget(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+
+	c, err := endpoints.GivenJSONEndpoint(context.TODO(), &Call{Path: "json/assured", Callbacks: []Callback{{Method: http.MethodPost}}})
+
+	require.Nil(t, c)
+	require.EqualError(t, err, "cannot stub callback without target")
+	require.Empty(t, endpoints.assuredCalls.data)
+}
+
 func TestWhenEndpointSuccess(t *testing.T) {
 	endpoints := &AssuredEndpoints{
 		assuredCalls:   fullAssuredCalls,
@@ -122,16 +164,12 @@ func TestWhenEndpointSuccess(t *testing.T) {
 		callbackCalls:  NewCallStore(),
 		trackMadeCalls: true,
 	}
-	expected := map[string][]*Call{
-		"GET:test/assured":    {testCall2(), testCall1()},
-		"POST:teapot/assured": {testCall3()},
-	}
 
 	c, err := endpoints.WhenEndpoint(context.TODO(), testCall1())
 
 	require.NoError(t, err)
 	require.Equal(t, testCall1(), c)
-	require.Equal(t, expected, endpoints.assuredCalls.data)
+	require.Equal(t, testCall2(), endpoints.assuredCalls.Next(endpoints.assuredCalls.Get("GET:test/assured")))
 
 	c, err = endpoints.WhenEndpoint(context.TODO(), testCall2())
 
@@ -144,7 +182,94 @@ func TestWhenEndpointSuccess(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, testCall3(), c)
 	require.Equal(t, fullAssuredCalls, endpoints.assuredCalls)
-	require.Equal(t, fullAssuredCalls, endpoints.madeCalls)
+	require.Equal(t, fullAssuredCalls.data, endpoints.madeCalls.data)
+}
+
+func TestWhenEndpointConcurrency(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	stub := testCall1()
+	stub.Concurrency = 1
+	endpoints.assuredCalls.Add(stub)
+
+	// Occupy the only slot, so the next request queues until it is released
+	limiter := endpoints.limiter(stub.ID(), 1)
+	limiter <- struct{}{}
+	done := make(chan struct{})
+	go func() {
+		_, err := endpoints.WhenEndpoint(context.TODO(), testCall1())
+		require.NoError(t, err)
+		close(done)
+	}()
+
+	require.Never(t, func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, 50*time.Millisecond, 10*time.Millisecond)
+	<-limiter
+	require.Eventually(t, func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+	require.Empty(t, limiter)
+}
+
+func TestWhenEndpointConcurrencyPathTemplate(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	stub := &Call{Path: "users/{id}", Method: http.MethodGet, StatusCode: http.StatusOK, Concurrency: 1}
+	_, err := endpoints.GivenEndpoint(context.TODO(), stub)
+	require.NoError(t, err)
+
+	// Occupy the stubbed call's only slot, so a call made to any path it matches queues until it is released
+	limiter := endpoints.limiter(stub.ID(), 1)
+	limiter <- struct{}{}
+	done := make(chan struct{})
+	go func() {
+		_, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "users/2", Method: http.MethodGet})
+		require.NoError(t, err)
+		close(done)
+	}()
+
+	require.Never(t, func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, 50*time.Millisecond, 10*time.Millisecond)
+	<-limiter
+	require.Eventually(t, func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+	require.Empty(t, limiter)
+}
+
+func TestWhenEndpointConcurrencyCanceled(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	stub := testCall1()
+	stub.Concurrency = 1
+	endpoints.assuredCalls.Add(stub)
+	endpoints.limiter(stub.ID(), 1) <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	c, err := endpoints.WhenEndpoint(ctx, testCall1())
+
+	require.Nil(t, c)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
 }
 
 func TestWhenEndpointSuccessTrackingDisabled(t *testing.T) {
@@ -154,16 +279,12 @@ func TestWhenEndpointSuccessTrackingDisabled(t *testing.T) {
 		callbackCalls:  NewCallStore(),
 		trackMadeCalls: false,
 	}
-	expected := map[string][]*Call{
-		"GET:test/assured":    {testCall2(), testCall1()},
-		"POST:teapot/assured": {testCall3()},
-	}
 
 	c, err := endpoints.WhenEndpoint(context.TODO(), testCall1())
 
 	require.NoError(t, err)
 	require.Equal(t, testCall1(), c)
-	require.Equal(t, expected, endpoints.assuredCalls.data)
+	require.Equal(t, testCall2(), endpoints.assuredCalls.Next(endpoints.assuredCalls.Get("GET:test/assured")))
 
 	c, err = endpoints.WhenEndpoint(context.TODO(), testCall2())
 
@@ -179,6 +300,137 @@ func TestWhenEndpointSuccessTrackingDisabled(t *testing.T) {
 	require.Equal(t, NewCallStore(), endpoints.madeCalls)
 }
 
+func TestWhenEndpointSuccessTrace(t *testing.T) {
+	endpoints := &AssuredEndpoints{
+		assuredCalls: &CallStore{
+			data: map[string][]*Call{"GET:test/assured": {testCall1(), testCall2()}},
+		},
+		madeCalls:      NewCallStore(),
+		callbackCalls:  NewCallStore(),
+		trackMadeCalls: true,
+	}
+	call := testCall1()
+	call.Headers[AssuredTrace] = "true"
+
+	c, err := endpoints.WhenEndpoint(context.TODO(), call)
+
+	require.NoError(t, err)
+	expected := testCall1()
+	expected.Headers[AssuredTraceMatch] = "GET:test/assured[0]"
+	expected.Headers[AssuredTraceCandidates] = "GET:test/assured[0]=matched, GET:test/assured[1]=queued"
+	require.Equal(t, expected, c)
+	require.Equal(t, testCall2(), endpoints.assuredCalls.Next(endpoints.assuredCalls.Get("GET:test/assured")))
+}
+
+func TestWhenEndpointSuccessQuery(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	foo := &Call{Path: "search", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("foo"), Query: map[string]string{"term": "foo"}}
+	bar := &Call{Path: "search", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("bar"), Query: map[string]string{"term": "bar"}}
+	_, _ = endpoints.GivenEndpoint(context.TODO(), foo)
+	_, _ = endpoints.GivenEndpoint(context.TODO(), bar)
+
+	for _, term := range []string{"bar", "foo", "bar", "bar"} {
+		c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "search", Method: http.MethodGet, Query: map[string]string{"term": term, "page": "1"}})
+
+		require.NoError(t, err)
+		require.Equal(t, term, string(c.(*Call).Response))
+	}
+
+	_, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "search", Method: http.MethodGet, Query: map[string]string{"term": "baz"}})
+
+	require.EqualError(t, err, "No assured calls")
+}
+
+func TestWhenEndpointSuccessQueryFallback(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	fallback := &Call{Path: "search", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("fallback")}
+	foo := &Call{Path: "search", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("foo"), Query: map[string]string{"term": "foo"}}
+	_, _ = endpoints.GivenEndpoint(context.TODO(), fallback)
+	_, _ = endpoints.GivenEndpoint(context.TODO(), foo)
+
+	for _, term := range []string{"foo", "foo", "baz"} {
+		c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "search", Method: http.MethodGet, Query: map[string]string{"term": term}})
+
+		require.NoError(t, err)
+		expected := "foo"
+		if term != "foo" {
+			expected = "fallback"
+		}
+		require.Equal(t, expected, string(c.(*Call).Response))
+	}
+
+	c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "search", Method: http.MethodGet, Query: map[string]string{"term": "baz"}, Headers: map[string]string{AssuredTrace: "true"}})
+
+	require.NoError(t, err)
+	require.Equal(t, "GET:search[0]=matched, GET:search[1]=query mismatch", c.(*Call).Headers[AssuredTraceCandidates])
+}
+
+func TestWhenEndpointSuccessStatusSequence(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	assured := testCall1()
+	assured.StatusCodes = []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusOK}
+	_, _ = endpoints.GivenEndpoint(context.TODO(), assured)
+
+	for _, expected := range []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusOK, http.StatusInternalServerError} {
+		c, err := endpoints.WhenEndpoint(context.TODO(), testCall1())
+
+		require.NoError(t, err)
+		require.Equal(t, expected, c.(*Call).StatusCode)
+		require.Equal(t, assured.Response, c.(*Call).Response)
+	}
+}
+
+func TestWhenEndpointStatusSequenceConcurrent(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	assured := testCall1()
+	assured.StatusCodes = []int{http.StatusInternalServerError, http.StatusOK}
+	_, _ = endpoints.GivenEndpoint(context.TODO(), assured)
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 8*50)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				c, err := endpoints.WhenEndpoint(context.TODO(), testCall1())
+				if err == nil {
+					codes <- c.(*Call).StatusCode
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	served := map[int]int{}
+	for code := range codes {
+		served[code]++
+	}
+	require.Equal(t, map[int]int{http.StatusInternalServerError: 200, http.StatusOK: 200}, served)
+	require.Equal(t, []int{http.StatusInternalServerError, http.StatusOK}, assured.StatusCodes)
+}
+
+func TestWhenEndpointStatusSequenceRollback(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	endpoints.history.limit = 1
+	assured := testCall1()
+	assured.StatusCodes = []int{http.StatusInternalServerError, http.StatusOK}
+	_, _ = endpoints.GivenEndpoint(context.TODO(), assured)
+	c, err := endpoints.WhenEndpoint(context.TODO(), testCall1())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, c.(*Call).StatusCode)
+
+	endpoints.revise("")
+	_, _ = endpoints.GivenEndpoint(context.TODO(), testCall3())
+	_, err = endpoints.Rollback()
+	require.NoError(t, err)
+
+	c, err = endpoints.WhenEndpoint(context.TODO(), testCall1())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, c.(*Call).StatusCode)
+}
+
 func TestWhenEndpointSuccessCallbacks(t *testing.T) {
 	called := false
 	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -244,6 +496,26 @@ func TestWhenEndpointSuccessDelayed(t *testing.T) {
 	require.True(t, called, "callback was not hit")
 }
 
+func TestWhenEndpointLatency(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	endpoints.latency = 100 * time.Millisecond
+	_, _ = endpoints.GivenEndpoint(context.TODO(), testCall1())
+
+	start := time.Now()
+	c, err := endpoints.WhenEndpoint(context.TODO(), testCall1())
+
+	require.True(t, time.Since(start) >= 100*time.Millisecond, "matched response should be delayed")
+	require.NoError(t, err)
+	require.Equal(t, testCall1(), c)
+
+	start = time.Now()
+	c, err = endpoints.WhenEndpoint(context.TODO(), testCall3())
+
+	require.True(t, time.Since(start) >= 100*time.Millisecond, "unmatched response should be delayed")
+	require.Error(t, err)
+	require.Nil(t, c)
+}
+
 func TestSendCallbackBadRequest(t *testing.T) {
 	called := false
 	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -304,6 +576,104 @@ func TestVerifyEndpointTrackingDisabled(t *testing.T) {
 	require.Equal(t, "Tracking made calls is disabled", err.Error())
 }
 
+func TestDuplicatesEndpointSuccess(t *testing.T) {
+	endpoints := &AssuredEndpoints{
+		madeCalls:      NewCallStore(),
+		trackMadeCalls: true,
+	}
+	now := time.Now()
+	first, retry, late, other := testCall1(), testCall1(), testCall1(), testCall2()
+	endpoints.madeCalls.Record(first, now)
+	endpoints.madeCalls.Record(other, now)
+	endpoints.madeCalls.Record(retry, now.Add(time.Second))
+	endpoints.madeCalls.Record(late, now.Add(time.Minute))
+
+	c, err := endpoints.DuplicatesEndpoint(context.TODO(), testCall1())
+
+	require.NoError(t, err)
+	require.Equal(t, [][]*Call{{first, retry, late}}, c)
+
+	windowed := testCall1()
+	windowed.Query = map[string]string{"window": "5s"}
+	c, err = endpoints.DuplicatesEndpoint(context.TODO(), windowed)
+
+	require.NoError(t, err)
+	require.Equal(t, [][]*Call{{first, retry}}, c)
+
+	c, err = endpoints.DuplicatesEndpoint(context.TODO(), testCall3())
+
+	require.NoError(t, err)
+	require.Equal(t, [][]*Call{}, c)
+}
+
+func TestDuplicatesEndpointInvalidWindow(t *testing.T) {
+	endpoints := &AssuredEndpoints{
+		madeCalls:      NewCallStore(),
+		trackMadeCalls: true,
+	}
+	call := testCall1()
+	call.Query = map[string]string{"window": "soon"}
+
+	c, err := endpoints.DuplicatesEndpoint(context.TODO(), call)
+
+	require.Nil(t, c)
+	require.Error(t, err)
+	require.Equal(t, `invalid window: time: invalid duration "soon"`, err.Error())
+}
+
+func TestDuplicatesEndpointTrackingDisabled(t *testing.T) {
+	endpoints := &AssuredEndpoints{
+		madeCalls:      NewCallStore(),
+		trackMadeCalls: false,
+	}
+
+	c, err := endpoints.DuplicatesEndpoint(context.TODO(), testCall1())
+
+	require.Nil(t, c)
+	require.Error(t, err)
+	require.Equal(t, "Tracking made calls is disabled", err.Error())
+}
+
+func TestStats(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	endpoints.assuredCalls.Add(testCall1())
+	endpoints.assuredCalls.Add(testCall2())
+	endpoints.madeCalls.Add(&Call{Path: "test/assured", Method: "GET", Response: []byte("assured")})
+	endpoints.callbacks.Add(2)
+
+	stats := endpoints.Stats()
+
+	require.Equal(t, 2, stats.Stubs)
+	require.Equal(t, 1, stats.JournalEntries)
+	require.Equal(t, 22, stats.JournalBytes)
+	require.Equal(t, 2, stats.PendingCallbacks)
+	require.Greater(t, stats.Uptime, 0.0)
+}
+
+func TestCompactPurgesExpiredCalls(t *testing.T) {
+	endpoints := &AssuredEndpoints{
+		madeCalls:  NewCallStore(),
+		journalTTL: time.Minute,
+	}
+	expired, recent := testCall1(), testCall3()
+	endpoints.madeCalls.Record(expired, time.Now().Add(-time.Hour))
+	endpoints.madeCalls.Record(recent, time.Now())
+
+	require.Equal(t, 1, endpoints.Compact())
+	require.Equal(t, map[string][]*Call{"POST:teapot/assured": {recent}}, endpoints.madeCalls.data)
+	require.Equal(t, 0, endpoints.Compact())
+}
+
+func TestCompactWithoutJournalTTL(t *testing.T) {
+	endpoints := &AssuredEndpoints{
+		madeCalls: NewCallStore(),
+	}
+	endpoints.madeCalls.Record(testCall1(), time.Now().Add(-time.Hour))
+
+	require.Equal(t, 0, endpoints.Compact())
+	require.Len(t, endpoints.madeCalls.data["GET:test/assured"], 1)
+}
+
 func TestClearEndpointSuccess(t *testing.T) {
 	endpoints := &AssuredEndpoints{
 		assuredCalls:   fullAssuredCalls,
@@ -0,0 +1,94 @@
+package assured
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+)
+
+// ErrorFormat is the representation of the rest assured server's error responses, such as to calls made without a stubbed call,
+// to match the errors of the upstream being mocked, so clients parse the errors as they would the upstream's
+type ErrorFormat string
+
+const (
+	// ErrorFormatText responds with the error as plain text, e.g. No assured calls
+	ErrorFormatText ErrorFormat = "text"
+	// ErrorFormatJSON responds with the error as a JSON object, e.g. {"error":"No assured calls","status":500}
+	ErrorFormatJSON ErrorFormat = "json"
+	// ErrorFormatProblem responds with the error as an RFC 9457 problem details JSON object, with the application/problem+json content type
+	ErrorFormatProblem ErrorFormat = "problem"
+	// ErrorFormatXML responds with the error as an XML document, e.g. <error><status>500</status><message>No assured calls</message></error>
+	ErrorFormatXML ErrorFormat = "xml"
+)
+
+// valid reports whether the error format is one of the error formats, or the default
+func (f ErrorFormat) valid() bool {
+	switch f {
+	case "", ErrorFormatText, ErrorFormatJSON, ErrorFormatProblem, ErrorFormatXML:
+		return true
+	}
+	return false
+}
+
+// plain reports whether the error format is the default plain text
+func (f ErrorFormat) plain() bool {
+	return f == "" || f == ErrorFormatText
+}
+
+// problem is an RFC 9457 problem details object
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// xmlError is the XML document of an error
+type xmlError struct {
+	XMLName xml.Name `xml:"error"`
+	Status  int      `xml:"status"`
+	Message string   `xml:"message"`
+}
+
+// write writes the error response with the status code in the error format
+func (f ErrorFormat) write(w http.ResponseWriter, statusCode int, message string) {
+	header := w.Header()
+	header.Del("Content-Length")
+	switch f {
+	case ErrorFormatJSON:
+		header.Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": message, "status": statusCode})
+	case ErrorFormatProblem:
+		header.Set("Content-Type", "application/problem+json")
+		w.WriteHeader(statusCode)
+		_ = json.NewEncoder(w).Encode(problem{Type: "about:blank", Title: http.StatusText(statusCode), Status: statusCode, Detail: message})
+	case ErrorFormatXML:
+		header.Set("Content-Type", "application/xml")
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(xml.Header))
+		_ = xml.NewEncoder(w).Encode(xmlError{Status: statusCode, Message: message})
+	default:
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(message))
+	}
+}
+
+// encodeError writes the error to the http response as a server error, in the error format
+// A call made that no stubbed call matched responds with the evaluation of its candidates, if traced
+func (a *AssuredEndpoints) encodeError(w http.ResponseWriter, err error) {
+	var unmatched *unmatchedError
+	if errors.As(err, &unmatched) && unmatched.trace != "" {
+		w.Header().Set(AssuredTraceCandidates, unmatched.trace)
+	}
+	a.errorFormat.write(w, http.StatusInternalServerError, err.Error())
+}
+
+// notFoundHandler responds 404 Not Found to the requests made to paths the rest assured server doesn't serve, in the error format
+func (a *AssuredEndpoints) notFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		a.errorFormat.write(w, http.StatusNotFound, "404 page not found")
+	})
+}
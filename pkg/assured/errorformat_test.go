@@ -0,0 +1,73 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorFormat(t *testing.T) {
+	for _, tc := range []struct {
+		format      ErrorFormat
+		contentType string
+		noMatch     string
+		notFound    string
+	}{
+		{
+			format:      ErrorFormatText,
+			contentType: "text/plain; charset=utf-8",
+			noMatch:     "No assured calls",
+		},
+		{
+			format:      ErrorFormatJSON,
+			contentType: "application/json",
+			noMatch:     `{"error":"No assured calls","status":500}` + "\n",
+			notFound:    `{"error":"404 page not found","status":404}` + "\n",
+		},
+		{
+			format:      ErrorFormatProblem,
+			contentType: "application/problem+json",
+			noMatch:     `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"No assured calls"}` + "\n",
+			notFound:    `{"type":"about:blank","title":"Not Found","status":404,"detail":"404 page not found"}` + "\n",
+		},
+		{
+			format:      ErrorFormatXML,
+			contentType: "application/xml",
+			noMatch:     `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<error><status>500</status><message>No assured calls</message></error>`,
+			notFound:    `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<error><status>404</status><message>404 page not found</message></error>`,
+		},
+	} {
+		for _, plain := range []bool{false, true} {
+			_, client := NewTestServer(t, WithErrorFormat(tc.format), WithPlainHandlers(plain))
+
+			resp, err := http.Get(client.URL() + "/not/stubbed")
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			require.Equal(t, http.StatusInternalServerError, resp.StatusCode, tc.format)
+			require.Equal(t, tc.contentType, resp.Header.Get("Content-Type"), tc.format)
+			require.Equal(t, tc.noMatch, string(body), tc.format)
+
+			if tc.notFound == "" {
+				continue
+			}
+			resp, err = http.Get(client.URL()[:len(client.URL())-len("/when")] + "/not/served")
+			require.NoError(t, err)
+			body, err = io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			require.Equal(t, http.StatusNotFound, resp.StatusCode, tc.format)
+			require.Equal(t, tc.contentType, resp.Header.Get("Content-Type"), tc.format)
+			require.Equal(t, tc.notFound, string(body), tc.format)
+		}
+	}
+}
+
+func TestErrorFormatInvalid(t *testing.T) {
+	_, err := NewClientE(WithErrorFormat("yaml"))
+
+	require.EqualError(t, err, `invalid error format "yaml"`)
+}
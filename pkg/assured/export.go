@@ -0,0 +1,168 @@
+package assured
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// The media types of CSV and NDJSON responses
+const (
+	CSVMediaType    = "text/csv; charset=utf-8"
+	NDJSONMediaType = "application/x-ndjson"
+)
+
+// CSVCall returns the stubbed call responding with the rows as CSV, see CSV
+// A streamed call responds with chunked transfer encoding, like an export that is written as it is generated
+func CSVCall(call Call, rows any, stream bool) Call {
+	response, _ := CSV(rows)
+	return exportCall(call, CSVMediaType, response, stream)
+}
+
+// NDJSONCall returns the stubbed call responding with the rows as newline delimited JSON, see NDJSON
+// A streamed call responds with chunked transfer encoding, like an export that is written as it is generated
+func NDJSONCall(call Call, rows any, stream bool) Call {
+	response, _ := NDJSON(rows)
+	return exportCall(call, NDJSONMediaType, response, stream)
+}
+
+// CSV serializes a slice of structs or maps into CSV, with a header row of their JSON field names in the order they are encoded
+// A field missing from a row, such as an omitted empty field, is an empty value, and a nested object or array is its JSON
+// An empty slice of structs has only the header row
+// A [][]string is serialized as is, with its first row as the header row
+func CSV(rows any) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if records, ok := rows.([][]string); ok {
+		if err := w.WriteAll(records); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	objects, err := exportObjects(rows)
+	if err != nil {
+		return nil, err
+	}
+	header := objects
+	if len(objects) == 0 {
+		// An empty export of structs still has the header row of the struct's fields
+		if elem := reflect.TypeOf(rows).Elem(); elem.Kind() == reflect.Struct {
+			header, _ = exportObjects([]any{reflect.Zero(elem).Interface()})
+		}
+	}
+	var columns []string
+	seen := map[string]bool{}
+	for _, object := range header {
+		for _, field := range object {
+			if !seen[field.name] {
+				seen[field.name] = true
+				columns = append(columns, field.name)
+			}
+		}
+	}
+	if len(columns) == 0 {
+		return buf.Bytes(), nil
+	}
+	if err := w.Write(columns); err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		values := map[string]string{}
+		for _, field := range object {
+			values[field.name] = field.value
+		}
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = values[column]
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// NDJSON serializes a slice into newline delimited JSON, with each element encoded as JSON on its own line
+func NDJSON(rows any) ([]byte, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("ndjson rows must be a slice, not %T", rows)
+	}
+	var buf bytes.Buffer
+	for i := 0; i < v.Len(); i++ {
+		line, err := json.Marshal(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// exportField is a field of a row, its JSON name and its value in a CSV record
+type exportField struct {
+	name  string
+	value string
+}
+
+// exportObjects returns the fields of each row of a slice of structs or maps, in the order they are encoded as JSON
+func exportObjects(rows any) ([][]exportField, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("csv rows must be a slice, not %T", rows)
+	}
+	objects := make([][]exportField, v.Len())
+	for i := range objects {
+		b, err := json.Marshal(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.UseNumber()
+		if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+			return nil, fmt.Errorf("csv row %d must be a struct or map", i)
+		}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			var value json.RawMessage
+			if err := decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+			objects[i] = append(objects[i], exportField{name: key.(string), value: exportValue(value)})
+		}
+	}
+	return objects, nil
+}
+
+// exportValue returns the CSV value of a JSON value, a string unquoted, null empty, and any other value as its JSON
+func exportValue(value json.RawMessage) string {
+	var s string
+	switch {
+	case string(value) == "null":
+		return ""
+	case json.Unmarshal(value, &s) == nil:
+		return s
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, value); err != nil {
+		return string(value)
+	}
+	return compact.String()
+}
+
+// exportCall returns the stubbed call responding with the export and its media type, with chunked transfer encoding if streamed
+func exportCall(call Call, mediaType string, response []byte, stream bool) Call {
+	call = mediaTypeCall(call, mediaType, response)
+	if stream {
+		call.Framing = FramingChunked
+	}
+	return call
+}
@@ -0,0 +1,107 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testExportRow struct {
+	ID     int               `json:"id"`
+	Name   string            `json:"name"`
+	Note   string            `json:"note,omitempty"`
+	Tags   []string          `json:"tags,omitempty"`
+	Labels map[string]string `json:"-"`
+}
+
+func TestCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		rows any
+		want string
+	}{
+		{
+			name: "structs",
+			rows: []testExportRow{{ID: 1, Name: "Ada, Countess", Tags: []string{"math"}}, {ID: 2, Name: `"Grace"`, Note: "navy"}},
+			want: "id,name,tags,note\n1,\"Ada, Countess\",\"[\"\"math\"\"]\",\n2,\"\"\"Grace\"\"\",,navy\n",
+		},
+		{
+			name: "maps",
+			rows: []map[string]any{{"b": true, "a": nil}, {"c": 1.5}},
+			want: "a,b,c\n,true,\n,,1.5\n",
+		},
+		{
+			name: "records",
+			rows: [][]string{{"id", "name"}, {"1", "Ada"}},
+			want: "id,name\n1,Ada\n",
+		},
+		{
+			name: "empty",
+			rows: []testExportRow{},
+			want: "id,name\n",
+		},
+		{
+			name: "empty maps",
+			rows: []map[string]any{},
+			want: "",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			csv, err := CSV(tc.rows)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, string(csv))
+		})
+	}
+}
+
+func TestCSVFailure(t *testing.T) {
+	_, err := CSV(testExportRow{})
+	require.ErrorContains(t, err, "csv rows must be a slice, not assured.testExportRow")
+
+	_, err = CSV([]int{1})
+	require.ErrorContains(t, err, "csv row 0 must be a struct or map")
+}
+
+func TestNDJSON(t *testing.T) {
+	ndjson, err := NDJSON([]any{testExportRow{ID: 1, Name: "Ada"}, map[string]int{"id": 2}, "three"})
+
+	require.NoError(t, err)
+	require.Equal(t, "{\"id\":1,\"name\":\"Ada\"}\n{\"id\":2}\n\"three\"\n", string(ndjson))
+
+	_, err = NDJSON(map[string]int{})
+	require.ErrorContains(t, err, "ndjson rows must be a slice")
+}
+
+func TestExportCalls(t *testing.T) {
+	rows := []testExportRow{{ID: 1, Name: "Ada"}}
+
+	csv := CSVCall(Call{Path: "export.csv", Headers: map[string]string{"Content-Length": "1"}}, rows, false)
+	require.Equal(t, map[string]string{"Content-Type": CSVMediaType}, csv.Headers)
+	require.Equal(t, "id,name\n1,Ada\n", string(csv.Response))
+	require.Empty(t, csv.Framing)
+
+	ndjson := NDJSONCall(Call{Path: "export.ndjson"}, rows, true)
+	require.Equal(t, map[string]string{"Content-Type": NDJSONMediaType}, ndjson.Headers)
+	require.Equal(t, "{\"id\":1,\"name\":\"Ada\"}\n", string(ndjson.Response))
+	require.Equal(t, FramingChunked, ndjson.Framing)
+}
+
+func TestClientCSVCallStreamed(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(CSVCall(Call{Path: "reports/export", Method: http.MethodGet, StatusCode: http.StatusOK},
+		[]testExportRow{{ID: 1, Name: "Ada"}, {ID: 2, Name: "Grace"}}, true)))
+
+	resp, err := http.Get(client.URL() + "/reports/export")
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, CSVMediaType, resp.Header.Get("Content-Type"))
+	require.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "id,name\n1,Ada\n2,Grace\n", string(body))
+}
@@ -0,0 +1,25 @@
+package assured
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// FlakyCall returns the calls to stub for a call that fails with 503 Service Unavailable and a Retry-After header
+// the number of failures times, then succeeds with the call. Stubbed calls rotate, so the sequence repeats after the call succeeds
+// The failures are copies of the call, so they match the same requests as the call, by its path, path regex, query, and headers
+func FlakyCall(call Call, failures int, retryAfter time.Duration) []Call {
+	calls := make([]Call, 0, failures+1)
+	for i := 0; i < failures; i++ {
+		failure := call
+		failure.StatusCode = http.StatusServiceUnavailable
+		failure.Headers = map[string]string{"Retry-After": strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))}
+		failure.Response = nil
+		// The failure responds with nothing but the 503, without the call's status sequence, branches, or callbacks
+		failure.StatusCodes, failure.Branches, failure.Callbacks = nil, nil, nil
+		calls = append(calls, failure)
+	}
+	return append(calls, call)
+}
@@ -0,0 +1,65 @@
+package assured
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlakyCall(t *testing.T) {
+	call := Call{Path: "test/assured", Method: http.MethodPost, StatusCode: http.StatusCreated, Response: []byte(`{"assured": true}`)}
+
+	calls := FlakyCall(call, 2, 1500*time.Millisecond)
+
+	failure := Call{Path: "test/assured", Method: http.MethodPost, StatusCode: http.StatusServiceUnavailable, Headers: map[string]string{"Retry-After": "2"}}
+	require.Equal(t, []Call{failure, failure, call}, calls)
+}
+
+func TestFlakyCallNoFailures(t *testing.T) {
+	call := Call{Path: "test/assured"}
+
+	require.Equal(t, []Call{call}, FlakyCall(call, 0, time.Second))
+}
+
+func TestClientFlakyCall(t *testing.T) {
+	_, client := NewTestServer(t)
+
+	require.NoError(t, client.Given(FlakyCall(Call{Path: "flaky/assured"}, 2, time.Second)...))
+
+	for _, expected := range []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK} {
+		resp, err := http.Get(client.URL() + "/flaky/assured")
+		require.NoError(t, err)
+		require.Equal(t, expected, resp.StatusCode)
+		if expected == http.StatusServiceUnavailable {
+			require.Equal(t, "1", resp.Header.Get("Retry-After"))
+		}
+	}
+}
+
+func TestClientFlakyCallConditions(t *testing.T) {
+	for name, tc := range map[string]struct {
+		call Call
+		path string
+	}{
+		"query":      {call: Call{Path: "search", Query: map[string]string{"term": "foo"}, Response: []byte("found")}, path: "/search?term=foo"},
+		"path regex": {call: Call{PathRegex: "users/[0-9]+", Response: []byte("found")}, path: "/users/1"},
+		"headers":    {call: Call{Path: "tenants", RequiredHeaders: map[string]HeaderMatcher{"X-Tenant": {Equals: "acme"}}, Response: []byte("found")}, path: "/tenants"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, client := NewTestServer(t)
+			require.NoError(t, client.Given(FlakyCall(tc.call, 2, time.Second)...))
+
+			for _, expected := range []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK} {
+				req, err := http.NewRequest(http.MethodGet, client.URL()+tc.path, nil)
+				require.NoError(t, err)
+				req.Header.Set("X-Tenant", "acme")
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				_ = resp.Body.Close()
+				require.Equal(t, expected, resp.StatusCode)
+			}
+		})
+	}
+}
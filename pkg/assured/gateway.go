@@ -0,0 +1,449 @@
+package assured
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GatewayRoute is a REST route of a gRPC service method, transcoded by grpc-gateway from its google.api.http annotation
+// The path is the annotation's path template, e.g. /v1/{name=shelves/*/books/*}
+type GatewayRoute struct {
+	Service      string `json:"service"`
+	RPC          string `json:"rpc"`
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	Body         string `json:"body,omitempty"`
+	ResponseBody string `json:"response_body,omitempty"`
+}
+
+// GRPCCode is a gRPC status code, returned in grpc-gateway's error envelope
+type GRPCCode int
+
+// The gRPC status codes
+const (
+	GRPCOK GRPCCode = iota
+	GRPCCanceled
+	GRPCUnknown
+	GRPCInvalidArgument
+	GRPCDeadlineExceeded
+	GRPCNotFound
+	GRPCAlreadyExists
+	GRPCPermissionDenied
+	GRPCResourceExhausted
+	GRPCFailedPrecondition
+	GRPCAborted
+	GRPCOutOfRange
+	GRPCUnimplemented
+	GRPCInternal
+	GRPCUnavailable
+	GRPCDataLoss
+	GRPCUnauthenticated
+)
+
+// HTTPStatus returns the HTTP status code grpc-gateway responds with for the gRPC status code
+func (c GRPCCode) HTTPStatus() int {
+	switch c {
+	case GRPCOK:
+		return http.StatusOK
+	case GRPCCanceled:
+		return 499
+	case GRPCInvalidArgument, GRPCFailedPrecondition, GRPCOutOfRange:
+		return http.StatusBadRequest
+	case GRPCDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case GRPCNotFound:
+		return http.StatusNotFound
+	case GRPCAlreadyExists, GRPCAborted:
+		return http.StatusConflict
+	case GRPCPermissionDenied:
+		return http.StatusForbidden
+	case GRPCUnauthenticated:
+		return http.StatusUnauthorized
+	case GRPCResourceExhausted:
+		return http.StatusTooManyRequests
+	case GRPCUnimplemented:
+		return http.StatusNotImplemented
+	case GRPCUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GatewayStatus is grpc-gateway's error envelope, the JSON encoding of a google.rpc.Status
+// The legacy error envelope of grpc-gateway v1 also repeats the message as the error
+type GatewayStatus struct {
+	Error   string   `json:"error,omitempty"`
+	Code    GRPCCode `json:"code"`
+	Message string   `json:"message"`
+	Details []any    `json:"details"`
+}
+
+// Call returns the call to stub for the route, with its path template expanded with the path parameters,
+// responding 200 OK with the JSON encoded response
+// e.g. route.Call(map[string]string{"name": "shelves/1/books/2"}, book)
+func (r GatewayRoute) Call(params map[string]string, response any) (Call, error) {
+	return r.call(params, http.StatusOK, response)
+}
+
+// Error returns the call to stub for the route, with its path template expanded with the path parameters,
+// responding with grpc-gateway's error envelope for the gRPC status code and message. Details are google.protobuf.Any JSON objects, with an @type
+func (r GatewayRoute) Error(params map[string]string, code GRPCCode, message string, details ...any) (Call, error) {
+	if details == nil {
+		details = []any{}
+	}
+	return r.call(params, code.HTTPStatus(), GatewayStatus{Code: code, Message: message, Details: details})
+}
+
+// LegacyError returns the call to stub for the route like Error, responding with grpc-gateway v1's legacy error envelope
+func (r GatewayRoute) LegacyError(params map[string]string, code GRPCCode, message string, details ...any) (Call, error) {
+	if details == nil {
+		details = []any{}
+	}
+	return r.call(params, code.HTTPStatus(), GatewayStatus{Error: message, Code: code, Message: message, Details: details})
+}
+
+// call returns the call to stub for the route, responding with the status code and JSON encoded response
+func (r GatewayRoute) call(params map[string]string, statusCode int, response any) (Call, error) {
+	path, err := r.Expand(params)
+	if err != nil {
+		return Call{}, err
+	}
+	body, err := json.Marshal(response)
+	if err != nil {
+		return Call{}, err
+	}
+	return Call{
+		Path:       strings.Trim(path, "/"),
+		Method:     r.Method,
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Response:   body,
+	}, nil
+}
+
+// Expand returns the route's path with each variable of its path template replaced by its path parameter
+// A parameter for a variable with a multi segment pattern, e.g. {name=shelves/*}, includes the slashes, e.g. shelves/1
+func (r GatewayRoute) Expand(params map[string]string) (string, error) {
+	var path strings.Builder
+	template := r.Path
+	for {
+		start := strings.Index(template, "{")
+		if start < 0 {
+			path.WriteString(template)
+			return path.String(), nil
+		}
+		end := strings.Index(template[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("invalid path template %q", r.Path)
+		}
+		path.WriteString(template[:start])
+		variable, _, _ := strings.Cut(template[start+1:start+end], "=")
+		value, ok := params[variable]
+		if !ok {
+			return "", fmt.Errorf("missing path parameter %q for %s", variable, r.Path)
+		}
+		path.WriteString(value)
+		template = template[start+end+1:]
+	}
+}
+
+// ParseGatewayRoutes parses the REST routes of the services in a proto file from their methods' google.api.http annotations,
+// including their additional bindings. Methods without an annotation are skipped
+func ParseGatewayRoutes(proto io.Reader) ([]GatewayRoute, error) {
+	data, err := io.ReadAll(proto)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := tokenizeProto(string(data))
+	if err != nil {
+		return nil, err
+	}
+	p := &protoParser{tokens: tokens}
+	return p.parseFile()
+}
+
+// protoParser parses the services of a tokenized proto file, skipping its other definitions
+type protoParser struct {
+	tokens  []string
+	pos     int
+	pkg     string
+	routes  []GatewayRoute
+	service string
+	rpc     string
+}
+
+// next returns the next token, or an empty token at the end of the file
+func (p *protoParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+// peek returns the next token without consuming it
+func (p *protoParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+// expect consumes the next token, returning an error if it is not the expected token
+func (p *protoParser) expect(token string) error {
+	if next := p.next(); next != token {
+		return fmt.Errorf("invalid proto: expected %q, found %q", token, next)
+	}
+	return nil
+}
+
+// skipBlock consumes the tokens of a block until its closing brace, after its opening brace is consumed
+func (p *protoParser) skipBlock() error {
+	for depth := 1; depth > 0; {
+		switch p.next() {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		case "":
+			return fmt.Errorf("invalid proto: unclosed block")
+		}
+	}
+	return nil
+}
+
+// parseFile parses the routes of each service in the file, qualified by the file's package
+func (p *protoParser) parseFile() ([]GatewayRoute, error) {
+	for p.peek() != "" {
+		switch p.next() {
+		case "package":
+			p.pkg = p.next()
+		case "service":
+			if err := p.parseService(); err != nil {
+				return nil, err
+			}
+		case "{":
+			if err := p.skipBlock(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p.routes, nil
+}
+
+// parseService parses the routes of each method of a service, after the service keyword is consumed
+func (p *protoParser) parseService() error {
+	p.service = p.next()
+	if p.pkg != "" {
+		p.service = p.pkg + "." + p.service
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		switch p.next() {
+		case "}":
+			return nil
+		case "rpc":
+			if err := p.parseRPC(); err != nil {
+				return err
+			}
+		case "{":
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+		case "":
+			return fmt.Errorf("invalid proto: unclosed service %s", p.service)
+		}
+	}
+}
+
+// parseRPC parses the routes of a method from its options, after the rpc keyword is consumed
+func (p *protoParser) parseRPC() error {
+	p.rpc = p.next()
+	// Skip the request and response types, until the method's options or the end of the method
+	for token := p.next(); token != "{"; token = p.next() {
+		switch token {
+		case ";":
+			return nil
+		case "":
+			return fmt.Errorf("invalid proto: unclosed rpc %s", p.rpc)
+		}
+	}
+	for {
+		switch p.next() {
+		case "}":
+			return nil
+		case "option":
+			if err := p.parseOption(); err != nil {
+				return err
+			}
+		case "{":
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+		case "":
+			return fmt.Errorf("invalid proto: unclosed rpc %s", p.rpc)
+		}
+	}
+}
+
+// parseOption parses the routes of a google.api.http option, skipping other options, after the option keyword is consumed
+func (p *protoParser) parseOption() error {
+	var name strings.Builder
+	for token := p.next(); token != "="; token = p.next() {
+		if token == "" {
+			return fmt.Errorf("invalid proto: unterminated option in rpc %s", p.rpc)
+		}
+		name.WriteString(token)
+	}
+	// The annotation's pattern can be set as a field of the option, e.g. option (google.api.http).get = "/v1/books";
+	if method, ok := strings.CutPrefix(name.String(), "(google.api.http)."); ok {
+		switch method {
+		case "get", "put", "post", "delete", "patch":
+			p.routes = append(p.routes, GatewayRoute{Service: p.service, RPC: p.rpc, Method: strings.ToUpper(method), Path: unquoteProto(p.next())})
+		}
+		name.Reset()
+	}
+	if name.String() != "(google.api.http)" {
+		// Skip the value of other options
+		for token := p.next(); token != ";"; token = p.next() {
+			switch token {
+			case "{":
+				if err := p.skipBlock(); err != nil {
+					return err
+				}
+			case "":
+				return fmt.Errorf("invalid proto: unterminated option in rpc %s", p.rpc)
+			}
+		}
+		return nil
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	return p.parseHTTPRule()
+}
+
+// parseHTTPRule parses a google.api.HttpRule message literal, after its opening brace is consumed
+func (p *protoParser) parseHTTPRule() error {
+	route := GatewayRoute{Service: p.service, RPC: p.rpc}
+	// Reserve the route's place, so it comes before its additional bindings
+	index := len(p.routes)
+	p.routes = append(p.routes, route)
+	for {
+		field := p.next()
+		switch field {
+		case "}":
+			if route.Method == "" {
+				return fmt.Errorf("invalid proto: google.api.http annotation of rpc %s has no pattern", p.rpc)
+			}
+			p.routes[index] = route
+			return nil
+		case "", "{":
+			return fmt.Errorf("invalid proto: invalid google.api.http annotation of rpc %s", p.rpc)
+		case ",", ";":
+			continue
+		}
+		if p.peek() == ":" {
+			p.next()
+		}
+		switch field {
+		case "get", "put", "post", "delete", "patch":
+			route.Method = strings.ToUpper(field)
+			route.Path = unquoteProto(p.next())
+		case "body":
+			route.Body = unquoteProto(p.next())
+		case "response_body":
+			route.ResponseBody = unquoteProto(p.next())
+		case "custom":
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			for token := p.next(); token != "}"; token = p.next() {
+				if p.peek() == ":" {
+					p.next()
+				}
+				switch token {
+				case "kind":
+					route.Method = unquoteProto(p.next())
+				case "path":
+					route.Path = unquoteProto(p.next())
+				case "":
+					return fmt.Errorf("invalid proto: invalid google.api.http annotation of rpc %s", p.rpc)
+				}
+			}
+		case "additional_bindings":
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			if err := p.parseHTTPRule(); err != nil {
+				return err
+			}
+		default:
+			// Skip the value of unknown fields
+			p.next()
+		}
+	}
+}
+
+// tokenizeProto splits the proto file into identifiers, string literals, and symbols, without its comments
+func tokenizeProto(proto string) ([]string, error) {
+	var tokens []string
+	runes := []rune(proto)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i+1 < len(runes) && (runes[i] != '*' || runes[i+1] != '/'); i++ {
+			}
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("invalid proto: unterminated comment")
+			}
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("invalid proto: unterminated string")
+			}
+			tokens = append(tokens, string(runes[start:i+1]))
+		case r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			start := i
+			for i+1 < len(runes) && (runes[i+1] == '_' || runes[i+1] == '.' || unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1])) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i+1]))
+		default:
+			tokens = append(tokens, string(r))
+		}
+	}
+	return tokens, nil
+}
+
+// unquoteProto returns the value of a proto string literal
+func unquoteProto(literal string) string {
+	if strings.HasPrefix(literal, "'") {
+		literal = `"` + strings.ReplaceAll(strings.Trim(literal, "'"), `"`, `\"`) + `"`
+	}
+	value, err := strconv.Unquote(literal)
+	if err != nil {
+		return literal
+	}
+	return value
+}
@@ -0,0 +1,133 @@
+package assured
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGatewayRoutes(t *testing.T) {
+	f, err := os.Open("testdata/library.proto")
+	require.NoError(t, err)
+	defer f.Close()
+
+	routes, err := ParseGatewayRoutes(f)
+	require.NoError(t, err)
+	require.Equal(t, []GatewayRoute{
+		{Service: "library.v1.LibraryService", RPC: "GetBook", Method: http.MethodGet, Path: "/v1/{name=shelves/*/books/*}"},
+		{Service: "library.v1.LibraryService", RPC: "GetBook", Method: http.MethodGet, Path: "/v1/books/{book_id}"},
+		{Service: "library.v1.LibraryService", RPC: "CreateBook", Method: http.MethodPost, Path: "/v1/{parent=shelves/*}/books", Body: "book"},
+		{Service: "library.v1.LibraryService", RPC: "DeleteBook", Method: http.MethodDelete, Path: "/v1/{name=shelves/*/books/*}"},
+		{Service: "library.v1.LibraryService", RPC: "WatchBooks", Method: http.MethodHead, Path: "/v1/books:watch", ResponseBody: "book"},
+	}, routes)
+}
+
+func TestParseGatewayRoutesInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		proto string
+		err   string
+	}{
+		{
+			name:  "unclosed service",
+			proto: `service Library { rpc GetBook(Req) returns (Book);`,
+			err:   "invalid proto: unclosed service Library",
+		},
+		{
+			name:  "annotation without pattern",
+			proto: `service Library { rpc GetBook(Req) returns (Book) { option (google.api.http) = { body: "*" }; } }`,
+			err:   "invalid proto: google.api.http annotation of rpc GetBook has no pattern",
+		},
+		{
+			name:  "unterminated comment",
+			proto: `/* service Library {}`,
+			err:   "invalid proto: unterminated comment",
+		},
+		{
+			name:  "unterminated string",
+			proto: `service Library { rpc GetBook(Req) returns (Book) { option (google.api.http) = { get: "/v1 }; } }`,
+			err:   "invalid proto: unterminated string",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseGatewayRoutes(strings.NewReader(tc.proto))
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestGatewayRouteExpand(t *testing.T) {
+	route := GatewayRoute{Method: http.MethodPost, Path: "/v1/{parent=shelves/*}/books/{book_id}:publish"}
+
+	path, err := route.Expand(map[string]string{"parent": "shelves/1", "book_id": "2"})
+	require.NoError(t, err)
+	require.Equal(t, "/v1/shelves/1/books/2:publish", path)
+
+	_, err = route.Expand(map[string]string{"parent": "shelves/1"})
+	require.EqualError(t, err, `missing path parameter "book_id" for /v1/{parent=shelves/*}/books/{book_id}:publish`)
+
+	_, err = GatewayRoute{Path: "/v1/{name"}.Expand(nil)
+	require.EqualError(t, err, `invalid path template "/v1/{name"`)
+}
+
+func TestGatewayRouteCalls(t *testing.T) {
+	route := GatewayRoute{Method: http.MethodGet, Path: "/v1/{name=shelves/*/books/*}"}
+	params := map[string]string{"name": "shelves/1/books/2"}
+
+	ok, err := route.Call(params, map[string]string{"name": "shelves/1/books/2", "title": "Dune"})
+	require.NoError(t, err)
+	require.Equal(t, Call{
+		Path:       "v1/shelves/1/books/2",
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Response:   []byte(`{"name":"shelves/1/books/2","title":"Dune"}`),
+	}, ok)
+
+	notFound, err := route.Error(params, GRPCNotFound, "book not found")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, notFound.StatusCode)
+	require.JSONEq(t, `{"code":5,"message":"book not found","details":[]}`, string(notFound.Response))
+
+	details := map[string]any{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "QUOTA"}
+	exhausted, err := route.LegacyError(params, GRPCResourceExhausted, "quota exceeded", details)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTooManyRequests, exhausted.StatusCode)
+	require.JSONEq(t, `{"error":"quota exceeded","code":8,"message":"quota exceeded","details":[{"@type":"type.googleapis.com/google.rpc.ErrorInfo","reason":"QUOTA"}]}`, string(exhausted.Response))
+
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(ok))
+	resp, err := http.Get(client.URL() + "/v1/shelves/1/books/2")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var book map[string]string
+	require.NoError(t, json.Unmarshal(body, &book))
+	require.Equal(t, "Dune", book["title"])
+}
+
+func TestGRPCCodeHTTPStatus(t *testing.T) {
+	require.Equal(t, http.StatusOK, GRPCOK.HTTPStatus())
+	require.Equal(t, 499, GRPCCanceled.HTTPStatus())
+	require.Equal(t, http.StatusInternalServerError, GRPCUnknown.HTTPStatus())
+	require.Equal(t, http.StatusBadRequest, GRPCInvalidArgument.HTTPStatus())
+	require.Equal(t, http.StatusGatewayTimeout, GRPCDeadlineExceeded.HTTPStatus())
+	require.Equal(t, http.StatusConflict, GRPCAlreadyExists.HTTPStatus())
+	require.Equal(t, http.StatusForbidden, GRPCPermissionDenied.HTTPStatus())
+	require.Equal(t, http.StatusBadRequest, GRPCFailedPrecondition.HTTPStatus())
+	require.Equal(t, http.StatusConflict, GRPCAborted.HTTPStatus())
+	require.Equal(t, http.StatusBadRequest, GRPCOutOfRange.HTTPStatus())
+	require.Equal(t, http.StatusNotImplemented, GRPCUnimplemented.HTTPStatus())
+	require.Equal(t, http.StatusInternalServerError, GRPCInternal.HTTPStatus())
+	require.Equal(t, http.StatusServiceUnavailable, GRPCUnavailable.HTTPStatus())
+	require.Equal(t, http.StatusInternalServerError, GRPCDataLoss.HTTPStatus())
+	require.Equal(t, http.StatusUnauthorized, GRPCUnauthenticated.HTTPStatus())
+}
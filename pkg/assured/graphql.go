@@ -0,0 +1,634 @@
+package assured
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GraphQLSchema is the types of a GraphQL schema, parsed from its SDL, for auto-mocking the queries and mutations made against it
+type GraphQLSchema struct {
+	query    string
+	mutation string
+	types    map[string]*graphQLType
+	order    []string
+}
+
+// graphQLKind is the kind of a named GraphQL type
+type graphQLKind int
+
+// The kinds of named GraphQL types
+const (
+	graphQLScalar graphQLKind = iota
+	graphQLObject
+	graphQLInterface
+	graphQLUnion
+	graphQLEnum
+	graphQLInput
+)
+
+// graphQLType is a named type of a GraphQL schema
+// The members of an interface are the object types implementing it, and the members of a union are its object types
+type graphQLType struct {
+	name       string
+	kind       graphQLKind
+	fields     map[string]graphQLTypeRef
+	interfaces []string
+	members    []string
+	values     []string
+}
+
+// graphQLTypeRef is the type of a field, a named type wrapped in a number of lists
+type graphQLTypeRef struct {
+	name  string
+	lists int
+}
+
+// GraphQLCall returns the stubbed POST call auto-mocking the GraphQL schema's queries and mutations
+func GraphQLCall(path, sdl string) Call {
+	return Call{
+		Path:       path,
+		Method:     http.MethodPost,
+		StatusCode: http.StatusOK,
+		GraphQL:    sdl,
+	}
+}
+
+// ParseGraphQLSchema parses the object, interface, union, enum, and scalar types of a GraphQL SDL, including type extensions
+// Input types, directives, arguments, and descriptions are skipped
+func ParseGraphQLSchema(sdl io.Reader) (*GraphQLSchema, error) {
+	data, err := io.ReadAll(sdl)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := tokenizeGraphQL(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid graphql schema: %w", err)
+	}
+	p := &graphQLParser{tokens: tokens, document: "schema"}
+	return p.parseSchema()
+}
+
+// graphQLParser parses a tokenized GraphQL schema or query document
+type graphQLParser struct {
+	tokens   []string
+	pos      int
+	document string
+}
+
+// next returns the next token, or an empty token at the end of the document
+func (p *graphQLParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+// peek returns the next token without consuming it
+func (p *graphQLParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+// errorf returns an error in the document
+func (p *graphQLParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid graphql %s: %s", p.document, fmt.Sprintf(format, args...))
+}
+
+// expect consumes the next token, returning an error if it is not the expected token
+func (p *graphQLParser) expect(token string) error {
+	if next := p.next(); next != token {
+		return p.errorf("expected %q, found %q", token, next)
+	}
+	return nil
+}
+
+// name consumes the next token, returning an error if it is not a name
+func (p *graphQLParser) name() (string, error) {
+	next := p.next()
+	if !isGraphQLName(next) {
+		return "", p.errorf("expected a name, found %q", next)
+	}
+	return next, nil
+}
+
+// skipParens consumes the tokens of parentheses, such as arguments, until their closing parenthesis, if the next token opens them
+func (p *graphQLParser) skipParens() error {
+	if p.peek() != "(" {
+		return nil
+	}
+	for depth := 0; ; {
+		switch p.next() {
+		case "(":
+			depth++
+		case ")":
+			if depth--; depth == 0 {
+				return nil
+			}
+		case "":
+			return p.errorf("unclosed parentheses")
+		}
+	}
+}
+
+// skipDirectives consumes the directives applied to a definition, e.g. @deprecated(reason: "unused")
+func (p *graphQLParser) skipDirectives() error {
+	for p.peek() == "@" {
+		p.next()
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if err := p.skipParens(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipDescriptions consumes the description strings of a definition
+func (p *graphQLParser) skipDescriptions() {
+	for strings.HasPrefix(p.peek(), `"`) {
+		p.next()
+	}
+}
+
+// parseSchema parses the type definitions and extensions of the schema
+func (p *graphQLParser) parseSchema() (*GraphQLSchema, error) {
+	s := &GraphQLSchema{query: "Query", mutation: "Mutation", types: map[string]*graphQLType{}}
+	for _, scalar := range []string{"Int", "Float", "String", "Boolean", "ID"} {
+		s.types[scalar] = &graphQLType{name: scalar, kind: graphQLScalar}
+	}
+	for {
+		p.skipDescriptions()
+		keyword := p.next()
+		if keyword == "extend" {
+			keyword = p.next()
+		}
+		var err error
+		switch keyword {
+		case "":
+			s.implement()
+			return s, nil
+		case "schema":
+			err = p.parseSchemaDefinition(s)
+		case "type":
+			err = p.parseObject(s, graphQLObject)
+		case "interface":
+			err = p.parseObject(s, graphQLInterface)
+		case "union":
+			err = p.parseUnion(s)
+		case "enum":
+			err = p.parseEnum(s)
+		case "input":
+			err = p.parseInput(s)
+		case "scalar":
+			err = p.parseScalar(s)
+		case "directive":
+			err = p.parseDirective()
+		default:
+			err = p.errorf("unexpected %q", keyword)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// define returns the schema's type with the name, defining it with the kind if it is not yet defined
+func (s *GraphQLSchema) define(name string, kind graphQLKind) *graphQLType {
+	t, ok := s.types[name]
+	if !ok {
+		t = &graphQLType{name: name, kind: kind, fields: map[string]graphQLTypeRef{}}
+		s.types[name] = t
+		s.order = append(s.order, name)
+	}
+	return t
+}
+
+// implement lists the object types implementing each interface, in the order they are defined
+func (s *GraphQLSchema) implement() {
+	for _, name := range s.order {
+		t := s.types[name]
+		if t.kind != graphQLObject {
+			continue
+		}
+		for _, iface := range t.interfaces {
+			if implemented, ok := s.types[iface]; ok && implemented.kind == graphQLInterface {
+				implemented.members = append(implemented.members, name)
+			}
+		}
+	}
+}
+
+// parseSchemaDefinition parses the root operation types of the schema, after the schema keyword is consumed
+func (p *graphQLParser) parseSchemaDefinition(s *GraphQLSchema) error {
+	if err := p.skipDirectives(); err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for p.peek() != "}" {
+		operation, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		switch operation {
+		case "query":
+			s.query = name
+		case "mutation":
+			s.mutation = name
+		}
+	}
+	p.next()
+	return nil
+}
+
+// parseObject parses the interfaces and fields of an object or interface type, after the type or interface keyword is consumed
+func (p *graphQLParser) parseObject(s *GraphQLSchema, kind graphQLKind) error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	t := s.define(name, kind)
+	if p.peek() == "implements" {
+		p.next()
+		if p.peek() == "&" {
+			p.next()
+		}
+		for {
+			iface, err := p.name()
+			if err != nil {
+				return err
+			}
+			t.interfaces = append(t.interfaces, iface)
+			if p.peek() != "&" {
+				break
+			}
+			p.next()
+		}
+	}
+	if err := p.skipDirectives(); err != nil {
+		return err
+	}
+	if p.peek() != "{" {
+		return nil
+	}
+	p.next()
+	for {
+		p.skipDescriptions()
+		if p.peek() == "}" {
+			p.next()
+			return nil
+		}
+		field, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.skipParens(); err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		ref, err := p.parseTypeRef()
+		if err != nil {
+			return err
+		}
+		t.fields[field] = ref
+		if err := p.skipDirectives(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseTypeRef parses the type of a field, e.g. [User!]!
+func (p *graphQLParser) parseTypeRef() (graphQLTypeRef, error) {
+	var ref graphQLTypeRef
+	for p.peek() == "[" {
+		p.next()
+		ref.lists++
+	}
+	name, err := p.name()
+	if err != nil {
+		return ref, err
+	}
+	ref.name = name
+	if p.peek() == "!" {
+		p.next()
+	}
+	for i := 0; i < ref.lists; i++ {
+		if err := p.expect("]"); err != nil {
+			return ref, err
+		}
+		if p.peek() == "!" {
+			p.next()
+		}
+	}
+	return ref, nil
+}
+
+// parseUnion parses the member types of a union, after the union keyword is consumed
+func (p *graphQLParser) parseUnion(s *GraphQLSchema) error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	t := s.define(name, graphQLUnion)
+	if err := p.skipDirectives(); err != nil {
+		return err
+	}
+	if p.peek() != "=" {
+		return nil
+	}
+	p.next()
+	if p.peek() == "|" {
+		p.next()
+	}
+	for {
+		member, err := p.name()
+		if err != nil {
+			return err
+		}
+		t.members = append(t.members, member)
+		if p.peek() != "|" {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// parseEnum parses the values of an enum, after the enum keyword is consumed
+func (p *graphQLParser) parseEnum(s *GraphQLSchema) error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	t := s.define(name, graphQLEnum)
+	if err := p.skipDirectives(); err != nil {
+		return err
+	}
+	if p.peek() != "{" {
+		return nil
+	}
+	p.next()
+	for {
+		p.skipDescriptions()
+		if p.peek() == "}" {
+			p.next()
+			return nil
+		}
+		value, err := p.name()
+		if err != nil {
+			return err
+		}
+		t.values = append(t.values, value)
+		if err := p.skipDirectives(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseInput skips the fields of an input type, after the input keyword is consumed
+func (p *graphQLParser) parseInput(s *GraphQLSchema) error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	s.define(name, graphQLInput)
+	if err := p.skipDirectives(); err != nil {
+		return err
+	}
+	if p.peek() != "{" {
+		return nil
+	}
+	for depth := 0; ; {
+		switch p.next() {
+		case "{":
+			depth++
+		case "}":
+			if depth--; depth == 0 {
+				return nil
+			}
+		case "":
+			return p.errorf("unclosed input %s", name)
+		}
+	}
+}
+
+// parseScalar parses a custom scalar, after the scalar keyword is consumed
+func (p *graphQLParser) parseScalar(s *GraphQLSchema) error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	s.define(name, graphQLScalar)
+	return p.skipDirectives()
+}
+
+// parseDirective skips a directive definition, after the directive keyword is consumed, e.g. directive @auth(role: String) on FIELD_DEFINITION
+func (p *graphQLParser) parseDirective() error {
+	if err := p.expect("@"); err != nil {
+		return err
+	}
+	if _, err := p.name(); err != nil {
+		return err
+	}
+	if err := p.skipParens(); err != nil {
+		return err
+	}
+	if p.peek() == "repeatable" {
+		p.next()
+	}
+	if err := p.expect("on"); err != nil {
+		return err
+	}
+	if p.peek() == "|" {
+		p.next()
+	}
+	for {
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if p.peek() != "|" {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// tokenizeGraphQL splits the GraphQL document into names, numbers, string literals, and punctuators, without its comments and commas
+func tokenizeGraphQL(document string) ([]string, error) {
+	var tokens []string
+	runes := []rune(document)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r) || r == ',' || r == '\uFEFF':
+		case r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '"' && i+2 < len(runes) && runes[i+1] == '"' && runes[i+2] == '"':
+			start := i
+			for i += 3; i+2 < len(runes) && (runes[i] != '"' || runes[i+1] != '"' || runes[i+2] != '"' || runes[i-1] == '\\'); i++ {
+			}
+			if i+2 >= len(runes) {
+				return nil, errors.New("unterminated block string")
+			}
+			i += 2
+			tokens = append(tokens, string(runes[start:i+1]))
+		case r == '"':
+			start := i
+			for i++; i < len(runes) && runes[i] != '"' && runes[i] != '\n'; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(runes) || runes[i] != '"' {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, string(runes[start:i+1]))
+		case r == '.':
+			if i+2 >= len(runes) || runes[i+1] != '.' || runes[i+2] != '.' {
+				return nil, fmt.Errorf("unexpected character %q", r)
+			}
+			tokens = append(tokens, "...")
+			i += 2
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i+1 < len(runes) && (runes[i+1] == '_' || unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1])) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i+1]))
+		case r == '-' || unicode.IsDigit(r):
+			start := i
+			for i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || strings.ContainsRune(".eE", runes[i+1]) ||
+				strings.ContainsRune("+-", runes[i+1]) && strings.ContainsRune("eE", runes[i])) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i+1]))
+		case strings.ContainsRune("!$&():=@[]{}|", r):
+			tokens = append(tokens, string(r))
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	return tokens, nil
+}
+
+// isGraphQLName reports whether the token is a name, e.g. a type, field, or argument name
+func isGraphQLName(token string) bool {
+	if token == "" || !(token[0] == '_' || unicode.IsLetter(rune(token[0]))) {
+		return false
+	}
+	for _, r := range token {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// graphQLRequest is the query, operation name, and variables of a request made to a GraphQL endpoint
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// parseGraphQLRequest parses the GraphQL request of a call made, a JSON body, an application/graphql body, or the query parameters of a GET
+func parseGraphQLRequest(call *Call) (graphQLRequest, error) {
+	var request graphQLRequest
+	switch {
+	case call.Query["query"] != "":
+		request.Query = call.Query["query"]
+		request.OperationName = call.Query["operationName"]
+		if variables := call.Query["variables"]; variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				return request, fmt.Errorf("invalid variables: %w", err)
+			}
+		}
+	case strings.HasPrefix(call.Headers["Content-Type"], "application/graphql"):
+		request.Query = string(call.Response)
+	default:
+		if err := json.Unmarshal(call.Response, &request); err != nil {
+			return request, fmt.Errorf("invalid graphql request: %w", err)
+		}
+	}
+	if request.Query == "" {
+		return request, errors.New("must provide a query")
+	}
+	return request, nil
+}
+
+// graphQLSchema returns the parsed GraphQL schema of the SDL, parsing it once for every call stubbed with it
+func (a *AssuredEndpoints) graphQLSchema(sdl string) (*GraphQLSchema, error) {
+	if schema, ok := a.graphQLSchemas.Load(sdl); ok {
+		return schema.(*GraphQLSchema), nil
+	}
+	schema, err := ParseGraphQLSchema(strings.NewReader(sdl))
+	if err != nil {
+		return nil, err
+	}
+	a.graphQLSchemas.Store(sdl, schema)
+	return schema, nil
+}
+
+// mockGraphQL returns a copy of the stubbed call responding with the mock data resolved from its GraphQL schema for the request made,
+// or with the errors of the request, in a GraphQL JSON response
+func (a *AssuredEndpoints) mockGraphQL(assured, call *Call) *Call {
+	mocked := *assured
+	mocked.Headers = maps.Clone(assured.Headers)
+	if mocked.Headers == nil {
+		mocked.Headers = map[string]string{}
+	}
+	delete(mocked.Headers, "Content-Length")
+	mocked.Headers["Content-Type"] = "application/json"
+
+	data, err := func() (json.RawMessage, error) {
+		schema, err := a.graphQLSchema(assured.GraphQL)
+		if err != nil {
+			return nil, err
+		}
+		request, err := parseGraphQLRequest(call)
+		if err != nil {
+			return nil, err
+		}
+		return schema.Resolve(request.Query, request.OperationName, request.Variables)
+	}()
+	if err != nil {
+		mocked.Response, _ = json.Marshal(map[string]any{"errors": []map[string]string{{"message": err.Error()}}})
+		return &mocked
+	}
+	mocked.Response, _ = json.Marshal(map[string]json.RawMessage{"data": data})
+	return &mocked
+}
+
+// unquoteGraphQL returns the value of a GraphQL string literal, or the raw content of a block string
+func unquoteGraphQL(literal string) string {
+	if block, ok := strings.CutPrefix(literal, `"""`); ok {
+		return strings.ReplaceAll(strings.TrimSuffix(block, `"""`), `\"""`, `"""`)
+	}
+	value, err := strconv.Unquote(literal)
+	if err != nil {
+		return strings.Trim(literal, `"`)
+	}
+	return value
+}
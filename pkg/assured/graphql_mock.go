@@ -0,0 +1,573 @@
+package assured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// graphQLDocument is the operations and fragments of a GraphQL query document
+type graphQLDocument struct {
+	operations []graphQLOperation
+	fragments  map[string]graphQLFragment
+}
+
+// graphQLOperation is a query, mutation, or subscription of a GraphQL query document
+type graphQLOperation struct {
+	kind       string
+	name       string
+	selections []graphQLSelection
+}
+
+// graphQLFragment is a named fragment of a GraphQL query document, selecting fields on its type condition
+type graphQLFragment struct {
+	on         string
+	selections []graphQLSelection
+}
+
+// graphQLSelection is a field, a fragment spread, or an inline fragment of a selection set
+type graphQLSelection struct {
+	alias      string
+	name       string
+	args       map[string]any
+	selections []graphQLSelection
+	fragment   string
+	inline     bool
+	on         string
+}
+
+// graphQLVariable is a variable referenced by an argument value, resolved from the request's variables
+type graphQLVariable string
+
+// graphQLListArgs are the arguments limiting the number of items of a list field
+var graphQLListArgs = []string{"first", "last", "limit", "count", "take", "size", "pageSize", "perPage"}
+
+// graphQLMaxItems is the maximum number of items mocked in a list field
+const graphQLMaxItems = 100
+
+// Resolve resolves the operation of a GraphQL query document to deterministic mock data, returning the JSON of its data
+// The same field at the same path is always mocked with the same value, chosen by the field's name and type, such as a name or an email,
+// lists have 2 or 3 items unless limited by a first, last, limit, or count argument, and objects fetched by an id argument have that id
+// The operation name is required when the document has more than one operation
+func (s *GraphQLSchema) Resolve(query, operationName string, variables map[string]any) (json.RawMessage, error) {
+	tokens, err := tokenizeGraphQL(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid graphql query: %w", err)
+	}
+	p := &graphQLParser{tokens: tokens, document: "query"}
+	document, err := p.parseDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	var operation *graphQLOperation
+	for i, op := range document.operations {
+		if operationName == "" && len(document.operations) > 1 {
+			return nil, fmt.Errorf("must provide an operation name when the query has %d operations", len(document.operations))
+		}
+		if operationName == "" || op.name == operationName {
+			operation = &document.operations[i]
+			break
+		}
+	}
+	if operation == nil {
+		return nil, fmt.Errorf("unknown operation %q", operationName)
+	}
+	root := s.query
+	switch operation.kind {
+	case "mutation":
+		root = s.mutation
+	case "subscription":
+		return nil, fmt.Errorf("subscriptions are not supported")
+	}
+	if _, ok := s.types[root]; !ok {
+		return nil, fmt.Errorf("schema has no %s type %s", operation.kind, root)
+	}
+
+	r := &graphQLResolver{schema: s, fragments: document.fragments, variables: variables}
+	data, err := r.resolve(root, operation.selections, operation.kind, nil)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(data)
+}
+
+// parseDocument parses the operations and fragments of a query document
+func (p *graphQLParser) parseDocument() (*graphQLDocument, error) {
+	document := &graphQLDocument{fragments: map[string]graphQLFragment{}}
+	for p.peek() != "" {
+		switch keyword := p.next(); keyword {
+		case "{":
+			p.pos--
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			document.operations = append(document.operations, graphQLOperation{kind: "query", selections: selections})
+		case "query", "mutation", "subscription":
+			operation := graphQLOperation{kind: keyword}
+			if isGraphQLName(p.peek()) {
+				operation.name = p.next()
+			}
+			if err := p.skipParens(); err != nil {
+				return nil, err
+			}
+			if err := p.skipDirectives(); err != nil {
+				return nil, err
+			}
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			operation.selections = selections
+			document.operations = append(document.operations, operation)
+		case "fragment":
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("on"); err != nil {
+				return nil, err
+			}
+			on, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.skipDirectives(); err != nil {
+				return nil, err
+			}
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			document.fragments[name] = graphQLFragment{on: on, selections: selections}
+		default:
+			return nil, p.errorf("unexpected %q", keyword)
+		}
+	}
+	if len(document.operations) == 0 {
+		return nil, p.errorf("no operations")
+	}
+	return document, nil
+}
+
+// parseSelectionSet parses the fields, fragment spreads, and inline fragments of a selection set
+func (p *graphQLParser) parseSelectionSet() ([]graphQLSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []graphQLSelection
+	for p.peek() != "}" {
+		var selection graphQLSelection
+		switch {
+		case p.peek() == "...":
+			p.next()
+			switch p.peek() {
+			case "on", "@", "{":
+				selection.inline = true
+				if p.peek() == "on" {
+					p.next()
+					on, err := p.name()
+					if err != nil {
+						return nil, err
+					}
+					selection.on = on
+				}
+				if err := p.skipDirectives(); err != nil {
+					return nil, err
+				}
+				sub, err := p.parseSelectionSet()
+				if err != nil {
+					return nil, err
+				}
+				selection.selections = sub
+			default:
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				selection.fragment = name
+				if err := p.skipDirectives(); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			selection.name = name
+			if p.peek() == ":" {
+				p.next()
+				if selection.name, err = p.name(); err != nil {
+					return nil, err
+				}
+				selection.alias = name
+			}
+			if p.peek() == "(" {
+				if selection.args, err = p.parseArguments(); err != nil {
+					return nil, err
+				}
+			}
+			if err := p.skipDirectives(); err != nil {
+				return nil, err
+			}
+			if p.peek() == "{" {
+				if selection.selections, err = p.parseSelectionSet(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		selections = append(selections, selection)
+	}
+	p.next()
+	return selections, nil
+}
+
+// parseArguments parses the arguments of a field, by name
+func (p *graphQLParser) parseArguments() (map[string]any, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	args := map[string]any{}
+	for p.peek() != ")" {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.parseValue(); err != nil {
+			return nil, err
+		}
+	}
+	p.next()
+	return args, nil
+}
+
+// parseValue parses an argument value, a variable, number, string, boolean, null, enum value, list, or object
+func (p *graphQLParser) parseValue() (any, error) {
+	token := p.next()
+	switch {
+	case token == "$":
+		name, err := p.name()
+		return graphQLVariable(name), err
+	case token == "[":
+		list := []any{}
+		for p.peek() != "]" {
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		p.next()
+		return list, nil
+	case token == "{":
+		object := map[string]any{}
+		for p.peek() != "}" {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.parseValue(); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+		return object, nil
+	case strings.HasPrefix(token, `"`):
+		return unquoteGraphQL(token), nil
+	case token == "true" || token == "false":
+		return token == "true", nil
+	case token == "null":
+		return nil, nil
+	case isGraphQLName(token):
+		return token, nil
+	}
+	if n, err := strconv.ParseFloat(token, 64); err == nil {
+		return n, nil
+	}
+	return nil, p.errorf("unexpected %q", token)
+}
+
+// graphQLResolver resolves the selections of an operation to mock data
+type graphQLResolver struct {
+	schema    *GraphQLSchema
+	fragments map[string]graphQLFragment
+	variables map[string]any
+}
+
+// resolve resolves the selections on the named type at the path to a mock object, with the id, if the object was fetched by one
+func (r *graphQLResolver) resolve(typeName string, selections []graphQLSelection, at string, id any) (*graphQLMock, error) {
+	t := r.schema.types[typeName]
+	mock := &graphQLMock{}
+	for _, selection := range selections {
+		switch {
+		case selection.fragment != "" || selection.inline:
+			on, sub := selection.on, selection.selections
+			if selection.fragment != "" {
+				fragment, ok := r.fragments[selection.fragment]
+				if !ok {
+					return nil, fmt.Errorf("unknown fragment %q", selection.fragment)
+				}
+				on, sub = fragment.on, fragment.selections
+			}
+			if on != "" && !r.schema.applies(on, typeName) {
+				continue
+			}
+			fragment, err := r.resolve(typeName, sub, at, id)
+			if err != nil {
+				return nil, err
+			}
+			mock.merge(fragment)
+		case selection.name == "__typename":
+			mock.set(selection.key(), typeName)
+		case selection.name == "id" && id != nil:
+			if n, ok := id.(float64); ok && t.fields["id"].name == "ID" {
+				id = strconv.FormatFloat(n, 'f', -1, 64)
+			}
+			mock.set(selection.key(), id)
+		default:
+			ref, ok := t.fields[selection.name]
+			if !ok {
+				return nil, fmt.Errorf("cannot query field %q on type %q", selection.name, typeName)
+			}
+			value, err := r.mock(ref, selection, at+"."+selection.name)
+			if err != nil {
+				return nil, err
+			}
+			mock.set(selection.key(), value)
+		}
+	}
+	return mock, nil
+}
+
+// mock returns the mock value of the selected field with the type at the path
+func (r *graphQLResolver) mock(ref graphQLTypeRef, selection graphQLSelection, at string) (any, error) {
+	if ref.lists > 0 {
+		items := make([]any, r.count(selection, at))
+		item := graphQLTypeRef{name: ref.name, lists: ref.lists - 1}
+		for i := range items {
+			value, err := r.mock(item, graphQLSelection{name: selection.name, selections: selection.selections}, at+"."+strconv.Itoa(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+	}
+
+	t, ok := r.schema.types[ref.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q of field %q", ref.name, selection.name)
+	}
+	switch t.kind {
+	case graphQLScalar:
+		return graphQLScalarMock(t.name, selection.name, at), nil
+	case graphQLEnum:
+		if len(t.values) == 0 {
+			return nil, fmt.Errorf("enum %q has no values", t.name)
+		}
+		return t.values[graphQLHash(at)%uint64(len(t.values))], nil
+	case graphQLObject, graphQLInterface, graphQLUnion:
+		if selection.selections == nil {
+			return nil, fmt.Errorf("field %q of type %q must have a selection of subfields", selection.name, t.name)
+		}
+		concrete := t.name
+		if t.kind != graphQLObject {
+			if len(t.members) == 0 {
+				return nil, fmt.Errorf("no object types implement %q", t.name)
+			}
+			concrete = t.members[graphQLHash(at)%uint64(len(t.members))]
+		}
+		return r.resolve(concrete, selection.selections, at, r.argument(selection, "id"))
+	}
+	return nil, fmt.Errorf("field %q has input type %q", selection.name, t.name)
+}
+
+// count returns the number of items to mock in a list field, limited by its arguments or 2 or 3 items by the path
+func (r *graphQLResolver) count(selection graphQLSelection, at string) int {
+	for _, arg := range graphQLListArgs {
+		if n, ok := r.argument(selection, arg).(float64); ok {
+			return int(math.Max(0, math.Min(n, graphQLMaxItems)))
+		}
+	}
+	return 2 + int(graphQLHash(at)%2)
+}
+
+// argument returns the value of the selected field's argument, resolving a variable from the request's variables
+func (r *graphQLResolver) argument(selection graphQLSelection, name string) any {
+	value := selection.args[name]
+	if variable, ok := value.(graphQLVariable); ok {
+		return r.variables[string(variable)]
+	}
+	return value
+}
+
+// applies reports whether a fragment with the type condition applies to the object type, the type itself, one of its interfaces,
+// or a union it is a member of
+func (s *GraphQLSchema) applies(on, typeName string) bool {
+	if on == typeName || slices.Contains(s.types[typeName].interfaces, on) {
+		return true
+	}
+	union, ok := s.types[on]
+	return ok && union.kind == graphQLUnion && slices.Contains(union.members, typeName)
+}
+
+// key returns the response key of a selected field, its alias or its name
+func (s graphQLSelection) key() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+// graphQLMock is a mock object, keeping its fields in the order they are selected
+type graphQLMock struct {
+	keys   []string
+	values map[string]any
+}
+
+// set sets a field of the mock object, merging the subfields of a field selected more than once
+func (m *graphQLMock) set(key string, value any) {
+	if m.values == nil {
+		m.values = map[string]any{}
+	}
+	existing, ok := m.values[key]
+	if !ok {
+		m.keys = append(m.keys, key)
+		m.values[key] = value
+		return
+	}
+	if object, ok := existing.(*graphQLMock); ok {
+		if fields, ok := value.(*graphQLMock); ok {
+			object.merge(fields)
+		}
+	}
+}
+
+// merge sets the fields of another mock object, such as those selected by a fragment
+func (m *graphQLMock) merge(other *graphQLMock) {
+	for _, key := range other.keys {
+		m.set(key, other.values[key])
+	}
+}
+
+// MarshalJSON encodes the mock object with its fields in the order they are selected
+func (m *graphQLMock) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// graphQLHash returns the hash of a path, seeding its mock value
+func graphQLHash(at string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(at))
+	return h.Sum64()
+}
+
+// The words mock strings are chosen from
+var (
+	graphQLFirstNames = []string{"Ada", "Alan", "Grace", "Linus", "Margaret", "Dennis", "Barbara", "Ken", "Frances", "Edsger"}
+	graphQLLastNames  = []string{"Lovelace", "Turing", "Hopper", "Torvalds", "Hamilton", "Ritchie", "Liskov", "Thompson", "Allen", "Dijkstra"}
+	graphQLCities     = []string{"London", "Paris", "Tokyo", "Nairobi", "Lima", "Oslo", "Sydney", "Toronto", "Seoul", "Lisbon"}
+	graphQLCountries  = []string{"United Kingdom", "France", "Japan", "Kenya", "Peru", "Norway", "Australia", "Canada", "South Korea", "Portugal"}
+	graphQLWords      = []string{"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do", "eiusmod", "tempor"}
+)
+
+// graphQLScalarMock returns the mock value of a scalar field at the path, chosen by the scalar and the field's name
+// Names, emails, and usernames are chosen by the path of the object they are in, so the fields of one object describe the same person
+func graphQLScalarMock(scalar, field, at string) any {
+	h := graphQLHash(at)
+	person := graphQLHash(path.Dir(strings.ReplaceAll(at, ".", "/")))
+	first := graphQLFirstNames[person%uint64(len(graphQLFirstNames))]
+	last := graphQLLastNames[person/10%uint64(len(graphQLLastNames))]
+	name := strings.ToLower(field)
+
+	switch scalar {
+	case "Int":
+		if strings.Contains(name, "age") {
+			return 18 + int(h%60)
+		}
+		return int(h % 1000)
+	case "Float":
+		return float64(h%100000) / 100
+	case "Boolean":
+		return h%2 == 0
+	case "ID":
+		return strconv.FormatUint(h%100000, 10)
+	case "JSON", "JSONObject":
+		return map[string]any{}
+	}
+
+	date := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(h%(365*24*60*60)) * time.Second)
+	words := func(n int) string {
+		w := make([]string, n)
+		for i := range w {
+			w[i] = graphQLWords[(h>>(i*4))%uint64(len(graphQLWords))]
+		}
+		return strings.Join(w, " ")
+	}
+	switch {
+	case scalar == "Date":
+		return date.Format(time.DateOnly)
+	case scalar == "DateTime" || scalar == "Time" || scalar == "Timestamp" || strings.HasSuffix(field, "At") ||
+		strings.Contains(name, "date") || strings.Contains(name, "time"):
+		return date.Format(time.RFC3339)
+	case strings.Contains(name, "email"):
+		return strings.ToLower(first + "." + last + "@example.com")
+	case strings.Contains(name, "username") || name == "login" || name == "handle":
+		return strings.ToLower(first) + strconv.FormatUint(person%100, 10)
+	case name == "firstname" || name == "givenname":
+		return first
+	case name == "lastname" || name == "surname" || name == "familyname":
+		return last
+	case strings.Contains(name, "name") || name == "author":
+		return first + " " + last
+	case scalar == "URL" || strings.Contains(name, "url") || strings.Contains(name, "website") || strings.Contains(name, "avatar") ||
+		strings.Contains(name, "image") || strings.Contains(name, "link"):
+		return "https://example.com/" + field + "/" + strconv.FormatUint(h%100000, 10)
+	case strings.Contains(name, "phone"):
+		return fmt.Sprintf("+1-555-01%02d", h%100)
+	case strings.Contains(name, "city"):
+		return graphQLCities[h%uint64(len(graphQLCities))]
+	case strings.Contains(name, "country"):
+		return graphQLCountries[h%uint64(len(graphQLCountries))]
+	case strings.Contains(name, "title") || strings.Contains(name, "subject") || strings.Contains(name, "headline"):
+		title := words(3)
+		return strings.ToUpper(title[:1]) + title[1:]
+	case strings.Contains(name, "description") || strings.Contains(name, "body") || strings.Contains(name, "content") ||
+		strings.Contains(name, "text") || strings.Contains(name, "summary") || strings.Contains(name, "bio") ||
+		strings.Contains(name, "comment") || strings.Contains(name, "message"):
+		sentence := words(8)
+		return strings.ToUpper(sentence[:1]) + sentence[1:] + "."
+	}
+	return words(2)
+}
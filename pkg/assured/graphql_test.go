@@ -0,0 +1,269 @@
+package assured
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testGraphQLSchema = `
+"""The root query"""
+schema { query: RootQuery mutation: RootMutation }
+
+directive @auth(role: String = "admin") repeatable on FIELD_DEFINITION | OBJECT
+
+scalar DateTime
+
+type RootQuery {
+  "A user by their id"
+  user(id: ID!): User @auth
+  users(first: Int = 10, filter: UserFilter): [User!]!
+  search(text: String!): [SearchResult!]!
+  node(id: ID!): Node
+}
+
+type RootMutation {
+  createUser(input: UserFilter!): User!
+}
+
+interface Node { id: ID! }
+
+type User implements Node & Named @key(fields: "id") {
+  id: ID!
+  name: String!
+  email: String!
+  age: Int
+  score: Float
+  active: Boolean!
+  role: Role!
+  createdAt: DateTime!
+  friends(first: Int): [User!]!
+}
+
+interface Named { name: String! }
+
+type Post implements Node {
+  id: ID!
+  title: String! @deprecated(reason: "use headline")
+}
+
+extend type Post {
+  body: String
+}
+
+union SearchResult = | User | Post
+
+enum Role {
+  ADMIN
+  "A regular user"
+  MEMBER @deprecated
+}
+
+input UserFilter {
+  name: String
+  nested: [UserFilter!] = [{ name: "a" }]
+}
+`
+
+func TestParseGraphQLSchema(t *testing.T) {
+	schema, err := ParseGraphQLSchema(strings.NewReader(testGraphQLSchema))
+
+	require.NoError(t, err)
+	require.Equal(t, "RootQuery", schema.query)
+	require.Equal(t, "RootMutation", schema.mutation)
+	require.Equal(t, []string{"Node", "Named"}, schema.types["User"].interfaces)
+	require.Equal(t, []string{"User", "Post"}, schema.types["Node"].members)
+	require.Equal(t, []string{"User", "Post"}, schema.types["SearchResult"].members)
+	require.Equal(t, []string{"ADMIN", "MEMBER"}, schema.types["Role"].values)
+	require.Equal(t, graphQLTypeRef{name: "User", lists: 1}, schema.types["RootQuery"].fields["users"])
+	require.Equal(t, graphQLTypeRef{name: "String"}, schema.types["Post"].fields["body"])
+	require.Equal(t, graphQLScalar, schema.types["DateTime"].kind)
+	require.Equal(t, graphQLInput, schema.types["UserFilter"].kind)
+}
+
+func TestParseGraphQLSchemaFailure(t *testing.T) {
+	for _, sdl := range []string{
+		`type Query { user: }`,
+		`type Query { user: [User }`,
+		`type Query { user(id: ID!: User }`,
+		`type Query { name: String } query`,
+		`type Query { name: "String }`,
+		`type Query { name: String % }`,
+	} {
+		_, err := ParseGraphQLSchema(strings.NewReader(sdl))
+
+		require.ErrorContains(t, err, "invalid graphql schema", sdl)
+	}
+}
+
+func TestGraphQLSchemaResolve(t *testing.T) {
+	schema, err := ParseGraphQLSchema(strings.NewReader(testGraphQLSchema))
+	require.NoError(t, err)
+
+	query := `
+	# Fetch a user and their friends
+	query User($id: ID!) {
+	  user(id: $id) {
+	    __typename
+	    id
+	    ...Person
+	    friends(first: 3) { id name }
+	  }
+	  member: user(id: 7) { id role }
+	}
+	fragment Person on Named { name email }
+	`
+	data, err := schema.Resolve(query, "User", map[string]any{"id": "u-1"})
+
+	require.NoError(t, err)
+	var result struct {
+		User struct {
+			Typename string `json:"__typename"`
+			ID       string `json:"id"`
+			Name     string `json:"name"`
+			Email    string `json:"email"`
+			Friends  []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"friends"`
+		} `json:"user"`
+		Member struct {
+			ID   string `json:"id"`
+			Role string `json:"role"`
+		} `json:"member"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.True(t, strings.HasPrefix(string(data), `{"user":{"__typename":"User","id":"u-1","name":`), string(data))
+	require.Equal(t, "User", result.User.Typename)
+	require.Equal(t, "u-1", result.User.ID)
+	first, last, _ := strings.Cut(result.User.Name, " ")
+	require.Contains(t, graphQLFirstNames, first)
+	require.Contains(t, graphQLLastNames, last)
+	require.Equal(t, strings.ToLower(first+"."+last+"@example.com"), result.User.Email)
+	require.Len(t, result.User.Friends, 3)
+	require.NotEqual(t, result.User.Friends[0].ID, result.User.Friends[1].ID)
+	require.Equal(t, "7", result.Member.ID)
+	require.Contains(t, []string{"ADMIN", "MEMBER"}, result.Member.Role)
+
+	again, err := schema.Resolve(query, "User", map[string]any{"id": "u-1"})
+	require.NoError(t, err)
+	require.Equal(t, data, again)
+}
+
+func TestGraphQLSchemaResolveScalars(t *testing.T) {
+	schema, err := ParseGraphQLSchema(strings.NewReader(testGraphQLSchema))
+	require.NoError(t, err)
+
+	data, err := schema.Resolve(`{ users(first: 1) { age score active createdAt } }`, "", nil)
+
+	require.NoError(t, err)
+	var result struct {
+		Users []map[string]any `json:"users"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.Len(t, result.Users, 1)
+	user := result.Users[0]
+	require.IsType(t, float64(0), user["age"])
+	require.GreaterOrEqual(t, user["age"], float64(18))
+	require.IsType(t, float64(0), user["score"])
+	require.IsType(t, true, user["active"])
+	require.Regexp(t, `^2024-\d\d-\d\dT\d\d:\d\d:\d\dZ$`, user["createdAt"])
+}
+
+func TestGraphQLSchemaResolveAbstractTypes(t *testing.T) {
+	schema, err := ParseGraphQLSchema(strings.NewReader(testGraphQLSchema))
+	require.NoError(t, err)
+
+	data, err := schema.Resolve(`{
+	  search(text: "a") { __typename ... on User { name } ... on Post { title body } }
+	  node(id: "n") { id ... on Post { title } }
+	}`, "", nil)
+
+	require.NoError(t, err)
+	var result struct {
+		Search []map[string]any `json:"search"`
+		Node   map[string]any   `json:"node"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.NotEmpty(t, result.Search)
+	for _, item := range result.Search {
+		switch item["__typename"] {
+		case "User":
+			require.Len(t, item, 2)
+			require.Contains(t, item, "name")
+		case "Post":
+			require.Len(t, item, 3)
+			require.Contains(t, item, "title")
+			require.Contains(t, item, "body")
+		default:
+			t.Fatalf("unexpected type %v", item["__typename"])
+		}
+	}
+	require.Equal(t, "n", result.Node["id"])
+}
+
+func TestGraphQLSchemaResolveFailure(t *testing.T) {
+	schema, err := ParseGraphQLSchema(strings.NewReader(testGraphQLSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		want          string
+	}{
+		{name: "unknown field", query: `{ user(id: 1) { password } }`, want: `cannot query field "password" on type "User"`},
+		{name: "missing subfields", query: `{ user(id: 1) }`, want: `field "user" of type "User" must have a selection of subfields`},
+		{name: "unknown fragment", query: `{ user(id: 1) { ...Missing } }`, want: `unknown fragment "Missing"`},
+		{name: "multiple operations", query: `query A { user(id: 1) { id } } query B { user(id: 2) { id } }`, want: "must provide an operation name"},
+		{name: "unknown operation", query: `query A { user(id: 1) { id } }`, operationName: "B", want: `unknown operation "B"`},
+		{name: "subscription", query: `subscription { user(id: 1) { id } }`, want: "subscriptions are not supported"},
+		{name: "invalid query", query: `{ user(id: 1) { id }`, want: "invalid graphql query"},
+		{name: "no operations", query: `fragment A on User { id }`, want: "invalid graphql query: no operations"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := schema.Resolve(tc.query, tc.operationName, nil)
+
+			require.ErrorContains(t, err, tc.want)
+		})
+	}
+}
+
+func TestClientGraphQL(t *testing.T) {
+	_, client := NewTestServer(t)
+	call := GraphQLCall("graphql", testGraphQLSchema)
+	call.Branches = []Branch{{When: Condition{BodyContains: "createUser"}, Headers: map[string]string{"Content-Type": "application/json"}, Response: []byte(`{"errors":[{"message":"forbidden"}]}`)}}
+	require.NoError(t, client.Given(call, Call{Path: "graphql", Method: http.MethodGet, GraphQL: testGraphQLSchema}))
+
+	post := func(contentType, body string) string {
+		resp, err := http.Post(client.URL()+"/graphql", contentType, strings.NewReader(body))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+	data := post("application/json", `{"query":"query ($id: ID!) { user(id: $id) { id } }","variables":{"id":"42"}}`)
+	require.Equal(t, `{"data":{"user":{"id":"42"}}}`, data)
+	require.Equal(t, data, post("application/graphql", `{ user(id: "42") { id } }`))
+	require.Equal(t, `{"errors":[{"message":"forbidden"}]}`, post("application/json", `{"query":"mutation { createUser(input: {}) { id } }"}`))
+	require.Equal(t, `{"errors":[{"message":"cannot query field \"password\" on type \"User\""}]}`, post("application/json", `{"query":"{ user(id: 1) { password } }"}`))
+	require.Equal(t, `{"errors":[{"message":"must provide a query"}]}`, post("application/json", `{}`))
+
+	resp, err := http.Get(client.URL() + "/graphql?" + url.Values{"query": {`{ user(id: "42") { id } }`}}.Encode())
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, data, string(b))
+
+	calls, err := client.Verify(http.MethodPost, "graphql")
+	require.NoError(t, err)
+	require.Len(t, calls, 5)
+}
@@ -0,0 +1,111 @@
+package assured
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"strings"
+)
+
+// The schemes of a connection-oriented Handshake
+const (
+	HandshakeNTLM      = "NTLM"
+	HandshakeNegotiate = "Negotiate"
+)
+
+// Handshake models a connection-oriented, multi-round-trip authentication handshake, such as NTLM or Negotiate behind an enterprise proxy
+// A request without a token of the scheme is challenged with 401 Unauthorized and a bare WWW-Authenticate: {scheme},
+// then each token sent on the same connection is challenged with the next of the opaque challenges, until the challenges run out
+// and the connection is authenticated for the call. The tokens are not verified, but a new connection starts the handshake over
+// An NTLM handshake without challenges is challenged with a canned NTLM challenge message, and a Negotiate handshake authenticates its first token
+type Handshake struct {
+	Scheme     string   `json:"scheme"`
+	Challenges []string `json:"challenges,omitempty"`
+}
+
+// handshakeState is the progress of a stubbed call's handshake on a connection
+type handshakeState struct {
+	rounds        int
+	authenticated bool
+}
+
+// ntlmChallenge is a canned NTLM challenge message, with an empty target name and target info, and a fixed server challenge
+var ntlmChallenge = func() string {
+	message := make([]byte, 52)
+	copy(message, "NTLMSSP\x00")
+	binary.LittleEndian.PutUint32(message[8:], 2)
+	binary.LittleEndian.PutUint32(message[16:], 48)
+	// Negotiate unicode, request target, NTLM, always sign, extended session security, and target info
+	binary.LittleEndian.PutUint32(message[20:], 0x00888205)
+	copy(message[24:], "assured!")
+	binary.LittleEndian.PutUint16(message[40:], 4)
+	binary.LittleEndian.PutUint16(message[42:], 4)
+	binary.LittleEndian.PutUint32(message[44:], 48)
+	return base64.StdEncoding.EncodeToString(message)
+}()
+
+// challenges returns the handshake's challenges, or the default challenges of its scheme
+func (h Handshake) challenges() []string {
+	if len(h.Challenges) == 0 && strings.EqualFold(h.Scheme, HandshakeNTLM) {
+		return []string{ntlmChallenge}
+	}
+	return h.Challenges
+}
+
+// handshakeHandler challenges calls made to stubbed calls with a handshake, until the connection the call is made on has completed the handshake
+// Calls made without a connection, outside of the rest assured server, are challenged from the start of the handshake
+func (a *AssuredEndpoints) handshakeHandler(when http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		stub := matched(req.Context()).stub()
+		if stub == nil || stub.Handshake == nil {
+			when.ServeHTTP(w, req)
+			return
+		}
+		conn, ok := req.Context().Value(connectionKey{}).(*connection)
+		if !ok {
+			conn = &connection{handshakes: map[string]*handshakeState{}}
+		}
+		conn.Lock()
+		state, ok := conn.handshakes[stub.ID()]
+		if !ok {
+			state = &handshakeState{}
+			conn.handshakes[stub.ID()] = state
+		}
+		challenge, authenticated := stub.Handshake.next(state, req.Header.Get("Authorization"))
+		conn.Unlock()
+		if authenticated {
+			when.ServeHTTP(w, req)
+			return
+		}
+
+		if a.trackMadeCalls {
+			if call, err := a.decodeWhenCall(req.Context(), req); err == nil {
+				a.trackCall(call.(*Call))
+			}
+		}
+		w.Header().Set("WWW-Authenticate", challenge)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(http.StatusText(http.StatusUnauthorized)))
+	})
+}
+
+// next advances the handshake on the connection with the authorization sent, returning the challenge to respond with,
+// or whether the connection is authenticated. An authorization without a token of the scheme starts the handshake over
+func (h Handshake) next(state *handshakeState, authorization string) (string, bool) {
+	if state.authenticated {
+		return "", true
+	}
+	scheme, token, _ := strings.Cut(authorization, " ")
+	if !strings.EqualFold(scheme, h.Scheme) || strings.TrimSpace(token) == "" {
+		state.rounds = 0
+		return h.Scheme, false
+	}
+	challenges := h.challenges()
+	if state.rounds < len(challenges) {
+		state.rounds++
+		return h.Scheme + " " + challenges[state.rounds-1], false
+	}
+	state.authenticated = true
+	return "", true
+}
@@ -0,0 +1,91 @@
+package assured
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandshakeNext(t *testing.T) {
+	handshake := Handshake{Scheme: HandshakeNegotiate, Challenges: []string{"first", "second"}}
+	state := &handshakeState{}
+
+	for _, step := range []struct {
+		authorization string
+		challenge     string
+		authenticated bool
+	}{
+		{authorization: "", challenge: "Negotiate"},
+		{authorization: "Negotiate one", challenge: "Negotiate first"},
+		{authorization: "Basic dXNlcjpwYXNz", challenge: "Negotiate"},
+		{authorization: "negotiate one", challenge: "Negotiate first"},
+		{authorization: "Negotiate two", challenge: "Negotiate second"},
+		{authorization: "Negotiate three", authenticated: true},
+		{authorization: "", authenticated: true},
+	} {
+		challenge, authenticated := handshake.next(state, step.authorization)
+
+		require.Equal(t, step.challenge, challenge, step.authorization)
+		require.Equal(t, step.authenticated, authenticated, step.authorization)
+	}
+}
+
+func TestHandshakeNTLMChallenge(t *testing.T) {
+	challenges := Handshake{Scheme: HandshakeNTLM}.challenges()
+
+	require.Len(t, challenges, 1)
+	message, err := base64.StdEncoding.DecodeString(challenges[0])
+	require.NoError(t, err)
+	require.Equal(t, "NTLMSSP\x00", string(message[:8]))
+	require.Equal(t, uint32(2), binary.LittleEndian.Uint32(message[8:]))
+	require.Equal(t, "assured!", string(message[24:32]))
+	require.Equal(t, len(message), int(binary.LittleEndian.Uint32(message[44:])+uint32(binary.LittleEndian.Uint16(message[40:]))))
+	require.Empty(t, Handshake{Scheme: HandshakeNegotiate}.challenges())
+}
+
+func TestClientHandshake(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{Path: "proxy/assured", Response: []byte("authenticated"), Handshake: &Handshake{Scheme: HandshakeNTLM}}))
+	get := func(httpClient *http.Client, authorization string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, client.URL()+"/proxy/assured", nil)
+		require.NoError(t, err)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := httpClient.Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp, string(body)
+	}
+	connection := &http.Client{Transport: &http.Transport{MaxConnsPerHost: 1}}
+	defer connection.CloseIdleConnections()
+
+	resp, _ := get(connection, "")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, "NTLM", resp.Header.Get("WWW-Authenticate"))
+	resp, _ = get(connection, "NTLM TlRMTVNTUAABAAAAB4IIAAAAAAAAAAAAAAAAAAAAAAA=")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, "NTLM "+ntlmChallenge, resp.Header.Get("WWW-Authenticate"))
+	resp, body := get(connection, "NTLM TlRMTVNTUAADAAAA")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "authenticated", body)
+	resp, _ = get(connection, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// A new connection starts the handshake over
+	other := &http.Client{Transport: &http.Transport{}}
+	defer other.CloseIdleConnections()
+	resp, _ = get(other, "NTLM TlRMTVNTUAADAAAA")
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, "NTLM "+ntlmChallenge, resp.Header.Get("WWW-Authenticate"))
+
+	calls, err := client.Verify(http.MethodGet, "proxy/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 5)
+}
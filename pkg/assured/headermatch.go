@@ -0,0 +1,116 @@
+package assured
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// HeaderMatcher is a header the calls made are required to have to be served the stubbed call, such as an API key or tenant header,
+// so calls can be stubbed to respond differently by header. The header's value equals Equals, matches Regex, and contains Contains,
+// for each that is specified, or is any value if none are
+type HeaderMatcher struct {
+	Equals   string `json:"equals,omitempty"`
+	Regex    string `json:"regex,omitempty"`
+	Contains string `json:"contains,omitempty"`
+
+	// regex is the compiled regex, once the call is stubbed
+	regex *regexp.Regexp
+}
+
+// compile returns the header matcher with its regex compiled, so it isn't compiled for each call made
+func (h HeaderMatcher) compile() (HeaderMatcher, error) {
+	if h.Regex != "" {
+		regex, err := regexp.Compile(h.Regex)
+		if err != nil {
+			return h, fmt.Errorf("invalid regex: %w", err)
+		}
+		h.regex = regex
+	}
+	return h, nil
+}
+
+// Matches checks if the header's value satisfies the header matcher
+func (h HeaderMatcher) Matches(value string) bool {
+	// Header matchers that weren't stubbed are compiled as they match
+	if h.Regex != "" && h.regex == nil {
+		compiled, err := h.compile()
+		if err != nil {
+			return false
+		}
+		h = compiled
+	}
+	if h.Equals != "" && value != h.Equals {
+		return false
+	}
+	if h.regex != nil && !h.regex.MatchString(value) {
+		return false
+	}
+	return strings.Contains(value, h.Contains)
+}
+
+// compileRequiredHeaders compiles the regexes of the stubbed call's required headers, once, when the call is stubbed
+func (c *Call) compileRequiredHeaders() error {
+	if len(c.RequiredHeaders) == 0 {
+		return nil
+	}
+	compiled := make(map[string]HeaderMatcher, len(c.RequiredHeaders))
+	for name, matcher := range c.RequiredHeaders {
+		matcher, err := matcher.compile()
+		if err != nil {
+			return fmt.Errorf("invalid required header %s: %w", name, err)
+		}
+		compiled[name] = matcher
+	}
+	c.RequiredHeaders = compiled
+	return nil
+}
+
+// matchesHeaders reports whether the call made has each of the stubbed call's required headers
+func (c *Call) matchesHeaders(made *Call) bool {
+	for name, matcher := range c.RequiredHeaders {
+		value, ok := made.Headers[http.CanonicalHeaderKey(name)]
+		if !ok || !matcher.Matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesRequest reports whether the call made has each of the stubbed call's query parameters and required headers
+func (c *Call) matchesRequest(made *Call) bool {
+	return c.matchesQuery(made) && c.matchesHeaders(made)
+}
+
+// conditions returns the number of query parameters and required headers the stubbed call is stubbed with
+func (c *Call) conditions() int {
+	return len(c.Query) + len(c.RequiredHeaders)
+}
+
+// hasConditions reports whether any of the stubbed calls is stubbed with query parameters or required headers
+func hasConditions(calls []*Call) bool {
+	return slices.ContainsFunc(calls, func(c *Call) bool { return c.conditions() > 0 })
+}
+
+// matchRequest returns the stubbed calls with the most query parameters and required headers that the call made has, so a call
+// stubbed with the call made's query parameters or headers is used before a call stubbed without them.
+// Without query parameters or required headers, every stubbed call matches
+func matchRequest(calls []*Call, made *Call) []*Call {
+	if !hasConditions(calls) {
+		return calls
+	}
+	var matched []*Call
+	most := 0
+	for _, c := range calls {
+		if !c.matchesRequest(made) || c.conditions() < most {
+			continue
+		}
+		if c.conditions() > most {
+			matched, most = nil, c.conditions()
+		}
+		matched = append(matched, c)
+	}
+	return matched
+}
@@ -0,0 +1,156 @@
+package assured
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderMatcherMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher HeaderMatcher
+		value   string
+		want    bool
+	}{
+		{name: "any", matcher: HeaderMatcher{}, value: "anything", want: true},
+		{name: "equals", matcher: HeaderMatcher{Equals: "acme"}, value: "acme", want: true},
+		{name: "not equals", matcher: HeaderMatcher{Equals: "acme"}, value: "acme-corp", want: false},
+		{name: "regex", matcher: HeaderMatcher{Regex: "^Bearer [a-z]+$"}, value: "Bearer token", want: true},
+		{name: "not regex", matcher: HeaderMatcher{Regex: "^Bearer [a-z]+$"}, value: "Basic dXNlcg==", want: false},
+		{name: "invalid regex", matcher: HeaderMatcher{Regex: "("}, value: "(", want: false},
+		{name: "contains", matcher: HeaderMatcher{Contains: "gzip"}, value: "deflate, gzip", want: true},
+		{name: "not contains", matcher: HeaderMatcher{Contains: "br"}, value: "deflate, gzip", want: false},
+		{name: "every mode", matcher: HeaderMatcher{Equals: "key-123", Regex: "^key-", Contains: "123"}, value: "key-123", want: true},
+		{name: "one mode mismatch", matcher: HeaderMatcher{Regex: "^key-", Contains: "456"}, value: "key-123", want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.matcher.Matches(tc.value))
+		})
+	}
+}
+
+func TestWhenEndpointRequiredHeaders(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	for _, call := range []*Call{
+		{Path: "orders", Method: http.MethodGet, Response: []byte("default")},
+		{Path: "orders", Method: http.MethodGet, Response: []byte("acme"), RequiredHeaders: map[string]HeaderMatcher{"x-tenant": {Equals: "acme"}}},
+		{Path: "orders", Method: http.MethodGet, Response: []byte("acme admin"), RequiredHeaders: map[string]HeaderMatcher{"X-Tenant": {Equals: "acme"}, "Authorization": {Regex: "^Bearer admin-"}}},
+		{Path: "orders", Method: http.MethodGet, Response: []byte("any key"), RequiredHeaders: map[string]HeaderMatcher{"X-Api-Key": {}}},
+	} {
+		_, err := endpoints.GivenEndpoint(context.TODO(), call)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		headers map[string]string
+		want    string
+	}{
+		{headers: map[string]string{}, want: "default"},
+		{headers: map[string]string{"X-Tenant": "globex"}, want: "default"},
+		{headers: map[string]string{"X-Tenant": "acme"}, want: "acme"},
+		{headers: map[string]string{"X-Tenant": "acme", "Authorization": "Bearer user-1"}, want: "acme"},
+		{headers: map[string]string{"X-Tenant": "acme", "Authorization": "Bearer admin-1"}, want: "acme admin"},
+		{headers: map[string]string{"X-Api-Key": "secret"}, want: "any key"},
+	}
+	for _, tc := range tests {
+		c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "orders", Method: http.MethodGet, Headers: tc.headers})
+		require.NoError(t, err, tc.headers)
+		require.Equal(t, tc.want, string(c.(*Call).Response), tc.headers)
+	}
+}
+
+func TestWhenEndpointRequiredHeadersNoFallback(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{Path: "orders", Method: http.MethodGet, RequiredHeaders: map[string]HeaderMatcher{"X-Api-Key": {Equals: "secret"}}})
+
+	_, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "orders", Method: http.MethodGet, Headers: map[string]string{"X-Api-Key": "guess"}})
+
+	require.EqualError(t, err, "No assured calls")
+}
+
+func TestWhenEndpointRequiredHeadersTrace(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{Path: "orders", Method: http.MethodGet, Response: []byte("default")})
+	_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{Path: "orders", Method: http.MethodGet, RequiredHeaders: map[string]HeaderMatcher{"X-Tenant": {Equals: "acme"}}})
+
+	c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "orders", Method: http.MethodGet, Headers: map[string]string{AssuredTrace: "true"}})
+
+	require.NoError(t, err)
+	require.Equal(t, "GET:orders[0]=matched, GET:orders[1]=header mismatch", c.(*Call).Headers[AssuredTraceCandidates])
+}
+
+func TestWhenEndpointUnmatchedTrace(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{Path: "orders", Method: http.MethodGet, Query: map[string]string{"page": "1"}})
+	_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{Path: "orders", Method: http.MethodGet, RequiredHeaders: map[string]HeaderMatcher{"X-Tenant": {Equals: "acme"}}})
+
+	for path, want := range map[string]string{"orders": "GET:orders[0]=query mismatch, GET:orders[1]=header mismatch", "invoices": "none"} {
+		_, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: path, Method: http.MethodGet, Headers: map[string]string{AssuredTrace: "true"}})
+
+		require.EqualError(t, err, "No assured calls")
+		var unmatched *unmatchedError
+		require.ErrorAs(t, err, &unmatched)
+		require.Equal(t, want, unmatched.trace, path)
+	}
+
+	_, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "orders", Method: http.MethodGet})
+
+	require.Equal(t, &unmatchedError{}, err)
+}
+
+func TestClientUnmatchedTrace(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{Path: "orders", Method: http.MethodGet, StatusCode: http.StatusOK, RequiredHeaders: map[string]HeaderMatcher{"X-Tenant": {Equals: "acme"}}}))
+	req, err := http.NewRequest(http.MethodGet, client.URL()+"/orders", nil)
+	require.NoError(t, err)
+	req.Header.Set(AssuredTrace, "true")
+
+	resp, err := http.DefaultClient.Do(req)
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.Equal(t, "GET:orders[0]=header mismatch", resp.Header.Get(AssuredTraceCandidates))
+	require.Empty(t, resp.Header.Get(AssuredTraceMatch))
+	_ = resp.Body.Close()
+}
+
+func TestGivenEndpointRequiredHeadersFailure(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+
+	_, err := endpoints.GivenEndpoint(context.TODO(), &Call{Path: "orders", Method: http.MethodGet, RequiredHeaders: map[string]HeaderMatcher{"X-Tenant": {Regex: "("}}})
+
+	require.EqualError(t, err, "invalid required header X-Tenant: invalid regex: error parsing regexp: missing closing ): `(`")
+	require.Zero(t, endpoints.assuredCalls.Len())
+}
+
+func TestClientRequiredHeaders(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		_, client := NewTestServer(t)
+		client.legacy.Store(legacy)
+		require.NoError(t, client.Given(
+			Call{Path: "orders", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("no orders")},
+			Call{Path: "orders", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("orders"), RequiredHeaders: map[string]HeaderMatcher{"X-Api-Key": {Equals: "secret"}}},
+		))
+
+		req, err := http.NewRequest(http.MethodGet, client.URL()+"/orders", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Api-Key", "secret")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "orders", string(body))
+
+		resp, err = http.Get(client.URL() + "/orders")
+		require.NoError(t, err)
+		body, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "no orders", string(body))
+	}
+}
@@ -0,0 +1,57 @@
+package assured
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// HostsEntries generates an /etc/hosts snippet that resolves the given hostnames to the rest assured server's address
+// A hosts entry can't carry a port or a path, so the calls an application makes to the hostnames only reach the stubbed calls
+// if the server serves them at the root, on the default port of its scheme, 80 for http or 443 for https, at an address that
+// isn't unspecified. A loopback address only reaches the server from an application on its host. Otherwise it returns an error
+func (c *Client) HostsEntries(hostnames ...string) (string, error) {
+	if !c.rootServing {
+		return "", fmt.Errorf("hosts entries require the stubbed calls served at the root, use WithRootServing(true)")
+	}
+	scheme, port := "http", 80
+	if strings.HasPrefix(c.hostURL(), "https:") {
+		scheme, port = "https", 443
+	}
+	if c.Port != port {
+		return "", fmt.Errorf("hosts entries require serving %s on port %d, not %d", scheme, port, c.Port)
+	}
+	ip, err := c.hostIP()
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(ip).IsUnspecified() {
+		return "", fmt.Errorf("hosts entries require a host address, not %s, use WithHost", ip)
+	}
+
+	var entries strings.Builder
+	for _, hostname := range hostnames {
+		fmt.Fprintf(&entries, "%s\t%s\n", ip, hostname)
+	}
+	return entries.String(), nil
+}
+
+// hostIP resolves the client's host to an ip address
+func (c *Client) hostIP() (string, error) {
+	if ip := net.ParseIP(c.host); ip != nil {
+		return ip.String(), nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(context.Background(), c.host)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve host %s: %w", c.host, err)
+	}
+	// Prefer IPv4 addresses as they are most commonly supported
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+			return addr, nil
+		}
+	}
+	return addrs[0], nil
+}
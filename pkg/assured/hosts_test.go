@@ -0,0 +1,65 @@
+package assured
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientHostsEntries(t *testing.T) {
+	client := &Client{Options: Options{host: "10.0.0.5", Port: 80, rootServing: true}}
+
+	entries, err := client.HostsEntries("api.example.com", "auth.example.com")
+
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.5\tapi.example.com\n10.0.0.5\tauth.example.com\n", entries)
+}
+
+func TestClientHostsEntriesTLS(t *testing.T) {
+	client := &Client{Options: Options{host: "10.0.0.5", Port: 443, rootServing: true, tlsCertFile: "cert.pem", tlsKeyFile: "key.pem"}}
+
+	entries, err := client.HostsEntries("api.example.com")
+
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.5\tapi.example.com\n", entries)
+}
+
+func TestClientHostsEntriesLoopback(t *testing.T) {
+	client := &Client{Options: Options{host: "localhost", Port: 80, rootServing: true}}
+
+	entries, err := client.HostsEntries("api.example.com")
+
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1\tapi.example.com\n", entries)
+}
+
+func TestClientHostsEntriesInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		options Options
+		err     string
+	}{
+		"not root serving": {options: Options{host: "10.0.0.5", Port: 80}, err: "hosts entries require the stubbed calls served at the root, use WithRootServing(true)"},
+		"port":             {options: Options{host: "10.0.0.5", Port: 11011, rootServing: true}, err: "hosts entries require serving http on port 80, not 11011"},
+		"tls port":         {options: Options{host: "10.0.0.5", Port: 80, rootServing: true, tlsCertFile: "cert.pem", tlsKeyFile: "key.pem"}, err: "hosts entries require serving https on port 443, not 80"},
+		"unspecified":      {options: Options{host: "0.0.0.0", Port: 80, rootServing: true}, err: "hosts entries require a host address, not 0.0.0.0, use WithHost"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := &Client{Options: tc.options}
+
+			entries, err := client.HostsEntries("api.example.com")
+
+			require.EqualError(t, err, tc.err)
+			require.Empty(t, entries)
+		})
+	}
+}
+
+func TestClientHostsEntriesUnresolvable(t *testing.T) {
+	client := &Client{Options: Options{host: "rest-assured.invalid", Port: 80, rootServing: true}}
+
+	entries, err := client.HostsEntries("api.example.com")
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to resolve host rest-assured.invalid")
+	require.Empty(t, entries)
+}
@@ -0,0 +1,203 @@
+package assured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// The media types of JSON:API and HAL responses
+const (
+	JSONAPIMediaType = "application/vnd.api+json"
+	HALMediaType     = "application/hal+json"
+)
+
+// JSONAPIDocument is the top-level document of a JSON:API response. The data is a JSONAPIResource, a []JSONAPIResource, or nil
+type JSONAPIDocument struct {
+	Data     any               `json:"data"`
+	Included []JSONAPIResource `json:"included,omitempty"`
+	Links    map[string]string `json:"links,omitempty"`
+	Meta     map[string]any    `json:"meta,omitempty"`
+}
+
+// JSONAPIResource is a resource object of a JSON:API document. The attributes are a plain struct or map
+type JSONAPIResource struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id,omitempty"`
+	Attributes    any                            `json:"attributes,omitempty"`
+	Relationships map[string]JSONAPIRelationship `json:"relationships,omitempty"`
+	Links         map[string]string              `json:"links,omitempty"`
+	Meta          map[string]any                 `json:"meta,omitempty"`
+}
+
+// JSONAPIIdentifier identifies a resource of a JSON:API relationship by its type and id
+type JSONAPIIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// JSONAPIRelationship is a relationship of a JSON:API resource. The data is a *JSONAPIIdentifier for a to-one relationship,
+// nil for an empty to-one relationship, or a []JSONAPIIdentifier for a to-many relationship
+type JSONAPIRelationship struct {
+	Data  any               `json:"data"`
+	Links map[string]string `json:"links,omitempty"`
+}
+
+// JSONAPIErrorsDocument is the top-level document of a JSON:API error response
+type JSONAPIErrorsDocument struct {
+	Errors []JSONAPIError `json:"errors"`
+}
+
+// JSONAPIError is an error object of a JSON:API error response. The source is a JSON pointer to the request document's invalid member, if any
+type JSONAPIError struct {
+	Status string              `json:"status,omitempty"`
+	Code   string              `json:"code,omitempty"`
+	Title  string              `json:"title,omitempty"`
+	Detail string              `json:"detail,omitempty"`
+	Source *JSONAPIErrorSource `json:"source,omitempty"`
+}
+
+// JSONAPIErrorSource is the source of a JSON:API error, a JSON pointer into the request document or a query parameter
+type JSONAPIErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// HALResource is a resource of a HAL response. The properties are a plain struct or map, encoded as the resource's JSON object,
+// with its links in _links and its embedded resources in _embedded
+type HALResource struct {
+	Properties any
+	Links      map[string]HALLink
+	Embedded   map[string][]HALResource
+}
+
+// HALLink is a link of a HAL resource, e.g. {"href": "/orders{?id}", "templated": true}
+type HALLink struct {
+	Href      string `json:"href"`
+	Templated bool   `json:"templated,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Title     string `json:"title,omitempty"`
+}
+
+// JSONAPIResourceOf returns the JSON:API resource of the type wrapping a plain struct or map
+// The value's id field becomes the resource's id, and its other fields become the resource's attributes
+func JSONAPIResourceOf(resourceType string, v any) JSONAPIResource {
+	resource := JSONAPIResource{Type: resourceType}
+	attributes := map[string]any{}
+	if b, err := json.Marshal(v); err == nil {
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.UseNumber()
+		_ = decoder.Decode(&attributes)
+	}
+	if id, ok := attributes["id"]; ok {
+		resource.ID = fmt.Sprint(id)
+		delete(attributes, "id")
+	}
+	if len(attributes) > 0 {
+		resource.Attributes = attributes
+	}
+	return resource
+}
+
+// Identifier returns the identifier of the JSON:API resource, for the relationships of other resources
+func (r JSONAPIResource) Identifier() JSONAPIIdentifier {
+	return JSONAPIIdentifier{Type: r.Type, ID: r.ID}
+}
+
+// JSONAPIToOne returns the to-one JSON:API relationship with the related resource
+func JSONAPIToOne(related JSONAPIResource) JSONAPIRelationship {
+	identifier := related.Identifier()
+	return JSONAPIRelationship{Data: &identifier}
+}
+
+// JSONAPIToMany returns the to-many JSON:API relationship with the related resources, an empty list without resources
+func JSONAPIToMany(related ...JSONAPIResource) JSONAPIRelationship {
+	identifiers := make([]JSONAPIIdentifier, len(related))
+	for i, resource := range related {
+		identifiers[i] = resource.Identifier()
+	}
+	return JSONAPIRelationship{Data: identifiers}
+}
+
+// JSONAPICall returns the stubbed call responding with the JSON:API document, with the JSON:API media type
+// A document without a self link links to the call's path
+func JSONAPICall(call Call, document JSONAPIDocument) Call {
+	if document.Links["self"] == "" {
+		document.Links = maps.Clone(document.Links)
+		if document.Links == nil {
+			document.Links = map[string]string{}
+		}
+		document.Links["self"] = "/" + strings.Trim(call.Path, "/")
+	}
+	response, _ := json.Marshal(document)
+	return mediaTypeCall(call, JSONAPIMediaType, response)
+}
+
+// JSONAPIErrorCall returns the stubbed call responding with the status code and the JSON:API errors
+// Errors without a status have the call's status code
+func JSONAPIErrorCall(call Call, statusCode int, errors ...JSONAPIError) Call {
+	errors = append([]JSONAPIError{}, errors...)
+	for i := range errors {
+		if errors[i].Status == "" {
+			errors[i].Status = fmt.Sprint(statusCode)
+		}
+	}
+	response, _ := json.Marshal(JSONAPIErrorsDocument{Errors: errors})
+	call.StatusCode = statusCode
+	return mediaTypeCall(call, JSONAPIMediaType, response)
+}
+
+// HALCall returns the stubbed call responding with the HAL resource, with the HAL media type
+// A resource without a self link links to the call's path
+func HALCall(call Call, resource HALResource) Call {
+	if resource.Links["self"].Href == "" {
+		resource.Links = maps.Clone(resource.Links)
+		if resource.Links == nil {
+			resource.Links = map[string]HALLink{}
+		}
+		resource.Links["self"] = HALLink{Href: "/" + strings.Trim(call.Path, "/")}
+	}
+	response, _ := json.Marshal(resource)
+	return mediaTypeCall(call, HALMediaType, response)
+}
+
+// MarshalJSON encodes the HAL resource's properties as a JSON object, with its _links and _embedded resources
+// The properties must encode to a JSON object, or null for a resource of only links and embedded resources
+func (r HALResource) MarshalJSON() ([]byte, error) {
+	resource := map[string]any{}
+	if r.Properties != nil {
+		b, err := json.Marshal(r.Properties)
+		if err != nil {
+			return nil, err
+		}
+		var properties map[string]json.RawMessage
+		if err := json.Unmarshal(b, &properties); err != nil {
+			return nil, fmt.Errorf("hal resource properties must be a JSON object: %w", err)
+		}
+		for key, value := range properties {
+			resource[key] = value
+		}
+	}
+	if len(r.Links) > 0 {
+		resource["_links"] = r.Links
+	}
+	if len(r.Embedded) > 0 {
+		resource["_embedded"] = r.Embedded
+	}
+	return json.Marshal(resource)
+}
+
+// mediaTypeCall returns the stubbed call responding with the response and its media type
+func mediaTypeCall(call Call, mediaType string, response []byte) Call {
+	call.Headers = maps.Clone(call.Headers)
+	if call.Headers == nil {
+		call.Headers = map[string]string{}
+	}
+	call.Headers["Content-Type"] = mediaType
+	delete(call.Headers, "Content-Length")
+	call.Response = response
+	return call
+}
@@ -0,0 +1,109 @@
+package assured
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testArticle struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Words int    `json:"words,omitempty"`
+}
+
+func TestJSONAPIResourceOf(t *testing.T) {
+	resource := JSONAPIResourceOf("articles", testArticle{ID: 1, Title: "JSON:API paints my bikeshed!", Words: 120})
+
+	require.Equal(t, "articles", resource.Type)
+	require.Equal(t, "1", resource.ID)
+	require.Equal(t, map[string]any{"title": "JSON:API paints my bikeshed!", "words": json.Number("120")}, resource.Attributes)
+	require.Equal(t, JSONAPIIdentifier{Type: "articles", ID: "1"}, resource.Identifier())
+	require.Nil(t, JSONAPIResourceOf("tags", map[string]string{"id": "go"}).Attributes)
+}
+
+func TestJSONAPICall(t *testing.T) {
+	author := JSONAPIResourceOf("people", map[string]any{"id": "9", "name": "Dan"})
+	article := JSONAPIResourceOf("articles", testArticle{ID: 1, Title: "Rails is Omakase"})
+	article.Relationships = map[string]JSONAPIRelationship{
+		"author":   JSONAPIToOne(author),
+		"comments": JSONAPIToMany(),
+	}
+
+	call := JSONAPICall(Call{Path: "articles/1", StatusCode: http.StatusOK, Headers: map[string]string{"Content-Length": "2"}},
+		JSONAPIDocument{Data: article, Included: []JSONAPIResource{author}})
+
+	require.Equal(t, map[string]string{"Content-Type": JSONAPIMediaType}, call.Headers)
+	require.JSONEq(t, `{
+		"data": {
+			"type": "articles",
+			"id": "1",
+			"attributes": {"title": "Rails is Omakase"},
+			"relationships": {
+				"author": {"data": {"type": "people", "id": "9"}},
+				"comments": {"data": []}
+			}
+		},
+		"included": [{"type": "people", "id": "9", "attributes": {"name": "Dan"}}],
+		"links": {"self": "/articles/1"}
+	}`, string(call.Response))
+
+	collection := JSONAPICall(Call{Path: "articles"}, JSONAPIDocument{Data: []JSONAPIResource{article}, Links: map[string]string{"self": "/articles?page=1"}})
+	require.JSONEq(t, `{"data": [{"type": "articles", "id": "1", "attributes": {"title": "Rails is Omakase"}, "relationships": {"author": {"data": {"type": "people", "id": "9"}}, "comments": {"data": []}}}], "links": {"self": "/articles?page=1"}}`, string(collection.Response))
+}
+
+func TestJSONAPIErrorCall(t *testing.T) {
+	call := JSONAPIErrorCall(Call{Path: "articles"}, http.StatusUnprocessableEntity,
+		JSONAPIError{Title: "Invalid Attribute", Detail: "Title must not be blank.", Source: &JSONAPIErrorSource{Pointer: "/data/attributes/title"}},
+		JSONAPIError{Status: "409", Title: "Conflict"},
+	)
+
+	require.Equal(t, http.StatusUnprocessableEntity, call.StatusCode)
+	require.Equal(t, JSONAPIMediaType, call.Headers["Content-Type"])
+	require.JSONEq(t, `{"errors": [
+		{"status": "422", "title": "Invalid Attribute", "detail": "Title must not be blank.", "source": {"pointer": "/data/attributes/title"}},
+		{"status": "409", "title": "Conflict"}
+	]}`, string(call.Response))
+}
+
+func TestHALCall(t *testing.T) {
+	call := HALCall(Call{Path: "/orders/"}, HALResource{
+		Properties: map[string]any{"total": 30, "currency": "USD"},
+		Links:      map[string]HALLink{"find": {Href: "/orders{?id}", Templated: true}},
+		Embedded: map[string][]HALResource{
+			"items": {{Properties: testArticle{ID: 1, Title: "Widget"}, Links: map[string]HALLink{"self": {Href: "/items/1"}}}},
+		},
+	})
+
+	require.Equal(t, HALMediaType, call.Headers["Content-Type"])
+	require.JSONEq(t, `{
+		"total": 30,
+		"currency": "USD",
+		"_links": {"self": {"href": "/orders"}, "find": {"href": "/orders{?id}", "templated": true}},
+		"_embedded": {"items": [{"id": 1, "title": "Widget", "_links": {"self": {"href": "/items/1"}}}]}
+	}`, string(call.Response))
+}
+
+func TestHALResourceMarshalJSONFailure(t *testing.T) {
+	_, err := json.Marshal(HALResource{Properties: []string{"not", "an", "object"}})
+
+	require.ErrorContains(t, err, "hal resource properties must be a JSON object")
+}
+
+func TestClientJSONAPICall(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(JSONAPICall(Call{Path: "articles/1", Method: http.MethodGet, StatusCode: http.StatusOK},
+		JSONAPIDocument{Data: JSONAPIResourceOf("articles", testArticle{ID: 1, Title: "Rails is Omakase"})})))
+
+	resp, err := http.Get(client.URL() + "/articles/1")
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, JSONAPIMediaType, resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"type": "articles", "id": "1", "attributes": {"title": "Rails is Omakase"}}, "links": {"self": "/articles/1"}}`, string(body))
+}
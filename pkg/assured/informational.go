@@ -0,0 +1,43 @@
+package assured
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Informational is a 1xx informational response sent before a stubbed call's final response, such as 103 Early Hints
+type Informational struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+// EarlyHints returns a 103 Early Hints informational response with the Link header values
+// e.g. EarlyHints("</style.css>; rel=preload; as=style")
+func EarlyHints(links ...string) Informational {
+	return Informational{StatusCode: http.StatusEarlyHints, Headers: map[string]string{"Link": strings.Join(links, ", ")}}
+}
+
+// inform sends the informational responses before the final response
+// Each informational response's headers are removed once it is sent, so they are not repeated in the final response
+func inform(w http.ResponseWriter, informational []Informational) {
+	header := w.Header()
+	for _, info := range informational {
+		// net/http would send an invalid informational status code as the final response, or panic
+		if !validInformationalStatus(info.StatusCode) {
+			continue
+		}
+		for key, value := range info.Headers {
+			header.Set(key, value)
+		}
+		w.WriteHeader(info.StatusCode)
+		for key := range info.Headers {
+			header.Del(key)
+		}
+	}
+}
+
+// validInformationalStatus reports whether the status code can be sent as an informational response
+// 101 Switching Protocols is a final response, so it cannot be sent before one
+func validInformationalStatus(statusCode int) bool {
+	return statusCode >= 100 && statusCode <= 199 && statusCode != http.StatusSwitchingProtocols
+}
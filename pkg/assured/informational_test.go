@@ -0,0 +1,62 @@
+package assured
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEarlyHints(t *testing.T) {
+	hints := EarlyHints("</style.css>; rel=preload; as=style", "</script.js>; rel=preload; as=script")
+
+	require.Equal(t, Informational{
+		StatusCode: http.StatusEarlyHints,
+		Headers:    map[string]string{"Link": "</style.css>; rel=preload; as=style, </script.js>; rel=preload; as=script"},
+	}, hints)
+}
+
+func TestClientInformational(t *testing.T) {
+	informational := []Informational{{StatusCode: http.StatusContinue}, EarlyHints("</style.css>; rel=preload; as=style")}
+	tests := []struct {
+		name string
+		call Call
+	}{
+		{name: "static", call: Call{Path: "hints/assured", Response: []byte("assured"), Informational: informational}},
+		{name: "sequenced", call: Call{Path: "hints/assured", StatusCodes: []int{http.StatusOK}, Response: []byte("assured"), Informational: informational}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, client := NewTestServer(t)
+			require.NoError(t, client.Given(tt.call))
+
+			var codes []int
+			var links []string
+			trace := &httptrace.ClientTrace{
+				Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+					codes = append(codes, code)
+					links = append(links, header.Get("Link"))
+					return nil
+				},
+			}
+			req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.TODO(), trace), http.MethodGet, client.URL()+"/hints/assured", nil)
+			require.NoError(t, err)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, []int{http.StatusContinue, http.StatusEarlyHints}, codes)
+			require.Equal(t, []string{"", "</style.css>; rel=preload; as=style"}, links)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Empty(t, resp.Header.Get("Link"))
+			require.Equal(t, "assured", string(body))
+		})
+	}
+}
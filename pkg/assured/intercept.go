@@ -0,0 +1,55 @@
+package assured
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+)
+
+// Intercept patches the http client's transport to route requests for the hostnames to the client's stubbed endpoints in-process
+// Requests for any other hosts pass through to the http client's original transport
+func (c *Client) Intercept(httpClient *http.Client, hostnames ...string) error {
+	if c.router == nil {
+		return errors.New("cannot intercept requests for a remote client")
+	}
+
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	hosts := map[string]bool{}
+	for _, hostname := range hostnames {
+		hosts[hostname] = true
+	}
+	httpClient.Transport = &interceptTransport{client: c, hosts: hosts, next: next}
+	return nil
+}
+
+// interceptTransport is an http.RoundTripper that serves requests for intercepted hosts with the client's router
+type interceptTransport struct {
+	client *Client
+	hosts  map[string]bool
+	next   http.RoundTripper
+}
+
+// RoundTrip serves the request in-process if its host is intercepted, else it passes the request through
+func (t *interceptTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.hosts[req.URL.Hostname()] {
+		return t.next.RoundTrip(req)
+	}
+
+	// Rewrite the request to the path the stubbed endpoints are served at
+	intercepted := req.Clone(req.Context())
+	if !t.client.rootServing {
+		intercepted.URL.Path = t.client.basePath + "/when" + req.URL.Path
+		intercepted.URL.RawPath = ""
+	}
+	intercepted.RequestURI = intercepted.URL.RequestURI()
+
+	rec := httptest.NewRecorder()
+	t.client.router.ServeHTTP(rec, intercepted)
+
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
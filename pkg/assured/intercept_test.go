@@ -0,0 +1,60 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIntercept(t *testing.T) {
+	passthrough := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer passthrough.Close()
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(*testCall1()))
+	httpClient := &http.Client{}
+
+	require.NoError(t, client.Intercept(httpClient, "api.example.com"))
+
+	resp, err := httpClient.Get("https://api.example.com/test/assured?assured=max")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"assured": true}`), body)
+
+	resp, err = httpClient.Get(passthrough.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	calls, err := client.Verify("GET", "test/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	require.Equal(t, map[string]string{"assured": "max"}, calls[0].Query)
+}
+
+func TestClientInterceptRootServing(t *testing.T) {
+	_, client := NewTestServer(t, WithRootServing(true))
+	require.NoError(t, client.Given(*testCall1()))
+	httpClient := &http.Client{}
+
+	require.NoError(t, client.Intercept(httpClient, "api.example.com"))
+
+	resp, err := httpClient.Get("http://api.example.com/test/assured?assured=max")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestClientInterceptRemote(t *testing.T) {
+	client, err := NewRemoteClient("http://localhost:9093")
+	require.NoError(t, err)
+
+	err = client.Intercept(&http.Client{}, "api.example.com")
+
+	require.Error(t, err)
+	require.Equal(t, "cannot intercept requests for a remote client", err.Error())
+}
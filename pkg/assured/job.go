@@ -0,0 +1,212 @@
+package assured
+
+import (
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job simulates an asynchronous job of an upstream, shared by name between the call that starts it and the call that polls its status
+// A call that starts the job, such as a POST responding 202 Accepted, restarts it with each request, and a call polling the job
+// responds with the state the job is in by the time since it started. Before the job is started, it is in its first state
+// A call that starts the job sends its webhook, if set, the webhook's delay in seconds after the job started, unless the job restarted
+type Job struct {
+	Name    string     `json:"name"`
+	Start   bool       `json:"start,omitempty"`
+	States  []JobState `json:"states,omitempty"`
+	Webhook *Callback  `json:"webhook,omitempty"`
+}
+
+// JobState is a state of a Job, which the job is in from the seconds after it started until the next state
+// A state without a response responds with a JSON object of its status, and a state without a status code responds 200 OK
+type JobState struct {
+	Status     string            `json:"status"`
+	After      int               `json:"after,omitempty"`
+	StatusCode int               `json:"status_code,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Response   CallResponse      `json:"response,omitempty"`
+}
+
+// jobs are the start times of the jobs started by the stubbed calls, by name
+type jobs struct {
+	started map[string]time.Time
+	sync.Mutex
+}
+
+// start restarts the job, returning the time it started
+func (j *jobs) start(name string) time.Time {
+	j.Lock()
+	defer j.Unlock()
+	if j.started == nil {
+		j.started = map[string]time.Time{}
+	}
+	started := time.Now()
+	j.started[name] = started
+	return started
+}
+
+// restarted reports whether the job restarted since the time it started
+func (j *jobs) restarted(name string, started time.Time) bool {
+	j.Lock()
+	defer j.Unlock()
+	return !j.started[name].Equal(started)
+}
+
+// elapsed returns the time since the job started, and whether it has started
+func (j *jobs) elapsed(name string) (time.Duration, bool) {
+	j.Lock()
+	defer j.Unlock()
+	started, ok := j.started[name]
+	return time.Since(started), ok
+}
+
+// state returns the index of the state the job is in after the elapsed time
+func (j Job) state(elapsed time.Duration) int {
+	state := 0
+	for i, s := range j.States {
+		if elapsed >= time.Duration(s.After)*time.Second {
+			state = i
+		}
+	}
+	return state
+}
+
+// job starts the job of the stubbed call, or returns a copy of the stubbed call responding with the state its job is in
+// A state with a later state responds with the Retry-After of the seconds until the later state
+func (a *AssuredEndpoints) job(assured *Call) *Call {
+	if assured.Job.Start {
+		started := a.jobs.start(assured.Job.Name)
+		if assured.Job.Webhook != nil {
+			a.callbacks.Add(1)
+			go func(name string, webhook Callback) {
+				defer a.callbacks.Add(-1)
+				time.Sleep(time.Duration(webhook.Delay) * time.Second)
+				if a.jobs.restarted(name, started) {
+					slog.With("job", name).Info("job restarted, skipping its webhook")
+					return
+				}
+				if webhook.Method == "" {
+					webhook.Method = http.MethodPost
+				}
+				a.sendCallback(webhook.Target, &Call{Method: webhook.Method, Headers: webhook.Headers, Response: webhook.Response})
+			}(assured.Job.Name, *assured.Job.Webhook)
+		}
+	}
+	if len(assured.Job.States) == 0 {
+		return assured
+	}
+
+	elapsed, started := a.jobs.elapsed(assured.Job.Name)
+	if !started {
+		elapsed = 0
+	}
+	index := assured.Job.state(elapsed)
+	state := assured.Job.States[index]
+
+	polled := *assured
+	polled.Headers = maps.Clone(assured.Headers)
+	if polled.Headers == nil {
+		polled.Headers = map[string]string{}
+	}
+	delete(polled.Headers, "Content-Length")
+	polled.StatusCode = http.StatusOK
+	if state.StatusCode != 0 {
+		polled.StatusCode = state.StatusCode
+	}
+	polled.Response = state.Response
+	if state.Response == nil {
+		polled.Headers["Content-Type"] = "application/json"
+		polled.Response, _ = json.Marshal(map[string]string{"status": state.Status})
+	}
+	delete(polled.Headers, "Retry-After")
+	if index+1 < len(assured.Job.States) {
+		remaining := time.Duration(assured.Job.States[index+1].After)*time.Second - elapsed
+		polled.Headers["Retry-After"] = strconv.Itoa(max(int(remaining.Round(time.Second).Seconds()), 1))
+	}
+	maps.Copy(polled.Headers, state.Headers)
+	return &polled
+}
+
+// AsyncJobWebhook returns the stubbed calls of an asynchronous job, see AsyncJobCalls, that also POST a completion webhook to the target,
+// when the job reaches its last state, with the last state's response, so both the webhook and the polling of a client can be tested
+func AsyncJobWebhook(calls []Call, target string) []Call {
+	calls = append([]Call{}, calls...)
+	var states []JobState
+	for _, call := range calls {
+		if call.Job != nil && len(call.Job.States) > 0 {
+			states = call.Job.States
+		}
+	}
+	if len(states) == 0 {
+		return calls
+	}
+	last := states[len(states)-1]
+	for i, call := range calls {
+		if call.Job == nil || !call.Job.Start {
+			continue
+		}
+		job := *call.Job
+		job.Webhook = &Callback{
+			Target:   target,
+			Method:   http.MethodPost,
+			Delay:    last.After,
+			Headers:  map[string]string{"Content-Type": "application/json"},
+			Response: last.Response,
+		}
+		calls[i].Job = &job
+	}
+	return calls
+}
+
+// AsyncJobCalls returns the stubbed calls of an asynchronous job at the path, for testing the polling of async API clients end to end
+// A POST to the path starts the job, responding 202 Accepted with the Location of its status at the path's /status,
+// which responds with the states of the job over time, and the last state links to the result, the stubbed call serving its payload
+// The links are to the base URL of the stubbed calls, e.g. client.URL(). The states are queued, running after 1 second,
+// and succeeded after 2 seconds, without states
+func AsyncJobCalls(baseURL, path string, result Call, states ...JobState) []Call {
+	baseURL = strings.TrimRight(baseURL, "/")
+	path = strings.Trim(path, "/")
+	status := path + "/status"
+	if len(states) == 0 {
+		states = []JobState{{Status: "queued"}, {Status: "running", After: 1}, {Status: "succeeded", After: 2}}
+	}
+	states = append([]JobState{}, states...)
+	if last := &states[len(states)-1]; last.Response == nil {
+		last.Response, _ = json.Marshal(map[string]string{"status": last.Status, "result": baseURL + "/" + strings.Trim(result.Path, "/")})
+		last.Headers = maps.Clone(last.Headers)
+		if last.Headers == nil {
+			last.Headers = map[string]string{}
+		}
+		last.Headers["Content-Type"] = "application/json"
+	}
+	accepted, _ := json.Marshal(map[string]string{"status": states[0].Status, "job": baseURL + "/" + status})
+
+	if result.Method == "" {
+		result.Method = http.MethodGet
+	}
+	if result.StatusCode == 0 {
+		result.StatusCode = http.StatusOK
+	}
+	return []Call{
+		{
+			Path:       path,
+			Method:     http.MethodPost,
+			StatusCode: http.StatusAccepted,
+			Headers:    map[string]string{"Content-Type": "application/json", "Location": baseURL + "/" + status},
+			Response:   accepted,
+			Job:        &Job{Name: path, Start: true},
+		},
+		{
+			Path:       status,
+			Method:     http.MethodGet,
+			StatusCode: http.StatusOK,
+			Job:        &Job{Name: path, States: states},
+		},
+		result,
+	}
+}
@@ -0,0 +1,141 @@
+package assured
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobState(t *testing.T) {
+	job := Job{States: []JobState{{Status: "queued"}, {Status: "running", After: 2}, {Status: "done", After: 5}}}
+
+	require.Equal(t, 0, job.state(0))
+	require.Equal(t, 0, job.state(1999*time.Millisecond))
+	require.Equal(t, 1, job.state(2*time.Second))
+	require.Equal(t, 2, job.state(time.Minute))
+}
+
+func TestAsyncJobCalls(t *testing.T) {
+	calls := AsyncJobCalls("http://localhost:8080/when/", "/exports/", Call{Path: "exports/result", Response: []byte("id\n1\n")}, JobState{Status: "pending"}, JobState{Status: "failed", After: 3, StatusCode: http.StatusInternalServerError})
+
+	require.Equal(t, []Call{
+		{
+			Path:       "exports",
+			Method:     http.MethodPost,
+			StatusCode: http.StatusAccepted,
+			Headers:    map[string]string{"Content-Type": "application/json", "Location": "http://localhost:8080/when/exports/status"},
+			Response:   []byte(`{"job":"http://localhost:8080/when/exports/status","status":"pending"}`),
+			Job:        &Job{Name: "exports", Start: true},
+		},
+		{
+			Path:       "exports/status",
+			Method:     http.MethodGet,
+			StatusCode: http.StatusOK,
+			Job: &Job{Name: "exports", States: []JobState{
+				{Status: "pending"},
+				{
+					Status:     "failed",
+					After:      3,
+					StatusCode: http.StatusInternalServerError,
+					Headers:    map[string]string{"Content-Type": "application/json"},
+					Response:   []byte(`{"result":"http://localhost:8080/when/exports/result","status":"failed"}`),
+				},
+			}},
+		},
+		{Path: "exports/result", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("id\n1\n")},
+	}, calls)
+}
+
+func TestClientAsyncJobCalls(t *testing.T) {
+	_, client := NewTestServer(t)
+	states := []JobState{{Status: "running"}, {Status: "succeeded", After: 1}}
+	require.NoError(t, client.Given(AsyncJobCalls(client.URL(), "reports", Call{Path: "reports/1", Response: []byte(`{"total":42}`)}, states...)...))
+
+	poll := func(url string) (*http.Response, map[string]string) {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp, body
+	}
+
+	// The job is in its first state until it is started
+	resp, body := poll(client.URL() + "/reports/status")
+	require.Equal(t, map[string]string{"status": "running"}, body)
+	require.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	resp, err := http.Post(client.URL()+"/reports", "application/json", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	location := resp.Header.Get("Location")
+	require.Equal(t, client.URL()+"/reports/status", location)
+
+	resp, body = poll(location)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, map[string]string{"status": "running"}, body)
+	require.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	time.Sleep(time.Second)
+	resp, body = poll(location)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("Retry-After"))
+	require.Equal(t, map[string]string{"status": "succeeded", "result": client.URL() + "/reports/1"}, body)
+
+	resp, err = http.Get(body["result"])
+	require.NoError(t, err)
+	result, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"total":42}`, string(result))
+}
+
+func TestAsyncJobWebhook(t *testing.T) {
+	calls := AsyncJobWebhook(AsyncJobCalls("", "exports", Call{Path: "exports/result"}), "http://localhost:9000/hooks")
+
+	require.Equal(t, &Callback{
+		Target:   "http://localhost:9000/hooks",
+		Method:   http.MethodPost,
+		Delay:    2,
+		Headers:  map[string]string{"Content-Type": "application/json"},
+		Response: []byte(`{"result":"/exports/result","status":"succeeded"}`),
+	}, calls[0].Job.Webhook)
+	require.Nil(t, calls[1].Job.Webhook)
+	require.Equal(t, AsyncJobCalls("", "exports", Call{Path: "exports/result"})[1:], calls[1:])
+}
+
+func TestClientAsyncJobWebhook(t *testing.T) {
+	_, client := NewTestServer(t)
+	states := []JobState{{Status: "running"}, {Status: "succeeded", After: 1}}
+	calls := AsyncJobWebhook(AsyncJobCalls(client.URL(), "reports", Call{Path: "reports/1", Response: []byte(`{"total":42}`)}, states...), client.URL()+"/hooks")
+	require.NoError(t, client.Given(append(calls, Call{Path: "hooks", Method: http.MethodPost, StatusCode: http.StatusOK})...))
+
+	// Restarting the job skips the webhook of the job it restarted
+	for i := 0; i < 2; i++ {
+		resp, err := http.Post(client.URL()+"/reports", "application/json", nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	}
+
+	require.Eventually(t, func() bool {
+		hooks, err := client.Verify(http.MethodPost, "hooks")
+		return err == nil && len(hooks) == 1
+	}, 3*time.Second, 50*time.Millisecond)
+	hooks, err := client.Verify(http.MethodPost, "hooks")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"status":"succeeded","result":"`+client.URL()+`/reports/1"}`, string(hooks[0].Response))
+
+	// The job is complete when its webhook is sent
+	resp, err := http.Get(client.URL() + "/reports/status")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, string(hooks[0].Response), string(body))
+
+	time.Sleep(100 * time.Millisecond)
+	hooks, err = client.Verify(http.MethodPost, "hooks")
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+}
@@ -0,0 +1,93 @@
+package assured
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// GivenFromJournal stubs the calls recorded in a made calls journal, such as the output of Verify, to replay what was seen
+// The roles of a recorded call are swapped, so the body and Content-Type it was made with become the stub's response,
+// while the rest of its request headers, TLS details, and raw URI are dropped
+// The calls made to a method and path with different queries are stubbed as branches of one call, matching each query,
+// and the most recently recorded call is replayed for a repeated query
+func (c *Client) GivenFromJournal(calls []Call) error {
+	return c.Given(journalStubs(calls)...)
+}
+
+// journalStubs converts the recorded calls into stubbed calls, in the order the method and paths were first recorded
+// The call recorded without a query, else the first query recorded, is the stub's default response,
+// and the branches are ordered by the number of query parameters they match, so the most specific branch is used
+func journalStubs(calls []Call) []Call {
+	var ids []string
+	queries := map[string][]string{}
+	recorded := map[string]map[string]Call{}
+	for _, call := range calls {
+		id, query := call.ID(), journalQuery(call.Query)
+		if _, ok := recorded[id]; !ok {
+			ids = append(ids, id)
+			recorded[id] = map[string]Call{}
+		}
+		if _, ok := recorded[id][query]; !ok {
+			queries[id] = append(queries[id], query)
+		}
+		recorded[id][query] = call
+	}
+
+	stubs := make([]Call, 0, len(ids))
+	for _, id := range ids {
+		fallback, ok := recorded[id][""]
+		if !ok {
+			fallback = recorded[id][queries[id][0]]
+		}
+		stub := journalStub(fallback)
+		if len(queries[id]) > 1 {
+			for _, query := range queries[id] {
+				if query == "" {
+					continue
+				}
+				call := recorded[id][query]
+				replayed := journalStub(call)
+				if replayed.Response == nil {
+					replayed.Response = CallResponse{}
+				}
+				stub.Branches = append(stub.Branches, Branch{
+					When:       Condition{Query: maps.Clone(call.Query)},
+					StatusCode: replayed.StatusCode,
+					Headers:    replayed.Headers,
+					Response:   replayed.Response,
+				})
+			}
+			slices.SortStableFunc(stub.Branches, func(a, b Branch) int {
+				return len(b.When.Query) - len(a.When.Query)
+			})
+		}
+		stubs = append(stubs, stub)
+	}
+	return stubs
+}
+
+// journalStub converts the recorded call into the stubbed call replaying it
+func journalStub(call Call) Call {
+	stub := Call{
+		Path:       call.Path,
+		Method:     call.Method,
+		StatusCode: call.StatusCode,
+		Headers:    map[string]string{},
+		Response:   call.Response,
+	}
+	if contentType := call.Headers["Content-Type"]; contentType != "" {
+		stub.Headers["Content-Type"] = contentType
+	}
+	return stub
+}
+
+// journalQuery returns a key identifying the recorded call's query
+func journalQuery(query map[string]string) string {
+	var pairs []string
+	for key, value := range query {
+		pairs = append(pairs, key+"="+value)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
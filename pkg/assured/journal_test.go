@@ -0,0 +1,62 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientGivenFromJournal(t *testing.T) {
+	_, recorder := NewTestServer(t)
+	require.NoError(t, recorder.Given(Call{Path: "orders", Method: http.MethodPost}, Call{Path: "orders", Method: http.MethodGet}))
+
+	req, err := http.NewRequest(http.MethodPost, recorder.URL()+"/orders", strings.NewReader(`{"id":1}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	_, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	for _, get := range []struct{ query, body string }{
+		{"?status=open", "open v1"},
+		{"", "all"},
+		{"?status=open&page=2", "open page 2"},
+		{"?status=open", "open v2"},
+	} {
+		req, err := http.NewRequest(http.MethodGet, recorder.URL()+"/orders"+get.query, strings.NewReader(get.body))
+		require.NoError(t, err)
+		_, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+	}
+	posts, err := recorder.Verify(http.MethodPost, "orders")
+	require.NoError(t, err)
+	gets, err := recorder.Verify(http.MethodGet, "orders")
+	require.NoError(t, err)
+
+	_, client := NewTestServer(t)
+	require.NoError(t, client.GivenFromJournal(append(posts, gets...)))
+
+	resp, err := http.Post(client.URL()+"/orders", "text/plain", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.Empty(t, resp.Header.Get("Authorization"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"id":1}`, string(body))
+
+	for query, expected := range map[string]string{
+		"":                    "all",
+		"?status=open":        "open v2",
+		"?page=2&status=open": "open page 2",
+		"?status=closed":      "all",
+	} {
+		resp, err := http.Get(client.URL() + "/orders" + query)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(body), query)
+	}
+}
@@ -0,0 +1,126 @@
+package assured
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// latencyBounds are the upper bounds of the buckets of the latency histograms
+var latencyBounds = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// LatencyHistogram is the distribution of the latencies a stubbed call was served with, including any latency and delay,
+// in milliseconds, so performance suites can confirm the rest assured server wasn't the bottleneck
+type LatencyHistogram struct {
+	Count   int64           `json:"count"`
+	Min     float64         `json:"min_ms"`
+	Max     float64         `json:"max_ms"`
+	Mean    float64         `json:"mean_ms"`
+	Buckets []LatencyBucket `json:"buckets"`
+}
+
+// LatencyBucket is the number of calls served within the bucket's upper bound, e.g. 25ms, and above the previous bucket's
+// The last bucket, +Inf, has no upper bound
+type LatencyBucket struct {
+	LE    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+// latencyHistogram records the latencies of a stubbed call
+type latencyHistogram struct {
+	count    int64
+	sum      time.Duration
+	min, max time.Duration
+	buckets  []int64
+}
+
+// latencies are the latency histograms of the stubbed calls, by Method/Path
+type latencies struct {
+	histograms map[string]*latencyHistogram
+	sync.Mutex
+}
+
+// record records the latency a call to the Method/Path was served with
+func (l *latencies) record(id string, latency time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	if l.histograms == nil {
+		l.histograms = map[string]*latencyHistogram{}
+	}
+	h, ok := l.histograms[id]
+	if !ok {
+		h = &latencyHistogram{min: latency, buckets: make([]int64, len(latencyBounds)+1)}
+		l.histograms[id] = h
+	}
+	h.count++
+	h.sum += latency
+	h.min = min(h.min, latency)
+	h.max = max(h.max, latency)
+	bucket := len(latencyBounds)
+	for i, bound := range latencyBounds {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+	h.buckets[bucket]++
+}
+
+// report returns the latency histograms of the stubbed calls, by Method/Path
+func (l *latencies) report() map[string]LatencyHistogram {
+	l.Lock()
+	defer l.Unlock()
+	if len(l.histograms) == 0 {
+		return nil
+	}
+	milliseconds := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	report := make(map[string]LatencyHistogram, len(l.histograms))
+	for id, h := range l.histograms {
+		histogram := LatencyHistogram{
+			Count:   h.count,
+			Min:     milliseconds(h.min),
+			Max:     milliseconds(h.max),
+			Mean:    milliseconds(h.sum) / float64(h.count),
+			Buckets: make([]LatencyBucket, len(h.buckets)),
+		}
+		for i, count := range h.buckets {
+			histogram.Buckets[i] = LatencyBucket{LE: "+Inf", Count: count}
+			if i < len(latencyBounds) {
+				histogram.Buckets[i].LE = latencyBounds[i].String()
+			}
+		}
+		report[id] = histogram
+	}
+	return report
+}
+
+// reset clears the latency histograms
+func (l *latencies) reset() {
+	l.Lock()
+	defer l.Unlock()
+	l.histograms = nil
+}
+
+// latencyHandler records the latency each call to a stubbed call is served with, from the request until the response is written
+func (a *AssuredEndpoints) latencyHandler(when http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		when.ServeHTTP(w, req)
+		if stub := matched(req.Context()).stub(); stub != nil {
+			a.latencies.record(stub.ID(), time.Since(start))
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package assured
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatenciesReport(t *testing.T) {
+	var l latencies
+	require.Nil(t, l.report())
+
+	l.record("GET:test/assured", 2*time.Millisecond)
+	l.record("GET:test/assured", 4*time.Millisecond)
+	l.record("GET:test/assured", time.Minute)
+
+	report := l.report()
+	require.Len(t, report, 1)
+	histogram := report["GET:test/assured"]
+	require.Equal(t, int64(3), histogram.Count)
+	require.Equal(t, 2.0, histogram.Min)
+	require.Equal(t, 60000.0, histogram.Max)
+	require.Equal(t, 20002.0, histogram.Mean)
+	require.Len(t, histogram.Buckets, len(latencyBounds)+1)
+	require.Equal(t, LatencyBucket{LE: "1ms", Count: 0}, histogram.Buckets[0])
+	require.Equal(t, LatencyBucket{LE: "5ms", Count: 2}, histogram.Buckets[1])
+	require.Equal(t, LatencyBucket{LE: "+Inf", Count: 1}, histogram.Buckets[len(latencyBounds)])
+
+	l.reset()
+	require.Nil(t, l.report())
+}
+
+func TestClientStatsLatencies(t *testing.T) {
+	_, client := NewTestServer(t, WithLatency(20*time.Millisecond))
+	require.NoError(t, client.Given(Call{Path: "slow/assured"}))
+
+	for i := 0; i < 3; i++ {
+		_, err := http.Get(client.URL() + "/slow/assured")
+		require.NoError(t, err)
+	}
+	_, err := http.Get(client.URL() + "/missing/assured")
+	require.NoError(t, err)
+
+	stats, err := client.Stats()
+	require.NoError(t, err)
+	require.Len(t, stats.Latencies, 1)
+	histogram := stats.Latencies["GET:slow/assured"]
+	require.Equal(t, int64(3), histogram.Count)
+	require.GreaterOrEqual(t, histogram.Min, 20.0)
+	require.GreaterOrEqual(t, histogram.Max, histogram.Min)
+
+	require.NoError(t, client.ClearJournal())
+	stats, err = client.Stats()
+	require.NoError(t, err)
+	require.Empty(t, stats.Latencies)
+}
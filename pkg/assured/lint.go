@@ -0,0 +1,53 @@
+package assured
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ValidateStubs loads the preload file, or directory of files, without starting a server and checks the calls for mistakes
+// Every problem found is returned, including load errors, conflicting duplicate calls, and unreachable branches
+func ValidateStubs(path string) error {
+	preloadCalls, fragments, err := readPreload(path)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	loaded := []PreloadCall{}
+	for _, preloadCall := range preloadCalls {
+		call, err := preloadCall.compose(fragments)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		preloadCall.Call = interpolateCall(call)
+
+		// An identical call stubbed for the same method and path is most likely a copy and paste mistake
+		for _, other := range loaded {
+			if reflect.DeepEqual(other.Call, preloadCall.Call) {
+				errs = append(errs, &PreloadError{File: preloadCall.file, Field: preloadCall.field,
+					Err: fmt.Errorf("call %s conflicts with the identical call %s in %s", preloadCall.Call.ID(), other.field, other.file)})
+				break
+			}
+		}
+		loaded = append(loaded, preloadCall)
+
+		errs = append(errs, lintBranches(preloadCall)...)
+	}
+	return errors.Join(errs...)
+}
+
+// lintBranches checks for branches that can never be used, because an earlier branch always matches
+func lintBranches(preloadCall PreloadCall) []error {
+	errs := []error{}
+	for i, branch := range preloadCall.Branches {
+		if reflect.DeepEqual(branch.When, Condition{}) && i < len(preloadCall.Branches)-1 {
+			errs = append(errs, &PreloadError{File: preloadCall.file, Field: fmt.Sprintf("%s.branches[%d]", preloadCall.field, i+1),
+				Err: fmt.Errorf("branch is unreachable, branches[%d] always matches", i)})
+			break
+		}
+	}
+	return errs
+}
@@ -0,0 +1,43 @@
+package assured
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateStubs(t *testing.T) {
+	require.NoError(t, ValidateStubs("testdata/preload"))
+}
+
+func TestValidateStubsProblems(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "01-calls.json"), []byte(`{
+		"calls": [
+			{"path": "test", "method": "GET", "response": "rotate"},
+			{"path": "test", "method": "GET", "response": "rotated"},
+			{"path": "fragment", "method": "GET", "fragments": ["missing"]},
+			{"path": "branch", "method": "GET", "branches": [{"when": {"body_contains": "a"}}, {"when": {}}, {"when": {"body_contains": "b"}}]}
+		]
+	}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "02-calls.json"), []byte(`{
+		"calls": [
+			{"path": "test", "method": "GET", "response": "rotate"}
+		]
+	}`), 0o644))
+
+	err := ValidateStubs(dir)
+
+	require.Error(t, err)
+	require.Equal(t, `invalid preload file `+filepath.Join(dir, "01-calls.json")+`: calls[2].fragments: call GET:fragment references unknown fragment "missing"
+invalid preload file `+filepath.Join(dir, "01-calls.json")+`: calls[3].branches[2]: branch is unreachable, branches[1] always matches
+invalid preload file `+filepath.Join(dir, "02-calls.json")+`: calls[0]: call GET:test conflicts with the identical call calls[0] in `+filepath.Join(dir, "01-calls.json"), err.Error())
+}
+
+func TestValidateStubsLoadError(t *testing.T) {
+	err := ValidateStubs("testdata/missing")
+
+	require.Error(t, err)
+}
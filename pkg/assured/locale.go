@@ -0,0 +1,137 @@
+package assured
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Locale selects the response of a stubbed call by the request's Accept-Language, for exercising the i18n behavior of API clients
+// The language negotiated is the most preferred of the request's languages that the locale has a response or messages for,
+// where a more specific language, e.g. fr-CA, falls back to its language, e.g. fr, or else the default language
+// The negotiated language's response replaces the call's response, and its messages are rendered in place of {{key}} partials,
+// falling back to the messages of the default language. The response has the Content-Language negotiated
+type Locale struct {
+	Default   string                       `json:"default,omitempty"`
+	Responses map[string]CallResponse      `json:"responses,omitempty"`
+	Messages  map[string]map[string]string `json:"messages,omitempty"`
+}
+
+// languages returns the languages the locale has a response or messages for
+func (l Locale) languages() []string {
+	var languages []string
+	for language := range l.Responses {
+		languages = append(languages, language)
+	}
+	for language := range l.Messages {
+		if !slices.Contains(languages, language) {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// negotiate returns the language of the locale negotiated by the Accept-Language header, or the default language
+func (l Locale) negotiate(acceptLanguage string) string {
+	languages := l.languages()
+	for _, tag := range acceptLanguages(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+		// Fall back from a more specific tag to its prefixes, e.g. zh-Hant-TW, zh-Hant, then zh
+		for ; tag != ""; tag, _, _ = cutLast(tag, "-") {
+			for _, language := range languages {
+				if strings.EqualFold(language, tag) {
+					return language
+				}
+			}
+		}
+	}
+	return l.Default
+}
+
+// localized returns a copy of the stubbed call responding in the language negotiated by the call made's Accept-Language
+// If the call has no locale, or no language is negotiated, the stubbed call is returned
+func (c *Call) localized(made *Call) *Call {
+	if c.Locale == nil {
+		return c
+	}
+	language := c.Locale.negotiate(made.Headers["Accept-Language"])
+	if language == "" {
+		return c
+	}
+
+	localized := *c
+	localized.Headers = maps.Clone(c.Headers)
+	if localized.Headers == nil {
+		localized.Headers = map[string]string{}
+	}
+	if response, ok := c.Locale.Responses[language]; ok {
+		localized.Response = response
+	}
+	messages := maps.Clone(c.Locale.Messages[c.Locale.Default])
+	if messages == nil {
+		messages = map[string]string{}
+	}
+	maps.Copy(messages, c.Locale.Messages[language])
+	response := []byte(localized.Response)
+	for key, message := range messages {
+		response = bytes.ReplaceAll(response, []byte("{{"+key+"}}"), []byte(message))
+	}
+	// The stubbed content length no longer applies to the localized response
+	delete(localized.Headers, "Content-Length")
+	localized.Response = response
+	localized.Headers["Content-Language"] = language
+	localized.Headers["Vary"] = "Accept-Language"
+	return &localized
+}
+
+// acceptLanguages returns the language tags of an Accept-Language header, from the most to the least preferred by their quality
+// Tags with a quality of 0 are not acceptable, and are excluded
+func acceptLanguages(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > 0 {
+			tags = append(tags, weighted{tag: tag, quality: quality})
+		}
+	}
+	slices.SortStableFunc(tags, func(a, b weighted) int {
+		switch {
+		case a.quality > b.quality:
+			return -1
+		case a.quality < b.quality:
+			return 1
+		}
+		return 0
+	})
+	languages := make([]string, len(tags))
+	for i, tag := range tags {
+		languages[i] = tag.tag
+	}
+	return languages
+}
+
+// cutLast slices s around the last instance of sep, returning the text before and after it, or an empty before if sep is not in s
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return "", s, false
+}
@@ -0,0 +1,107 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptLanguages(t *testing.T) {
+	require.Equal(t, []string{"fr-CA", "en", "fr", "*"}, acceptLanguages("fr;q=0.9, de;q=0, en;q=0.95, fr-CA, *;q=0.1"))
+	require.Equal(t, []string{"en-US", "en"}, acceptLanguages("en-US,en;q=invalid"))
+	require.Empty(t, acceptLanguages(""))
+}
+
+func TestLocaleNegotiate(t *testing.T) {
+	locale := Locale{
+		Default:   "en",
+		Responses: map[string]CallResponse{"en": []byte("Hello"), "zh-Hant": []byte("你好")},
+		Messages:  map[string]map[string]string{"fr": {"greeting": "Bonjour"}},
+	}
+
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{acceptLanguage: "fr", want: "fr"},
+		{acceptLanguage: "FR-ca", want: "fr"},
+		{acceptLanguage: "zh-Hant-TW", want: "zh-Hant"},
+		{acceptLanguage: "de, fr;q=0.5", want: "fr"},
+		{acceptLanguage: "de, *;q=0.5, fr;q=0.1", want: "en"},
+		{acceptLanguage: "fr;q=0, de", want: "en"},
+		{acceptLanguage: "", want: "en"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.acceptLanguage, func(t *testing.T) {
+			require.Equal(t, tc.want, locale.negotiate(tc.acceptLanguage))
+		})
+	}
+	require.Empty(t, Locale{Messages: map[string]map[string]string{"fr": {}}}.negotiate("de"))
+}
+
+func TestCallLocalized(t *testing.T) {
+	call := &Call{
+		Headers:  map[string]string{"Content-Length": "27", "Content-Type": "application/json"},
+		Response: []byte(`{"message":"{{greeting}}, {{name}}!"}`),
+		Locale: &Locale{
+			Default:   "en",
+			Responses: map[string]CallResponse{"ja": []byte(`{"message":"{{greeting}}"}`)},
+			Messages: map[string]map[string]string{
+				"en": {"greeting": "Hello", "name": "friend"},
+				"fr": {"greeting": "Bonjour"},
+				"ja": {"greeting": "こんにちは"},
+			},
+		},
+	}
+
+	fr := call.localized(&Call{Headers: map[string]string{"Accept-Language": "fr-FR,fr;q=0.9"}})
+	require.Equal(t, `{"message":"Bonjour, friend!"}`, string(fr.Response))
+	require.Equal(t, map[string]string{"Content-Type": "application/json", "Content-Language": "fr", "Vary": "Accept-Language"}, fr.Headers)
+
+	ja := call.localized(&Call{Headers: map[string]string{"Accept-Language": "ja"}})
+	require.Equal(t, `{"message":"こんにちは"}`, string(ja.Response))
+
+	en := call.localized(&Call{Headers: map[string]string{}})
+	require.Equal(t, `{"message":"Hello, friend!"}`, string(en.Response))
+	require.Equal(t, "en", en.Headers["Content-Language"])
+
+	require.Equal(t, `{"message":"{{greeting}}, {{name}}!"}`, string(call.Response), "the stubbed call is not modified")
+	unlocalized := &Call{Response: []byte("hi")}
+	require.Same(t, unlocalized, unlocalized.localized(&Call{}))
+}
+
+func TestClientLocale(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{
+		Path:       "greeting",
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Response:   []byte("{{greeting}}"),
+		Locale:     &Locale{Default: "en", Messages: map[string]map[string]string{"en": {"greeting": "Hello"}, "es": {"greeting": "Hola"}}},
+		Branches:   []Branch{{When: Condition{Query: map[string]string{"fail": "true"}}, StatusCode: http.StatusInternalServerError}},
+	}))
+
+	get := func(query, acceptLanguage string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, client.URL()+"/greeting"+query, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+	resp, body := get("", "es-MX, en;q=0.8")
+	require.Equal(t, "Hola", body)
+	require.Equal(t, "es", resp.Header.Get("Content-Language"))
+	require.Equal(t, "Accept-Language", resp.Header.Get("Vary"))
+
+	_, body = get("", "de")
+	require.Equal(t, "Hello", body)
+
+	resp, body = get("?fail=true", "es")
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.Equal(t, "Hola", body)
+}
@@ -0,0 +1,77 @@
+package assured
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// matchKey is the context key of the stubbed calls matched for a call made to the when endpoint
+type matchKey struct{}
+
+// match is the stubbed calls matched for a call made, resolved once so the when endpoint and each of its middleware act on the same stubbed call
+type match struct {
+	// candidates are the calls stubbed for the call made's path, before matching its query parameters and required headers
+	candidates []*Call
+	// calls are the candidates stubbed with the most query parameters and required headers the call made has
+	calls []*Call
+	// next is the call of the calls served next, in turn
+	next *Call
+	// path is the path the calls are stubbed for, the call made's path without its matrix parameters if they are matched without them
+	path string
+}
+
+// stub returns the stubbed call served for the call made, or nil if no call is stubbed for it
+func (m *match) stub() *Call {
+	if m == nil {
+		return nil
+	}
+	return m.next
+}
+
+// match matches the call made to the calls stubbed for its path, or its path without its matrix parameters, or else to the calls
+// stubbed with a path regex, and then to the stubbed calls' query parameters and required headers
+func (a *AssuredEndpoints) match(call *Call) *match {
+	m := &match{path: call.Path, candidates: a.assuredCalls.Get(call.ID())}
+	// Match the path without its matrix parameters, if no call is stubbed with them
+	if len(m.candidates) == 0 && len(call.Matrix) > 0 {
+		path, _ := parseMatrix(call.Path)
+		if calls := a.assuredCalls.Get(call.Method + ":" + path); len(calls) > 0 {
+			m.candidates, m.path = calls, path
+		}
+	}
+	// Match the calls stubbed with a path regex, if no call is stubbed for the path
+	if len(m.candidates) == 0 {
+		m.candidates = a.pathRegexCalls(call.Method, call.Path)
+	}
+	// Match the stubbed calls' query parameters and required headers, if they are stubbed with any
+	m.calls = matchRequest(m.candidates, call)
+	m.next = a.assuredCalls.Next(m.calls)
+	return m
+}
+
+// matched returns the stubbed calls matched for the call made to the when endpoint, or nil if they weren't matched by the matchHandler
+func matched(ctx context.Context) *match {
+	m, _ := ctx.Value(matchKey{}).(*match)
+	return m
+}
+
+// matchHandler matches the call made to the stubbed calls once, before the when endpoint's middleware, from the call's method, path,
+// query parameters, and headers, which is all the stubbed calls are matched by, so the body is left unread
+func (a *AssuredEndpoints) matchHandler(when http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		call := &Call{Method: req.Method, Path: mux.Vars(req)["path"], Query: map[string]string{}, Headers: map[string]string{}}
+		if m := req.Header.Get(AssuredMethod); m != "" {
+			call.Method = m
+		}
+		for key, value := range req.URL.Query() {
+			call.Query[key] = value[0]
+		}
+		for key, value := range req.Header {
+			call.Headers[key] = value[0]
+		}
+		_, call.Matrix = parseMatrix(call.Path)
+		when.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), matchKey{}, a.match(call))))
+	})
+}
@@ -0,0 +1,66 @@
+package assured
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	orders := &Call{Path: "orders", Method: http.MethodGet}
+	acme := &Call{Path: "orders", Method: http.MethodGet, RequiredHeaders: map[string]HeaderMatcher{"X-Tenant": {Equals: "acme"}}}
+	user := &Call{Path: "users/{id}", Method: http.MethodGet}
+	for _, call := range []*Call{orders, acme, user} {
+		_, err := endpoints.GivenEndpoint(context.TODO(), call)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name string
+		call *Call
+		want *Call
+		path string
+	}{
+		{name: "literal", call: &Call{Path: "orders", Method: http.MethodGet}, want: orders, path: "orders"},
+		{name: "required headers", call: &Call{Path: "orders", Method: http.MethodGet, Headers: map[string]string{"X-Tenant": "acme"}}, want: acme, path: "orders"},
+		{name: "matrix", call: &Call{Path: "orders;version=2", Method: http.MethodGet, Matrix: map[string]string{"version": "2"}}, want: orders, path: "orders"},
+		{name: "template", call: &Call{Path: "users/42", Method: http.MethodGet}, want: user, path: "users/42"},
+		{name: "not stubbed", call: &Call{Path: "invoices", Method: http.MethodGet}, path: "invoices"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := endpoints.match(tc.call)
+			require.Same(t, tc.want, m.stub())
+			require.Equal(t, tc.path, m.path)
+		})
+	}
+	require.Nil(t, matched(context.TODO()).stub())
+}
+
+func TestClientMatchedStubLimits(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(
+		Call{Path: "uploads", Method: http.MethodPost, StatusCode: http.StatusOK},
+		Call{Path: "uploads", Method: http.MethodPost, StatusCode: http.StatusOK, MaxBodySize: 4, RequiredHeaders: map[string]HeaderMatcher{"X-Tenant": {Equals: "acme"}}},
+		Call{Path: "orders", Method: http.MethodGet, StatusCode: http.StatusOK, MaxURILength: 16},
+	))
+
+	req, err := http.NewRequest(http.MethodPost, client.URL()+"/uploads", bytes.NewBufferString("too large"))
+	require.NoError(t, err)
+	req.Header.Set("X-Tenant", "acme")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp, err = http.Post(client.URL()+"/uploads", "text/plain", bytes.NewBufferString("too large"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(client.URL() + "/orders;version=2")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestURITooLong, resp.StatusCode)
+}
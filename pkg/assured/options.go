@@ -1,13 +1,21 @@
 package assured
 
 import (
+	"crypto/tls"
 	"net/http"
+	"strings"
+	"time"
 )
 
+// RootServingBasePath is the path prefix the rest assured endpoints are served under when serving stubbed endpoints at the root
+const RootServingBasePath = "/__assured__"
+
 var DefaultOptions = Options{
-	httpClient:     http.DefaultClient,
-	host:           "localhost",
-	trackMadeCalls: true,
+	httpClient:       http.DefaultClient,
+	host:             "localhost",
+	trackMadeCalls:   true,
+	stubHistory:      10,
+	inferContentType: true,
 }
 
 // Option is a function on that configures rest assured settings
@@ -24,14 +32,114 @@ type Options struct {
 	// port for the rest assured server to listen on. Defaults to any available port.
 	Port int
 
+	// portRetries is the number of subsequent ports to try if the configured port is unavailable. Defaults to 0.
+	portRetries int
+
+	// portMin and portMax are the inclusive range of ports to find an available port in. Overrides the port option.
+	portMin int
+	portMax int
+
+	// portFile is the location to write the port the rest assured server is listening on.
+	portFile string
+
+	// basePath is the path prefix the rest assured endpoints are served under. Defaults to no prefix.
+	basePath string
+
+	// rootServing toggles serving the stubbed endpoints at the root path, without the /when prefix.
+	// The rest assured endpoints are served under RootServingBasePath unless a basePath is set. Defaults to false.
+	rootServing bool
+
 	// tlsCertFile is the location of the tls cert for serving https.
 	tlsCertFile string
 
 	// tlsKeyFile is the location of the tls key for serving https.
 	tlsKeyFile string
 
+	// latency is the network latency to simulate for every stubbed endpoint call, applied before matching a stubbed call.
+	// A call's delay simulates upstream processing time and is applied after matching. Defaults to 0.
+	latency time.Duration
+
+	// maxBodySize is the size in bytes of the largest request body accepted by the stubbed endpoints, responding 413 Request Entity Too Large to larger bodies.
+	// A call's max body size overrides it. Defaults to no limit.
+	maxBodySize int64
+
+	// maxHeaderSize is the size in bytes of the largest request headers accepted by the stubbed endpoints, responding 431 Request Header Fields Too Large to larger headers.
+	// A call's max header size overrides it. Defaults to no limit.
+	maxHeaderSize int
+
+	// maxURILength is the length in bytes of the longest request URI accepted by the stubbed endpoints, responding 414 URI Too Long to longer URIs.
+	// A call's max URI length overrides it. Defaults to no limit.
+	maxURILength int
+
 	// trackMadeCalls toggles storing the requests made against the rest assured server. Defaults to true.
 	trackMadeCalls bool
+
+	// journalTTL is how long the made calls are kept before they are purged in the background. Defaults to keeping them forever.
+	journalTTL time.Duration
+
+	// pprof toggles serving the net/http/pprof profiling endpoints under /debug/pprof alongside the rest assured endpoints. Defaults to false.
+	pprof bool
+
+	// maxIdleConns is the number of idle connections the http client keeps open to the rest assured server. Defaults to the http client's transport.
+	maxIdleConns int
+
+	// idleConnTimeout is how long the http client keeps idle connections to the rest assured server open. Defaults to the http client's transport.
+	idleConnTimeout time.Duration
+
+	// clientTimeout is the time limit for requests made by the http client to the rest assured server. Defaults to the http client's timeout.
+	clientTimeout time.Duration
+
+	// serverReadTimeout, serverWriteTimeout, and serverIdleTimeout are the rest assured server's timeouts
+	// for reading requests, writing responses, and keeping idle keep-alive connections open. Defaults to no timeouts.
+	serverReadTimeout  time.Duration
+	serverWriteTimeout time.Duration
+	serverIdleTimeout  time.Duration
+
+	// plainHandlers toggles serving the rest assured endpoints with plain net/http handlers instead of go-kit servers. Defaults to false.
+	plainHandlers bool
+
+	// responseValidation toggles checking stubbed responses parse as their declared Content-Type, as they're served: warn or strict. Defaults to no validation.
+	responseValidation ResponseValidation
+
+	// errorFormat is the representation of the rest assured server's error responses: text, json, problem, or xml. Defaults to text.
+	errorFormat ErrorFormat
+
+	// tlsFault breaks the TLS handshake of the rest assured server in a configurable way. Defaults to no fault.
+	tlsFault TLSFault
+
+	// tlsFaultCert is the certificate served with the tls fault, generated when the client is created.
+	tlsFaultCert *tls.Certificate
+
+	// autoTLS toggles serving HTTPS with generated certificates, signed by a generated certificate authority. Defaults to false.
+	autoTLS bool
+
+	// certLifetime is how long each auto tls certificate is valid for. Defaults to 24 hours.
+	certLifetime time.Duration
+
+	// certRotation is the interval the auto tls certificate is rotated at. Defaults to never rotating.
+	certRotation time.Duration
+
+	// autoCerts are the certificates served with the auto tls option, generated when the client is created.
+	autoCerts *autoCertificates
+
+	// s3Buckets are the buckets mocked with S3 object storage semantics. Defaults to none.
+	s3Buckets []string
+
+	// tusEndpoints are the paths of the upload endpoints mocked with tus resumable upload semantics. Defaults to none.
+	tusEndpoints []string
+
+	// stubHistory is the number of stub set revisions kept to roll back to. Defaults to 10.
+	stubHistory int
+
+	// inferContentType toggles setting the Content-Type of stubbed calls' responses that look like JSON or XML, if they aren't stubbed with one. Defaults to true.
+	inferContentType bool
+
+	// rawURI toggles capturing the raw request URI of the calls made, as it was sent on the request line. Defaults to false.
+	rawURI bool
+
+	// counterFile is the location to persist the counters of the counter placeholders in responses, so they continue across restarts.
+	// Defaults to keeping the counters in memory.
+	counterFile string
 }
 
 // WithHTTPClient sets the http client option.
@@ -55,6 +163,42 @@ func WithPort(p int) Option {
 	}
 }
 
+// WithPortRetries sets the portRetries option.
+func WithPortRetries(r int) Option {
+	return func(o *Options) {
+		o.portRetries = r
+	}
+}
+
+// WithPortRange sets the portMin and portMax options.
+func WithPortRange(min, max int) Option {
+	return func(o *Options) {
+		o.portMin = min
+		o.portMax = max
+	}
+}
+
+// WithPortFile sets the portFile option.
+func WithPortFile(path string) Option {
+	return func(o *Options) {
+		o.portFile = path
+	}
+}
+
+// WithBasePath sets the basePath option.
+func WithBasePath(p string) Option {
+	return func(o *Options) {
+		o.basePath = strings.TrimRight("/"+strings.Trim(p, "/"), "/")
+	}
+}
+
+// WithRootServing sets the rootServing option.
+func WithRootServing(r bool) Option {
+	return func(o *Options) {
+		o.rootServing = r
+	}
+}
+
 // WithTLS sets the tls options.
 func WithTLS(cert, key string) Option {
 	return func(o *Options) {
@@ -63,6 +207,34 @@ func WithTLS(cert, key string) Option {
 	}
 }
 
+// WithLatency sets the latency option.
+func WithLatency(l time.Duration) Option {
+	return func(o *Options) {
+		o.latency = l
+	}
+}
+
+// WithMaxBodySize sets the maxBodySize option.
+func WithMaxBodySize(n int64) Option {
+	return func(o *Options) {
+		o.maxBodySize = n
+	}
+}
+
+// WithMaxHeaderSize sets the maxHeaderSize option.
+func WithMaxHeaderSize(n int) Option {
+	return func(o *Options) {
+		o.maxHeaderSize = n
+	}
+}
+
+// WithMaxURILength sets the maxURILength option.
+func WithMaxURILength(n int) Option {
+	return func(o *Options) {
+		o.maxURILength = n
+	}
+}
+
 // WithCallTracking sets the trackMadeCalls option.
 func WithCallTracking(t bool) Option {
 	return func(o *Options) {
@@ -70,6 +242,183 @@ func WithCallTracking(t bool) Option {
 	}
 }
 
+// WithJournalTTL sets the journalTTL option.
+func WithJournalTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.journalTTL = d
+	}
+}
+
+// WithPprof sets the pprof option.
+func WithPprof(p bool) Option {
+	return func(o *Options) {
+		o.pprof = p
+	}
+}
+
+// WithPlainHandlers sets the plainHandlers option.
+func WithPlainHandlers(p bool) Option {
+	return func(o *Options) {
+		o.plainHandlers = p
+	}
+}
+
+// WithResponseValidation sets the responseValidation option.
+func WithResponseValidation(v ResponseValidation) Option {
+	return func(o *Options) {
+		o.responseValidation = v
+	}
+}
+
+// WithErrorFormat sets the errorFormat option.
+func WithErrorFormat(f ErrorFormat) Option {
+	return func(o *Options) {
+		o.errorFormat = f
+	}
+}
+
+// WithTLSFault sets the tlsFault option.
+func WithTLSFault(f TLSFault) Option {
+	return func(o *Options) {
+		o.tlsFault = f
+	}
+}
+
+// WithAutoTLS sets the autoTLS, certLifetime, and certRotation options.
+func WithAutoTLS(lifetime, rotation time.Duration) Option {
+	return func(o *Options) {
+		o.autoTLS = true
+		o.certLifetime = lifetime
+		o.certRotation = rotation
+	}
+}
+
+// WithS3Buckets sets the s3Buckets option.
+func WithS3Buckets(buckets ...string) Option {
+	return func(o *Options) {
+		o.s3Buckets = buckets
+	}
+}
+
+// WithTusEndpoints sets the tusEndpoints option.
+func WithTusEndpoints(paths ...string) Option {
+	return func(o *Options) {
+		o.tusEndpoints = paths
+	}
+}
+
+// WithContentTypeInference sets the inferContentType option.
+func WithContentTypeInference(i bool) Option {
+	return func(o *Options) {
+		o.inferContentType = i
+	}
+}
+
+// WithRawURI sets the rawURI option.
+func WithRawURI(r bool) Option {
+	return func(o *Options) {
+		o.rawURI = r
+	}
+}
+
+// WithCounterFile sets the counterFile option.
+func WithCounterFile(path string) Option {
+	return func(o *Options) {
+		o.counterFile = path
+	}
+}
+
+// WithStubHistory sets the stubHistory option.
+func WithStubHistory(n int) Option {
+	return func(o *Options) {
+		o.stubHistory = n
+	}
+}
+
+// WithConnectionPool sets the maxIdleConns and idleConnTimeout options.
+func WithConnectionPool(maxIdleConns int, idleConnTimeout time.Duration) Option {
+	return func(o *Options) {
+		o.maxIdleConns = maxIdleConns
+		o.idleConnTimeout = idleConnTimeout
+	}
+}
+
+// WithClientTimeout sets the clientTimeout option.
+func WithClientTimeout(t time.Duration) Option {
+	return func(o *Options) {
+		o.clientTimeout = t
+	}
+}
+
+// WithServerTimeouts sets the serverReadTimeout, serverWriteTimeout, and serverIdleTimeout options.
+func WithServerTimeouts(read, write, idle time.Duration) Option {
+	return func(o *Options) {
+		o.serverReadTimeout = read
+		o.serverWriteTimeout = write
+		o.serverIdleTimeout = idle
+	}
+}
+
+// tunedHTTPClient returns a copy of the http client with the connection pool and timeout options applied
+// The http client is returned as is if none are set, so a shared client such as http.DefaultClient is never modified
+func (o *Options) tunedHTTPClient() *http.Client {
+	if o.maxIdleConns == 0 && o.idleConnTimeout == 0 && o.clientTimeout == 0 && o.tlsFault == "" && !o.autoTLS {
+		return o.httpClient
+	}
+	client := *o.httpClient
+	if o.clientTimeout > 0 {
+		client.Timeout = o.clientTimeout
+	}
+	if o.maxIdleConns > 0 || o.idleConnTimeout > 0 {
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok || transport == nil {
+			transport = http.DefaultTransport.(*http.Transport)
+		}
+		transport = transport.Clone()
+		if o.maxIdleConns > 0 {
+			transport.MaxIdleConns = o.maxIdleConns
+			transport.MaxIdleConnsPerHost = o.maxIdleConns
+		}
+		if o.idleConnTimeout > 0 {
+			transport.IdleConnTimeout = o.idleConnTimeout
+		}
+		client.Transport = transport
+	}
+	if o.tlsFault != "" || o.autoTLS {
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok || transport == nil {
+			transport = http.DefaultTransport.(*http.Transport)
+		}
+		transport = transport.Clone()
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		o.trustServerTLS(transport.TLSClientConfig)
+		client.Transport = transport
+	}
+	return &client
+}
+
+// ports returns the ports to attempt to listen on, in order
+func (o *Options) ports() []int {
+	if o.portMin > 0 {
+		ports := []int{}
+		for port := o.portMin; port <= o.portMax; port++ {
+			ports = append(ports, port)
+		}
+		return ports
+	}
+	if o.Port == 0 {
+		return []int{0}
+	}
+
+	ports := make([]int, o.portRetries+1)
+	for i := range ports {
+		ports[i] = o.Port + i
+	}
+	return ports
+}
+
 func (o *Options) applyOptions(opts ...Option) {
 	for _, opt := range opts {
 		opt(o)
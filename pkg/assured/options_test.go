@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_applyOptions(t *testing.T) {
@@ -33,6 +34,75 @@ func Test_applyOptions(t *testing.T) {
 				Port: 8889,
 			},
 		},
+		{
+			name:   "with port retries",
+			option: WithPortRetries(3),
+			want: Options{
+				portRetries: 3,
+			},
+		},
+		{
+			name:   "with port range",
+			option: WithPortRange(9000, 9010),
+			want: Options{
+				portMin: 9000,
+				portMax: 9010,
+			},
+		},
+		{
+			name:   "with port file",
+			option: WithPortFile("assured.port"),
+			want: Options{
+				portFile: "assured.port",
+			},
+		},
+		{
+			name:   "with base path",
+			option: WithBasePath("mock/"),
+			want: Options{
+				basePath: "/mock",
+			},
+		},
+		{
+			name:   "with empty base path",
+			option: WithBasePath("/"),
+			want:   Options{},
+		},
+		{
+			name:   "with root serving",
+			option: WithRootServing(true),
+			want: Options{
+				rootServing: true,
+			},
+		},
+		{
+			name:   "with latency",
+			option: WithLatency(time.Second),
+			want: Options{
+				latency: time.Second,
+			},
+		},
+		{
+			name:   "with max body size",
+			option: WithMaxBodySize(1024),
+			want: Options{
+				maxBodySize: 1024,
+			},
+		},
+		{
+			name:   "with max header size",
+			option: WithMaxHeaderSize(8192),
+			want: Options{
+				maxHeaderSize: 8192,
+			},
+		},
+		{
+			name:   "with max uri length",
+			option: WithMaxURILength(2048),
+			want: Options{
+				maxURILength: 2048,
+			},
+		},
 		{
 			name:   "with track",
 			option: WithCallTracking(true),
@@ -40,6 +110,102 @@ func Test_applyOptions(t *testing.T) {
 				trackMadeCalls: true,
 			},
 		},
+		{
+			name:   "with journal ttl",
+			option: WithJournalTTL(time.Hour),
+			want: Options{
+				journalTTL: time.Hour,
+			},
+		},
+		{
+			name:   "with pprof",
+			option: WithPprof(true),
+			want: Options{
+				pprof: true,
+			},
+		},
+		{
+			name:   "with plain handlers",
+			option: WithPlainHandlers(true),
+			want: Options{
+				plainHandlers: true,
+			},
+		},
+		{
+			name:   "with tls fault",
+			option: WithTLSFault(TLSFaultExpired),
+			want: Options{
+				tlsFault: TLSFaultExpired,
+			},
+		},
+		{
+			name:   "with auto tls",
+			option: WithAutoTLS(time.Minute, time.Second),
+			want: Options{
+				autoTLS:      true,
+				certLifetime: time.Minute,
+				certRotation: time.Second,
+			},
+		},
+		{
+			name:   "with s3 buckets",
+			option: WithS3Buckets("uploads", "reports"),
+			want: Options{
+				s3Buckets: []string{"uploads", "reports"},
+			},
+		},
+		{
+			name:   "with tus endpoints",
+			option: WithTusEndpoints("files"),
+			want: Options{
+				tusEndpoints: []string{"files"},
+			},
+		},
+		{
+			name:   "with counter file",
+			option: WithCounterFile("counters.json"),
+			want: Options{
+				counterFile: "counters.json",
+			},
+		},
+		{
+			name:   "with stub history",
+			option: WithStubHistory(5),
+			want: Options{
+				stubHistory: 5,
+			},
+		},
+		{
+			name:   "with raw uri",
+			option: WithRawURI(true),
+			want: Options{
+				rawURI: true,
+			},
+		},
+		{
+			name:   "with connection pool",
+			option: WithConnectionPool(100, time.Minute),
+			want: Options{
+				maxIdleConns:    100,
+				idleConnTimeout: time.Minute,
+			},
+		},
+		{
+			name:   "with client timeout",
+			option: WithClientTimeout(time.Second),
+			want: Options{
+				clientTimeout: time.Second,
+			},
+		},
+		{
+			name:   "with server timeouts",
+			option: WithServerTimeouts(time.Second, 2*time.Second, time.Minute),
+			want: Options{
+				serverReadTimeout:  time.Second,
+				serverWriteTimeout: 2 * time.Second,
+				serverIdleTimeout:  time.Minute,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -51,3 +217,23 @@ func Test_applyOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestTunedHTTPClient(t *testing.T) {
+	o := Options{httpClient: http.DefaultClient}
+	if got := o.tunedHTTPClient(); got != http.DefaultClient {
+		t.Errorf("tunedHTTPClient() = %v, want http.DefaultClient", got)
+	}
+
+	o = Options{httpClient: http.DefaultClient, maxIdleConns: 100, idleConnTimeout: time.Minute, clientTimeout: time.Second}
+	client := o.tunedHTTPClient()
+	if client == http.DefaultClient || client.Timeout != time.Second {
+		t.Errorf("tunedHTTPClient() = %v, want a copy with a %v timeout", client, time.Second)
+	}
+	transport := client.Transport.(*http.Transport)
+	if transport.MaxIdleConns != 100 || transport.MaxIdleConnsPerHost != 100 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("tunedHTTPClient() transport = %v, want 100 idle conns for %v", transport, time.Minute)
+	}
+	if http.DefaultClient.Timeout != 0 || http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost != 0 {
+		t.Errorf("tunedHTTPClient() modified the default http client")
+	}
+}
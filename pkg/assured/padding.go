@@ -0,0 +1,38 @@
+package assured
+
+import (
+	"bytes"
+	"crypto/rand"
+)
+
+// paddingText is the repeated content of compressible padding
+const paddingText = "go rest assured "
+
+// Padding sizes the response of a stubbed call to an exact number of bytes, so throughput tests can sweep payload sizes
+// The response is padded with compressible text, or random bytes if incompressible, or truncated if it is larger than the size
+// A response without a body is generated entirely. The response is padded when the call is stubbed, so it responds as fast as any call
+type Padding struct {
+	Size           int  `json:"size"`
+	Incompressible bool `json:"incompressible,omitempty"`
+}
+
+// PaddedCall returns the stubbed call responding with its response padded to the size in bytes, see Padding
+func PaddedCall(call Call, size int, incompressible bool) Call {
+	call.Padding = &Padding{Size: size, Incompressible: incompressible}
+	return call
+}
+
+// pad returns the response padded, or truncated, to the padding's size
+func (p Padding) pad(response []byte) []byte {
+	size := max(p.Size, 0)
+	if len(response) >= size {
+		return response[:size:size]
+	}
+	padding := make([]byte, size-len(response))
+	if p.Incompressible {
+		_, _ = rand.Read(padding)
+	} else {
+		copy(padding, bytes.Repeat([]byte(paddingText), len(padding)/len(paddingText)+1))
+	}
+	return append(append(make([]byte, 0, size), response...), padding...)
+}
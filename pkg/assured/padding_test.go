@@ -0,0 +1,64 @@
+package assured
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaddingPad(t *testing.T) {
+	tests := []struct {
+		name     string
+		padding  Padding
+		response string
+		want     string
+	}{
+		{name: "generated", padding: Padding{Size: 20}, want: "go rest assured go r"},
+		{name: "padded", padding: Padding{Size: 20}, response: `{"id":1}`, want: `{"id":1}go rest assu`},
+		{name: "truncated", padding: Padding{Size: 4}, response: `{"id":1}`, want: `{"id`},
+		{name: "empty", padding: Padding{Size: 0}, response: `{"id":1}`, want: ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, string(tc.padding.pad([]byte(tc.response))))
+		})
+	}
+}
+
+func TestPaddingPadCompressibility(t *testing.T) {
+	compressed := func(b []byte) int {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write(b)
+		_ = w.Close()
+		return buf.Len()
+	}
+
+	compressible := Padding{Size: 1 << 16}.pad(nil)
+	incompressible := Padding{Size: 1 << 16, Incompressible: true}.pad(nil)
+
+	require.Len(t, compressible, 1<<16)
+	require.Len(t, incompressible, 1<<16)
+	require.Less(t, compressed(compressible), 1<<10)
+	require.Greater(t, compressed(incompressible), 1<<16)
+}
+
+func TestClientPaddedCall(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(
+		PaddedCall(Call{Path: "small", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte(`{"id":1}`)}, 1000, false),
+		PaddedCall(Call{Path: "large", Method: http.MethodGet, StatusCode: http.StatusOK}, 1<<20, true),
+	))
+
+	for path, size := range map[string]int{"small": 1000, "large": 1 << 20} {
+		resp, err := http.Get(client.URL() + "/" + path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Len(t, body, size)
+	}
+}
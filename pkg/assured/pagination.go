@@ -0,0 +1,78 @@
+package assured
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// LinkPaginatedCall returns the stubbed call responding with the pages of the items, a slice, as JSON arrays of the page size,
+// selected by the page query parameter like the GitHub API. The first page is the call's response, and each following page is a branch
+// Each page has a Link header to the prev, next, last, and first pages it has, so the last page has no next or last link,
+// linking to the base URL of the items, e.g. client.URL() + "/repos", with the page and per_page query parameters
+func LinkPaginatedCall(call Call, baseURL string, pageSize int, items any) Call {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		response, _ := json.Marshal(items)
+		return mediaTypeCall(call, "application/json", response)
+	}
+	if pageSize <= 0 {
+		pageSize = max(v.Len(), 1)
+	}
+	pages := max((v.Len()+pageSize-1)/pageSize, 1)
+
+	call = mediaTypeCall(call, "application/json", linkPage(v, 1, pageSize))
+	if link := linkHeader(baseURL, 1, pages, pageSize); link != "" {
+		call.Headers["Link"] = link
+	}
+	call.Branches = append([]Branch{}, call.Branches...)
+	for page := 2; page <= pages; page++ {
+		call.Branches = append(call.Branches, Branch{
+			When:     Condition{Query: map[string]string{"page": strconv.Itoa(page)}},
+			Headers:  map[string]string{"Link": linkHeader(baseURL, page, pages, pageSize)},
+			Response: linkPage(v, page, pageSize),
+		})
+	}
+	return call
+}
+
+// linkPage returns the JSON array of the items of the page, starting at page 1
+func linkPage(items reflect.Value, page, pageSize int) []byte {
+	start := min((page-1)*pageSize, items.Len())
+	end := min(start+pageSize, items.Len())
+	elements := make([]any, 0, end-start)
+	for i := start; i < end; i++ {
+		elements = append(elements, items.Index(i).Interface())
+	}
+	response, _ := json.Marshal(elements)
+	return response
+}
+
+// linkHeader returns the Link header of the page, linking to its prev, next, last, and first pages, or empty if it is the only page
+func linkHeader(baseURL string, page, pages, pageSize int) string {
+	var links []string
+	link := func(page int, rel string) {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			u = &url.URL{Path: baseURL}
+		}
+		query := u.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(pageSize))
+		u.RawQuery = query.Encode()
+		links = append(links, "<"+u.String()+`>; rel="`+rel+`"`)
+	}
+	if page > 1 {
+		link(page-1, "prev")
+	}
+	if page < pages {
+		link(page+1, "next")
+		link(pages, "last")
+	}
+	if page > 1 {
+		link(1, "first")
+	}
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,88 @@
+package assured
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkPaginatedCall(t *testing.T) {
+	call := LinkPaginatedCall(Call{Path: "repos", Method: http.MethodGet, StatusCode: http.StatusOK}, "https://api.example.com/repos?sort=name", 2, []int{1, 2, 3, 4, 5})
+
+	require.Equal(t, "[1,2]", string(call.Response))
+	require.Equal(t, map[string]string{
+		"Content-Type": "application/json",
+		"Link":         `<https://api.example.com/repos?page=2&per_page=2&sort=name>; rel="next", <https://api.example.com/repos?page=3&per_page=2&sort=name>; rel="last"`,
+	}, call.Headers)
+	require.Equal(t, []Branch{
+		{
+			When:     Condition{Query: map[string]string{"page": "2"}},
+			Headers:  map[string]string{"Link": `<https://api.example.com/repos?page=1&per_page=2&sort=name>; rel="prev", <https://api.example.com/repos?page=3&per_page=2&sort=name>; rel="next", <https://api.example.com/repos?page=3&per_page=2&sort=name>; rel="last", <https://api.example.com/repos?page=1&per_page=2&sort=name>; rel="first"`},
+			Response: []byte("[3,4]"),
+		},
+		{
+			When:     Condition{Query: map[string]string{"page": "3"}},
+			Headers:  map[string]string{"Link": `<https://api.example.com/repos?page=2&per_page=2&sort=name>; rel="prev", <https://api.example.com/repos?page=1&per_page=2&sort=name>; rel="first"`},
+			Response: []byte("[5]"),
+		},
+	}, call.Branches)
+}
+
+func TestLinkPaginatedCallSinglePage(t *testing.T) {
+	tests := []struct {
+		name     string
+		pageSize int
+		items    any
+		want     string
+	}{
+		{name: "empty", pageSize: 10, items: []string{}, want: "[]"},
+		{name: "fits", pageSize: 10, items: []string{"a", "b"}, want: `["a","b"]`},
+		{name: "unpaged", pageSize: 0, items: []string{"a", "b"}, want: `["a","b"]`},
+		{name: "not a slice", pageSize: 10, items: map[string]int{"a": 1}, want: `{"a":1}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			call := LinkPaginatedCall(Call{Path: "repos"}, "/repos", tc.pageSize, tc.items)
+
+			require.Equal(t, tc.want, string(call.Response))
+			require.NotContains(t, call.Headers, "Link")
+			require.Empty(t, call.Branches)
+		})
+	}
+}
+
+func TestClientLinkPaginatedCall(t *testing.T) {
+	_, client := NewTestServer(t)
+	type repo struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	repos := []repo{{1, "alpha"}, {2, "beta"}, {3, "gamma"}, {4, "delta"}, {5, "epsilon"}}
+	require.NoError(t, client.Given(LinkPaginatedCall(Call{Path: "repos", Method: http.MethodGet, StatusCode: http.StatusOK}, client.URL()+"/repos", 2, repos)))
+
+	// Follow the next links until the last page, like a client of a paginated API
+	next := regexp.MustCompile(`<([^>]+)>; rel="next"`)
+	var followed []repo
+	pages := 0
+	for url := client.URL() + "/repos"; url != ""; pages++ {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var page []repo
+		require.NoError(t, json.Unmarshal(body, &page))
+		followed = append(followed, page...)
+
+		url = ""
+		if match := next.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+			url = match[1]
+		}
+	}
+
+	require.Equal(t, 3, pages)
+	require.Equal(t, repos, followed)
+}
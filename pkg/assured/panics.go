@@ -0,0 +1,145 @@
+package assured
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// panicBuffer is the most panics kept in the diagnostics, dropping the oldest to record more
+const panicBuffer = 100
+
+// Panic is a panic recovered while serving a call made to the stubbed calls, with the stubbed call it was matched to, if any,
+// so a crafted stub that breaks the rest assured server can be found instead of only responding 500 Internal Server Error
+type Panic struct {
+	Time   time.Time `json:"time"`
+	Stub   string    `json:"stub,omitempty"`
+	Method string    `json:"method"`
+	URI    string    `json:"uri"`
+	Error  string    `json:"error"`
+	Stack  string    `json:"stack"`
+}
+
+// panics are the diagnostics of the panics recovered, and the number of panics of each stubbed call, by Method/Path
+type panics struct {
+	recovered []Panic
+	stubs     map[string]int
+	sync.Mutex
+}
+
+// record records the panic into the diagnostics, marking the stubbed call it was matched to
+func (p *panics) record(recovered Panic) {
+	p.Lock()
+	defer p.Unlock()
+	if len(p.recovered) == panicBuffer {
+		p.recovered = p.recovered[1:]
+	}
+	p.recovered = append(p.recovered, recovered)
+	if recovered.Stub != "" {
+		if p.stubs == nil {
+			p.stubs = map[string]int{}
+		}
+		p.stubs[recovered.Stub]++
+	}
+}
+
+// counts returns the number of panics of each stubbed call, by Method/Path
+func (p *panics) counts() map[string]int {
+	p.Lock()
+	defer p.Unlock()
+	if len(p.stubs) == 0 {
+		return nil
+	}
+	counts := make(map[string]int, len(p.stubs))
+	for id, count := range p.stubs {
+		counts[id] = count
+	}
+	return counts
+}
+
+// Panics returns the panics recovered while serving the calls made, in the order they were recovered
+func (a *AssuredEndpoints) Panics() []Panic {
+	a.panics.Lock()
+	defer a.panics.Unlock()
+	return append([]Panic{}, a.panics.recovered...)
+}
+
+// ClearPanics clears the panics recovered, and returns the number of panics cleared
+func (a *AssuredEndpoints) ClearPanics() int {
+	a.panics.Lock()
+	defer a.panics.Unlock()
+	cleared := len(a.panics.recovered)
+	a.panics.recovered, a.panics.stubs = nil, nil
+	slog.With("cleared", cleared).Info("cleared panics")
+	return cleared
+}
+
+// recoveryHandler recovers the panics of serving a call made, recording the panic with its stack, the call made,
+// and the stubbed call it was matched to into the diagnostics, and responds 500 Internal Server Error with the panic
+// A panic after the response has started can't be responded to, so the connection is aborted instead, with http.ErrAbortHandler
+// A handler aborted with http.ErrAbortHandler is not a panic to diagnose, and is left to abort the response
+func (a *AssuredEndpoints) recoveryHandler(when http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rw := &recoveryWriter{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+			p := Panic{
+				Time:   time.Now(),
+				Method: req.Method,
+				URI:    req.RequestURI,
+				Error:  fmt.Sprint(recovered),
+				Stack:  string(debug.Stack()),
+			}
+			if stub := matched(req.Context()).stub(); stub != nil {
+				p.Stub = stub.ID()
+			}
+			a.panics.record(p)
+			slog.With("stub", p.Stub, "uri", p.URI, "error", p.Error).Error("assured call panicked")
+			if rw.written {
+				panic(http.ErrAbortHandler)
+			}
+			message := fmt.Sprintf("panic serving %s: %s", p.URI, p.Error)
+			if a.errorFormat.plain() {
+				http.Error(w, message, http.StatusInternalServerError)
+				return
+			}
+			a.errorFormat.write(w, http.StatusInternalServerError, message)
+		}()
+		when.ServeHTTP(rw, req)
+	})
+}
+
+// recoveryWriter is an http.ResponseWriter that tracks whether the response has started, once a final status code or the body is written
+type recoveryWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+// WriteHeader marks the response as started when writing a final status code, as informational status codes can be followed by any response
+func (w *recoveryWriter) WriteHeader(statusCode int) {
+	if statusCode >= http.StatusOK {
+		w.written = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write marks the response as started, and writes the response body
+func (w *recoveryWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController
+func (w *recoveryWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,119 @@
+package assured
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoveryHandler(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	_, _ = endpoints.GivenEndpoint(context.TODO(), testCall1())
+	handler := endpoints.matchHandler(endpoints.recoveryHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("crafted stub")
+	})))
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/when/test/assured?assured=max", nil), map[string]string{"path": "test/assured"})
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, "panic serving /when/test/assured?assured=max: crafted stub\n", w.Body.String())
+	panics := endpoints.Panics()
+	require.Len(t, panics, 1)
+	require.Equal(t, "GET:test/assured", panics[0].Stub)
+	require.Equal(t, http.MethodGet, panics[0].Method)
+	require.Equal(t, "/when/test/assured?assured=max", panics[0].URI)
+	require.Equal(t, "crafted stub", panics[0].Error)
+	require.Contains(t, panics[0].Stack, "TestRecoveryHandler")
+	require.False(t, panics[0].Time.IsZero())
+	require.Equal(t, map[string]int{"GET:test/assured": 1}, endpoints.Stats().Panics)
+
+	require.Equal(t, 1, endpoints.ClearPanics())
+	require.Empty(t, endpoints.Panics())
+	require.Nil(t, endpoints.Stats().Panics)
+}
+
+func TestRecoveryHandlerBuffer(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	handler := endpoints.recoveryHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic(fmt.Errorf("panic %s", req.URL.Path))
+	}))
+
+	for i := 0; i < panicBuffer+5; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, fmt.Sprintf("/when/%d", i), nil))
+	}
+
+	panics := endpoints.Panics()
+	require.Len(t, panics, panicBuffer)
+	require.Equal(t, "panic /when/5", panics[0].Error)
+	require.Empty(t, panics[0].Stub)
+	require.Nil(t, endpoints.Stats().Panics)
+}
+
+func TestRecoveryHandlerAbort(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	handler := endpoints.recoveryHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/when/abort", nil))
+	})
+	require.Empty(t, endpoints.Panics())
+}
+
+func TestRecoveryHandlerResponseStarted(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	_, _ = endpoints.GivenEndpoint(context.TODO(), testCall1())
+	for name, write := range map[string]func(w http.ResponseWriter){
+		"header": func(w http.ResponseWriter) { w.WriteHeader(http.StatusOK) },
+		"body":   func(w http.ResponseWriter) { _, _ = w.Write([]byte("partial")) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler := endpoints.matchHandler(endpoints.recoveryHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				write(w)
+				panic("crafted stub")
+			})))
+			req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/when/test/assured?assured=max", nil), map[string]string{"path": "test/assured"})
+
+			require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			})
+		})
+	}
+
+	panics := endpoints.Panics()
+	require.Len(t, panics, 2)
+	require.Equal(t, "GET:test/assured", panics[0].Stub)
+	require.Equal(t, map[string]int{"GET:test/assured": 2}, endpoints.Stats().Panics)
+}
+
+func TestRecoveryHandlerInformational(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	handler := endpoints.recoveryHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusEarlyHints)
+		panic("crafted stub")
+	}))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/when/informational", nil))
+
+	require.Len(t, endpoints.Panics(), 1)
+	require.Contains(t, w.Body.String(), "panic serving /when/informational: crafted stub")
+}
+
+func TestClientPanics(t *testing.T) {
+	_, client := NewTestServer(t)
+
+	panics, err := client.Panics()
+
+	require.NoError(t, err)
+	require.Empty(t, panics)
+	require.NoError(t, client.ClearPanics())
+}
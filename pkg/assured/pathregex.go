@@ -0,0 +1,62 @@
+package assured
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// compilePathRegex compiles the stubbed call's path regex, or path template, once, when the call is stubbed, to match the whole path of the calls made
+// A call stubbed without a path is stubbed under its path regex, e.g. GET:users/[0-9]+/orders
+func (c *Call) compilePathRegex() error {
+	pattern := c.PathRegex
+	if pattern == "" {
+		template, rank, err := pathTemplateRegex(c.Path)
+		if err != nil {
+			return err
+		}
+		pattern, c.pathRank = template, rank
+	}
+	if pattern == "" {
+		return nil
+	}
+	regex, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid path regex: %w", err)
+	}
+	c.pathRegex = regex
+	if c.Path == "" {
+		c.Path = c.PathRegex
+	}
+	return nil
+}
+
+// countPathRegexes recounts the calls stubbed with a path regex, or path template, after the stub set changes, so calls made
+// skip matching them once there are none. Calls stubbed meanwhile are counted after the recount, or by it
+func (a *AssuredEndpoints) countPathRegexes() {
+	a.pathRegexesMu.Lock()
+	defer a.pathRegexesMu.Unlock()
+	var count int64
+	for _, calls := range a.assuredCalls.Snapshot() {
+		for _, call := range calls {
+			if call.pathRegex != nil {
+				count++
+			}
+		}
+	}
+	a.pathRegexes.Store(count)
+}
+
+// pathRegexCalls returns the calls stubbed with a path regex, or path template, that matches the path of the call made, for calls made without a literal stub
+// If several match, the calls stubbed under the most specific path template are used, or else under the first path regex in order
+func (a *AssuredEndpoints) pathRegexCalls(method, path string) []*Call {
+	if a.pathRegexes.Load() == 0 {
+		return nil
+	}
+	_, calls := a.assuredCalls.Best(method+":", func(calls []*Call) int {
+		if len(calls) == 0 || calls[0].pathRegex == nil || !calls[0].pathRegex.MatchString(path) {
+			return -1
+		}
+		return calls[0].pathRank
+	})
+	return calls
+}
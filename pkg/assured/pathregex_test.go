@@ -0,0 +1,127 @@
+package assured
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhenEndpointPathRegex(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	regex := &Call{PathRegex: "users/[0-9]+/orders", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("regex")}
+	literal := &Call{Path: "users/1/orders", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("literal")}
+	_, err := endpoints.GivenEndpoint(context.TODO(), regex)
+	require.NoError(t, err)
+	_, err = endpoints.GivenEndpoint(context.TODO(), literal)
+	require.NoError(t, err)
+
+	require.Equal(t, "GET:users/[0-9]+/orders", regex.ID())
+
+	c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "users/42/orders", Method: http.MethodGet})
+	require.NoError(t, err)
+	require.Equal(t, "regex", string(c.(*Call).Response))
+
+	c, err = endpoints.WhenEndpoint(context.TODO(), &Call{Path: "users/1/orders", Method: http.MethodGet})
+	require.NoError(t, err)
+	require.Equal(t, "literal", string(c.(*Call).Response))
+
+	for _, path := range []string{"users/x/orders", "users/42/orders/1", "api/users/42/orders"} {
+		_, err = endpoints.WhenEndpoint(context.TODO(), &Call{Path: path, Method: http.MethodGet})
+		require.EqualError(t, err, "No assured calls", path)
+	}
+	_, err = endpoints.WhenEndpoint(context.TODO(), &Call{Path: "users/42/orders", Method: http.MethodPost})
+	require.EqualError(t, err, "No assured calls")
+
+	calls, err := endpoints.VerifyEndpoint(context.TODO(), &Call{Path: "users/42/orders", Method: http.MethodGet})
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+}
+
+func TestWhenEndpointPathRegexOrder(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{PathRegex: "users/.*", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("any")})
+	_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{PathRegex: "users/[0-9]+", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("id")})
+
+	c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "users/42", Method: http.MethodGet})
+
+	require.NoError(t, err)
+	require.Equal(t, "any", string(c.(*Call).Response))
+}
+
+func TestGivenEndpointPathRegexFailure(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+
+	_, err := endpoints.GivenEndpoint(context.TODO(), &Call{PathRegex: "users/(", Method: http.MethodGet})
+
+	require.EqualError(t, err, "invalid path regex: error parsing regexp: missing closing ): `^(?:users/()$`")
+	require.Zero(t, endpoints.assuredCalls.Len())
+}
+
+func TestPathRegexesCounted(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	endpoints.history.limit = 5
+	regex := func() *Call {
+		return &Call{PathRegex: "users/[0-9]+", Method: http.MethodGet, StatusCode: http.StatusOK}
+	}
+	given := func(call *Call) {
+		endpoints.revise("")
+		_, err := endpoints.GivenEndpoint(context.TODO(), call)
+		require.NoError(t, err)
+	}
+
+	given(regex())
+	given(&Call{Path: "users/me", Method: http.MethodGet, StatusCode: http.StatusOK})
+	require.EqualValues(t, 1, endpoints.pathRegexes.Load())
+
+	_, err := endpoints.ClearEndpoint(context.TODO(), &Call{Path: "users/[0-9]+", Method: http.MethodGet})
+	require.NoError(t, err)
+	require.Zero(t, endpoints.pathRegexes.Load())
+
+	_, err = endpoints.Rollback()
+	require.NoError(t, err)
+	require.EqualValues(t, 1, endpoints.pathRegexes.Load())
+
+	_, err = endpoints.ClearMatching(http.MethodGet, "users/")
+	require.NoError(t, err)
+	require.Zero(t, endpoints.pathRegexes.Load())
+
+	given(regex())
+	given(regex())
+	require.EqualValues(t, 2, endpoints.pathRegexes.Load())
+	_, err = endpoints.ClearAllEndpoint(context.TODO(), nil)
+	require.NoError(t, err)
+	require.Zero(t, endpoints.pathRegexes.Load())
+
+	_, err = endpoints.ReplaceEndpoint(context.TODO(), []*Call{regex(), {Path: "users/me", Method: http.MethodGet}})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, endpoints.pathRegexes.Load())
+	_, err = endpoints.ReplaceEndpoint(context.TODO(), []*Call{{Path: "users/me", Method: http.MethodGet}})
+	require.NoError(t, err)
+	require.Zero(t, endpoints.pathRegexes.Load())
+}
+
+func TestClientPathRegex(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		_, client := NewTestServer(t)
+		client.legacy.Store(legacy)
+		require.NoError(t, client.Given(Call{PathRegex: "users/[0-9]+/orders", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("orders")}))
+
+		resp, err := http.Get(client.URL() + "/users/42/orders")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "orders", string(body))
+
+		resp, err = http.Get(client.URL() + "/users/me/orders")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+		calls, err := client.Verify(http.MethodGet, "users/42/orders")
+		require.NoError(t, err)
+		require.Len(t, calls, 1)
+	}
+}
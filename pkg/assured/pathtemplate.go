@@ -0,0 +1,109 @@
+package assured
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// pathTemplatePattern matches the parameters and wildcards of a path template, e.g. {id} in users/{id}/profile,
+// * matching a path segment in api/*/status, or ** matching any number of path segments in files/**
+var pathTemplatePattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}|\*\*|\*`)
+
+// pathParamPattern matches the path parameter placeholders in responses, e.g. {{path "id"}}
+var pathParamPattern = regexp.MustCompile(`\{\{\s*path\s+"([^"]+)"\s*\}\}`)
+
+// pathTemplateRegex returns the path regex of the path template, matching each parameter and * wildcard to a path segment,
+// and each ** wildcard to any number of path segments, e.g. users/(?P<id>[^/]+)/profile for users/{id}/profile,
+// or empty if the path is not a template
+// The rank of the template is how specific it is, by its literal characters, and then by not having a ** wildcard,
+// so the most specific template matching a call made is used, e.g. api/*/status before api/**
+func pathTemplateRegex(path string) (string, int, error) {
+	params := pathTemplatePattern.FindAllStringSubmatchIndex(path, -1)
+	if len(params) == 0 {
+		return "", 0, nil
+	}
+	var regex strings.Builder
+	seen := map[string]bool{}
+	last, literals, bounded := 0, 0, 1
+	for _, param := range params {
+		regex.WriteString(regexp.QuoteMeta(path[last:param[0]]))
+		literals += param[0] - last
+		last = param[1]
+		switch path[param[0]:param[1]] {
+		case "**":
+			regex.WriteString(".*")
+			bounded = 0
+		case "*":
+			regex.WriteString("[^/]+")
+		default:
+			name := path[param[2]:param[3]]
+			if seen[name] {
+				return "", 0, fmt.Errorf("invalid path template: duplicate parameter %q", name)
+			}
+			seen[name] = true
+			regex.WriteString("(?P<" + name + ">[^/]+)")
+		}
+	}
+	regex.WriteString(regexp.QuoteMeta(path[last:]))
+	literals += len(path) - last
+	return regex.String(), 1 + 2*literals + bounded, nil
+}
+
+// pathParams returns the values of the named capturing groups of the stubbed call's path regex, or path template,
+// in the path of the call made, e.g. {"id": "42"} for users/42/profile stubbed as users/{id}/profile
+func (c *Call) pathParams(path string) map[string]string {
+	if c.pathRegex == nil {
+		return nil
+	}
+	match := c.pathRegex.FindStringSubmatch(path)
+	if match == nil {
+		return nil
+	}
+	var params map[string]string
+	for i, name := range c.pathRegex.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if params == nil {
+			params = map[string]string{}
+		}
+		params[name] = match[i]
+	}
+	return params
+}
+
+// hasPathParams reports whether the stubbed call has path parameter placeholders in its response or headers
+func (c *Call) hasPathParams() bool {
+	if bytes.Contains(c.Response, []byte("{{")) && pathParamPattern.Match(c.Response) {
+		return true
+	}
+	for _, value := range c.Headers {
+		if pathParamPattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderPathParams returns a copy of the stubbed call with its path parameter placeholders replaced by the values
+// captured from the path of the call made, or empty if the call made has no value for the parameter
+func (c *Call) renderPathParams(made *Call) *Call {
+	render := func(text []byte) []byte {
+		return pathParamPattern.ReplaceAllFunc(text, func(placeholder []byte) []byte {
+			return []byte(made.PathParams[string(pathParamPattern.FindSubmatch(placeholder)[1])])
+		})
+	}
+	rendered := *c
+	rendered.Response = render(c.Response)
+	rendered.Headers = make(map[string]string, len(c.Headers))
+	for name, value := range c.Headers {
+		// The stubbed content length no longer applies to the rendered response
+		if name == "Content-Length" && pathParamPattern.Match(c.Response) {
+			continue
+		}
+		rendered.Headers[name] = string(render([]byte(value)))
+	}
+	return &rendered
+}
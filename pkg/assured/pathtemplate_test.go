@@ -0,0 +1,173 @@
+package assured
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathTemplateRegex(t *testing.T) {
+	for path, want := range map[string]string{
+		"users/profile":             "",
+		"users/{id}/profile":        `users/(?P<id>[^/]+)/profile`,
+		"users/{id}/orders/{order}": `users/(?P<id>[^/]+)/orders/(?P<order>[^/]+)`,
+		"files/{name}.json":         `files/(?P<name>[^/]+)\.json`,
+		"users/{1}/{-}/{ id}":       "",
+		"api/*/status":              `api/[^/]+/status`,
+		"files/**":                  `files/.*`,
+		"files/**/{name}.json":      `files/.*/(?P<name>[^/]+)\.json`,
+	} {
+		regex, _, err := pathTemplateRegex(path)
+		require.NoError(t, err, path)
+		require.Equal(t, want, regex, path)
+	}
+
+	_, _, err := pathTemplateRegex("users/{id}/friends/{id}")
+	require.EqualError(t, err, `invalid path template: duplicate parameter "id"`)
+}
+
+func TestWhenEndpointPathTemplate(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	template := &Call{
+		Path:       "users/{id}/orders/{order}",
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Location": `/users/{{path "id"}}`, "Content-Length": "32"},
+		Response:   []byte(`{"id":"{{ path "id" }}","order":"{{path "order"}}","missing":"{{path "missing"}}"}`),
+	}
+	_, err := endpoints.GivenEndpoint(context.TODO(), template)
+	require.NoError(t, err)
+	require.Equal(t, "GET:users/{id}/orders/{order}", template.ID())
+	require.False(t, template.static())
+
+	made := &Call{Path: "users/42/orders/7", Method: http.MethodGet}
+	c, err := endpoints.WhenEndpoint(context.TODO(), made)
+
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"42","order":"7","missing":""}`, string(c.(*Call).Response))
+	require.Equal(t, map[string]string{"Location": "/users/42"}, c.(*Call).Headers)
+	require.Equal(t, map[string]string{"id": "42", "order": "7"}, made.PathParams)
+	require.Equal(t, `{"id":"{{ path "id" }}","order":"{{path "order"}}","missing":"{{path "missing"}}"}`, string(template.Response))
+
+	calls, err := endpoints.VerifyEndpoint(context.TODO(), &Call{Path: "users/42/orders/7", Method: http.MethodGet})
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	require.Equal(t, map[string]string{"id": "42", "order": "7"}, calls.([]*Call)[0].PathParams)
+
+	for _, path := range []string{"users/42/orders", "users/42/orders/7/items", "users//orders/7"} {
+		_, err = endpoints.WhenEndpoint(context.TODO(), &Call{Path: path, Method: http.MethodGet})
+		require.EqualError(t, err, "No assured calls", path)
+	}
+}
+
+func TestWhenEndpointPathRegexParams(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	_, err := endpoints.GivenEndpoint(context.TODO(), &Call{PathRegex: `users/(?P<id>[0-9]+)/(orders|invoices)`, Method: http.MethodGet, Response: []byte(`{{path "id"}}`)})
+	require.NoError(t, err)
+
+	made := &Call{Path: "users/42/invoices", Method: http.MethodGet}
+	c, err := endpoints.WhenEndpoint(context.TODO(), made)
+
+	require.NoError(t, err)
+	require.Equal(t, "42", string(c.(*Call).Response))
+	require.Equal(t, map[string]string{"id": "42"}, made.PathParams)
+}
+
+func TestWhenEndpointPathTemplateLiteral(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{Path: "users/{id}", Method: http.MethodGet, Response: []byte("template")})
+	_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{Path: "users/me", Method: http.MethodGet, Response: []byte("literal")})
+
+	made := &Call{Path: "users/me", Method: http.MethodGet}
+	c, err := endpoints.WhenEndpoint(context.TODO(), made)
+
+	require.NoError(t, err)
+	require.Equal(t, "literal", string(c.(*Call).Response))
+	require.Nil(t, made.PathParams)
+}
+
+func TestGivenEndpointPathTemplateFailure(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+
+	_, err := endpoints.GivenEndpoint(context.TODO(), &Call{Path: "users/{id}/friends/{id}", Method: http.MethodGet})
+
+	require.EqualError(t, err, `invalid path template: duplicate parameter "id"`)
+	require.Zero(t, endpoints.assuredCalls.Len())
+}
+
+func TestClientPathTemplate(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		_, client := NewTestServer(t)
+		client.legacy.Store(legacy)
+		require.NoError(t, client.Given(Call{Path: "users/{id}/profile", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte(`{"id":"{{path "id"}}"}`)}))
+
+		resp, err := http.Get(client.URL() + "/users/42/profile")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, `{"id":"42"}`, string(body))
+
+		calls, err := client.Verify(http.MethodGet, "users/42/profile")
+		require.NoError(t, err)
+		require.Len(t, calls, 1)
+		require.Equal(t, map[string]string{"id": "42"}, calls[0].PathParams)
+	}
+}
+
+func TestWhenEndpointPathWildcards(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	for _, path := range []string{"api/**", "api/*/status", "api/v1/status", "api/{version}/status/**"} {
+		_, err := endpoints.GivenEndpoint(context.TODO(), &Call{Path: path, Method: http.MethodGet, Response: []byte(path)})
+		require.NoError(t, err)
+	}
+
+	for path, want := range map[string]string{
+		"api/v1/status":          "api/v1/status",
+		"api/v2/status":          "api/*/status",
+		"api/v2/status/db":       "api/{version}/status/**",
+		"api/v2/health":          "api/**",
+		"api/v2/status/db/ready": "api/{version}/status/**",
+		"api/":                   "api/**",
+	} {
+		c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: path, Method: http.MethodGet})
+		require.NoError(t, err, path)
+		require.Equal(t, want, string(c.(*Call).Response), path)
+	}
+
+	for _, path := range []string{"api", "apis/v1/status"} {
+		_, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: path, Method: http.MethodGet})
+		require.EqualError(t, err, "No assured calls", path)
+	}
+}
+
+func TestWhenEndpointPathWildcardRegexOrder(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{PathRegex: "files/.*", Method: http.MethodGet, Response: []byte("regex")})
+	_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{Path: "files/**", Method: http.MethodGet, Response: []byte("wildcard")})
+
+	c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "files/a/b", Method: http.MethodGet})
+
+	require.NoError(t, err)
+	require.Equal(t, "wildcard", string(c.(*Call).Response))
+}
+
+func TestClientPathWildcard(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{Path: "files/**", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("file")}))
+
+	resp, err := http.Get(client.URL() + "/files/2024/report.pdf")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "file", string(body))
+
+	calls, err := client.Verify(http.MethodGet, "files/2024/report.pdf")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	require.NoError(t, client.Clear(http.MethodGet, "files/**"))
+}
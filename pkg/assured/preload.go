@@ -0,0 +1,228 @@
+package assured
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// envPattern matches environment variable references with an optional default, ${VAR} or ${VAR:-default}
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// Preload is the expected format for preloading assured endpoints through the go rest assured application
+type Preload struct {
+	Defaults  *Defaults           `json:"defaults,omitempty"`
+	Fragments map[string]Fragment `json:"fragments,omitempty"`
+	Calls     []PreloadCall       `json:"calls"`
+}
+
+// PreloadCall is an assured call in a preload file, which can reference named fragments
+type PreloadCall struct {
+	Call
+	Fragments []string `json:"fragments,omitempty"`
+
+	defaults *Defaults
+	file     string
+	field    string
+}
+
+// Defaults are the values used by every call in a preload file that does not set its own
+// A call's own headers, and its fragments' headers, take precedence over the default headers
+type Defaults struct {
+	Method     string            `json:"method,omitempty"`
+	StatusCode int               `json:"status_code,omitempty"`
+	Delay      int               `json:"delay,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+// Fragment is a reusable set of headers and body partial that preloaded calls can reference by name
+// A call's own headers take precedence over a fragment's headers. A fragment's response is used as the call's response
+// if the call has none, and is inserted into the call's response wherever the call references it with {{> name}}
+type Fragment struct {
+	Headers  map[string]string `json:"headers,omitempty"`
+	Response CallResponse      `json:"response,omitempty"`
+}
+
+// LoadPreload reads the assured calls from a preload file
+// If the path is a directory, every JSON file in the directory is loaded in lexical order
+// and fragments defined in any of the files can be referenced by calls in every file
+// Environment variables referenced as ${VAR} or ${VAR:-default} in the calls' paths, headers, and responses are interpolated
+func LoadPreload(path string) ([]Call, error) {
+	preloadCalls, fragments, err := readPreload(path)
+	if err != nil {
+		return nil, err
+	}
+
+	calls := []Call{}
+	for _, preloadCall := range preloadCalls {
+		call, err := preloadCall.compose(fragments)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, interpolateCall(call))
+	}
+	return calls, nil
+}
+
+// readPreload reads the preloaded calls and fragments from a preload file, or directory of files
+func readPreload(path string) ([]PreloadCall, map[string]Fragment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, nil, err
+		}
+		sort.Strings(files)
+	}
+
+	fragments := map[string]Fragment{}
+	preloadCalls := []PreloadCall{}
+	for _, file := range files {
+		preload, err := loadPreloadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, fragment := range preload.Fragments {
+			fragments[name] = fragment
+		}
+		for i, preloadCall := range preload.Calls {
+			preloadCall.defaults = preload.Defaults
+			preloadCall.file = file
+			preloadCall.field = fmt.Sprintf("calls[%d]", i)
+			preloadCalls = append(preloadCalls, preloadCall)
+		}
+	}
+	return preloadCalls, fragments, nil
+}
+
+// loadPreloadFile reads a single preload file
+func loadPreloadFile(path string) (*Preload, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodePreload(path, b)
+}
+
+// compose builds the assured call from the preloaded call, the fragments it references, and its file's defaults
+func (p PreloadCall) compose(fragments map[string]Fragment) (Call, error) {
+	call := p.Call
+	if len(p.Fragments) == 0 && p.defaults == nil {
+		return call, nil
+	}
+
+	headers := map[string]string{}
+	if p.defaults != nil {
+		if call.Method == "" {
+			call.Method = p.defaults.Method
+		}
+		if call.StatusCode == 0 {
+			call.StatusCode = p.defaults.StatusCode
+		}
+		if call.Delay == 0 {
+			call.Delay = p.defaults.Delay
+		}
+		for key, value := range p.defaults.Headers {
+			headers[key] = value
+		}
+	}
+	for _, name := range p.Fragments {
+		fragment, ok := fragments[name]
+		if !ok {
+			return call, &PreloadError{File: p.file, Field: p.field + ".fragments", Err: fmt.Errorf("call %s references unknown fragment %q", call.ID(), name)}
+		}
+		for key, value := range fragment.Headers {
+			headers[key] = value
+		}
+		if len(call.Response) == 0 {
+			call.Response = fragment.Response
+		} else {
+			call.Response = bytes.ReplaceAll(call.Response, []byte(fmt.Sprintf("{{> %s}}", name)), fragment.Response)
+		}
+	}
+	for key, value := range call.Headers {
+		headers[key] = value
+	}
+	call.Headers = headers
+
+	return call, nil
+}
+
+// interpolateCall replaces the environment variable references in the call's paths, headers, and responses
+func interpolateCall(call Call) Call {
+	call.Path = interpolate(call.Path)
+	call.Headers = interpolateMap(call.Headers)
+	call.Query = interpolateMap(call.Query)
+	call.Response = interpolateResponse(call.Response)
+
+	if call.ResponseHeaders != nil {
+		headers := make([]Header, len(call.ResponseHeaders))
+		for i, header := range call.ResponseHeaders {
+			headers[i] = Header{Name: header.Name, Value: interpolate(header.Value)}
+		}
+		call.ResponseHeaders = headers
+	}
+
+	callbacks := make([]Callback, len(call.Callbacks))
+	for i, callback := range call.Callbacks {
+		callback.Target = interpolate(callback.Target)
+		callback.Headers = interpolateMap(callback.Headers)
+		callback.Response = interpolateResponse(callback.Response)
+		callbacks[i] = callback
+	}
+	if call.Callbacks != nil {
+		call.Callbacks = callbacks
+	}
+
+	branches := make([]Branch, len(call.Branches))
+	for i, branch := range call.Branches {
+		branch.Headers = interpolateMap(branch.Headers)
+		branch.Response = interpolateResponse(branch.Response)
+		branches[i] = branch
+	}
+	if call.Branches != nil {
+		call.Branches = branches
+	}
+
+	return call
+}
+
+// interpolate replaces the environment variable references in the string
+// If a variable is not set its default, or an empty string, is used
+func interpolate(s string) string {
+	return envPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		match := envPattern.FindStringSubmatch(ref)
+		if value, ok := os.LookupEnv(match[1]); ok {
+			return value
+		}
+		return match[2]
+	})
+}
+
+// interpolateMap replaces the environment variable references in the map's values
+func interpolateMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	interpolated := make(map[string]string, len(m))
+	for key, value := range m {
+		interpolated[key] = interpolate(value)
+	}
+	return interpolated
+}
+
+// interpolateResponse replaces the environment variable references in the response
+func interpolateResponse(response CallResponse) CallResponse {
+	if !envPattern.Match(response) {
+		return response
+	}
+	return CallResponse(interpolate(string(response)))
+}
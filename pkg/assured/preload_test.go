@@ -0,0 +1,187 @@
+package assured
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPreloadFile(t *testing.T) {
+	calls, err := LoadPreload("testdata/preload/01-calls.json")
+
+	require.NoError(t, err)
+	require.Equal(t, []Call{
+		{Path: "test/assured", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte(`{"assured": true}`)},
+	}, calls)
+}
+
+func TestLoadPreloadDirectory(t *testing.T) {
+	calls, err := LoadPreload("testdata/preload")
+
+	require.NoError(t, err)
+	require.Equal(t, []Call{
+		{Path: "test/assured", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte(`{"assured": true}`)},
+		{Path: "teapot/assured", Method: http.MethodPost, StatusCode: http.StatusTeapot},
+	}, calls)
+}
+
+func TestLoadPreloadMissing(t *testing.T) {
+	calls, err := LoadPreload("testdata/missing.json")
+
+	require.Error(t, err)
+	require.Nil(t, calls)
+}
+
+func TestLoadPreloadInvalid(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{"calls": {}}`), 0o644))
+
+	calls, err := LoadPreload(dir)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid preload file")
+	require.Nil(t, calls)
+}
+
+func TestLoadPreloadFragments(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "01-fragments.json"), []byte(`{
+		"fragments": {
+			"auth": {"headers": {"Authorization": "Bearer assured", "Content-Type": "text/plain"}},
+			"user": {"response": "{\"name\": \"assured\"}"}
+		}
+	}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "02-calls.json"), []byte(`{
+		"calls": [
+			{
+				"path": "user",
+				"method": "GET",
+				"fragments": ["auth", "user"],
+				"headers": {"Content-Type": "application/json"},
+				"response": "{\"data\": {{> user}}}"
+			},
+			{
+				"path": "me",
+				"method": "GET",
+				"fragments": ["user"]
+			}
+		]
+	}`), 0o644))
+
+	calls, err := LoadPreload(dir)
+
+	require.NoError(t, err)
+	require.Equal(t, []Call{
+		{
+			Path:     "user",
+			Method:   http.MethodGet,
+			Headers:  map[string]string{"Authorization": "Bearer assured", "Content-Type": "application/json"},
+			Response: []byte(`{"data": {"name": "assured"}}`),
+		},
+		{
+			Path:     "me",
+			Method:   http.MethodGet,
+			Headers:  map[string]string{},
+			Response: []byte(`{"name": "assured"}`),
+		},
+	}, calls)
+}
+
+func TestLoadPreloadUnknownFragment(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "calls.json"), []byte(`{
+		"calls": [{"path": "user", "method": "GET", "fragments": ["missing"]}]
+	}`), 0o644))
+
+	calls, err := LoadPreload(dir)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `calls[0].fragments: call GET:user references unknown fragment "missing"`)
+	require.Nil(t, calls)
+}
+
+func TestLoadPreloadInterpolation(t *testing.T) {
+	t.Setenv("ASSURED_TEST_VERSION", "v2")
+	t.Setenv("ASSURED_TEST_TOKEN", "secret")
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "calls.json"), []byte(`{
+		"calls": [
+			{
+				"path": "${ASSURED_TEST_VERSION}/users",
+				"method": "GET",
+				"headers": {"Authorization": "Bearer ${ASSURED_TEST_TOKEN}"},
+				"response_headers": [{"name": "Set-Cookie", "value": "token=${ASSURED_TEST_TOKEN}"}],
+				"response": "{\"env\": \"${ASSURED_TEST_ENV:-local}\", \"$ref\": \"${ASSURED_TEST_UNSET}\"}",
+				"callbacks": [{"target": "http://${ASSURED_TEST_HOST:-localhost}/hook", "method": "POST"}]
+			}
+		]
+	}`), 0o644))
+
+	calls, err := LoadPreload(dir)
+
+	require.NoError(t, err)
+	require.Equal(t, []Call{
+		{
+			Path:            "v2/users",
+			Method:          http.MethodGet,
+			Headers:         map[string]string{"Authorization": "Bearer secret"},
+			ResponseHeaders: []Header{{Name: "Set-Cookie", Value: "token=secret"}},
+			Response:        []byte(`{"env": "local", "$ref": ""}`),
+			Callbacks:       []Callback{{Target: "http://localhost/hook", Method: http.MethodPost}},
+		},
+	}, calls)
+}
+
+func TestLoadPreloadDefaults(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "01-defaults.json"), []byte(`{
+		"defaults": {
+			"method": "POST",
+			"status_code": 201,
+			"delay": 1,
+			"headers": {"Content-Type": "application/json", "X-Version": "1"}
+		},
+		"fragments": {
+			"v2": {"headers": {"X-Version": "2"}}
+		},
+		"calls": [
+			{"path": "created"},
+			{"path": "overridden", "method": "PUT", "status_code": 200, "delay": 2, "headers": {"Content-Type": "text/plain"}},
+			{"path": "fragment", "fragments": ["v2"]}
+		]
+	}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "02-calls.json"), []byte(`{
+		"calls": [{"path": "other", "method": "GET"}]
+	}`), 0o644))
+
+	calls, err := LoadPreload(dir)
+
+	require.NoError(t, err)
+	require.Equal(t, []Call{
+		{
+			Path:       "created",
+			Method:     http.MethodPost,
+			StatusCode: http.StatusCreated,
+			Delay:      1,
+			Headers:    map[string]string{"Content-Type": "application/json", "X-Version": "1"},
+		},
+		{
+			Path:       "overridden",
+			Method:     http.MethodPut,
+			StatusCode: http.StatusOK,
+			Delay:      2,
+			Headers:    map[string]string{"Content-Type": "text/plain", "X-Version": "1"},
+		},
+		{
+			Path:       "fragment",
+			Method:     http.MethodPost,
+			StatusCode: http.StatusCreated,
+			Delay:      1,
+			Headers:    map[string]string{"Content-Type": "application/json", "X-Version": "2"},
+		},
+		{Path: "other", Method: http.MethodGet},
+	}, calls)
+}
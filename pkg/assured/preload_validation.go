@@ -0,0 +1,279 @@
+package assured
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// unknownFieldPattern matches the field name of an unknown field decoding error
+var unknownFieldPattern = regexp.MustCompile(`unknown field "([^"]+)"`)
+
+// methodPattern matches a valid http method token
+var methodPattern = regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+$")
+
+// PreloadError is an error in a preload file, located by line or by field
+type PreloadError struct {
+	File  string
+	Line  int
+	Field string
+	Err   error
+}
+
+// Error reports the location of the error in the preload file
+func (e *PreloadError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("invalid preload file %s: %s: %v", e.File, e.Field, e.Err)
+	}
+	return fmt.Sprintf("invalid preload file %s:%d: %v", e.File, e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *PreloadError) Unwrap() error {
+	return e.Err
+}
+
+// decodePreload strictly decodes a preload file, so a typo in a field name is reported instead of ignored
+func decodePreload(file string, b []byte) (*Preload, error) {
+	var preload Preload
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&preload); err != nil {
+		return nil, &PreloadError{File: file, Line: errorLine(b, err), Err: err}
+	}
+	if err := preload.validate(file); err != nil {
+		return nil, err
+	}
+	return &preload, nil
+}
+
+// errorLine finds the line of the preload file a decoding error occurred on
+func errorLine(b []byte, err error) int {
+	offset := int64(-1)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		// Unknown field errors don't include an offset, so find the first use of the field as a key
+		if match := unknownFieldPattern.FindStringSubmatch(err.Error()); match != nil {
+			key := regexp.MustCompile(fmt.Sprintf(`"%s"\s*:`, regexp.QuoteMeta(match[1])))
+			if loc := key.FindIndex(b); loc != nil {
+				offset = int64(loc[0]) + 1
+			}
+		}
+	}
+	if offset < 0 {
+		return 0
+	}
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	return bytes.Count(b[:offset], []byte("\n")) + 1
+}
+
+// validate checks the preload's fields for values that would produce a stub that can never be used
+func (p *Preload) validate(file string) error {
+	errs := []error{}
+	invalid := func(field string, format string, args ...interface{}) {
+		errs = append(errs, &PreloadError{File: file, Field: field, Err: fmt.Errorf(format, args...)})
+	}
+
+	if p.Defaults != nil {
+		validateMethod("defaults.method", p.Defaults.Method, invalid)
+		validateStatusCode("defaults.status_code", p.Defaults.StatusCode, invalid)
+		if p.Defaults.Delay < 0 {
+			invalid("defaults.delay", "delay must not be negative")
+		}
+	}
+	for i, call := range p.Calls {
+		field := fmt.Sprintf("calls[%d]", i)
+		validateMethod(field+".method", call.Method, invalid)
+		if _, _, err := pathTemplateRegex(call.Path); err != nil {
+			invalid(field+".path", "%s", err)
+		}
+		if err := (&Call{PathRegex: call.PathRegex}).compilePathRegex(); err != nil {
+			invalid(field+".path_regex", "%s", err)
+		}
+		validateStatusCode(field+".status_code", call.StatusCode, invalid)
+		for j, code := range call.StatusCodes {
+			validateStatusCode(fmt.Sprintf("%s.status_codes[%d]", field, j), code, invalid)
+		}
+		if call.Delay < 0 {
+			invalid(field+".delay", "delay must not be negative")
+		}
+		if call.Concurrency < 0 {
+			invalid(field+".concurrency", "concurrency must not be negative")
+		}
+		if call.MaxBodySize < 0 {
+			invalid(field+".max_body_size", "max body size must not be negative")
+		}
+		if call.MaxHeaderSize < 0 {
+			invalid(field+".max_header_size", "max header size must not be negative")
+		}
+		if call.MaxURILength < 0 {
+			invalid(field+".max_uri_length", "max uri length must not be negative")
+		}
+		if call.Breaker != nil {
+			if call.Breaker.Failures < 1 {
+				invalid(field+".breaker.failures", "failures must be at least 1")
+			}
+			if call.Breaker.Cooldown < 0 {
+				invalid(field+".breaker.cooldown", "cooldown must not be negative")
+			}
+		}
+		if call.Session != nil && call.Session.TTL < 0 {
+			invalid(field+".session.ttl", "ttl must not be negative")
+		}
+		if call.Handshake != nil {
+			switch strings.ToLower(call.Handshake.Scheme) {
+			case "ntlm", "negotiate":
+			default:
+				invalid(field+".handshake.scheme", "invalid scheme %q, must be one of NTLM or Negotiate", call.Handshake.Scheme)
+			}
+		}
+		if call.Job != nil {
+			if call.Job.Name == "" {
+				invalid(field+".job.name", "name is required")
+			}
+			if call.Job.Webhook != nil {
+				if call.Job.Webhook.Target == "" {
+					invalid(field+".job.webhook.target", "target is required")
+				}
+				validateMethod(field+".job.webhook.method", call.Job.Webhook.Method, invalid)
+				if call.Job.Webhook.Delay < 0 {
+					invalid(field+".job.webhook.delay", "delay must not be negative")
+				}
+			}
+			for j, state := range call.Job.States {
+				if state.After < 0 {
+					invalid(fmt.Sprintf("%s.job.states[%d].after", field, j), "after must not be negative")
+				}
+				validateStatusCode(fmt.Sprintf("%s.job.states[%d].status_code", field, j), state.StatusCode, invalid)
+			}
+		}
+		if call.SignedURL != nil {
+			if call.SignedURL.Secret == "" {
+				invalid(field+".signed_url.secret", "secret is required")
+			}
+			if call.SignedURL.Issue == "" && !call.SignedURL.Validate {
+				invalid(field+".signed_url.issue", "issue is required, unless the signed url is validated")
+			}
+			if call.SignedURL.TTL < 0 {
+				invalid(field+".signed_url.ttl", "ttl must not be negative")
+			}
+			if call.SignedURL.Skew < 0 {
+				invalid(field+".signed_url.skew", "skew must not be negative")
+			}
+			if call.SignedURL.Replay < 0 {
+				invalid(field+".signed_url.replay", "replay must not be negative")
+			}
+		}
+		if call.Locale != nil && call.Locale.Default != "" && !slices.Contains(call.Locale.languages(), call.Locale.Default) {
+			invalid(field+".locale.default", "default %q has no response or messages", call.Locale.Default)
+		}
+		names := make([]string, 0, len(call.Extract))
+		for name := range call.Extract {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if key, err := call.Extract[name].validate(); err != nil {
+				invalid(field+".extract."+name+"."+key, "%s", err)
+			}
+		}
+		names = make([]string, 0, len(call.RequiredHeaders))
+		for name := range call.RequiredHeaders {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if _, err := call.RequiredHeaders[name].compile(); err != nil {
+				invalid(field+".required_headers."+name+".regex", "%s", err)
+			}
+		}
+		if call.GraphQL != "" {
+			if _, err := ParseGraphQLSchema(strings.NewReader(call.GraphQL)); err != nil {
+				invalid(field+".graphql", "%s", err)
+			}
+		}
+		for j, header := range call.ResponseHeaders {
+			if header.Name == "" {
+				invalid(fmt.Sprintf("%s.response_headers[%d].name", field, j), "name is required")
+			}
+		}
+		for j, info := range call.Informational {
+			if !validInformationalStatus(info.StatusCode) {
+				invalid(fmt.Sprintf("%s.informational[%d].status_code", field, j), fmt.Sprintf("invalid informational status code %d", info.StatusCode))
+			}
+		}
+		if call.Framing != "" && !validFraming(call.Framing) {
+			invalid(field+".framing", fmt.Sprintf("invalid framing %q, must be one of %s, %s, or %s", call.Framing, FramingContentLength, FramingChunked, FramingClose))
+		}
+		if call.Padding != nil && call.Padding.Size < 0 {
+			invalid(field+".padding.size", "size must not be negative")
+		}
+		if call.Cache != nil {
+			if call.Cache.MaxAge < 0 {
+				invalid(field+".cache.max_age", "max_age must not be negative")
+			}
+			if call.Cache.NoStore && (call.Cache.MaxAge > 0 || call.Cache.Revalidate || call.Cache.Private) {
+				invalid(field+".cache.no_store", "no_store cannot be combined with max_age, private, or revalidate")
+			}
+		}
+		for j, callback := range call.Callbacks {
+			if callback.Target == "" {
+				invalid(fmt.Sprintf("%s.callbacks[%d].target", field, j), "target is required")
+			}
+			validateMethod(fmt.Sprintf("%s.callbacks[%d].method", field, j), callback.Method, invalid)
+			if callback.Delay < 0 {
+				invalid(fmt.Sprintf("%s.callbacks[%d].delay", field, j), "delay must not be negative")
+			}
+		}
+		for j, branch := range call.Branches {
+			validateStatusCode(fmt.Sprintf("%s.branches[%d].status_code", field, j), branch.StatusCode, invalid)
+			for key, condition := range branch.When.QueryValues {
+				if condition.Count != nil && *condition.Count < 0 {
+					invalid(fmt.Sprintf("%s.branches[%d].when.query_values.%s.count", field, j, key), "count must not be negative")
+				}
+			}
+			if branch.When.ConnectionRequest < 0 {
+				invalid(fmt.Sprintf("%s.branches[%d].when.connection_request", field, j), "connection request must not be negative")
+			}
+			if auth := branch.When.Unauthenticated; auth != nil {
+				switch strings.ToLower(auth.Scheme) {
+				case "basic", "bearer", "digest":
+				default:
+					invalid(fmt.Sprintf("%s.branches[%d].when.unauthenticated.scheme", field, j), "invalid scheme %q, must be one of Basic, Bearer, or Digest", auth.Scheme)
+				}
+				switch strings.ToUpper(auth.Algorithm) {
+				case "", "MD5", "SHA-256":
+				default:
+					invalid(fmt.Sprintf("%s.branches[%d].when.unauthenticated.algorithm", field, j), "invalid algorithm %q, must be one of MD5 or SHA-256", auth.Algorithm)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateMethod checks that the method, if set, is a valid http method
+func validateMethod(field, method string, invalid func(string, string, ...interface{})) {
+	if method != "" && !methodPattern.MatchString(method) {
+		invalid(field, "invalid method %q", method)
+	}
+}
+
+// validateStatusCode checks that the status code, if set, is a valid http status code
+func validateStatusCode(field string, code int, invalid func(string, string, ...interface{})) {
+	if code != 0 && (code < 100 || code > 599) {
+		invalid(field, "invalid status code %d", code)
+	}
+}
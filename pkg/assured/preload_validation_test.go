@@ -0,0 +1,107 @@
+package assured
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPreloadValidationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		preload string
+		want    []string
+	}{
+		{
+			name:    "syntax error",
+			preload: "{\n  \"calls\": [\n    {\"path\": \"test\",}\n  ]\n}",
+			want:    []string{"invalid preload file calls.json:3: invalid character '}' looking for beginning of object key string"},
+		},
+		{
+			name:    "type error",
+			preload: "{\n  \"calls\": [\n    {\"path\": \"test\", \"status_code\": \"200\"}\n  ]\n}",
+			want:    []string{"invalid preload file calls.json:3: json: cannot unmarshal string into Go struct field"},
+		},
+		{
+			name:    "unknown field",
+			preload: "{\n  \"calls\": [\n    {\n      \"path\": \"test\",\n      \"stauts_code\": 200\n    }\n  ]\n}",
+			want:    []string{`invalid preload file calls.json:5: json: unknown field "stauts_code"`},
+		},
+		{
+			name: "invalid fields",
+			preload: `{
+				"defaults": {"method": "GET ME", "delay": -1},
+				"calls": [
+					{"path": "users/{id}/{id}", "status_code": 2000, "status_codes": [500, 99], "concurrency": -1, "max_body_size": -1, "max_header_size": -1, "max_uri_length": -1, "framing": "gzip", "informational": [{"status_code": 200}], "response_headers": [{"value": "a=1"}], "breaker": {"failures": 0, "cooldown": -1}, "handshake": {"scheme": "Kerberos"}, "graphql": "type Query { user: }", "locale": {"default": "en", "messages": {"fr": {}}}, "extract": {"id": {"from": "query"}, "email": {"json_path": "email"}}, "required_headers": {"X-Tenant": {"regex": "acme("}}, "job": {"states": [{"status": "done", "after": -1, "status_code": 999}], "webhook": {"delay": -1}}, "signed_url": {"ttl": -1, "skew": -1, "replay": -1}, "session": {"start": true, "ttl": -1}},
+					{"path": "callback", "path_regex": "users/(", "callbacks": [{"method": "POST"}], "branches": [{"when": {"query_values": {"id": {"count": -1}}}, "status_code": 1}, {"when": {"unauthenticated": {"scheme": "NTLM", "algorithm": "SHA-1"}, "connection_request": -1}}], "padding": {"size": -1}, "cache": {"max_age": -1, "no_store": true, "revalidate": true}}
+				]
+			}`,
+			want: []string{
+				`invalid preload file calls.json: defaults.method: invalid method "GET ME"`,
+				`invalid preload file calls.json: defaults.delay: delay must not be negative`,
+				`invalid preload file calls.json: calls[0].path: invalid path template: duplicate parameter "id"`,
+				`invalid preload file calls.json: calls[0].status_code: invalid status code 2000`,
+				`invalid preload file calls.json: calls[0].status_codes[1]: invalid status code 99`,
+				`invalid preload file calls.json: calls[0].concurrency: concurrency must not be negative`,
+				`invalid preload file calls.json: calls[0].max_body_size: max body size must not be negative`,
+				`invalid preload file calls.json: calls[0].max_header_size: max header size must not be negative`,
+				`invalid preload file calls.json: calls[0].max_uri_length: max uri length must not be negative`,
+				`invalid preload file calls.json: calls[0].response_headers[0].name: name is required`,
+				`invalid preload file calls.json: calls[0].informational[0].status_code: invalid informational status code 200`,
+				`invalid preload file calls.json: calls[0].framing: invalid framing "gzip", must be one of content-length, chunked, or close`,
+				`invalid preload file calls.json: calls[0].breaker.failures: failures must be at least 1`,
+				`invalid preload file calls.json: calls[0].breaker.cooldown: cooldown must not be negative`,
+				`invalid preload file calls.json: calls[0].session.ttl: ttl must not be negative`,
+				`invalid preload file calls.json: calls[0].handshake.scheme: invalid scheme "Kerberos", must be one of NTLM or Negotiate`,
+				`invalid preload file calls.json: calls[0].job.name: name is required`,
+				`invalid preload file calls.json: calls[0].job.webhook.target: target is required`,
+				`invalid preload file calls.json: calls[0].job.webhook.delay: delay must not be negative`,
+				`invalid preload file calls.json: calls[0].job.states[0].after: after must not be negative`,
+				`invalid preload file calls.json: calls[0].job.states[0].status_code: invalid status code 999`,
+				`invalid preload file calls.json: calls[0].signed_url.secret: secret is required`,
+				`invalid preload file calls.json: calls[0].signed_url.issue: issue is required, unless the signed url is validated`,
+				`invalid preload file calls.json: calls[0].signed_url.ttl: ttl must not be negative`,
+				`invalid preload file calls.json: calls[0].signed_url.skew: skew must not be negative`,
+				`invalid preload file calls.json: calls[0].signed_url.replay: replay must not be negative`,
+				`invalid preload file calls.json: calls[0].locale.default: default "en" has no response or messages`,
+				`invalid preload file calls.json: calls[0].extract.email.json_path: invalid json path "email": must start with $`,
+				`invalid preload file calls.json: calls[0].extract.id.name: name is required to extract from the query`,
+				"invalid preload file calls.json: calls[0].required_headers.X-Tenant.regex: invalid regex: error parsing regexp: missing closing ): `acme(`",
+				`invalid preload file calls.json: calls[0].graphql: invalid graphql schema: expected a name, found "}"`,
+				"invalid preload file calls.json: calls[1].path_regex: invalid path regex: error parsing regexp: missing closing ): `^(?:users/()$`",
+				`invalid preload file calls.json: calls[1].callbacks[0].target: target is required`,
+				`invalid preload file calls.json: calls[1].branches[0].status_code: invalid status code 1`,
+				`invalid preload file calls.json: calls[1].branches[0].when.query_values.id.count: count must not be negative`,
+				`invalid preload file calls.json: calls[1].branches[1].when.connection_request: connection request must not be negative`,
+				`invalid preload file calls.json: calls[1].branches[1].when.unauthenticated.scheme: invalid scheme "NTLM", must be one of Basic, Bearer, or Digest`,
+				`invalid preload file calls.json: calls[1].branches[1].when.unauthenticated.algorithm: invalid algorithm "SHA-1", must be one of MD5 or SHA-256`,
+				`invalid preload file calls.json: calls[1].padding.size: size must not be negative`,
+				`invalid preload file calls.json: calls[1].cache.max_age: max_age must not be negative`,
+				`invalid preload file calls.json: calls[1].cache.no_store: no_store cannot be combined with max_age, private, or revalidate`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "calls.json"), []byte(tt.preload), 0o644))
+			wd, err := os.Getwd()
+			require.NoError(t, err)
+			require.NoError(t, os.Chdir(dir))
+			defer func() { require.NoError(t, os.Chdir(wd)) }()
+
+			calls, err := LoadPreload("calls.json")
+
+			require.Nil(t, calls)
+			require.Error(t, err)
+			for _, want := range tt.want {
+				require.Contains(t, err.Error(), want)
+			}
+			var preloadErr *PreloadError
+			require.True(t, errors.As(err, &preloadErr))
+		})
+	}
+}
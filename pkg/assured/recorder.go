@@ -0,0 +1,136 @@
+package assured
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// The buffered calls of the recorder, and the most calls it records into the journal at once
+const (
+	recorderBuffer    = 4096
+	recorderBatchSize = 256
+)
+
+// recording is a call made to record into the made calls journal, with the time it was made,
+// or a flush barrier, closed once every call made before it is recorded
+type recording struct {
+	call    *Call
+	at      time.Time
+	flushed chan struct{}
+}
+
+// recorder is the buffered pipeline of the calls made, recorded into the made calls journal in batches,
+// so tracking a call doesn't contend on the journal's lock on the hot path. Until it is running, calls are recorded directly
+type recorder struct {
+	recordings chan recording
+	running    atomic.Bool
+	// sending is the count of calls being recorded, sent to the pipeline unless it has stopped running
+	sending atomic.Int64
+	stopped chan struct{}
+}
+
+// newRecorder creates a recorder that isn't running yet
+func newRecorder() *recorder {
+	return &recorder{recordings: make(chan recording, recorderBuffer), stopped: make(chan struct{})}
+}
+
+// run records the calls made into the journal in batches until the context is done, and then the calls still buffered
+func (r *recorder) run(ctx context.Context, journal *CallStore) {
+	r.running.Store(true)
+	defer close(r.stopped)
+	b := &batch{calls: make([]*Call, 0, recorderBatchSize), times: make([]time.Time, 0, recorderBatchSize)}
+	for {
+		select {
+		case <-ctx.Done():
+			// Calls are recorded directly from here on, so the calls buffered, or being sent, are the last through the pipeline
+			r.running.Store(false)
+			for {
+				select {
+				case first := <-r.recordings:
+					b.record(first, r.recordings, journal)
+				default:
+					if r.sending.Load() == 0 && len(r.recordings) == 0 {
+						return
+					}
+					runtime.Gosched()
+				}
+			}
+		case first := <-r.recordings:
+			b.record(first, r.recordings, journal)
+		}
+	}
+}
+
+// batch is the calls made, and flush barriers, recorded into the journal at once
+type batch struct {
+	calls    []*Call
+	times    []time.Time
+	barriers []chan struct{}
+}
+
+// record records the recording, and those buffered after it up to the batch size, into the journal, and then closes their flush barriers
+func (b *batch) record(first recording, recordings chan recording, journal *CallStore) {
+	next := first
+	for {
+		if next.flushed != nil {
+			b.barriers = append(b.barriers, next.flushed)
+		} else {
+			b.calls, b.times = append(b.calls, next.call), append(b.times, next.at)
+		}
+		if len(b.calls) == recorderBatchSize {
+			break
+		}
+		var ok bool
+		select {
+		case next, ok = <-recordings:
+		default:
+		}
+		if !ok {
+			break
+		}
+	}
+	journal.RecordBatch(b.calls, b.times)
+	for _, flushed := range b.barriers {
+		close(flushed)
+	}
+	b.calls, b.times, b.barriers = b.calls[:0], b.times[:0], b.barriers[:0]
+}
+
+// record records the call made into the journal, through the pipeline if it is running
+func (r *recorder) record(journal *CallStore, call *Call, at time.Time) {
+	if r == nil {
+		journal.Record(call, at)
+		return
+	}
+	// The call is counted before checking the pipeline is running, so it is either sent before the pipeline is drained, or recorded directly
+	r.sending.Add(1)
+	defer r.sending.Add(-1)
+	if !r.running.Load() {
+		journal.Record(call, at)
+		return
+	}
+	select {
+	case r.recordings <- recording{call: call, at: at}:
+	case <-r.stopped:
+		journal.Record(call, at)
+	}
+}
+
+// flush waits until every call made before it is recorded into the journal, so reading the journal sees every call
+func (r *recorder) flush() {
+	if r == nil || !r.running.Load() {
+		return
+	}
+	flushed := make(chan struct{})
+	select {
+	case r.recordings <- recording{flushed: flushed}:
+	case <-r.stopped:
+		return
+	}
+	select {
+	case <-flushed:
+	case <-r.stopped:
+	}
+}
@@ -0,0 +1,105 @@
+package assured
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderNotRunning(t *testing.T) {
+	journal := NewCallStore()
+	r := newRecorder()
+
+	r.record(journal, testCall1(), time.Now())
+	r.flush()
+
+	require.Equal(t, 1, journal.Len())
+}
+
+func TestRecorderFlush(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	journal := NewCallStore()
+	r := newRecorder()
+	go r.run(ctx, journal)
+	require.Eventually(t, r.running.Load, time.Second, time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				r.record(journal, &Call{Method: "GET", Path: fmt.Sprintf("recorder/%d", i)}, time.Now())
+			}
+		}(i)
+	}
+	wg.Wait()
+	r.flush()
+
+	require.Equal(t, 4000, journal.Len())
+	for i := 0; i < 8; i++ {
+		calls, times := journal.Recorded(fmt.Sprintf("GET:recorder/%d", i))
+		require.Len(t, calls, 500)
+		require.False(t, times[0].IsZero())
+	}
+}
+
+func TestRecorderStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	journal := NewCallStore()
+	r := newRecorder()
+	go r.run(ctx, journal)
+	require.Eventually(t, r.running.Load, time.Second, time.Millisecond)
+
+	cancel()
+	<-r.stopped
+	r.record(journal, testCall1(), time.Now())
+	r.flush()
+
+	require.Equal(t, 1, journal.Len())
+}
+
+func TestRecorderStoppedDrainsBuffered(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	journal := NewCallStore()
+	r := newRecorder()
+	r.running.Store(true)
+	for i := 0; i < 300; i++ {
+		r.record(journal, testCall1(), time.Now())
+	}
+	require.Equal(t, 0, journal.Len())
+
+	cancel()
+	go r.run(ctx, journal)
+	<-r.stopped
+
+	require.False(t, r.running.Load())
+	require.Equal(t, 300, journal.Len())
+}
+
+func TestClientVerifyRecordedCalls(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{Path: "recorded/assured"}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.httpClient.Get(client.URL() + "/recorded/assured")
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	calls, err := client.Verify("GET", "recorded/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 50)
+}
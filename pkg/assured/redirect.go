@@ -0,0 +1,54 @@
+package assured
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// RedirectChainCalls returns the calls to stub for a chain of redirects, from the call's path through the hops {path}/1 to {path}/{hops},
+// which responds with the call. The redirects respond with the status code, 302 Found by default, to test clients' redirect-limit policies
+// The Location headers are relative, so the chain is followed on any host and base path
+func RedirectChainCalls(call Call, hops, statusCode int) []Call {
+	if hops <= 0 {
+		return []Call{call}
+	}
+	base := strings.Trim(call.Path, "/")
+	calls := make([]Call, 0, hops+1)
+	calls = append(calls, redirectCall(base, call.Method, statusCode, path.Base(base)+"/1"))
+	for hop := 1; hop < hops; hop++ {
+		calls = append(calls, redirectCall(base+"/"+strconv.Itoa(hop), call.Method, statusCode, strconv.Itoa(hop+1)))
+	}
+	call.Path = base + "/" + strconv.Itoa(hops)
+	return append(calls, call)
+}
+
+// RedirectLoopCalls returns the calls to stub for an intentional redirect loop of the length, from the call's path through the hops {path}/1
+// to {path}/{length-1} and back to the call's path. A length of 1 redirects the call's path to itself
+// The redirects respond with the status code, 302 Found by default, and the call's response is never served
+func RedirectLoopCalls(call Call, length, statusCode int) []Call {
+	base := strings.Trim(call.Path, "/")
+	if length <= 1 {
+		return []Call{redirectCall(base, call.Method, statusCode, path.Base(base))}
+	}
+	calls := make([]Call, 0, length)
+	calls = append(calls, redirectCall(base, call.Method, statusCode, path.Base(base)+"/1"))
+	for hop := 1; hop < length-1; hop++ {
+		calls = append(calls, redirectCall(base+"/"+strconv.Itoa(hop), call.Method, statusCode, strconv.Itoa(hop+1)))
+	}
+	return append(calls, redirectCall(base+"/"+strconv.Itoa(length-1), call.Method, statusCode, "../"+path.Base(base)))
+}
+
+// redirectCall returns the call redirecting to the location, relative to the call's path
+func redirectCall(path, method string, statusCode int, location string) Call {
+	if statusCode == 0 {
+		statusCode = http.StatusFound
+	}
+	return Call{
+		Path:       path,
+		Method:     method,
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Location": location},
+	}
+}
@@ -0,0 +1,76 @@
+package assured
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectChainCalls(t *testing.T) {
+	call := Call{Path: "/chain/assured/", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("done")}
+
+	calls := RedirectChainCalls(call, 2, http.StatusMovedPermanently)
+
+	require.Equal(t, []Call{
+		{Path: "chain/assured", Method: http.MethodGet, StatusCode: http.StatusMovedPermanently, Headers: map[string]string{"Location": "assured/1"}},
+		{Path: "chain/assured/1", Method: http.MethodGet, StatusCode: http.StatusMovedPermanently, Headers: map[string]string{"Location": "2"}},
+		{Path: "chain/assured/2", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("done")},
+	}, calls)
+}
+
+func TestRedirectChainCallsNoHops(t *testing.T) {
+	call := Call{Path: "chain/assured"}
+
+	require.Equal(t, []Call{call}, RedirectChainCalls(call, 0, 0))
+}
+
+func TestRedirectLoopCalls(t *testing.T) {
+	calls := RedirectLoopCalls(Call{Path: "loop/assured", Method: http.MethodGet}, 3, 0)
+
+	require.Equal(t, []Call{
+		{Path: "loop/assured", Method: http.MethodGet, StatusCode: http.StatusFound, Headers: map[string]string{"Location": "assured/1"}},
+		{Path: "loop/assured/1", Method: http.MethodGet, StatusCode: http.StatusFound, Headers: map[string]string{"Location": "2"}},
+		{Path: "loop/assured/2", Method: http.MethodGet, StatusCode: http.StatusFound, Headers: map[string]string{"Location": "../assured"}},
+	}, calls)
+}
+
+func TestClientRedirectChainCalls(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(RedirectChainCalls(Call{Path: "chain/assured", Response: []byte("done")}, 3, 0)...))
+
+	resp, err := http.Get(client.URL() + "/chain/assured")
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "done", string(body))
+	require.Equal(t, client.URL()+"/chain/assured/3", resp.Request.URL.String())
+
+	limited := &http.Client{CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+		if len(via) >= 2 {
+			return errors.New("too many redirects")
+		}
+		return nil
+	}}
+	_, err = limited.Get(client.URL() + "/chain/assured")
+	require.ErrorContains(t, err, "too many redirects")
+}
+
+func TestClientRedirectLoopCalls(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(RedirectLoopCalls(Call{Path: "loop/assured"}, 2, http.StatusTemporaryRedirect)...))
+
+	_, err := http.Get(client.URL() + "/loop/assured")
+
+	require.ErrorContains(t, err, "stopped after 10 redirects")
+	calls, err := client.Verify(http.MethodGet, "loop/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 5)
+	calls, err = client.Verify(http.MethodGet, "loop/assured/1")
+	require.NoError(t, err)
+	require.Len(t, calls, 5)
+}
@@ -0,0 +1,88 @@
+package assured
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"strings"
+)
+
+// ResponseValidation is how the rest assured server handles a stubbed call responding with a body that doesn't parse as
+// its declared Content-Type, to catch broken fixtures early, such as JSON with a trailing comma
+type ResponseValidation string
+
+const (
+	// ResponseValidationWarn logs a warning for each invalid response, and responds with it anyway
+	ResponseValidationWarn ResponseValidation = "warn"
+	// ResponseValidationStrict responds 500 Internal Server Error in place of each invalid response
+	ResponseValidationStrict ResponseValidation = "strict"
+)
+
+// valid reports whether the response validation is one of the response validations, or the default of no validation
+func (v ResponseValidation) valid() bool {
+	switch v {
+	case "", ResponseValidationWarn, ResponseValidationStrict:
+		return true
+	}
+	return false
+}
+
+// validateResponse reports whether the stubbed call's response parses as its declared JSON or XML Content-Type
+// Responses without a body, with a Content-Encoding, or of other content types aren't validated
+func (c *Call) validateResponse() error {
+	return c.validateResponseAs(c.contentType())
+}
+
+// validateResponseAs reports whether the stubbed call's response parses as the JSON or XML content type it is served with
+func (c *Call) validateResponseAs(contentType string) error {
+	if contentType == "" || len(c.Response) == 0 {
+		return nil
+	}
+	for name := range c.Headers {
+		if strings.EqualFold(name, "Content-Encoding") {
+			return nil
+		}
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q: %w", contentType, err)
+	}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var document any
+		if err := json.Unmarshal(c.Response, &document); err != nil {
+			return fmt.Errorf("invalid %s response: %w", mediaType, err)
+		}
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		decoder := xml.NewDecoder(bytes.NewReader(c.Response))
+		for {
+			_, err := decoder.Token()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("invalid %s response: %w", mediaType, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateResponse checks the response of the stubbed call served for the call made parses as its declared, or inferred, Content-Type,
+// warning of an invalid response, or returning it as an error in strict mode
+func (a *AssuredEndpoints) validateResponse(assured *Call) error {
+	err := assured.validateResponseAs(a.servedContentType(assured))
+	if err == nil {
+		return nil
+	}
+	slog.With("path", assured.ID(), "error", err).Warn("assured call response is invalid")
+	if a.validation == ResponseValidationStrict {
+		return fmt.Errorf("invalid stubbed response for %s: %w", assured.ID(), err)
+	}
+	return nil
+}
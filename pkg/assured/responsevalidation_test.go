@@ -0,0 +1,86 @@
+package assured
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallValidateResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		call Call
+		want string
+	}{
+		{name: "no content type", call: Call{Response: []byte(`{"id":`)}},
+		{name: "no body", call: Call{Headers: map[string]string{"Content-Type": "application/json"}}},
+		{name: "json", call: Call{Headers: map[string]string{"Content-Type": "application/json; charset=utf-8"}, Response: []byte(`{"id":1}`)}},
+		{name: "invalid json", call: Call{Headers: map[string]string{"Content-Type": "application/json"}, Response: []byte(`{"id":1,}`)},
+			want: "invalid application/json response: invalid character '}' looking for beginning of object key string"},
+		{name: "invalid json suffix", call: Call{ResponseHeaders: []Header{{Name: "content-type", Value: "application/problem+json"}}, Response: []byte(`{"title":`)},
+			want: "invalid application/problem+json response: unexpected end of JSON input"},
+		{name: "xml", call: Call{Headers: map[string]string{"Content-Type": "application/xml"}, Response: []byte(`<?xml version="1.0"?><user><id>1</id></user>`)}},
+		{name: "invalid xml", call: Call{Headers: map[string]string{"Content-Type": "text/xml"}, Response: []byte(`<user><id>1</user>`)},
+			want: "invalid text/xml response: XML syntax error on line 1: element <id> closed by </user>"},
+		{name: "invalid xml suffix", call: Call{Headers: map[string]string{"Content-Type": "application/atom+xml"}, Response: []byte(`<feed>`)},
+			want: "invalid application/atom+xml response: XML syntax error on line 1: unexpected EOF"},
+		{name: "other content type", call: Call{Headers: map[string]string{"Content-Type": "text/plain"}, Response: []byte(`{"id":`)}},
+		{name: "encoded", call: Call{Headers: map[string]string{"Content-Type": "application/json", "Content-Encoding": "gzip"}, Response: []byte{0x1f, 0x8b}}},
+		{name: "invalid content type", call: Call{Headers: map[string]string{"Content-Type": "application/json; charset"}, Response: []byte(`{}`)},
+			want: `invalid content type "application/json; charset": mime: invalid media parameter`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call.validateResponse()
+			if tc.want == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.want)
+		})
+	}
+}
+
+func TestWhenEndpointResponseValidation(t *testing.T) {
+	for _, validation := range []ResponseValidation{"", ResponseValidationWarn, ResponseValidationStrict} {
+		options := DefaultOptions
+		WithResponseValidation(validation)(&options)
+		endpoints := NewAssuredEndpoints(options)
+		_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{Path: "users/1", Method: http.MethodGet, Headers: map[string]string{"Content-Type": "application/json"}, Response: []byte(`{"id":1,}`)})
+		_, _ = endpoints.GivenEndpoint(context.TODO(), &Call{Path: "users/2", Method: http.MethodGet, Response: []byte(`{"id":{{counter "user"}}}`)})
+
+		c, err := endpoints.WhenEndpoint(context.TODO(), &Call{Path: "users/1", Method: http.MethodGet})
+		if validation == ResponseValidationStrict {
+			require.EqualError(t, err, "invalid stubbed response for GET:users/1: invalid application/json response: invalid character '}' looking for beginning of object key string")
+		} else {
+			require.NoError(t, err, validation)
+			require.Equal(t, `{"id":1,}`, string(c.(*Call).Response), validation)
+		}
+
+		c, err = endpoints.WhenEndpoint(context.TODO(), &Call{Path: "users/2", Method: http.MethodGet})
+		require.NoError(t, err, validation)
+		require.Equal(t, `{"id":1}`, string(c.(*Call).Response), validation)
+	}
+}
+
+func TestClientResponseValidation(t *testing.T) {
+	_, client := NewTestServer(t, WithResponseValidation(ResponseValidationStrict), WithErrorFormat(ErrorFormatJSON))
+	require.NoError(t, client.Given(Call{Path: "users/1", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte(`<user><id>1</user>`)}))
+
+	resp, err := http.Get(client.URL() + "/users/1")
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error":"invalid stubbed response for GET:users/1: invalid application/xml response: XML syntax error on line 1: element <id> closed by </user>","status":500}`, string(body))
+}
+
+func TestResponseValidationInvalid(t *testing.T) {
+	_, err := NewClientE(WithResponseValidation("fail"))
+
+	require.EqualError(t, err, `invalid response validation "fail"`)
+}
@@ -0,0 +1,82 @@
+package assured
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AssuredRevision is the header labelling the stub set revision a change belongs to
+// Consecutive changes with the same label are one revision, so stubbing many calls at once is rolled back at once
+const AssuredRevision = "Assured-Revision"
+
+// stubRevision is a snapshot of the stubbed calls and callbacks, taken before the stub set is changed
+type stubRevision struct {
+	label     string
+	at        time.Time
+	calls     map[string][]*Call
+	callbacks map[string][]*Call
+}
+
+// stubHistory is the bounded history of stub set revisions to roll back to
+type stubHistory struct {
+	sync.Mutex
+	limit     int
+	label     string
+	revisions []stubRevision
+}
+
+// revise snapshots the stub set before it is changed, unless the change is labelled the same as the previous change
+// The oldest revisions are dropped past the history limit, and nothing is kept with a limit of 0
+func (a *AssuredEndpoints) revise(label string) {
+	h := &a.history
+	h.Lock()
+	defer h.Unlock()
+	if h.limit <= 0 || a.frozen.Load() || (label != "" && label == h.label) {
+		return
+	}
+	h.label = label
+	h.revisions = append(h.revisions, stubRevision{
+		label:     label,
+		at:        time.Now(),
+		calls:     a.assuredCalls.Snapshot(),
+		callbacks: a.callbackCalls.Snapshot(),
+	})
+	if len(h.revisions) > h.limit {
+		h.revisions = h.revisions[len(h.revisions)-h.limit:]
+	}
+}
+
+// Rollback restores the stub set to the revision before the latest change, and returns the number of revisions left to roll back to
+func (a *AssuredEndpoints) Rollback() (int, error) {
+	if a.frozen.Load() {
+		return 0, errFrozen
+	}
+	h := &a.history
+	h.Lock()
+	defer h.Unlock()
+	if len(h.revisions) == 0 {
+		return 0, errors.New("no stub revisions to roll back to")
+	}
+	revision := h.revisions[len(h.revisions)-1]
+	h.revisions = h.revisions[:len(h.revisions)-1]
+	h.label = ""
+	a.assuredCalls.Restore(revision.calls)
+	a.countPathRegexes()
+	a.callbackCalls.Restore(revision.callbacks)
+	a.sequences.resetAll()
+	slog.With("revision", revision.label, "at", revision.at, "remaining", len(h.revisions)).Info("rolled back stubbed calls")
+	return len(h.revisions), nil
+}
+
+// revisioned snapshots the stub set before the handler changes it, grouping the changes by their Assured-Revision header
+func revisioned(e *AssuredEndpoints, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		e.revise(req.Header.Get(AssuredRevision))
+		// The revision is not part of the stubbed call
+		req.Header.Del(AssuredRevision)
+		handler.ServeHTTP(w, req)
+	})
+}
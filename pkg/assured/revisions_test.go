@@ -0,0 +1,127 @@
+package assured
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRollback(t *testing.T) {
+	_, client := NewTestServer(t)
+	respond := func(path string) string {
+		resp, err := http.Get(client.URL() + "/" + path)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	require.NoError(t, client.Given(Call{Path: "fixture/a", Response: []byte("a1")}))
+	require.NoError(t, client.Given(Call{Path: "fixture/a", Response: []byte("a2")}, Call{Path: "fixture/b", Response: []byte("b")}))
+	require.Equal(t, "a1", respond("fixture/a"))
+	require.Equal(t, "a2", respond("fixture/a"))
+
+	require.NoError(t, client.Rollback())
+	require.Equal(t, "a1", respond("fixture/a"))
+	require.Equal(t, "a1", respond("fixture/a"))
+	require.Equal(t, "No assured calls", respond("fixture/b"))
+
+	require.NoError(t, client.Replace(Call{Path: "fixture/broken", Response: []byte("broken")}))
+	require.Equal(t, "No assured calls", respond("fixture/a"))
+	require.NoError(t, client.Rollback())
+	require.Equal(t, "a1", respond("fixture/a"))
+	require.Equal(t, "No assured calls", respond("fixture/broken"))
+
+	require.NoError(t, client.ClearAll())
+	require.NoError(t, client.Rollback())
+	require.Equal(t, "a1", respond("fixture/a"))
+
+	require.NoError(t, client.Freeze())
+	require.EqualError(t, client.Rollback(), "failure to roll back calls: stubbed calls are frozen")
+	require.NoError(t, client.Unfreeze())
+
+	require.NoError(t, client.Rollback())
+	require.Equal(t, "No assured calls", respond("fixture/a"))
+	require.EqualError(t, client.Rollback(), "failure to roll back calls: no stub revisions to roll back to")
+}
+
+func TestClientRollbackHistory(t *testing.T) {
+	_, client := NewTestServer(t, WithStubHistory(2))
+	for _, path := range []string{"fixture/a", "fixture/b", "fixture/c"} {
+		require.NoError(t, client.Given(Call{Path: path}))
+	}
+
+	require.NoError(t, client.Rollback())
+	require.NoError(t, client.Rollback())
+	require.EqualError(t, client.Rollback(), "failure to roll back calls: no stub revisions to roll back to")
+	resp, err := http.Get(client.URL() + "/fixture/a")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, disabled := NewTestServer(t, WithStubHistory(0))
+	require.NoError(t, disabled.Given(Call{Path: "fixture/a"}))
+	require.Error(t, disabled.Rollback())
+}
+
+func TestClientReplace(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		_, client := NewTestServer(t)
+		client.legacy.Store(legacy)
+		require.NoError(t, client.Given(Call{Path: "fixture/a", Response: []byte("a")}))
+		resp, err := http.Get(client.URL() + "/fixture/a")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		require.NoError(t, client.Replace(Call{Path: "/fixture/b", Response: []byte("b")}))
+
+		resp, err = http.Get(client.URL() + "/fixture/b")
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.Equal(t, "b", string(body))
+		made, err := client.Verify(http.MethodGet, "fixture/a")
+		require.NoError(t, err)
+		// A server replacing the calls at once keeps the made calls, and clearing the calls first clears them
+		if legacy {
+			require.Empty(t, made)
+		} else {
+			require.Len(t, made, 1)
+		}
+	}
+}
+
+func TestClientReplaceFailure(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{Path: "fixture/a", Response: []byte("a")}))
+
+	err := client.Replace(Call{Path: "fixture/b"}, Call{Path: "fixture/c", Callbacks: []Callback{{Method: http.MethodPost}}})
+
+	require.EqualError(t, err, "failure to replace calls: cannot stub callback without target")
+	resp, err := http.Get(client.URL() + "/fixture/a")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, "a", string(body))
+}
+
+func TestReplaceEndpointFailure(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	_, _ = endpoints.GivenEndpoint(context.TODO(), testCall1())
+
+	_, err := endpoints.ReplaceEndpoint(context.TODO(), []*Call{testCall2(), {Path: "fixture/c", Callbacks: []Callback{{Method: http.MethodPost}}}})
+
+	require.EqualError(t, err, "cannot stub callback without target")
+	require.Equal(t, []*Call{testCall1()}, endpoints.assuredCalls.Get("GET:test/assured"))
+
+	replaced, err := endpoints.ReplaceEndpoint(context.TODO(), []*Call{{Path: "fixture/c", Response: []byte("c")}})
+
+	require.NoError(t, err)
+	require.Equal(t, 1, replaced)
+	require.Empty(t, endpoints.assuredCalls.Get("GET:test/assured"))
+	require.Len(t, endpoints.assuredCalls.Get("GET:fixture/c"), 1)
+}
@@ -0,0 +1,227 @@
+package assured
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// s3TimeFormat is the format of the object timestamps in S3 listings
+const s3TimeFormat = "2006-01-02T15:04:05.000Z"
+
+// s3ListBucketResult is the XML response of listing the objects of an S3 bucket
+type s3ListBucketResult struct {
+	XMLName        xml.Name         `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name           string           `xml:"Name"`
+	Prefix         string           `xml:"Prefix"`
+	Delimiter      string           `xml:"Delimiter,omitempty"`
+	KeyCount       int              `xml:"KeyCount"`
+	MaxKeys        int              `xml:"MaxKeys"`
+	IsTruncated    bool             `xml:"IsTruncated"`
+	Contents       []s3Object       `xml:"Contents"`
+	CommonPrefixes []s3CommonPrefix `xml:"CommonPrefixes"`
+}
+
+// s3Object is an object of an S3 bucket listing
+type s3Object struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int    `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// s3CommonPrefix is a prefix of the keys rolled up by the delimiter in an S3 bucket listing
+type s3CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// s3Objects are the objects put to the S3 buckets, by path, stored as the GET calls that serve them
+// They are kept apart from the stubbed calls, so putting objects doesn't change the stub set, while it is frozen or otherwise
+type s3Objects struct {
+	objects map[string]*Call
+	sync.Mutex
+}
+
+// clear deletes the objects put to the S3 buckets
+func (o *s3Objects) clear() {
+	o.Lock()
+	defer o.Unlock()
+	o.objects = nil
+}
+
+// s3Call mocks the S3 semantics of the call made to an object or bucket of the S3 buckets, in path style, e.g. PUT /when/bucket/key
+// Putting an object stores it with its ETag, to serve its GET and HEAD calls, unless calls are stubbed for its path
+// Returns false if the call is not made to an S3 bucket
+func (a *AssuredEndpoints) s3Call(call *Call) (*Call, bool) {
+	bucket, key, _ := strings.Cut(call.Path, "/")
+	if !slices.Contains(a.s3Buckets, bucket) {
+		return nil, false
+	}
+	requestID := AWSRequestID()
+	response := &Call{Path: call.Path, Method: call.Method, StatusCode: http.StatusOK, Headers: awsHeaders(nil, requestID)}
+
+	if key == "" {
+		switch call.Method {
+		case http.MethodGet:
+			response.Headers["Content-Type"] = "application/xml"
+			response.Response = a.s3List(bucket, call.Query["prefix"], call.Query["delimiter"])
+		case http.MethodPut, http.MethodHead:
+		default:
+			return a.s3Error(call, http.StatusMethodNotAllowed, "MethodNotAllowed", "The specified method is not allowed against this resource."), true
+		}
+		return response, true
+	}
+
+	switch call.Method {
+	case http.MethodPut:
+		content := call.Response
+		if strings.Contains(call.Headers["Content-Encoding"], "aws-chunked") || strings.HasPrefix(call.Headers["X-Amz-Content-Sha256"], "STREAMING-") {
+			decoded, err := decodeAWSChunked(content)
+			if err != nil {
+				return a.s3Error(call, http.StatusBadRequest, "IncompleteBody", err.Error()), true
+			}
+			content = decoded
+		}
+		etag := s3ETag(content)
+		headers := map[string]string{
+			"Content-Type":   "binary/octet-stream",
+			"Content-Length": strconv.Itoa(len(content)),
+			"Etag":           etag,
+			"Last-Modified":  time.Now().UTC().Format(http.TimeFormat),
+		}
+		for name, value := range call.Headers {
+			if name == "Content-Type" || strings.HasPrefix(name, "X-Amz-Meta-") {
+				headers[name] = value
+			}
+		}
+		a.s3Objects.Lock()
+		if a.s3Objects.objects == nil {
+			a.s3Objects.objects = map[string]*Call{}
+		}
+		a.s3Objects.objects[call.Path] = &Call{Path: call.Path, Method: http.MethodGet, StatusCode: http.StatusOK, Headers: headers, Response: content}
+		a.s3Objects.Unlock()
+		response.Headers["Etag"] = etag
+	case http.MethodDelete:
+		a.s3Objects.Lock()
+		delete(a.s3Objects.objects, call.Path)
+		a.s3Objects.Unlock()
+		response.StatusCode = http.StatusNoContent
+	case http.MethodGet, http.MethodHead:
+		a.s3Objects.Lock()
+		object, ok := a.s3Objects.objects[call.Path]
+		a.s3Objects.Unlock()
+		if !ok && call.Method == http.MethodHead {
+			response.StatusCode = http.StatusNotFound
+			break
+		}
+		if !ok {
+			return a.s3Error(call, http.StatusNotFound, "NoSuchKey", "The specified key does not exist."), true
+		}
+		response.Headers = awsHeaders(object.Headers, requestID)
+		if call.Method == http.MethodGet {
+			response.Response = object.Response
+		}
+	default:
+		return a.s3Error(call, http.StatusMethodNotAllowed, "MethodNotAllowed", "The specified method is not allowed against this resource."), true
+	}
+	return response, true
+}
+
+// s3List returns the XML listing of the bucket's objects with the prefix, with the keys containing the delimiter after the prefix rolled up into common prefixes
+// The objects put are listed along with the GET calls stubbed for the bucket's paths, which take precedence
+func (a *AssuredEndpoints) s3List(bucket, prefix, delimiter string) []byte {
+	objects := map[string]*Call{}
+	a.s3Objects.Lock()
+	for path, object := range a.s3Objects.objects {
+		if key, ok := strings.CutPrefix(path, bucket+"/"); ok && strings.HasPrefix(key, prefix) {
+			objects[key] = object
+		}
+	}
+	a.s3Objects.Unlock()
+	for _, id := range a.assuredCalls.Keys(http.MethodGet + ":" + bucket + "/" + prefix) {
+		if calls := a.assuredCalls.Get(id); len(calls) > 0 {
+			objects[strings.TrimPrefix(id, http.MethodGet+":"+bucket+"/")] = calls[0]
+		}
+	}
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	result := s3ListBucketResult{Name: bucket, Prefix: prefix, Delimiter: delimiter, MaxKeys: 1000}
+	for _, key := range keys {
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				common := key[:len(prefix)+i+len(delimiter)]
+				if n := len(result.CommonPrefixes); n == 0 || result.CommonPrefixes[n-1].Prefix != common {
+					result.CommonPrefixes = append(result.CommonPrefixes, s3CommonPrefix{Prefix: common})
+				}
+				continue
+			}
+		}
+
+		object := objects[key]
+		modified, err := time.Parse(http.TimeFormat, object.Headers["Last-Modified"])
+		if err != nil {
+			modified = a.started
+		}
+		etag := object.Headers["Etag"]
+		if etag == "" {
+			etag = s3ETag(object.Response)
+		}
+		result.Contents = append(result.Contents, s3Object{
+			Key:          key,
+			LastModified: modified.UTC().Format(s3TimeFormat),
+			ETag:         etag,
+			Size:         len(object.Response),
+			StorageClass: "STANDARD",
+		})
+	}
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+	response, _ := xml.Marshal(result)
+	return append([]byte(xml.Header), response...)
+}
+
+// s3Error returns the S3 error response for the call made
+func (a *AssuredEndpoints) s3Error(call *Call, statusCode int, code, message string) *Call {
+	response := S3ErrorCall(Call{Path: call.Path, Method: call.Method}, statusCode, code, message)
+	return &response
+}
+
+// s3ETag returns the ETag S3 sets for an object uploaded in a single part, the quoted MD5 digest of its content
+func s3ETag(content []byte) string {
+	digest := md5.Sum(content)
+	return `"` + hex.EncodeToString(digest[:]) + `"`
+}
+
+// decodeAWSChunked returns the content of an aws-chunked encoded body, which the AWS SDKs stream uploads with
+// Each chunk is its hex size, optionally followed by its signature, then its data, until a zero sized chunk and the trailing headers
+func decodeAWSChunked(body []byte) ([]byte, error) {
+	var content []byte
+	for {
+		line, rest, ok := bytes.Cut(body, []byte("\r\n"))
+		if !ok {
+			return nil, errors.New("invalid aws-chunked body: missing chunk size")
+		}
+		sizeHex, _, _ := strings.Cut(string(line), ";")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeHex), 16, 64)
+		if err != nil || size < 0 || int(size) > len(rest) {
+			return nil, errors.New("invalid aws-chunked body: invalid chunk size")
+		}
+		if size == 0 {
+			return content, nil
+		}
+		content = append(content, rest[:size]...)
+		body = bytes.TrimPrefix(rest[size:], []byte("\r\n"))
+	}
+}
@@ -0,0 +1,185 @@
+package assured
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientS3(t *testing.T) {
+	_, client := NewTestServer(t, WithS3Buckets("uploads"))
+	base := client.URL() + "/uploads/"
+
+	resp, err := s3Do(t, http.MethodGet, base+"reports/2024.csv", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "<Code>NoSuchKey</Code>")
+
+	resp, err = s3Do(t, http.MethodPut, base+"reports/2024.csv", "id,total", map[string]string{"Content-Type": "text/csv", "X-Amz-Meta-Owner": "finance"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `"bd362fc396b10062acda04f9f5fad1c6"`, resp.Header.Get("ETag"))
+	require.NotEmpty(t, resp.Header.Get("x-amz-request-id"))
+
+	resp, err = s3Do(t, http.MethodGet, base+"reports/2024.csv", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+	require.Equal(t, "finance", resp.Header.Get("x-amz-meta-owner"))
+	require.Equal(t, `"bd362fc396b10062acda04f9f5fad1c6"`, resp.Header.Get("ETag"))
+	require.NotEmpty(t, resp.Header.Get("Last-Modified"))
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "id,total", string(body))
+
+	resp, err = s3Do(t, http.MethodHead, base+"reports/2024.csv", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int64(8), resp.ContentLength)
+
+	_, err = s3Do(t, http.MethodPut, base+"reports/2025.csv", "id", nil)
+	require.NoError(t, err)
+	_, err = s3Do(t, http.MethodPut, base+"readme.txt", "hello", nil)
+	require.NoError(t, err)
+
+	list := s3List(t, client.URL()+"/uploads?list-type=2&prefix=reports/")
+	require.Equal(t, "uploads", list.Name)
+	require.Equal(t, 2, list.KeyCount)
+	require.Equal(t, "reports/2024.csv", list.Contents[0].Key)
+	require.Equal(t, 8, list.Contents[0].Size)
+	require.Equal(t, `"bd362fc396b10062acda04f9f5fad1c6"`, list.Contents[0].ETag)
+	require.Equal(t, "reports/2025.csv", list.Contents[1].Key)
+
+	list = s3List(t, client.URL()+"/uploads?list-type=2&delimiter=/")
+	require.Equal(t, []s3Object{{Key: "readme.txt", LastModified: list.Contents[0].LastModified, ETag: `"5d41402abc4b2a76b9719d911017c592"`, Size: 5, StorageClass: "STANDARD"}}, list.Contents)
+	require.Equal(t, []s3CommonPrefix{{Prefix: "reports/"}}, list.CommonPrefixes)
+
+	resp, err = s3Do(t, http.MethodDelete, base+"reports/2024.csv", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, err = s3Do(t, http.MethodHead, base+"reports/2024.csv", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, 1, s3List(t, client.URL()+"/uploads?list-type=2&prefix=reports/").KeyCount)
+
+	calls, err := client.Verify(http.MethodPut, "uploads/reports/2024.csv")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	require.Equal(t, "id,total", string(calls[0].Response))
+}
+
+func TestClientS3Stubbed(t *testing.T) {
+	_, client := NewTestServer(t, WithS3Buckets("uploads"))
+	require.NoError(t, client.Given(
+		Call{Path: "uploads/seeded.txt", Response: []byte("seeded")},
+		S3ErrorCall(Call{Path: "uploads/denied.txt", Method: http.MethodPut}, http.StatusForbidden, "AccessDenied", "Access Denied"),
+	))
+
+	list := s3List(t, client.URL()+"/uploads")
+	require.Len(t, list.Contents, 1)
+	require.Equal(t, "seeded.txt", list.Contents[0].Key)
+	require.Equal(t, `"c0d22d2bc2480944a2ed4102d84abc5e"`, list.Contents[0].ETag)
+
+	resp, err := s3Do(t, http.MethodPut, client.URL()+"/uploads/denied.txt", "secret", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = http.Get(client.URL() + "/other/seeded.txt")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func TestClientS3AWSChunked(t *testing.T) {
+	_, client := NewTestServer(t, WithS3Buckets("uploads"))
+
+	chunked := "5;chunk-signature=ad80c730a21e5b8d04586a2213dd63b9a0e99e0e2307b0ade35a65485a288648\r\nhello\r\n" +
+		"6;chunk-signature=0055627c9e194cb4542bae2aa5492e3c1575bbb81b612b7d234b86a503ef5497\r\n world\r\n" +
+		"0;chunk-signature=b6c6ea8a5354eaf15b3cb7646744f4275b71ea724fed81ceb9323e279d449df9\r\n\r\n"
+	resp, err := s3Do(t, http.MethodPut, client.URL()+"/uploads/greeting.txt", chunked, map[string]string{
+		"Content-Encoding":     "aws-chunked",
+		"X-Amz-Content-Sha256": "STREAMING-AWS4-HMAC-SHA256-PAYLOAD",
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(client.URL() + "/uploads/greeting.txt")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(body))
+
+	_, err = decodeAWSChunked([]byte("zz\r\nhello"))
+	require.EqualError(t, err, "invalid aws-chunked body: invalid chunk size")
+	_, err = decodeAWSChunked([]byte("5"))
+	require.EqualError(t, err, "invalid aws-chunked body: missing chunk size")
+}
+
+func TestClientS3Frozen(t *testing.T) {
+	_, client := NewTestServer(t, WithS3Buckets("uploads"))
+	require.NoError(t, client.Freeze())
+
+	resp, err := s3Do(t, http.MethodPut, client.URL()+"/uploads/report.csv", "id", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = http.Get(client.URL() + "/uploads/report.csv")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "id", string(body))
+
+	// The object is put without changing the frozen stub set
+	stats, err := client.Stats()
+	require.NoError(t, err)
+	require.Zero(t, stats.Stubs)
+	require.EqualError(t, client.ClearAll(), "failure to clear calls: stubbed calls are frozen")
+}
+
+func TestClientS3Rollback(t *testing.T) {
+	_, client := NewTestServer(t, WithS3Buckets("uploads"))
+	require.NoError(t, client.Given(Call{Path: "uploads/seeded.txt", Response: []byte("seeded")}))
+	_, err := s3Do(t, http.MethodPut, client.URL()+"/uploads/report.csv", "id", nil)
+	require.NoError(t, err)
+
+	// Rolling back the seeded object keeps the object put
+	require.NoError(t, client.Rollback())
+	list := s3List(t, client.URL()+"/uploads")
+	require.Len(t, list.Contents, 1)
+	require.Equal(t, "report.csv", list.Contents[0].Key)
+	resp, err := s3Do(t, http.MethodHead, client.URL()+"/uploads/report.csv", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int64(2), resp.ContentLength)
+
+	require.NoError(t, client.ClearAll())
+	require.Empty(t, s3List(t, client.URL()+"/uploads").Contents)
+}
+
+// s3Do makes the request to the S3 mock with the body and headers
+func s3Do(t *testing.T, method, url, body string, headers map[string]string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// s3List lists the objects of the S3 mock's bucket
+func s3List(t *testing.T, url string) s3ListBucketResult {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var list s3ListBucketResult
+	require.NoError(t, xml.Unmarshal(body, &list))
+	return list
+}
@@ -0,0 +1,40 @@
+package assured
+
+import "sync"
+
+// sequences are the positions of the stubbed calls in their status code sequences, by stubbed call
+// The stubbed calls are shared with the published snapshots and the stub revisions, so they are never advanced in place
+type sequences struct {
+	next map[*Call]int
+	sync.Mutex
+}
+
+// advance returns the stubbed call's next status code in its sequence, starting over after the last
+func (s *sequences) advance(call *Call) int {
+	s.Lock()
+	defer s.Unlock()
+	if s.next == nil {
+		s.next = map[*Call]int{}
+	}
+	n := s.next[call]
+	s.next[call] = (n + 1) % len(call.StatusCodes)
+	return call.StatusCodes[n%len(call.StatusCodes)]
+}
+
+// reset starts the sequences of the stubbed calls with the call ID over
+func (s *sequences) reset(id string) {
+	s.Lock()
+	defer s.Unlock()
+	for call := range s.next {
+		if call.ID() == id {
+			delete(s.next, call)
+		}
+	}
+}
+
+// resetAll starts every sequence over
+func (s *sequences) resetAll() {
+	s.Lock()
+	s.next = nil
+	s.Unlock()
+}
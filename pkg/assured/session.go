@@ -0,0 +1,176 @@
+package assured
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultSessionCookie is the name of the session cookie of a Session without a cookie name
+const DefaultSessionCookie = "session"
+
+// Session simulates the cookie based sessions of an upstream. A call that starts a session, such as a login, issues a new session cookie
+// with a successful response, and a call that requires a session responds 401 Unauthorized to requests without a valid session cookie
+// A call that ends a session, such as a logout, expires the session it is sent. Sessions expire after the TTL, in seconds, if set
+type Session struct {
+	Cookie  string `json:"cookie,omitempty"`
+	Start   bool   `json:"start,omitempty"`
+	Require bool   `json:"require,omitempty"`
+	End     bool   `json:"end,omitempty"`
+	TTL     int    `json:"ttl,omitempty"`
+}
+
+// sessions are the active sessions started by the stubbed calls, by session ID
+type sessions struct {
+	active map[string]activeSession
+	sync.Mutex
+}
+
+// activeSession is the cookie name and expiry of an active session, without an expiry if the session has no TTL
+type activeSession struct {
+	cookie  string
+	expires time.Time
+}
+
+// cookie returns the name of the session's cookie
+func (s Session) cookie() string {
+	if s.Cookie == "" {
+		return DefaultSessionCookie
+	}
+	return s.Cookie
+}
+
+// start starts a new session, returning its ID
+func (s *sessions) start(cookie string, ttl int) string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	id := hex.EncodeToString(b)
+	session := activeSession{cookie: cookie}
+	if ttl > 0 {
+		session.expires = time.Now().Add(time.Duration(ttl) * time.Second)
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.active == nil {
+		s.active = map[string]activeSession{}
+	}
+	s.active[id] = session
+	return id
+}
+
+// valid reports whether the session is active for the cookie and has not expired
+func (s *sessions) valid(cookie, id string) bool {
+	s.Lock()
+	defer s.Unlock()
+	session, ok := s.active[id]
+	if ok && !session.expires.IsZero() && time.Now().After(session.expires) {
+		delete(s.active, id)
+		return false
+	}
+	return ok && session.cookie == cookie
+}
+
+// expire ends the sessions with the IDs, or every session without IDs, and returns the number of sessions expired
+func (s *sessions) expire(ids ...string) int {
+	s.Lock()
+	defer s.Unlock()
+	if len(ids) == 0 {
+		expired := len(s.active)
+		s.active = nil
+		return expired
+	}
+	expired := 0
+	for _, id := range ids {
+		if _, ok := s.active[id]; ok {
+			delete(s.active, id)
+			expired++
+		}
+	}
+	return expired
+}
+
+// ExpireSessions ends the sessions with the IDs, the values of their session cookies, or every session without IDs,
+// and returns the number of sessions expired
+func (a *AssuredEndpoints) ExpireSessions(ids ...string) int {
+	expired := a.sessions.expire(ids...)
+	slog.With("expired", expired).Info("expired sessions")
+	return expired
+}
+
+// sessionHandler simulates the sessions of stubbed calls with a session, challenging calls without a valid session with 401 Unauthorized
+// when the stubbed call requires one, and issuing a new session cookie with the successful responses of stubbed calls that start one
+func (a *AssuredEndpoints) sessionHandler(when http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		stub := matched(req.Context()).stub()
+		if stub == nil || stub.Session == nil {
+			when.ServeHTTP(w, req)
+			return
+		}
+		session := *stub.Session
+		var id string
+		if cookie, err := req.Cookie(session.cookie()); err == nil && a.sessions.valid(session.cookie(), cookie.Value) {
+			id = cookie.Value
+		}
+
+		if session.Require && id == "" {
+			if a.trackMadeCalls {
+				if call, err := a.decodeWhenCall(req.Context(), req); err == nil {
+					a.trackCall(call.(*Call))
+				}
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(http.StatusText(http.StatusUnauthorized)))
+			return
+		}
+		if session.End && id != "" {
+			a.sessions.expire(id)
+			http.SetCookie(w, &http.Cookie{Name: session.cookie(), Path: "/", MaxAge: -1})
+		}
+		if session.Start {
+			header := w.Header()
+			w = &sessionWriter{ResponseWriter: w, start: func() {
+				cookie := &http.Cookie{Name: session.cookie(), Value: a.sessions.start(session.cookie(), session.TTL), Path: "/", HttpOnly: true}
+				if session.TTL > 0 {
+					cookie.MaxAge = session.TTL
+				}
+				header.Add("Set-Cookie", cookie.String())
+			}}
+		}
+		when.ServeHTTP(w, req)
+	})
+}
+
+// sessionWriter is an http.ResponseWriter that starts a session when the response has a successful status code
+type sessionWriter struct {
+	http.ResponseWriter
+	start   func()
+	written bool
+}
+
+// WriteHeader starts the session before writing a final response with a status code below 400
+func (w *sessionWriter) WriteHeader(statusCode int) {
+	if !w.written && statusCode >= http.StatusOK {
+		w.written = true
+		if statusCode < http.StatusBadRequest {
+			w.start()
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write writes the response body, with an implicit 200 OK if the status code has not been written
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,109 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionsValid(t *testing.T) {
+	s := &sessions{}
+	id := s.start("session", 0)
+
+	require.True(t, s.valid("session", id))
+	require.False(t, s.valid("other", id))
+	require.False(t, s.valid("session", "unknown"))
+
+	s.active[id] = activeSession{cookie: "session", expires: time.Now().Add(-time.Second)}
+	require.False(t, s.valid("session", id))
+	require.Empty(t, s.active)
+}
+
+func TestClientSession(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(
+		Call{
+			Path:     "login",
+			Method:   http.MethodPost,
+			Session:  &Session{Start: true, TTL: 60},
+			Branches: []Branch{{When: Condition{BodyContains: "wrong"}, StatusCode: http.StatusForbidden}},
+		},
+		Call{Path: "profile", Method: http.MethodGet, Response: []byte("profile"), Session: &Session{Require: true}},
+		Call{Path: "logout", Method: http.MethodPost, Session: &Session{End: true}},
+	))
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	httpClient := &http.Client{Jar: jar}
+	do := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, client.URL()+"/"+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := httpClient.Do(req)
+		require.NoError(t, err)
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp
+	}
+
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "profile", "").StatusCode)
+	resp := do(http.MethodPost, "login", "wrong")
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Empty(t, resp.Cookies())
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "profile", "").StatusCode)
+
+	resp = do(http.MethodPost, "login", "right")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, resp.Cookies(), 1)
+	require.Equal(t, DefaultSessionCookie, resp.Cookies()[0].Name)
+	require.Equal(t, 60, resp.Cookies()[0].MaxAge)
+	require.True(t, resp.Cookies()[0].HttpOnly)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "profile", "").StatusCode)
+
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "logout", "").StatusCode)
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "profile", "").StatusCode)
+
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "login", "right").StatusCode)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "profile", "").StatusCode)
+	require.NoError(t, client.ExpireSessions())
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "profile", "").StatusCode)
+
+	calls, err := client.Verify(http.MethodGet, "profile")
+	require.NoError(t, err)
+	require.Len(t, calls, 6)
+}
+
+func TestClientExpireSessions(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(
+		Call{Path: "login", Method: http.MethodPost, Session: &Session{Cookie: "sid", Start: true}},
+		Call{Path: "profile", Method: http.MethodGet, Session: &Session{Cookie: "sid", Require: true}},
+	))
+	login := func() *http.Cookie {
+		resp, err := http.Post(client.URL()+"/login", "text/plain", nil)
+		require.NoError(t, err)
+		require.Len(t, resp.Cookies(), 1)
+		return resp.Cookies()[0]
+	}
+	profile := func(cookie *http.Cookie) int {
+		req, err := http.NewRequest(http.MethodGet, client.URL()+"/profile", nil)
+		require.NoError(t, err)
+		req.AddCookie(cookie)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	first, second := login(), login()
+	require.Equal(t, "sid", first.Name)
+	require.NotEqual(t, first.Value, second.Value)
+
+	require.NoError(t, client.ExpireSessions(first.Value))
+
+	require.Equal(t, http.StatusUnauthorized, profile(first))
+	require.Equal(t, http.StatusOK, profile(second))
+	require.Equal(t, http.StatusUnauthorized, profile(&http.Cookie{Name: "session", Value: second.Value}))
+}
@@ -0,0 +1,148 @@
+package assured
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SignedURLPlaceholder is replaced with the issued signed URL in the response and headers of a call issuing signed URLs
+const SignedURLPlaceholder = "{{signed_url}}"
+
+// DefaultSignedURLTTL is the number of seconds a signed URL is valid for, when its SignedURL has no TTL
+const DefaultSignedURLTTL = 900
+
+// SignedURL simulates the presigned URLs of an upstream. A call that issues a signed URL responds with a time-limited URL of the path
+// of the call to issue it for, at the base URL of the stubbed calls, in place of the {{signed_url}} placeholder in its response and headers
+// A call that validates signed URLs responds 403 Forbidden unless it is made with an unexpired signature of its path, see SignURL
+// A validated URL is still accepted the skew in seconds after it expires, tolerating the clock skew of its issuer,
+// and a signature used again within the replay window in seconds is rejected as replayed
+type SignedURL struct {
+	Secret   string `json:"secret"`
+	Issue    string `json:"issue,omitempty"`
+	BaseURL  string `json:"base_url,omitempty"`
+	TTL      int    `json:"ttl,omitempty"`
+	Validate bool   `json:"validate,omitempty"`
+	Skew     int    `json:"skew,omitempty"`
+	Replay   int    `json:"replay,omitempty"`
+}
+
+// signatures are the signatures of the signed URLs accepted, by path and signature, with the time they were accepted
+type signatures struct {
+	used map[string]time.Time
+	sync.Mutex
+}
+
+// SignedURLCall returns the stubbed GET call issuing signed URLs for the path, valid for the TTL, responding with the URL
+// in the JSON body {"url":"..."}. The base URL is the URL of the stubbed calls, e.g. client.URL()
+func SignedURLCall(path, baseURL, issue, secret string, ttl time.Duration) Call {
+	return Call{
+		Path:       path,
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Response:   []byte(`{"url":"` + SignedURLPlaceholder + `"}`),
+		SignedURL:  &SignedURL{Secret: secret, Issue: issue, BaseURL: baseURL, TTL: int(ttl.Seconds())},
+	}
+}
+
+// SignedURLProtectedCall returns the stubbed call validating the signed URLs issued by a SignedURLCall with the secret
+func SignedURLProtectedCall(call Call, secret string) Call {
+	call.SignedURL = &SignedURL{Secret: secret, Validate: true}
+	return call
+}
+
+// SignURL returns the query of the path signed with the secret until it expires, the expires and signature query parameters
+// The signature is the hex encoded HMAC-SHA256 of the path, without a leading slash, and the expiry in Unix seconds, separated by a newline
+func SignURL(path, secret string, expires time.Time) url.Values {
+	expiry := strconv.FormatInt(expires.Unix(), 10)
+	return url.Values{"expires": {expiry}, "signature": {signURLPath(path, secret, expiry)}}
+}
+
+// signURLPath returns the signature of the path and expiry with the secret
+func signURLPath(path, secret, expiry string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.TrimLeft(path, "/") + "\n" + expiry))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ttl returns the number of seconds the signed URLs are valid for
+func (s SignedURL) ttl() int {
+	if s.TTL == 0 {
+		return DefaultSignedURLTTL
+	}
+	return s.TTL
+}
+
+// rejection returns the reason the call made has no valid signature, or empty if it is valid
+func (s SignedURL) rejection(made *Call) string {
+	expiry, signature := made.Query["expires"], made.Query["signature"]
+	if expiry == "" || signature == "" {
+		return "missing signature"
+	}
+	if !hmac.Equal([]byte(signature), []byte(signURLPath(made.Path, s.Secret, expiry))) {
+		return "invalid signature"
+	}
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().Unix() > expires+int64(s.Skew) {
+		return "expired signature"
+	}
+	return ""
+}
+
+// signedURLRejection returns the reason the call made has no valid signature for the stubbed call's signed URL, or empty if it is valid
+// An accepted signature is remembered for the signed URL's replay window, rejecting its reuse within the window
+func (a *AssuredEndpoints) signedURLRejection(signedURL *SignedURL, made *Call) string {
+	if reason := signedURL.rejection(made); reason != "" || signedURL.Replay <= 0 {
+		return reason
+	}
+	a.signatures.Lock()
+	defer a.signatures.Unlock()
+	if a.signatures.used == nil {
+		a.signatures.used = map[string]time.Time{}
+	}
+	key := made.Path + "?" + made.Query["signature"]
+	if used, ok := a.signatures.used[key]; ok && time.Since(used) < time.Duration(signedURL.Replay)*time.Second {
+		return "replayed signature"
+	}
+	a.signatures.used[key] = time.Now()
+	return ""
+}
+
+// rejected returns the response of the stubbed call rejecting a call made without a valid signature
+func (c *Call) rejected(reason string) *Call {
+	return &Call{
+		Path:       c.Path,
+		Method:     c.Method,
+		StatusCode: http.StatusForbidden,
+		Headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		Response:   []byte(reason),
+	}
+}
+
+// signed returns a copy of the stubbed call with a new signed URL in place of the signed URL placeholders in its response and headers
+func (c *Call) signed() *Call {
+	issue := strings.Trim(c.SignedURL.Issue, "/")
+	query := SignURL(issue, c.SignedURL.Secret, time.Now().Add(time.Duration(c.SignedURL.ttl())*time.Second))
+	signedURL := []byte(strings.TrimRight(c.SignedURL.BaseURL, "/") + "/" + issue + "?" + query.Encode())
+
+	signed := *c
+	signed.Headers = maps.Clone(c.Headers)
+	for name, value := range signed.Headers {
+		signed.Headers[name] = strings.ReplaceAll(value, SignedURLPlaceholder, string(signedURL))
+	}
+	if bytes.Contains(c.Response, []byte(SignedURLPlaceholder)) {
+		// The stubbed content length no longer applies to the signed response
+		delete(signed.Headers, "Content-Length")
+		signed.Response = bytes.ReplaceAll(c.Response, []byte(SignedURLPlaceholder), signedURL)
+	}
+	return &signed
+}
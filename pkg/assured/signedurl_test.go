@@ -0,0 +1,129 @@
+package assured
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignURL(t *testing.T) {
+	expires := time.Unix(1700000000, 0)
+
+	query := SignURL("/downloads/report.pdf", "s3cr3t", expires)
+
+	require.Equal(t, "1700000000", query.Get("expires"))
+	require.Len(t, query.Get("signature"), 64)
+	require.Equal(t, query, SignURL("downloads/report.pdf", "s3cr3t", expires))
+	require.NotEqual(t, query.Get("signature"), SignURL("downloads/other.pdf", "s3cr3t", expires).Get("signature"))
+	require.NotEqual(t, query.Get("signature"), SignURL("downloads/report.pdf", "other", expires).Get("signature"))
+}
+
+func TestSignedURLRejection(t *testing.T) {
+	signedURL := SignedURL{Secret: "s3cr3t", Validate: true}
+	valid := SignURL("downloads/report.pdf", "s3cr3t", time.Now().Add(time.Minute))
+	expired := SignURL("downloads/report.pdf", "s3cr3t", time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name  string
+		path  string
+		query map[string]string
+		want  string
+	}{
+		{name: "valid", path: "downloads/report.pdf", query: map[string]string{"expires": valid.Get("expires"), "signature": valid.Get("signature")}},
+		{name: "missing", path: "downloads/report.pdf", query: map[string]string{}, want: "missing signature"},
+		{name: "tampered path", path: "downloads/other.pdf", query: map[string]string{"expires": valid.Get("expires"), "signature": valid.Get("signature")}, want: "invalid signature"},
+		{name: "tampered expiry", path: "downloads/report.pdf", query: map[string]string{"expires": "9999999999", "signature": valid.Get("signature")}, want: "invalid signature"},
+		{name: "expired", path: "downloads/report.pdf", query: map[string]string{"expires": expired.Get("expires"), "signature": expired.Get("signature")}, want: "expired signature"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, signedURL.rejection(&Call{Path: tc.path, Query: tc.query}))
+		})
+	}
+}
+
+func TestSignedURLRejectionSkew(t *testing.T) {
+	expired := SignURL("downloads/report.pdf", "s3cr3t", time.Now().Add(-30*time.Second))
+	made := &Call{Path: "downloads/report.pdf", Query: map[string]string{"expires": expired.Get("expires"), "signature": expired.Get("signature")}}
+
+	require.Equal(t, "", SignedURL{Secret: "s3cr3t", Skew: 60}.rejection(made))
+	require.Equal(t, "expired signature", SignedURL{Secret: "s3cr3t", Skew: 10}.rejection(made))
+}
+
+func TestSignedURLReplay(t *testing.T) {
+	a := NewAssuredEndpoints(DefaultOptions)
+	query := SignURL("downloads/report.pdf", "s3cr3t", time.Now().Add(time.Minute))
+	made := &Call{Path: "downloads/report.pdf", Query: map[string]string{"expires": query.Get("expires"), "signature": query.Get("signature")}}
+
+	require.Equal(t, "", a.signedURLRejection(&SignedURL{Secret: "s3cr3t"}, made))
+	require.Equal(t, "", a.signedURLRejection(&SignedURL{Secret: "s3cr3t"}, made))
+
+	replay := &SignedURL{Secret: "s3cr3t", Replay: 60}
+	require.Equal(t, "", a.signedURLRejection(replay, made))
+	require.Equal(t, "replayed signature", a.signedURLRejection(replay, made))
+
+	a.signatures.used[made.Path+"?"+query.Get("signature")] = time.Now().Add(-time.Minute)
+	require.Equal(t, "", a.signedURLRejection(replay, made))
+}
+
+func TestSignedURLCall(t *testing.T) {
+	call := SignedURLCall("downloads/report/url", "http://localhost:8080/when", "downloads/report.pdf", "s3cr3t", time.Minute)
+
+	require.Equal(t, Call{
+		Path:       "downloads/report/url",
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Response:   []byte(`{"url":"{{signed_url}}"}`),
+		SignedURL:  &SignedURL{Secret: "s3cr3t", Issue: "downloads/report.pdf", BaseURL: "http://localhost:8080/when", TTL: 60},
+	}, call)
+	require.False(t, call.static())
+}
+
+func TestClientSignedURL(t *testing.T) {
+	_, client := NewTestServer(t)
+	download := Call{Path: "downloads/report.pdf", Method: http.MethodGet, StatusCode: http.StatusOK, Response: []byte("%PDF-1.7")}
+	require.NoError(t, client.Given(
+		SignedURLCall("downloads/report/url", client.URL(), "downloads/report.pdf", "s3cr3t", time.Minute),
+		SignedURLProtectedCall(download, "s3cr3t"),
+	))
+
+	resp, err := http.Get(client.URL() + "/downloads/report/url")
+	require.NoError(t, err)
+	var body map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	signedURL, err := url.Parse(body["url"])
+	require.NoError(t, err)
+	require.Equal(t, client.URL()+"/downloads/report.pdf", signedURL.Scheme+"://"+signedURL.Host+signedURL.Path)
+
+	resp, err = http.Get(signedURL.String())
+	require.NoError(t, err)
+	content, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "%PDF-1.7", string(content))
+
+	query := signedURL.Query()
+	query.Set("expires", "9999999999")
+	for _, rejected := range []string{client.URL() + "/downloads/report.pdf", client.URL() + "/downloads/report.pdf?" + query.Encode()} {
+		resp, err = http.Get(rejected)
+		require.NoError(t, err)
+		content, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+		require.NotEqual(t, "%PDF-1.7", string(content))
+	}
+
+	expired := SignURL("downloads/report.pdf", "s3cr3t", time.Now().Add(-time.Second))
+	resp, err = http.Get(client.URL() + "/downloads/report.pdf?" + expired.Encode())
+	require.NoError(t, err)
+	content, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Equal(t, "expired signature", string(content))
+}
@@ -0,0 +1,208 @@
+package assured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// SinkCall returns the call to stub for a webhook receiver, a sink that accepts anything POSTed to the path with 202 Accepted
+// The webhooks received are verified with the client's Sink, so made calls must be tracked
+func SinkCall(path string) Call {
+	return Call{Path: path, Method: http.MethodPost, StatusCode: http.StatusAccepted}
+}
+
+// Sink is the webhooks a sink received, in the order they were received
+type Sink struct {
+	Calls []Call
+}
+
+// Sink returns the webhooks POSTed to the sink's path
+func (c *Client) Sink(path string) (*Sink, error) {
+	calls, err := c.Verify(http.MethodPost, strings.Trim(path, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{Calls: calls}, nil
+}
+
+// Count returns the number of webhooks the sink received
+func (s *Sink) Count() int {
+	return len(s.Calls)
+}
+
+// Bodies returns the bodies of the webhooks the sink received, in order
+func (s *Sink) Bodies() []string {
+	bodies := make([]string, 0, len(s.Calls))
+	for _, call := range s.Calls {
+		bodies = append(bodies, string(call.Response))
+	}
+	return bodies
+}
+
+// Values returns the value at the JSON path of each webhook the sink received, in order
+// e.g. sink.Values("$.event.id") to assert the order events were delivered in
+func (s *Sink) Values(path string) ([]any, error) {
+	values := make([]any, 0, len(s.Calls))
+	for i, call := range s.Calls {
+		value, err := jsonPath(call.Response, path)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %d: %w", i, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// AssertSinkCount reports a test error if the sink did not receive the number of webhooks
+func (c *Client) AssertSinkCount(t testing.TB, path string, count int) bool {
+	t.Helper()
+	sink, err := c.Sink(path)
+	if err != nil {
+		t.Errorf("failed to verify sink %s: %s", path, err)
+		return false
+	}
+	if sink.Count() != count {
+		t.Errorf("expected sink %s to receive %d webhooks, received %d", path, count, sink.Count())
+		return false
+	}
+	return true
+}
+
+// AssertSinkJSON reports a test error unless the sink received a webhook for each expected value, in order,
+// with the expected value at the JSON path. Values are compared by their JSON encoding, so 42 equals 42.0
+func (c *Client) AssertSinkJSON(t testing.TB, path, jsonPath string, expected ...any) bool {
+	t.Helper()
+	sink, err := c.Sink(path)
+	if err != nil {
+		t.Errorf("failed to verify sink %s: %s", path, err)
+		return false
+	}
+	values, err := sink.Values(jsonPath)
+	if err != nil {
+		t.Errorf("failed to find %s in the webhooks of sink %s: %s", jsonPath, path, err)
+		return false
+	}
+	if len(values) != len(expected) {
+		t.Errorf("expected sink %s to receive %d webhooks, received %d", path, len(expected), len(values))
+		return false
+	}
+	ok := true
+	for i := range values {
+		if !jsonEqual(values[i], expected[i]) {
+			t.Errorf("expected webhook %d of sink %s to have %s %v, found %v", i, path, jsonPath, expected[i], values[i])
+			ok = false
+		}
+	}
+	return ok
+}
+
+// jsonPath returns the value at the JSON path of the JSON document
+// The root $, child .name and ['name'], and array index [n] selectors are supported, e.g. $.items[0]['id']
+func jsonPath(document []byte, path string) (any, error) {
+	compiled, err := compileJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.lookup(document)
+}
+
+// jsonSelector is a child name, or array index, selector of a JSON path
+type jsonSelector struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// compiledJSONPath is a JSON path parsed into its selectors, so looking up a value doesn't parse the path again
+type compiledJSONPath struct {
+	path      string
+	selectors []jsonSelector
+}
+
+// compileJSONPath parses the JSON path into its selectors
+func compileJSONPath(path string) (compiledJSONPath, error) {
+	compiled := compiledJSONPath{path: path}
+	if !strings.HasPrefix(path, "$") {
+		return compiled, fmt.Errorf("invalid json path %q: must start with $", path)
+	}
+
+	rest := path[1:]
+	for rest != "" {
+		var selector jsonSelector
+		switch {
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			selector.name, rest = rest[1:end+1], rest[end+1:]
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return compiled, fmt.Errorf("invalid json path %q: unclosed selector", path)
+			}
+			selector.name, rest = rest[2:end], rest[end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return compiled, fmt.Errorf("invalid json path %q: unclosed selector", path)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return compiled, fmt.Errorf("invalid json path %q: invalid index %q", path, rest[1:end])
+			}
+			selector.index, selector.isIndex, rest = i, true, rest[end+1:]
+		default:
+			return compiled, fmt.Errorf("invalid json path %q", path)
+		}
+		compiled.selectors = append(compiled.selectors, selector)
+	}
+	return compiled, nil
+}
+
+// lookup returns the value at the JSON path of the JSON document
+func (p compiledJSONPath) lookup(document []byte) (any, error) {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+
+	for _, selector := range p.selectors {
+		if selector.isIndex {
+			array, ok := value.([]any)
+			if !ok || selector.index < 0 || selector.index >= len(array) {
+				return nil, fmt.Errorf("index %d not found in %s", selector.index, p.path)
+			}
+			value = array[selector.index]
+			continue
+		}
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("field %q not found in %s", selector.name, p.path)
+		}
+		if value, ok = object[selector.name]; !ok {
+			return nil, fmt.Errorf("field %q not found in %s", selector.name, p.path)
+		}
+	}
+	return value, nil
+}
+
+// jsonEqual reports whether the values are equal once encoded to and decoded from JSON
+func jsonEqual(a, b any) bool {
+	var decoded [2]any
+	for i, value := range []any{a, b} {
+		data, err := json.Marshal(value)
+		if err != nil || json.Unmarshal(data, &decoded[i]) != nil {
+			return false
+		}
+	}
+	return reflect.DeepEqual(decoded[0], decoded[1])
+}
@@ -0,0 +1,85 @@
+package assured
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSink(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(SinkCall("hooks/orders")))
+
+	for _, body := range []string{
+		`{"event":{"id":1,"type":"order.created"},"items":[{"sku":"a"}]}`,
+		`{"event":{"id":2,"type":"order.paid"},"items":[{"sku":"b"},{"sku":"c"}]}`,
+		`not json`,
+	} {
+		resp, err := http.Post(client.URL()+"/hooks/orders", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	}
+
+	sink, err := client.Sink("/hooks/orders/")
+	require.NoError(t, err)
+	require.Equal(t, 3, sink.Count())
+	require.Equal(t, "not json", sink.Bodies()[2])
+	_, err = sink.Values("$.event.id")
+	require.EqualError(t, err, "webhook 2: invalid json: invalid character 'o' in literal null (expecting 'u')")
+
+	sink.Calls = sink.Calls[:2]
+	values, err := sink.Values("$.event.type")
+	require.NoError(t, err)
+	require.Equal(t, []any{"order.created", "order.paid"}, values)
+
+	require.True(t, client.AssertSinkCount(t, "hooks/orders", 3))
+	mock := &testing.T{}
+	require.False(t, client.AssertSinkCount(mock, "hooks/orders", 2))
+	require.True(t, mock.Failed())
+	mock = &testing.T{}
+	require.False(t, client.AssertSinkJSON(mock, "hooks/orders", "$.event.id", 1, 2, 3))
+	require.True(t, mock.Failed())
+
+	require.NoError(t, client.ClearAll())
+	require.NoError(t, client.Given(SinkCall("hooks/orders")))
+	for _, body := range []string{`{"event":{"id":1}}`, `{"event":{"id":2}}`} {
+		_, err := http.Post(client.URL()+"/hooks/orders", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+	}
+	require.True(t, client.AssertSinkJSON(t, "hooks/orders", "$.event.id", 1, 2.0))
+	mock = &testing.T{}
+	require.False(t, client.AssertSinkJSON(mock, "hooks/orders", "$.event.id", 2, 1))
+	require.True(t, mock.Failed())
+}
+
+func TestJSONPath(t *testing.T) {
+	document := []byte(`{"event":{"id":1,"tags":["a","b"]},"dotted.key":{"value":true},"items":[{"sku":"a"}]}`)
+	tests := []struct {
+		path  string
+		value any
+		err   string
+	}{
+		{path: "$.event.tags[1]", value: "b"},
+		{path: "$['dotted.key'].value", value: true},
+		{path: "$.items[0]['sku']", value: "a"},
+		{path: "$.event", value: map[string]any{"id": float64(1), "tags": []any{"a", "b"}}},
+		{path: "$.event.missing", err: `field "missing" not found in $.event.missing`},
+		{path: "$.items[1]", err: "index 1 not found in $.items[1]"},
+		{path: "$.items[x]", err: `invalid json path "$.items[x]": invalid index "x"`},
+		{path: "$['event'", err: `invalid json path "$['event'": unclosed selector`},
+		{path: "event", err: `invalid json path "event": must start with $`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			value, err := jsonPath(document, tc.path)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, jsonEqual(tc.value, value), "%v != %v", tc.value, value)
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package assured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// The sources of the values extracted by stubbed calls
+const (
+	// ExtractBody extracts the value from the request body
+	ExtractBody = "body"
+	// ExtractPath extracts the value from the request path
+	ExtractPath = "path"
+	// ExtractQuery extracts the value from the request's named query parameter
+	ExtractQuery = "query"
+	// ExtractHeader extracts the value from the request's named header
+	ExtractHeader = "header"
+	// ExtractResponse extracts the value from the response of the stubbed call, after its placeholders are rendered
+	ExtractResponse = "response"
+)
+
+// statePattern matches the state placeholders of a response, e.g. {{state "orderID"}}
+var statePattern = regexp.MustCompile(`\{\{\s*state\s+"([^"]+)"\s*\}\}`)
+
+// Extraction extracts a value of the calls made to a stubbed call into the shared state, under the extraction's name,
+// for the stubbed calls that respond with the value's {{state "name"}} placeholders, e.g. a GET echoing the ID of a POST
+// The value is extracted from the source, the request body by default, at the JSON path, and then by the regex,
+// which extracts its first capturing group, or else its match. A call made without the value leaves the state unchanged
+type Extraction struct {
+	From     string `json:"from,omitempty"`
+	Name     string `json:"name,omitempty"`
+	JSONPath string `json:"json_path,omitempty"`
+	Regex    string `json:"regex,omitempty"`
+
+	// The compiled JSON path and regex, once the extraction is stubbed
+	path  *compiledJSONPath
+	regex *regexp.Regexp
+}
+
+// validate reports the first problem with the extraction, if any
+func (e Extraction) validate() (string, error) {
+	_, field, err := e.compile()
+	return field, err
+}
+
+// compile returns the extraction with its JSON path and regex compiled, so they aren't compiled for each call made,
+// or the field of the first problem with the extraction
+func (e Extraction) compile() (Extraction, string, error) {
+	switch e.From {
+	case "", ExtractBody, ExtractPath, ExtractResponse:
+	case ExtractQuery, ExtractHeader:
+		if e.Name == "" {
+			return e, "name", fmt.Errorf("name is required to extract from the %s", e.From)
+		}
+	default:
+		return e, "from", fmt.Errorf("invalid from %q, must be one of body, path, query, header, or response", e.From)
+	}
+	if e.JSONPath != "" {
+		path, err := compileJSONPath(e.JSONPath)
+		if err != nil {
+			return e, "json_path", err
+		}
+		e.path = &path
+	}
+	if e.Regex != "" {
+		regex, err := regexp.Compile(e.Regex)
+		if err != nil {
+			return e, "regex", fmt.Errorf("invalid regex: %w", err)
+		}
+		e.regex = regex
+	}
+	return e, "", nil
+}
+
+// extract returns the value extracted from the source, and whether it was found
+func (e Extraction) extract(source []byte) (string, bool) {
+	// Extractions that weren't stubbed are compiled as they extract
+	if (e.JSONPath != "" && e.path == nil) || (e.Regex != "" && e.regex == nil) {
+		compiled, _, err := e.compile()
+		if err != nil {
+			return "", false
+		}
+		e = compiled
+	}
+	value := string(source)
+	if e.path != nil {
+		found, err := e.path.lookup(source)
+		if err != nil {
+			return "", false
+		}
+		if s, ok := found.(string); ok {
+			value = s
+		} else {
+			encoded, _ := json.Marshal(found)
+			value = string(encoded)
+		}
+	}
+	if e.regex != nil {
+		match := e.regex.FindStringSubmatch(value)
+		if match == nil {
+			return "", false
+		}
+		value = match[0]
+		if len(match) > 1 {
+			value = match[1]
+		}
+	}
+	return value, true
+}
+
+// source returns the source of the extraction in the call made, and whether the call made has it
+func (e Extraction) source(made *Call) ([]byte, bool) {
+	switch e.From {
+	case ExtractPath:
+		return []byte(made.Path), true
+	case ExtractQuery:
+		value, ok := made.Query[e.Name]
+		return []byte(value), ok
+	case ExtractHeader:
+		for name, value := range made.Headers {
+			if strings.EqualFold(name, e.Name) {
+				return []byte(value), true
+			}
+		}
+		return nil, false
+	}
+	return made.Response, true
+}
+
+// state is the shared state of the values extracted by stubbed calls
+type state struct {
+	values map[string]string
+	sync.Mutex
+}
+
+// set sets the state's value with the name
+func (s *state) set(name, value string) {
+	s.Lock()
+	defer s.Unlock()
+	s.values[name] = value
+}
+
+// render returns the text with each state placeholder replaced by the state's value, or empty if it has no value
+func (s *state) render(text []byte) []byte {
+	s.Lock()
+	defer s.Unlock()
+	return statePattern.ReplaceAllFunc(text, func(placeholder []byte) []byte {
+		return []byte(s.values[string(statePattern.FindSubmatch(placeholder)[1])])
+	})
+}
+
+// clear clears the state's values with the names, or every value without names, and returns the number of values cleared
+func (s *state) clear(names ...string) int {
+	s.Lock()
+	defer s.Unlock()
+	cleared := 0
+	if len(names) == 0 {
+		cleared = len(s.values)
+		s.values = map[string]string{}
+	}
+	for _, name := range names {
+		if _, ok := s.values[name]; ok {
+			delete(s.values, name)
+			cleared++
+		}
+	}
+	return cleared
+}
+
+// snapshot returns the state's values
+func (s *state) snapshot() map[string]string {
+	s.Lock()
+	defer s.Unlock()
+	return maps.Clone(s.values)
+}
+
+// compileExtractions compiles the JSON paths and regexes of the stubbed call's extractions, once, when the call is stubbed
+func (c *Call) compileExtractions() error {
+	if len(c.Extract) == 0 {
+		return nil
+	}
+	compiled := make(map[string]Extraction, len(c.Extract))
+	for name, extraction := range c.Extract {
+		extraction, field, err := extraction.compile()
+		if err != nil {
+			return fmt.Errorf("invalid extraction %s.%s: %w", name, field, err)
+		}
+		compiled[name] = extraction
+	}
+	c.Extract = compiled
+	return nil
+}
+
+// hasState reports whether the stubbed call extracts values, or has state placeholders in its response or headers
+func (c *Call) hasState() bool {
+	if len(c.Extract) > 0 || (bytes.Contains(c.Response, []byte("{{")) && statePattern.Match(c.Response)) {
+		return true
+	}
+	for _, value := range c.Headers {
+		if statePattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// stateful returns a copy of the stubbed call with its state placeholders rendered, extracting the values of the call made
+// into the state before rendering the response, and the values of the rendered response before rendering the headers,
+// so a header can refer to a value of the response, e.g. a Location with its ID
+func (a *AssuredEndpoints) stateful(assured *Call, made *Call) *Call {
+	a.extract(assured, made, false)
+
+	stateful := *assured
+	stateful.Headers = map[string]string{}
+	if statePattern.Match(assured.Response) {
+		stateful.Response = a.state.render(assured.Response)
+	}
+	a.extract(&stateful, &Call{Response: stateful.Response}, true)
+
+	for name, value := range assured.Headers {
+		// The stubbed content length no longer applies to the rendered response
+		if name == "Content-Length" && statePattern.Match(assured.Response) {
+			continue
+		}
+		stateful.Headers[name] = string(a.state.render([]byte(value)))
+	}
+	return &stateful
+}
+
+// extract sets the state's values extracted by the stubbed call from the call made, either from the response or the request
+func (a *AssuredEndpoints) extract(assured *Call, made *Call, response bool) {
+	for name, extraction := range assured.Extract {
+		if (extraction.From == ExtractResponse) != response {
+			continue
+		}
+		source, ok := extraction.source(made)
+		if !ok {
+			continue
+		}
+		if value, ok := extraction.extract(source); ok {
+			a.state.set(name, value)
+			slog.With("name", name, "path", assured.ID()).Info("extracted state")
+		}
+	}
+}
+
+// State returns the shared state of the values extracted by stubbed calls
+func (a *AssuredEndpoints) State() map[string]string {
+	return a.state.snapshot()
+}
+
+// ClearState clears the state's values with the names, or every value without names, and returns the number of values cleared
+func (a *AssuredEndpoints) ClearState(names ...string) int {
+	cleared := a.state.clear(names...)
+	slog.With("cleared", cleared).Info("cleared state")
+	return cleared
+}
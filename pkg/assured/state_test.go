@@ -0,0 +1,165 @@
+package assured
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractionExtract(t *testing.T) {
+	made := &Call{
+		Path:     "orders/ord_42/items",
+		Headers:  map[string]string{"X-Request-Id": "req-1"},
+		Query:    map[string]string{"cursor": "abc"},
+		Response: []byte(`{"order":{"id":"ord_42","total":12.5,"items":[{"sku":"A-1"}]}}`),
+	}
+
+	tests := []struct {
+		name       string
+		extraction Extraction
+		want       string
+		found      bool
+	}{
+		{name: "body", extraction: Extraction{}, want: string(made.Response), found: true},
+		{name: "body json path", extraction: Extraction{JSONPath: "$.order.id"}, want: "ord_42", found: true},
+		{name: "body json number", extraction: Extraction{From: ExtractBody, JSONPath: "$.order.total"}, want: "12.5", found: true},
+		{name: "body json object", extraction: Extraction{JSONPath: "$.order.items[0]"}, want: `{"sku":"A-1"}`, found: true},
+		{name: "body json path missing", extraction: Extraction{JSONPath: "$.order.missing"}},
+		{name: "path regex group", extraction: Extraction{From: ExtractPath, Regex: `orders/([^/]+)`}, want: "ord_42", found: true},
+		{name: "path regex match", extraction: Extraction{From: ExtractPath, Regex: `ord_\d+`}, want: "ord_42", found: true},
+		{name: "path regex missing", extraction: Extraction{From: ExtractPath, Regex: `users/(\d+)`}},
+		{name: "query", extraction: Extraction{From: ExtractQuery, Name: "cursor"}, want: "abc", found: true},
+		{name: "query missing", extraction: Extraction{From: ExtractQuery, Name: "page"}},
+		{name: "header", extraction: Extraction{From: ExtractHeader, Name: "x-request-id"}, want: "req-1", found: true},
+		{name: "header missing", extraction: Extraction{From: ExtractHeader, Name: "Authorization"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			source, ok := tc.extraction.source(made)
+			value := ""
+			if ok {
+				value, ok = tc.extraction.extract(source)
+			}
+
+			require.Equal(t, tc.found, ok)
+			require.Equal(t, tc.want, value)
+		})
+	}
+}
+
+func TestExtractionValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		extraction Extraction
+		field      string
+		err        string
+	}{
+		{name: "valid", extraction: Extraction{From: ExtractHeader, Name: "Location", Regex: `/(\d+)$`}},
+		{name: "invalid from", extraction: Extraction{From: "cookie"}, field: "from", err: `invalid from "cookie", must be one of body, path, query, header, or response`},
+		{name: "missing name", extraction: Extraction{From: ExtractHeader}, field: "name", err: "name is required to extract from the header"},
+		{name: "invalid json path", extraction: Extraction{JSONPath: "id"}, field: "json_path", err: `invalid json path "id": must start with $`},
+		{name: "unclosed json path", extraction: Extraction{JSONPath: "$['id'"}, field: "json_path", err: `invalid json path "$['id'": unclosed selector`},
+		{name: "invalid regex", extraction: Extraction{Regex: "("}, field: "regex", err: "invalid regex: error parsing regexp: missing closing ): `(`"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			field, err := tc.extraction.validate()
+
+			require.Equal(t, tc.field, field)
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestGivenEndpointCompilesExtractions(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	call := &Call{Path: "orders", Method: http.MethodPost, StatusCode: http.StatusCreated, Extract: map[string]Extraction{
+		"customer": {JSONPath: "$.email", Regex: `^([^@]+)@`},
+	}}
+
+	_, err := endpoints.GivenEndpoint(context.Background(), call)
+
+	require.NoError(t, err)
+	require.NotNil(t, call.Extract["customer"].path)
+	require.NotNil(t, call.Extract["customer"].regex)
+	value, ok := call.Extract["customer"].extract([]byte(`{"email":"jane@example.com"}`))
+	require.True(t, ok)
+	require.Equal(t, "jane", value)
+}
+
+func TestGivenEndpointCompilesExtractionsFailure(t *testing.T) {
+	endpoints := NewAssuredEndpoints(DefaultOptions)
+	call := &Call{Path: "orders", Method: http.MethodPost, StatusCode: http.StatusCreated, Extract: map[string]Extraction{
+		"customer": {Regex: "("},
+	}}
+
+	_, err := endpoints.GivenEndpoint(context.Background(), call)
+
+	require.EqualError(t, err, "invalid extraction customer.regex: invalid regex: error parsing regexp: missing closing ): `(`")
+	require.Empty(t, endpoints.assuredCalls.Get(call.ID()))
+}
+
+func TestStateClear(t *testing.T) {
+	s := &state{values: map[string]string{"a": "1", "b": "2", "c": "3"}}
+
+	require.Equal(t, `1-2-`, string(s.render([]byte(`{{state "a"}}-{{ state "b" }}-{{state "missing"}}`))))
+	require.Equal(t, 1, s.clear("a", "missing"))
+	require.Equal(t, map[string]string{"b": "2", "c": "3"}, s.snapshot())
+	require.Equal(t, 2, s.clear())
+	require.Empty(t, s.snapshot())
+}
+
+func TestClientState(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(
+		Call{
+			Path:       "orders",
+			Method:     http.MethodPost,
+			StatusCode: http.StatusCreated,
+			Headers:    map[string]string{"Location": `/orders/{{state "orderID"}}`},
+			Response:   []byte(`{"id":"ord_{{counter "order"}}","customer":"{{state "customer"}}"}`),
+			Extract: map[string]Extraction{
+				"orderID":  {From: ExtractResponse, JSONPath: "$.id"},
+				"customer": {JSONPath: "$.email", Regex: `^([^@]+)@`},
+			},
+		},
+		Call{
+			Path:       "orders/latest",
+			Method:     http.MethodGet,
+			StatusCode: http.StatusOK,
+			Response:   []byte(`{"id":"{{state "orderID"}}","customer":"{{state "customer"}}"}`),
+		},
+	))
+
+	resp, err := http.Post(client.URL()+"/orders", "application/json", strings.NewReader(`{"email":"jane@example.com"}`))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"ord_1","customer":"jane"}`, string(body))
+	require.Equal(t, "/orders/ord_1", resp.Header.Get("Location"))
+
+	resp, err = http.Get(client.URL() + "/orders/latest")
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"ord_1","customer":"jane"}`, string(body))
+
+	state, err := client.State()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"orderID": "ord_1", "customer": "jane"}, state)
+
+	require.NoError(t, client.ClearState("orderID"))
+	resp, err = http.Get(client.URL() + "/orders/latest")
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"","customer":"jane"}`, string(body))
+}
@@ -0,0 +1,165 @@
+package assured
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The headers of Stripe's API requests and webhooks
+const (
+	StripeIdempotencyKeyHeader = "Idempotency-Key"
+	StripeSignatureHeader      = "Stripe-Signature"
+)
+
+// StripeList is the envelope of a page of Stripe's list APIs
+type StripeList struct {
+	Object  string           `json:"object"`
+	URL     string           `json:"url"`
+	HasMore bool             `json:"has_more"`
+	Data    []map[string]any `json:"data"`
+}
+
+// StripeErrorResponse is the JSON error envelope of Stripe's API
+type StripeErrorResponse struct {
+	Error StripeError `json:"error"`
+}
+
+// StripeError is the error of Stripe's JSON error envelope, e.g. a card_error with the code card_declined
+type StripeError struct {
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// StripeListCall returns the stubbed GET call of a Stripe list API, paginating the objects by their id with the starting_after cursor
+// The first page is the call's response, and each following page is a branch requiring the id of the last object of the page before
+func StripeListCall(path string, pageSize int, objects ...map[string]any) Call {
+	if pageSize <= 0 {
+		pageSize = len(objects)
+	}
+	call := Call{
+		Path:       path,
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json", "Request-Id": stripeRequestID()},
+		Response:   stripeListPage(path, objects, 0, pageSize),
+	}
+	for start := pageSize; start < len(objects); start += pageSize {
+		call.Branches = append(call.Branches, Branch{
+			When:     Condition{Query: map[string]string{"starting_after": fmt.Sprint(objects[start-1]["id"])}},
+			Response: stripeListPage(path, objects, start, pageSize),
+		})
+	}
+	return call
+}
+
+// stripeListPage returns the page of the objects starting at the index
+func stripeListPage(path string, objects []map[string]any, start, pageSize int) []byte {
+	end := min(start+pageSize, len(objects))
+	response, _ := json.Marshal(StripeList{
+		Object:  "list",
+		URL:     "/" + strings.Trim(path, "/"),
+		HasMore: end < len(objects),
+		Data:    append([]map[string]any{}, objects[start:end]...),
+	})
+	return response
+}
+
+// StripeErrorCall returns the stubbed call responding with the status code and Stripe's JSON error envelope,
+// e.g. http.StatusPaymentRequired, card_error, card_declined, and Your card was declined.
+func StripeErrorCall(call Call, statusCode int, errorType, code, message string) Call {
+	response, _ := json.Marshal(StripeErrorResponse{Error: StripeError{Type: errorType, Code: code, Message: message}})
+	call.StatusCode = statusCode
+	call.Headers = map[string]string{"Content-Type": "application/json", "Request-Id": stripeRequestID()}
+	call.Response = response
+	return call
+}
+
+// StripeWebhookCallback returns a callback delivering the Stripe event to the target, signed with the webhook endpoint's secret
+// The signature's timestamp is when the callback is created, so it must be delivered within the receiver's tolerance, 5 minutes by default
+func StripeWebhookCallback(target, secret string, event any) Callback {
+	return StripeSkewedWebhookCallback(target, secret, event, 0)
+}
+
+// StripeSkewedWebhookCallback returns a callback delivering the Stripe event to the target, signed with a timestamp skewed from now,
+// to probe the receiver's clock skew tolerance, e.g. -6*time.Minute for a signature the receiver should reject as too old
+func StripeSkewedWebhookCallback(target, secret string, event any, skew time.Duration) Callback {
+	payload, _ := json.Marshal(event)
+	return Callback{
+		Target: target,
+		Method: http.MethodPost,
+		Headers: map[string]string{
+			"Content-Type":        "application/json; charset=utf-8",
+			StripeSignatureHeader: StripeSignature(payload, secret, time.Now().Add(skew)),
+		},
+		Response: payload,
+	}
+}
+
+// ReplayedCallbacks returns the signed callback followed by the replays of its identical delivery, each the interval after the one before,
+// rounded to seconds, to probe the receiver's replay detection, which should reject the replays within its window
+func ReplayedCallbacks(callback Callback, replays int, interval time.Duration) []Callback {
+	callbacks := []Callback{callback}
+	for i := 1; i <= replays; i++ {
+		replay := callback
+		replay.Delay = callback.Delay + i*int(interval.Round(time.Second).Seconds())
+		callbacks = append(callbacks, replay)
+	}
+	return callbacks
+}
+
+// StripeSignature returns the Stripe-Signature header of the webhook payload signed with the secret at the timestamp,
+// t=timestamp,v1=the hex HMAC-SHA256 of timestamp.payload
+func StripeSignature(payload []byte, secret string, timestamp time.Time) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(payload)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// AssertIdempotent reports a test error unless every call made to the method and path had an Idempotency-Key,
+// and every retry of a request, a call with the same body, reused the request's key, while different requests used different keys
+func (c *Client) AssertIdempotent(t testing.TB, method, path string) bool {
+	t.Helper()
+	calls, err := c.Verify(method, strings.Trim(path, "/"))
+	if err != nil {
+		t.Errorf("failed to verify %s:%s: %s", method, path, err)
+		return false
+	}
+	ok := true
+	keys := map[string]string{}
+	bodies := map[string]string{}
+	for i, call := range calls {
+		key := call.Headers[StripeIdempotencyKeyHeader]
+		if key == "" {
+			t.Errorf("expected call %d to %s:%s to have an %s", i, method, path, StripeIdempotencyKeyHeader)
+			ok = false
+			continue
+		}
+		body := string(call.Response)
+		if previous, found := keys[body]; found && previous != key {
+			t.Errorf("expected call %d to %s:%s to retry with %s %q, used %q", i, method, path, StripeIdempotencyKeyHeader, previous, key)
+			ok = false
+		}
+		if previous, found := bodies[key]; found && previous != body {
+			t.Errorf("expected call %d to %s:%s to use a new %s for a different request, reused %q", i, method, path, StripeIdempotencyKeyHeader, key)
+			ok = false
+		}
+		keys[body], bodies[key] = key, body
+	}
+	return ok
+}
+
+// stripeRequestID returns a random request ID, formatted like the request IDs of Stripe's responses
+func stripeRequestID() string {
+	return "req_" + strings.ReplaceAll(AWSRequestID(), "-", "")[:14]
+}
@@ -0,0 +1,149 @@
+package assured
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientStripeList(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(StripeListCall("v1/customers", 2,
+		map[string]any{"id": "cus_1", "object": "customer"},
+		map[string]any{"id": "cus_2", "object": "customer"},
+		map[string]any{"id": "cus_3", "object": "customer"},
+	)))
+
+	var ids []string
+	url := client.URL() + "/v1/customers?limit=2"
+	for {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.True(t, strings.HasPrefix(resp.Header.Get("Request-Id"), "req_"))
+		var page StripeList
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		require.Equal(t, "list", page.Object)
+		require.Equal(t, "/v1/customers", page.URL)
+		for _, object := range page.Data {
+			ids = append(ids, object["id"].(string))
+		}
+		if !page.HasMore {
+			break
+		}
+		url = client.URL() + "/v1/customers?limit=2&starting_after=" + ids[len(ids)-1]
+	}
+	require.Equal(t, []string{"cus_1", "cus_2", "cus_3"}, ids)
+}
+
+func TestClientStripeError(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(StripeErrorCall(Call{Path: "v1/charges", Method: http.MethodPost}, http.StatusPaymentRequired, "card_error", "card_declined", "Your card was declined.")))
+
+	resp, err := http.Post(client.URL()+"/v1/charges", "application/x-www-form-urlencoded", strings.NewReader("amount=2000"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusPaymentRequired, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error":{"type":"card_error","code":"card_declined","message":"Your card was declined."}}`, string(body))
+}
+
+func TestClientStripeWebhook(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer receiver.Close()
+	_, client := NewTestServer(t)
+
+	event := map[string]any{"id": "evt_1", "type": "payment_intent.succeeded"}
+	require.NoError(t, client.Given(Call{
+		Path:      "v1/payment_intents/pi_1/confirm",
+		Method:    http.MethodPost,
+		Callbacks: []Callback{StripeWebhookCallback(receiver.URL, "whsec_test", event)},
+	}))
+	_, err := http.Post(client.URL()+"/v1/payment_intents/pi_1/confirm", "application/x-www-form-urlencoded", nil)
+	require.NoError(t, err)
+
+	select {
+	case r := <-received:
+		body := <-bodies
+		require.JSONEq(t, `{"id":"evt_1","type":"payment_intent.succeeded"}`, string(body))
+		signature := r.Header.Get(StripeSignatureHeader)
+		timestamp, _, _ := strings.Cut(strings.TrimPrefix(signature, "t="), ",")
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		require.NoError(t, err)
+		require.WithinDuration(t, time.Now(), time.Unix(unix, 0), time.Minute)
+		require.Equal(t, StripeSignature(body, "whsec_test", time.Unix(unix, 0)), signature)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+func TestStripeSkewedWebhookCallback(t *testing.T) {
+	event := map[string]any{"id": "evt_1"}
+
+	callback := StripeSkewedWebhookCallback("http://localhost:9000/hooks", "whsec_test", event, -6*time.Minute)
+
+	timestamp, _, _ := strings.Cut(strings.TrimPrefix(callback.Headers[StripeSignatureHeader], "t="), ",")
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(-6*time.Minute), time.Unix(unix, 0), 5*time.Second)
+	require.Equal(t, StripeSignature(callback.Response, "whsec_test", time.Unix(unix, 0)), callback.Headers[StripeSignatureHeader])
+}
+
+func TestReplayedCallbacks(t *testing.T) {
+	callback := StripeWebhookCallback("http://localhost:9000/hooks", "whsec_test", map[string]any{"id": "evt_1"})
+	callback.Delay = 1
+
+	callbacks := ReplayedCallbacks(callback, 2, 30*time.Second)
+
+	require.Len(t, callbacks, 3)
+	for i, replay := range callbacks {
+		require.Equal(t, 1+i*30, replay.Delay)
+		require.Equal(t, callback.Headers, replay.Headers)
+		require.Equal(t, callback.Response, replay.Response)
+	}
+}
+
+func TestStripeSignature(t *testing.T) {
+	signature := StripeSignature([]byte(`{"id":"evt_1"}`), "whsec_test", time.Unix(1700000000, 0))
+	require.Equal(t, "t=1700000000,v1=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925", signature)
+}
+
+func TestClientAssertIdempotent(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{Path: "v1/refunds", Method: http.MethodPost}, Call{Path: "v1/payouts", Method: http.MethodPost}))
+
+	post := func(path, key, body string) {
+		req, err := http.NewRequest(http.MethodPost, client.URL()+"/"+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if key != "" {
+			req.Header.Set(StripeIdempotencyKeyHeader, key)
+		}
+		_, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+	}
+	post("v1/refunds", "key-1", "charge=ch_1")
+	post("v1/refunds", "key-1", "charge=ch_1")
+	post("v1/refunds", "key-2", "charge=ch_2")
+	require.True(t, client.AssertIdempotent(t, http.MethodPost, "v1/refunds"))
+
+	post("v1/payouts", "key-3", "amount=1")
+	post("v1/payouts", "key-4", "amount=1")
+	post("v1/payouts", "key-3", "amount=2")
+	post("v1/payouts", "", "amount=3")
+	mock := &testing.T{}
+	require.False(t, client.AssertIdempotent(mock, http.MethodPost, "v1/payouts"))
+	require.True(t, mock.Failed())
+}
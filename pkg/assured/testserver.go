@@ -0,0 +1,60 @@
+package assured
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/handlers"
+)
+
+// NewTestServer creates a go-rest-assured client served by an httptest.Server
+// The server is closed when the test and all its subtests complete
+func NewTestServer(t testing.TB, opts ...Option) (*httptest.Server, *Client) {
+	return newTestServer(t, false, opts...)
+}
+
+// NewTestTLSServer creates a go-rest-assured client served by an httptest.Server using TLS
+// The client uses the server's http client, which trusts the server's certificate
+func NewTestTLSServer(t testing.TB, opts ...Option) (*httptest.Server, *Client) {
+	return newTestServer(t, true, opts...)
+}
+
+// newTestServer creates a go-rest-assured client and wires its handler into an httptest.Server
+func newTestServer(t testing.TB, tls bool, opts ...Option) (*httptest.Server, *Client) {
+	t.Helper()
+	c := newClient(opts...)
+	if c.err != nil {
+		t.Fatalf("failed to create test server client: %v", c.err)
+	}
+	c.router = c.createApplicationRouter()
+
+	server := httptest.NewUnstartedServer(handlers.RecoveryHandler()(c.router))
+	server.Config.ReadTimeout = c.serverReadTimeout
+	server.Config.WriteTimeout = c.serverWriteTimeout
+	server.Config.IdleTimeout = c.serverIdleTimeout
+	server.Config.ConnContext = withConnection
+	if tls {
+		server.TLS = c.serverTLSConfig()
+		server.StartTLS()
+		// Serve the auto tls certificates instead of the httptest certificate, before any connection is made
+		if c.autoCerts != nil {
+			server.TLS.Certificates = nil
+		}
+		// Trust the server's certificate, with the connection pool and timeout options applied
+		c.httpClient = server.Client()
+		c.httpClient = c.tunedHTTPClient()
+	} else {
+		server.Start()
+	}
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { _ = c.Close() })
+
+	remote, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse test server url: %v", err)
+	}
+	c.setRemote(remote)
+
+	return server, c
+}
@@ -0,0 +1,49 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTestServer(t *testing.T) {
+	server, client := NewTestServer(t)
+
+	require.Equal(t, server.URL+"/when", client.URL())
+	require.NoError(t, client.Given(*testCall1()))
+
+	resp, err := http.Get(client.URL() + "/test/assured?assured=max")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"assured": true}`), body)
+
+	calls, err := client.Verify("GET", "test/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+}
+
+func TestNewTestTLSServer(t *testing.T) {
+	server, client := NewTestTLSServer(t, WithRootServing(true))
+
+	require.Equal(t, server.URL, client.URL())
+	require.NoError(t, client.Given(*testCall1()))
+
+	resp, err := server.Client().Get(client.URL() + "/test/assured?assured=max")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotNil(t, resp.TLS)
+}
+
+func TestNewTestTLSServerTuning(t *testing.T) {
+	server, client := NewTestTLSServer(t, WithServerTimeouts(time.Second, 0, time.Minute), WithConnectionPool(100, 0))
+
+	require.Equal(t, time.Second, server.Config.ReadTimeout)
+	require.Equal(t, time.Minute, server.Config.IdleTimeout)
+	require.Equal(t, 100, client.httpClient.Transport.(*http.Transport).MaxIdleConnsPerHost)
+	require.NoError(t, client.Given(*testCall1()))
+}
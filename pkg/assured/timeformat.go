@@ -0,0 +1,59 @@
+package assured
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The named layouts of FormatTime that are not layouts of the time package
+const (
+	LayoutHTTP        = "HTTP"
+	LayoutEpoch       = "epoch"
+	LayoutEpochMillis = "epoch_millis"
+)
+
+// timeLayouts are the layouts of the time package, by name
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+// FormatTime formats the time in the layout and time zone, for the date values of stubbed responses
+// The layout is the name of a layout of the time package, e.g. RFC3339 or RFC1123, HTTP for the http.TimeFormat of headers in GMT,
+// epoch for Unix seconds, epoch_millis for Unix milliseconds, or else a Go reference layout, e.g. 02 Jan 2006 15:04
+// The zone is an IANA time zone, e.g. America/New_York, UTC, or Local, or empty to keep the time's zone
+func FormatTime(t time.Time, layout, zone string) (string, error) {
+	if zone != "" {
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			return "", fmt.Errorf("invalid time zone %q: %w", zone, err)
+		}
+		t = t.In(location)
+	}
+	switch layout {
+	case LayoutHTTP:
+		return t.UTC().Format(http.TimeFormat), nil
+	case LayoutEpoch:
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case LayoutEpochMillis:
+		return strconv.FormatInt(t.UnixMilli(), 10), nil
+	}
+	if named, ok := timeLayouts[layout]; ok {
+		layout = named
+	}
+	return t.Format(layout), nil
+}
@@ -0,0 +1,42 @@
+package assured
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatTime(t *testing.T) {
+	at := time.Date(2024, time.March, 10, 14, 30, 5, 123456789, time.UTC)
+
+	tests := []struct {
+		name   string
+		layout string
+		zone   string
+		want   string
+	}{
+		{name: "rfc3339", layout: "RFC3339", want: "2024-03-10T14:30:05Z"},
+		{name: "rfc3339 zone", layout: "RFC3339", zone: "America/New_York", want: "2024-03-10T10:30:05-04:00"},
+		{name: "rfc1123 zone", layout: "RFC1123", zone: "Asia/Tokyo", want: "Sun, 10 Mar 2024 23:30:05 JST"},
+		{name: "http", layout: LayoutHTTP, zone: "Europe/Paris", want: "Sun, 10 Mar 2024 14:30:05 GMT"},
+		{name: "epoch", layout: LayoutEpoch, zone: "Asia/Tokyo", want: "1710081005"},
+		{name: "epoch millis", layout: LayoutEpochMillis, want: "1710081005123"},
+		{name: "date only", layout: "DateOnly", zone: "Pacific/Auckland", want: "2024-03-11"},
+		{name: "reference layout", layout: "02 Jan 2006 15:04 MST", zone: "UTC", want: "10 Mar 2024 14:30 UTC"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			formatted, err := FormatTime(at, tc.layout, tc.zone)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, formatted)
+		})
+	}
+}
+
+func TestFormatTimeFailure(t *testing.T) {
+	_, err := FormatTime(time.Now(), "RFC3339", "Mars/Olympus_Mons")
+
+	require.ErrorContains(t, err, `invalid time zone "Mars/Olympus_Mons"`)
+}
@@ -0,0 +1,176 @@
+package assured
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// TLSDetails are the details a call made to a rest assured server serving HTTPS negotiated for its connection
+// The client certificate's subject is only recorded if the client sent a certificate
+type TLSDetails struct {
+	Version       string `json:"version"`
+	CipherSuite   string `json:"cipher_suite"`
+	ServerName    string `json:"server_name,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+	ClientSubject string `json:"client_subject,omitempty"`
+}
+
+// TLSFault breaks the TLS handshake of a rest assured server serving HTTPS, to test how clients handle certificate validation errors
+type TLSFault string
+
+const (
+	// TLSFaultWrongHost serves a certificate for a different hostname than the server's
+	TLSFaultWrongHost TLSFault = "wrong-host"
+	// TLSFaultExpired serves a certificate that has expired
+	TLSFaultExpired TLSFault = "expired"
+	// TLSFaultVersion only negotiates TLS 1.0 and TLS 1.1, which clients reject by default
+	TLSFaultVersion TLSFault = "version"
+)
+
+// tlsFaultHost is the hostname of the certificate served by the wrong host TLS fault
+const tlsFaultHost = "wrong-host.invalid"
+
+// tlsFaultExpiry is how long ago the certificate served by the expired TLS fault expired
+const tlsFaultExpiry = 24 * time.Hour
+
+// tlsDetails returns the details of the TLS connection state, or nil if the call was not made over TLS
+func tlsDetails(state *tls.ConnectionState) *TLSDetails {
+	if state == nil {
+		return nil
+	}
+	details := &TLSDetails{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+		Protocol:    state.NegotiatedProtocol,
+	}
+	if len(state.PeerCertificates) > 0 {
+		details.ClientSubject = state.PeerCertificates[0].Subject.String()
+	}
+	return details
+}
+
+// newTLSFaultCertificate creates the self-signed certificate served with the TLS fault
+// The certificate is valid for the local server, unless the fault breaks its hostname or expiry
+func newTLSFaultCertificate(fault TLSFault) (*tls.Certificate, error) {
+	switch fault {
+	case TLSFaultWrongHost, TLSFaultExpired, TLSFaultVersion:
+	default:
+		return nil, fmt.Errorf("invalid tls fault %q", fault)
+	}
+
+	template := serverCertificateTemplate(time.Now().Add(tlsFaultExpiry))
+	switch fault {
+	case TLSFaultWrongHost:
+		template.DNSNames = []string{tlsFaultHost}
+		template.IPAddresses = nil
+	case TLSFaultExpired:
+		template.NotBefore = time.Now().Add(-2 * tlsFaultExpiry)
+		template.NotAfter = time.Now().Add(-tlsFaultExpiry)
+	}
+
+	return signCertificate(template, nil)
+}
+
+// serverCertificateTemplate returns the template of a certificate for serving the local server until the expiry
+func serverCertificateTemplate(notAfter time.Time) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{Organization: []string{"go-rest-assured"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost", "example.com"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+}
+
+// signCertificate creates a certificate from the template with a new key, signed by the issuer, or self-signed if the issuer is nil
+func signCertificate(template *x509.Certificate, issuer *tls.Certificate) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	parent, signer := template, any(key)
+	if issuer != nil {
+		parent, signer = issuer.Leaf, issuer.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// generateCertificates generates the certificates served with the tls fault or auto tls options
+// The version fault is served with the tls cert, if specified
+func (o *Options) generateCertificates() error {
+	var err error
+	if o.tlsFault != "" && o.autoTLS {
+		return errors.New("tls fault and auto tls cannot be used together")
+	}
+	if o.tlsFault != "" && (o.tlsFault != TLSFaultVersion || o.tlsCertFile == "" || o.tlsKeyFile == "") {
+		o.tlsFaultCert, err = newTLSFaultCertificate(o.tlsFault)
+	}
+	if o.autoTLS {
+		o.autoCerts, err = newAutoCertificates(o.certLifetime)
+	}
+	return err
+}
+
+// serverTLSConfig returns the TLS config for serving HTTPS, which requests, without requiring or verifying,
+// the client's certificate so its subject can be recorded. The TLS fault's certificate and versions, or the current auto TLS certificate, are used, if configured
+func (o *Options) serverTLSConfig() *tls.Config {
+	config := &tls.Config{ClientAuth: tls.RequestClientCert}
+	if o.tlsFaultCert != nil {
+		config.Certificates = []tls.Certificate{*o.tlsFaultCert}
+	}
+	if o.autoCerts != nil {
+		config.GetCertificate = o.autoCerts.getCertificate
+	}
+	if o.tlsFault == TLSFaultVersion {
+		config.MinVersion = tls.VersionTLS10
+		config.MaxVersion = tls.VersionTLS11
+	}
+	return config
+}
+
+// trustServerTLS configures the client's TLS config to trust the generated certificates of its own rest assured server,
+// and to complete the handshake despite the TLS fault, so only the clients under test see the fault
+func (o *Options) trustServerTLS(config *tls.Config) {
+	if o.tlsFaultCert != nil || o.autoCerts != nil {
+		if config.RootCAs == nil {
+			config.RootCAs = x509.NewCertPool()
+		} else {
+			config.RootCAs = config.RootCAs.Clone()
+		}
+	}
+	if o.tlsFaultCert != nil {
+		config.RootCAs.AddCert(o.tlsFaultCert.Leaf)
+	}
+	if o.autoCerts != nil {
+		config.RootCAs.AddCert(o.autoCerts.authority.Leaf)
+	}
+	switch o.tlsFault {
+	case TLSFaultWrongHost:
+		config.ServerName = tlsFaultHost
+	case TLSFaultExpired:
+		config.Time = func() time.Time { return time.Now().Add(-3 * tlsFaultExpiry / 2) }
+	case TLSFaultVersion:
+		config.MinVersion = tls.VersionTLS10
+	}
+}
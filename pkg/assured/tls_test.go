@@ -0,0 +1,162 @@
+package assured
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSDetails(t *testing.T) {
+	require.Nil(t, tlsDetails(nil))
+
+	details := tlsDetails(&tls.ConnectionState{
+		Version:            tls.VersionTLS12,
+		CipherSuite:        tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		ServerName:         "assured.local",
+		NegotiatedProtocol: "http/1.1",
+		PeerCertificates:   []*x509.Certificate{{Subject: pkix.Name{CommonName: "client", Organization: []string{"assured"}}}},
+	})
+
+	require.Equal(t, &TLSDetails{
+		Version:       "TLS 1.2",
+		CipherSuite:   "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		ServerName:    "assured.local",
+		Protocol:      "http/1.1",
+		ClientSubject: "CN=client,O=assured",
+	}, details)
+}
+
+func TestClientTLSDetails(t *testing.T) {
+	server, client := NewTestTLSServer(t)
+	require.NoError(t, client.Given(Call{Path: "secure/assured"}))
+
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.ServerName = "example.com"
+	transport.TLSClientConfig.MaxVersion = tls.VersionTLS12
+	transport.TLSClientConfig.Certificates = []tls.Certificate{testClientCertificate(t, "client")}
+	resp, err := (&http.Client{Transport: transport}).Get(client.URL() + "/secure/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = server.Client().Get(client.URL() + "/secure/assured")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	calls, err := client.Verify(http.MethodGet, "secure/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	require.Equal(t, "TLS 1.2", calls[0].TLS.Version)
+	require.NotEmpty(t, calls[0].TLS.CipherSuite)
+	require.Equal(t, "example.com", calls[0].TLS.ServerName)
+	require.Equal(t, "CN=client", calls[0].TLS.ClientSubject)
+	require.Equal(t, "TLS 1.3", calls[1].TLS.Version)
+	require.Empty(t, calls[1].TLS.ClientSubject)
+}
+
+func TestClientTLSDetailsWithoutTLS(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{Path: "plain/assured"}))
+
+	_, err := http.Get(client.URL() + "/plain/assured")
+	require.NoError(t, err)
+
+	calls, err := client.Verify(http.MethodGet, "plain/assured")
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	require.Nil(t, calls[0].TLS)
+}
+
+func TestClientTLSFault(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault TLSFault
+		check func(t *testing.T, err error)
+	}{
+		{
+			name:  "wrong host",
+			fault: TLSFaultWrongHost,
+			check: func(t *testing.T, err error) {
+				var hostErr x509.HostnameError
+				require.ErrorAs(t, err, &hostErr)
+			},
+		},
+		{
+			name:  "expired",
+			fault: TLSFaultExpired,
+			check: func(t *testing.T, err error) {
+				var invalidErr x509.CertificateInvalidError
+				require.ErrorAs(t, err, &invalidErr)
+				require.Equal(t, x509.Expired, invalidErr.Reason)
+			},
+		},
+		{
+			name:  "version",
+			fault: TLSFaultVersion,
+			check: func(t *testing.T, err error) {
+				require.ErrorContains(t, err, "protocol version")
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, client := NewTestTLSServer(t, WithTLSFault(tc.fault))
+			require.NoError(t, client.Given(Call{Path: "secure/assured"}))
+
+			_, err := server.Client().Get(client.URL() + "/secure/assured")
+			require.Error(t, err)
+			tc.check(t, err)
+
+			calls, err := client.Verify(http.MethodGet, "secure/assured")
+			require.NoError(t, err)
+			require.Empty(t, calls)
+		})
+	}
+}
+
+func TestClientTLSFaultStandalone(t *testing.T) {
+	client := NewClientServe(WithTLSFault(TLSFaultExpired))
+	defer client.Close()
+	time.Sleep(time.Second)
+
+	require.Equal(t, fmt.Sprintf("https://localhost:%d/when", client.Port), client.URL())
+	require.NoError(t, client.Given(Call{Path: "secure/assured"}))
+
+	_, err := http.Get(client.URL() + "/secure/assured")
+	var invalidErr x509.CertificateInvalidError
+	require.ErrorAs(t, err, &invalidErr)
+}
+
+func TestNewTLSFaultCertificateInvalid(t *testing.T) {
+	_, err := newTLSFaultCertificate("unknown")
+	require.EqualError(t, err, `invalid tls fault "unknown"`)
+
+	_, err = NewClientE(WithTLSFault("unknown"))
+	require.EqualError(t, err, `invalid tls fault "unknown"`)
+}
+
+// testClientCertificate creates a self-signed client certificate with the common name
+func testClientCertificate(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
@@ -0,0 +1,178 @@
+package assured
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The tus resumable upload protocol version, extensions, and content type the tus upload endpoints support
+const (
+	TusVersion     = "1.0.0"
+	TusExtensions  = "creation,creation-defer-length,creation-with-upload,termination"
+	TusContentType = "application/offset+octet-stream"
+)
+
+// tusUpload is a resumable upload of a tus upload endpoint, with a length of -1 while it is deferred
+type tusUpload struct {
+	length   int64
+	metadata string
+	content  []byte
+}
+
+// tusUploads are the resumable uploads of the tus upload endpoints, by path
+type tusUploads struct {
+	uploads map[string]*tusUpload
+	sync.Mutex
+}
+
+// tusCall mocks the tus resumable upload protocol for the call made to an upload endpoint or an upload, e.g. POST /when/files
+// An upload is created with its Upload-Length, or a deferred length, and its Location is relative to the endpoint,
+// so it resolves for the stubbed calls served with or without the /when prefix. Its offset is queried with HEAD,
+// and its content is appended with PATCH at the offset, until it is complete. GET serves the content uploaded so far
+// Returns false if the call is not made to an upload endpoint
+func (a *AssuredEndpoints) tusCall(call *Call) (*Call, bool) {
+	endpoint, id := "", ""
+	for _, path := range a.tusEndpoints {
+		path = strings.Trim(path, "/")
+		if call.Path == path {
+			endpoint = path
+			break
+		}
+		if rest, ok := strings.CutPrefix(call.Path, path+"/"); ok && rest != "" && !strings.Contains(rest, "/") {
+			endpoint, id = path, rest
+			break
+		}
+	}
+	if endpoint == "" {
+		return nil, false
+	}
+	response := &Call{Path: call.Path, Method: call.Method, StatusCode: http.StatusNoContent, Headers: map[string]string{"Tus-Resumable": TusVersion}}
+
+	if call.Method == http.MethodOptions {
+		response.Headers["Tus-Version"] = TusVersion
+		response.Headers["Tus-Extension"] = TusExtensions
+		return response, true
+	}
+	if call.Method != http.MethodGet && call.Headers["Tus-Resumable"] != TusVersion {
+		response.StatusCode = http.StatusPreconditionFailed
+		response.Headers["Tus-Version"] = TusVersion
+		return response, true
+	}
+	if id == "" {
+		if call.Method != http.MethodPost {
+			response.StatusCode = http.StatusMethodNotAllowed
+			return response, true
+		}
+		return a.tusCreate(call, endpoint, response), true
+	}
+
+	a.tusUploads.Lock()
+	defer a.tusUploads.Unlock()
+	upload, ok := a.tusUploads.uploads[call.Path]
+	if !ok {
+		response.StatusCode = http.StatusNotFound
+		return response, true
+	}
+	switch call.Method {
+	case http.MethodHead:
+		response.StatusCode = http.StatusOK
+		response.Headers["Cache-Control"] = "no-store"
+		upload.headers(response.Headers)
+	case http.MethodGet:
+		response.StatusCode = http.StatusOK
+		response.Headers["Content-Type"] = "application/octet-stream"
+		response.Headers["Upload-Offset"] = strconv.Itoa(len(upload.content))
+		response.Response = slices.Clone(upload.content)
+	case http.MethodPatch:
+		if status := upload.append(call, call.Headers["Upload-Offset"]); status != 0 {
+			response.StatusCode = status
+		}
+		response.Headers["Upload-Offset"] = strconv.Itoa(len(upload.content))
+	case http.MethodDelete:
+		delete(a.tusUploads.uploads, call.Path)
+	default:
+		response.StatusCode = http.StatusMethodNotAllowed
+	}
+	return response, true
+}
+
+// tusCreate creates an upload of the upload endpoint, with the content of the call made if it has the tus content type
+func (a *AssuredEndpoints) tusCreate(call *Call, endpoint string, response *Call) *Call {
+	upload := &tusUpload{length: -1, metadata: call.Headers["Upload-Metadata"]}
+	if length := call.Headers["Upload-Length"]; length != "" {
+		var err error
+		if upload.length, err = strconv.ParseInt(length, 10, 64); err != nil || upload.length < 0 {
+			response.StatusCode = http.StatusBadRequest
+			return response
+		}
+	} else if call.Headers["Upload-Defer-Length"] != "1" {
+		response.StatusCode = http.StatusBadRequest
+		return response
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	id := hex.EncodeToString(b)
+	a.tusUploads.Lock()
+	defer a.tusUploads.Unlock()
+	if a.tusUploads.uploads == nil {
+		a.tusUploads.uploads = map[string]*tusUpload{}
+	}
+	a.tusUploads.uploads[endpoint+"/"+id] = upload
+
+	response.StatusCode = http.StatusCreated
+	// The location is relative to the endpoint's last path segment, so it resolves against the endpoint's URL
+	response.Headers["Location"] = endpoint[strings.LastIndex(endpoint, "/")+1:] + "/" + id
+	if len(call.Response) > 0 {
+		if status := upload.append(call, "0"); status != 0 {
+			response.StatusCode = status
+			return response
+		}
+		response.Headers["Upload-Offset"] = strconv.Itoa(len(upload.content))
+	}
+	return response
+}
+
+// headers sets the tus headers of the upload's offset, length, and metadata
+func (u *tusUpload) headers(headers map[string]string) {
+	headers["Upload-Offset"] = strconv.Itoa(len(u.content))
+	if u.length < 0 {
+		headers["Upload-Defer-Length"] = "1"
+	} else {
+		headers["Upload-Length"] = strconv.FormatInt(u.length, 10)
+	}
+	if u.metadata != "" {
+		headers["Upload-Metadata"] = u.metadata
+	}
+}
+
+// append appends the content of the call made to the upload at the offset, returning the failure status code, if any
+// The offset must be the upload's offset, and the content must not exceed the upload's length
+func (u *tusUpload) append(call *Call, uploadOffset string) int {
+	if call.Headers["Content-Type"] != TusContentType {
+		return http.StatusUnsupportedMediaType
+	}
+	offset, err := strconv.Atoi(uploadOffset)
+	if err != nil || offset < 0 {
+		return http.StatusBadRequest
+	}
+	if offset != len(u.content) {
+		return http.StatusConflict
+	}
+	if length := call.Headers["Upload-Length"]; length != "" && u.length < 0 {
+		if u.length, err = strconv.ParseInt(length, 10, 64); err != nil || u.length < 0 {
+			u.length = -1
+			return http.StatusBadRequest
+		}
+	}
+	if u.length >= 0 && int64(len(u.content)+len(call.Response)) > u.length {
+		return http.StatusRequestEntityTooLarge
+	}
+	u.content = append(u.content, call.Response...)
+	return 0
+}
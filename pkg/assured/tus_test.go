@@ -0,0 +1,143 @@
+package assured
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientTus(t *testing.T) {
+	_, client := NewTestServer(t, WithTusEndpoints("api/files"))
+	endpoint := client.URL() + "/api/files"
+
+	resp, err := tusDo(t, http.MethodOptions, endpoint, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, TusVersion, resp.Header.Get("Tus-Version"))
+	require.Equal(t, TusExtensions, resp.Header.Get("Tus-Extension"))
+
+	resp, err = tusDo(t, http.MethodPost, endpoint, "", map[string]string{"Upload-Length": "11", "Upload-Metadata": "filename aGVsbG8udHh0"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, TusVersion, resp.Header.Get("Tus-Resumable"))
+	// The location resolves against the endpoint's URL, like tus clients resolve it
+	base, err := url.Parse(endpoint)
+	require.NoError(t, err)
+	location, err := base.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(location.String(), endpoint+"/"), location.String())
+	upload := location.String()
+
+	resp, err = tusDo(t, http.MethodPatch, upload, "hello", map[string]string{"Upload-Offset": "0", "Content-Type": TusContentType})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, "5", resp.Header.Get("Upload-Offset"))
+
+	// Resume the upload from the offset the server has, after a stale offset is rejected
+	resp, err = tusDo(t, http.MethodPatch, upload, "hello world", map[string]string{"Upload-Offset": "0", "Content-Type": TusContentType})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp, err = tusDo(t, http.MethodHead, upload, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "5", resp.Header.Get("Upload-Offset"))
+	require.Equal(t, "11", resp.Header.Get("Upload-Length"))
+	require.Equal(t, "filename aGVsbG8udHh0", resp.Header.Get("Upload-Metadata"))
+	require.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+	resp, err = tusDo(t, http.MethodPatch, upload, " world!", map[string]string{"Upload-Offset": "5", "Content-Type": TusContentType})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	resp, err = tusDo(t, http.MethodPatch, upload, " world", map[string]string{"Upload-Offset": "5", "Content-Type": TusContentType})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, "11", resp.Header.Get("Upload-Offset"))
+
+	resp, err = http.Get(upload)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(body))
+
+	calls, err := client.Verify(http.MethodPatch, strings.TrimPrefix(upload, client.URL()+"/"))
+	require.NoError(t, err)
+	require.Len(t, calls, 4)
+
+	resp, err = tusDo(t, http.MethodDelete, upload, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, err = tusDo(t, http.MethodHead, upload, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestClientTusCreation(t *testing.T) {
+	_, client := NewTestServer(t, WithTusEndpoints("files"))
+	endpoint := client.URL() + "/files"
+
+	tests := []struct {
+		name       string
+		body       string
+		headers    map[string]string
+		statusCode int
+		offset     string
+		length     string
+	}{
+		{name: "deferred length", headers: map[string]string{"Upload-Defer-Length": "1"}, statusCode: http.StatusCreated, offset: "0"},
+		{name: "with upload", body: "abc", headers: map[string]string{"Upload-Length": "5", "Content-Type": TusContentType}, statusCode: http.StatusCreated, offset: "3", length: "5"},
+		{name: "missing length", statusCode: http.StatusBadRequest},
+		{name: "invalid length", headers: map[string]string{"Upload-Length": "-1"}, statusCode: http.StatusBadRequest},
+		{name: "unsupported version", headers: map[string]string{"Tus-Resumable": "0.2.2", "Upload-Length": "1"}, statusCode: http.StatusPreconditionFailed},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := tusDo(t, http.MethodPost, endpoint, tc.body, tc.headers)
+			require.NoError(t, err)
+			require.Equal(t, tc.statusCode, resp.StatusCode)
+			if tc.statusCode != http.StatusCreated {
+				return
+			}
+
+			upload := endpoint + "/" + strings.TrimPrefix(resp.Header.Get("Location"), "files/")
+			resp, err = tusDo(t, http.MethodHead, upload, "", nil)
+			require.NoError(t, err)
+			require.Equal(t, tc.offset, resp.Header.Get("Upload-Offset"))
+			require.Equal(t, tc.length, resp.Header.Get("Upload-Length"))
+		})
+	}
+
+	// A deferred length is set by a later PATCH
+	resp, err := tusDo(t, http.MethodPost, endpoint, "", map[string]string{"Upload-Defer-Length": "1"})
+	require.NoError(t, err)
+	upload := endpoint + "/" + strings.TrimPrefix(resp.Header.Get("Location"), "files/")
+	resp, err = tusDo(t, http.MethodPatch, upload, "abc", map[string]string{"Upload-Offset": "0", "Upload-Length": "3", "Content-Type": TusContentType})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, err = tusDo(t, http.MethodPatch, upload, "d", map[string]string{"Upload-Offset": "3", "Content-Type": "text/plain"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	resp, err = tusDo(t, http.MethodHead, upload, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, "3", resp.Header.Get("Upload-Length"))
+	require.Empty(t, resp.Header.Get("Upload-Defer-Length"))
+
+	resp, err = tusDo(t, http.MethodPut, endpoint, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+// tusDo makes the tus request to the tus mock with the body and headers
+func tusDo(t *testing.T, method, url, body string, headers map[string]string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Tus-Resumable", TusVersion)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return http.DefaultClient.Do(req)
+}
@@ -0,0 +1,36 @@
+package assured
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// writeCall writes the stubbed call as the response, hardened against the raw and malformed responses a call can be stubbed with,
+// so no stubbed call can panic the server. net/http panics writing a status code outside 100-999, so a call stubbed with one
+// responds 500 Internal Server Error instead, and header fields net/http can't write are dropped by net/http
+func writeCall(w http.ResponseWriter, call *Call) {
+	inform(w, call.Informational)
+	header := w.Header()
+	for key, value := range call.Headers {
+		if !strings.HasPrefix(key, "Assured-") || key == AssuredTraceMatch || key == AssuredTraceCandidates {
+			setHeader(header, key, value, call.RawHeaders)
+		}
+	}
+	addHeaders(header, call.ResponseHeaders, call.RawHeaders)
+	if !validStatus(call.StatusCode) {
+		for key := range header {
+			delete(header, key)
+		}
+		http.Error(w, fmt.Sprintf("invalid stubbed status code %d", call.StatusCode), http.StatusInternalServerError)
+		return
+	}
+	frame(header, call.Framing, call.Response)
+	w.WriteHeader(call.StatusCode)
+	_, _ = w.Write(call.Response)
+}
+
+// validStatus reports whether net/http can write the status code, a three-digit code
+func validStatus(statusCode int) bool {
+	return statusCode >= 100 && statusCode <= 999
+}
@@ -0,0 +1,96 @@
+package assured
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCallInvalidStatus(t *testing.T) {
+	for _, statusCode := range []int{0, 5, 99, 1000, -200} {
+		w := httptest.NewRecorder()
+
+		writeCall(w, &Call{StatusCode: statusCode, Headers: map[string]string{"X-Stubbed": "true"}, Response: []byte("stubbed")})
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Empty(t, w.Header().Get("X-Stubbed"))
+		require.Contains(t, w.Body.String(), "invalid stubbed status code")
+	}
+}
+
+func TestWriteCallInvalidInformational(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	writeCall(w, &Call{StatusCode: http.StatusOK, Informational: []Informational{{StatusCode: 42}, {StatusCode: http.StatusSwitchingProtocols}}, Response: []byte("stubbed")})
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "stubbed", w.Body.String())
+}
+
+func TestApplicationRouterInvalidStatus(t *testing.T) {
+	_, client := NewTestServer(t)
+	require.NoError(t, client.Given(Call{Path: "invalid", Method: http.MethodGet, StatusCode: 5}))
+
+	resp, err := http.Get(client.URL() + "/invalid")
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func FuzzWriteCall(f *testing.F) {
+	f.Add(200, "Content-Type", "application/json", false, "", 0, []byte(`{"ok":true}`))
+	f.Add(0, "content-type", "text/plain", true, FramingChunked, 103, []byte("raw"))
+	f.Add(1000, "Bad Name", "line\r\nbreak", true, FramingClose, 101, []byte{})
+	f.Add(204, "Content-Length", "-1", false, FramingContentLength, 42, []byte("no content"))
+	f.Add(101, "Transfer-Encoding", "gzip", true, "", 199, []byte(nil))
+	f.Fuzz(func(t *testing.T, statusCode int, key, value string, raw bool, framing string, informational int, body []byte) {
+		call := &Call{
+			StatusCode:      statusCode,
+			Headers:         map[string]string{key: value},
+			ResponseHeaders: []Header{{Name: key, Value: value}},
+			RawHeaders:      raw,
+			Framing:         framing,
+			Informational:   []Informational{{StatusCode: informational, Headers: map[string]string{key: value}}},
+			Response:        body,
+		}
+		w := httptest.NewRecorder()
+
+		writeCall(w, call)
+
+		require.True(t, validStatus(w.Code))
+	})
+}
+
+func FuzzDecodeAssuredCall(f *testing.F) {
+	headers := []string{
+		AssuredStatus, AssuredStatusSequence, AssuredMethod, AssuredDelay, AssuredConcurrency, AssuredMaxBodySize,
+		AssuredMaxHeaderSize, AssuredMaxURILength, AssuredCallbackKey, AssuredCallbackTarget, AssuredCallbackDelay,
+		AssuredBranches, AssuredBreaker, AssuredHandshake, AssuredSession, AssuredCSRF, AssuredGraphQL, AssuredLocale,
+		AssuredExtract, AssuredBatch, AssuredJob, AssuredSignedURL, AssuredCache, AssuredPadding, AssuredFraming,
+		AssuredInformational, AssuredResponseHeaders, AssuredRawHeaders, AssuredTrace,
+	}
+	f.Add(uint8(0), "5", []byte("body"))
+	f.Add(uint8(1), "200, 500,", []byte{})
+	f.Add(uint8(11), `[{"when":{"headers":{"a":"b"}},"status_code":-1}]`, []byte{})
+	f.Add(uint8(16), "type Query { a: String", []byte{})
+	f.Add(uint8(18), `{"a":{"json_path":"$[","regex":"("}}`, []byte{})
+	f.Add(uint8(25), `[{"status_code":1000}]`, []byte{})
+	f.Fuzz(func(t *testing.T, header uint8, value string, body []byte) {
+		req := httptest.NewRequest(http.MethodPost, "/given/fuzz", strings.NewReader(string(body)))
+		req.Header.Set(headers[int(header)%len(headers)], value)
+
+		call, err := decodeAssuredCall(context.Background(), req)
+		if err != nil {
+			return
+		}
+
+		w := httptest.NewRecorder()
+		writeCall(w, call.(*Call))
+
+		require.True(t, validStatus(w.Code))
+	})
+}
@@ -0,0 +1,97 @@
+package assuredcontainer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jesse0michael/go-rest-assured/v4/pkg/assured"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	// DefaultImage is the standalone go rest assured image
+	DefaultImage = "docker.pkg.github.com/jesse0michael/go-rest-assured/assured:latest"
+
+	port        = "8080/tcp"
+	preloadPath = "/stubs"
+)
+
+// Container is a running standalone go rest assured container with a client connected to it
+type Container struct {
+	testcontainers.Container
+	Client *assured.Client
+}
+
+// Option is a function that configures the go rest assured container request
+type Option func(*testcontainers.ContainerRequest)
+
+// WithImage sets the image of the container.
+func WithImage(image string) Option {
+	return func(req *testcontainers.ContainerRequest) {
+		req.Image = image
+	}
+}
+
+// WithPreload copies the preload file, or directory of files, into the container and preloads the calls.
+func WithPreload(path string) Option {
+	return func(req *testcontainers.ContainerRequest) {
+		req.Files = append(req.Files, testcontainers.ContainerFile{
+			HostFilePath:      path,
+			ContainerFilePath: preloadPath,
+			FileMode:          0o755,
+		})
+		req.Env["ASSURED_PRELOAD"] = preloadPath
+	}
+}
+
+// WithEnv sets an environment variable on the container, such as ASSURED_TRACK, except ASSURED_PORT.
+func WithEnv(key, value string) Option {
+	return func(req *testcontainers.ContainerRequest) {
+		req.Env[key] = value
+	}
+}
+
+// request returns the request for the standalone go rest assured container configured by the options
+// The container always serves on the exposed port, which is mapped to a random host port, so ASSURED_PORT can't be overridden
+func request(opts ...Option) testcontainers.ContainerRequest {
+	req := testcontainers.ContainerRequest{
+		Image:        DefaultImage,
+		ExposedPorts: []string{port},
+		Env:          map[string]string{},
+		WaitingFor:   wait.ForHTTP("/health").WithPort(port),
+	}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	req.Env["ASSURED_PORT"] = "8080"
+	return req
+}
+
+// Run starts the standalone go rest assured image and returns a client connected to it
+// Terminate the container when finished with it
+func Run(ctx context.Context, opts ...Option) (*Container, error) {
+	req := request(opts...)
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start go rest assured container: %w", err)
+	}
+
+	endpoint, err := container.PortEndpoint(ctx, port, "http")
+	if err != nil {
+		_ = container.Terminate(ctx)
+		return nil, err
+	}
+
+	client, err := assured.NewRemoteClient(endpoint)
+	if err != nil {
+		_ = container.Terminate(ctx)
+		return nil, err
+	}
+
+	return &Container{Container: container, Client: client}, nil
+}
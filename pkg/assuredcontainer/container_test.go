@@ -0,0 +1,32 @@
+package assuredcontainer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestRequest(t *testing.T) {
+	req := request()
+
+	require.Equal(t, DefaultImage, req.Image)
+	require.Equal(t, []string{"8080/tcp"}, req.ExposedPorts)
+	require.Equal(t, map[string]string{"ASSURED_PORT": "8080"}, req.Env)
+	require.Empty(t, req.Files)
+	require.NotNil(t, req.WaitingFor)
+}
+
+func TestRequestOptions(t *testing.T) {
+	req := request(
+		WithImage("go-rest-assured:test"),
+		WithPreload("testdata/stubs"),
+		WithEnv("ASSURED_TRACK", "false"),
+		WithEnv("ASSURED_PORT", "9090"),
+	)
+
+	require.Equal(t, "go-rest-assured:test", req.Image)
+	require.Equal(t, []string{"8080/tcp"}, req.ExposedPorts)
+	require.Equal(t, []testcontainers.ContainerFile{{HostFilePath: "testdata/stubs", ContainerFilePath: "/stubs", FileMode: 0o755}}, req.Files)
+	require.Equal(t, map[string]string{"ASSURED_PORT": "8080", "ASSURED_PRELOAD": "/stubs", "ASSURED_TRACK": "false"}, req.Env)
+}